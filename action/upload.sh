@@ -172,9 +172,10 @@ meta = {
     "repo_full_name": req("FG_META_REPO_FULL_NAME"),
     "workflow_name": req("FG_META_WORKFLOW_NAME"),
     "workflow_ref": req("FG_META_WORKFLOW_REF"),
-    "github_run_id": int(req("FG_META_GITHUB_RUN_ID")),
-    "github_run_attempt": int(req("FG_META_GITHUB_RUN_ATTEMPT")),
-    "github_run_number": int(req("FG_META_GITHUB_RUN_NUMBER")),
+    "provider": "github",
+    "run_id": req("FG_META_GITHUB_RUN_ID"),
+    "run_attempt": int(req("FG_META_GITHUB_RUN_ATTEMPT")),
+    "run_number": req("FG_META_GITHUB_RUN_NUMBER"),
     "run_url": req("FG_META_RUN_URL"),
     "sha": req("FG_META_SHA"),
     "branch": req("FG_META_BRANCH"),
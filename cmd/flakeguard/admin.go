@@ -12,6 +12,9 @@ import (
 	"time"
 
 	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -24,6 +27,8 @@ func runAdmin(args []string) int {
 	switch args[0] {
 	case "reset-password":
 		return runResetPassword(args[1:])
+	case "backfill-trends":
+		return runBackfillTrends(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown admin command: %s\n", args[0])
 		printAdminUsage()
@@ -34,9 +39,11 @@ func runAdmin(args []string) int {
 func printAdminUsage() {
 	fmt.Fprintln(os.Stderr, "Usage:")
 	fmt.Fprintln(os.Stderr, "  flakeguard admin reset-password --email user@example.com [--password <new>] [--db-dsn <dsn>]")
+	fmt.Fprintln(os.Stderr, "  flakeguard admin backfill-trends [--project <project_id>] [--db-dsn <dsn>]")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Notes:")
 	fmt.Fprintln(os.Stderr, "  - If --password is omitted, a random password is generated and printed.")
+	fmt.Fprintln(os.Stderr, "  - backfill-trends rebuilds the daily trend rollup of one or all projects from stored results.")
 	fmt.Fprintln(os.Stderr, "  - --db-dsn defaults to FG_DB_DSN.")
 }
 
@@ -123,6 +130,76 @@ func runResetPassword(args []string) int {
 	return 0
 }
 
+func runBackfillTrends(args []string) int {
+	fs := flag.NewFlagSet("backfill-trends", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var project string
+	var dbDSN string
+
+	fs.StringVar(&project, "project", "", "Project ID (defaults to all projects)")
+	fs.StringVar(&dbDSN, "db-dsn", "", "Postgres DSN (defaults to FG_DB_DSN)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var projectIDs []uuid.UUID
+	if project = strings.TrimSpace(project); project != "" {
+		projectID, err := uuid.Parse(project)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "--project must be a project ID")
+			return 2
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	if dbDSN == "" {
+		dbDSN = strings.TrimSpace(os.Getenv("FG_DB_DSN"))
+	}
+	if dbDSN == "" {
+		fmt.Fprintln(os.Stderr, "--db-dsn is required (or set FG_DB_DSN)")
+		return 2
+	}
+
+	// No timeout: backfilling a large project scans all of its results
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, dbDSN)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+
+	if len(projectIDs) == 0 {
+		rows, err := pool.Query(ctx, `SELECT id FROM projects ORDER BY created_at`)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list projects: %v\n", err)
+			return 1
+		}
+		projectIDs, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list projects: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, projectID := range projectIDs {
+		written, err := flake.BackfillDailyStats(ctx, pool, projectID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to backfill project %s: %v\n", projectID, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "Project %s: %d daily rows written.\n", projectID, written)
+	}
+
+	return 0
+}
+
 func generatePassword(bytesLen int) (string, error) {
 	if bytesLen < 8 {
 		bytesLen = 8
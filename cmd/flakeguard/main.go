@@ -7,11 +7,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/aliuyar1234/flakeguard/internal/app"
 	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/digest"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
 	"github.com/aliuyar1234/flakeguard/internal/retention"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -22,12 +27,18 @@ import (
 const (
 	retentionJunitDays  = 30
 	retentionEventsDays = 180
+
+	// rescoreInterval is how old a flake score may get before it is rescored
+	rescoreInterval = 24 * time.Hour
 )
 
 func main() {
 	if len(os.Args) > 1 && os.Args[1] == "admin" {
 		os.Exit(runAdmin(os.Args[2:]))
 	}
+	if len(os.Args) > 1 && os.Args[1] == "upload" {
+		os.Exit(runUpload(os.Args[2:]))
+	}
 
 	_ = godotenv.Load()
 
@@ -44,14 +55,16 @@ func main() {
 		os.Exit(1)
 	}
 
-	cronScheduler, err := setupRetentionCron(cfg, application.DB)
+	cronScheduler, err := setupCron(cfg, application.DB)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to setup retention cron: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to setup cron: %v\n", err)
 		os.Exit(1)
 	}
 	cronScheduler.Start()
 	defer cronScheduler.Stop()
 
+	stopWorkers := startWorkers(cfg, application.DB)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
@@ -68,6 +81,7 @@ func main() {
 		}
 	case sig := <-sigChan:
 		log.Info().Str("signal", sig.String()).Msg("Received shutdown signal")
+		stopWorkers()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := application.Shutdown(shutdownCtx); err != nil {
@@ -77,7 +91,7 @@ func main() {
 	}
 }
 
-func setupRetentionCron(cfg *config.Config, pool *pgxpool.Pool) (*cron.Cron, error) {
+func setupCron(cfg *config.Config, pool *pgxpool.Pool) (*cron.Cron, error) {
 	c := cron.New(cron.WithLocation(time.UTC))
 
 	schedule := "0 3 * * *"
@@ -101,5 +115,84 @@ func setupRetentionCron(cfg *config.Config, pool *pgxpool.Pool) (*cron.Cron, err
 		return nil, fmt.Errorf("failed to schedule retention job: %w", err)
 	}
 
+	// Digests are generated once per period; checking hourly catches up after downtime
+	digestSchedule := "0 * * * *"
+	if cfg.IsDev() {
+		digestSchedule = "* * * * *"
+	}
+
+	_, err = c.AddFunc(digestSchedule, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().Interface("panic", r).Msg("Digest job panicked")
+			}
+		}()
+
+		ctx := context.Background()
+		if err := digest.RunDigestJob(ctx, pool, cfg.BaseURL, time.Now()); err != nil {
+			log.Error().Err(err).Msg("Digest job failed")
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule digest job: %w", err)
+	}
+
+	// Flake scores decay with time; rescoring hourly keeps tests that stopped
+	// running from holding their rank
+	rescoreSchedule := "30 * * * *"
+	if cfg.IsDev() {
+		rescoreSchedule = "* * * * *"
+	}
+
+	_, err = c.AddFunc(rescoreSchedule, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().Interface("panic", r).Msg("Flake rescore job panicked")
+			}
+		}()
+
+		ctx := context.Background()
+		rescored, err := flake.RescoreStale(ctx, pool, time.Now().Add(-rescoreInterval))
+		if err != nil {
+			log.Error().Err(err).Msg("Flake rescore job failed")
+			return
+		}
+		log.Info().Int("rescored_tests", rescored).Msg("Flake rescore job complete")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule flake rescore job: %w", err)
+	}
+
 	return c, nil
 }
+
+// startWorkers runs cfg.IngestWorkers queue workers, plus one notification
+// outbox worker when any ingestion worker runs, and returns a function that
+// stops them and waits for in-flight jobs to be put back.
+func startWorkers(cfg *config.Config, pool *pgxpool.Pool) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	for i := 0; i < cfg.IngestWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ingest.NewWorker(pool, cfg).Run(ctx)
+		}()
+	}
+	log.Info().Int("workers", cfg.IngestWorkers).Msg("Ingestion workers started")
+
+	if cfg.IngestWorkers > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notify.NewWorker(pool, cfg).Run(ctx)
+		}()
+		log.Info().Msg("Notification worker started")
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
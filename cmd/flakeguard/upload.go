@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/uploader"
+)
+
+func printUploadUsage(fs *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  flakeguard upload [flags] <report-glob>...")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Example:")
+	fmt.Fprintln(os.Stderr, "  flakeguard upload --url https://flakeguard.example.com --project my-service 'build/test-results/**/*.xml'")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Notes:")
+	fmt.Fprintln(os.Stderr, "  - Run metadata is detected from GitHub Actions, GitLab CI, Jenkins, CircleCI,")
+	fmt.Fprintln(os.Stderr, "    Buildkite and Azure Pipelines; elsewhere the local git checkout is used.")
+	fmt.Fprintln(os.Stderr, "    Metadata flags override detected values.")
+	fmt.Fprintln(os.Stderr, "  - --url, --api-key and --project default to FLAKEGUARD_URL, FLAKEGUARD_API_KEY")
+	fmt.Fprintln(os.Stderr, "    and FLAKEGUARD_PROJECT.")
+	fmt.Fprintln(os.Stderr, "  - Reports are gzip-compressed; 429 and 5xx responses are retried.")
+	fmt.Fprintln(os.Stderr, "  - --codeowners replaces the project's CODEOWNERS, used to assign owners to tests.")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fs.PrintDefaults()
+}
+
+func runUpload(args []string) int {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() { printUploadUsage(fs) }
+
+	var (
+		baseURL     string
+		apiKey      string
+		provider    string
+		retries     int
+		wait        bool
+		waitTimeout time.Duration
+		dryRun      bool
+		prNumber    int64
+		codeowners  string
+	)
+	var meta ingest.IngestionMetadata
+
+	fs.StringVar(&baseURL, "url", os.Getenv("FLAKEGUARD_URL"), "FlakeGuard base URL")
+	fs.StringVar(&apiKey, "api-key", "", "API key with ingest:write scope (defaults to FLAKEGUARD_API_KEY)")
+	fs.StringVar(&meta.ProjectSlug, "project", os.Getenv("FLAKEGUARD_PROJECT"), "Project slug")
+	fs.StringVar(&provider, "provider", "auto", "CI provider: auto, "+joinProviders())
+	fs.StringVar(&meta.Format, "format", "", "Report format (default: auto-detect per file)")
+	fs.StringVar(&meta.JobVariant, "job-variant", "", "Job variant, e.g. a matrix combination")
+	fs.StringVar(&meta.RepoFullName, "repo", "", "Repository (owner/repo)")
+	fs.StringVar(&meta.WorkflowName, "workflow", "", "Workflow or pipeline name")
+	fs.StringVar(&meta.WorkflowRef, "workflow-ref", "", "Workflow or pipeline definition path")
+	fs.StringVar(&meta.RunID, "run-id", "", "Provider run ID")
+	fs.IntVar(&meta.RunAttempt, "run-attempt", 0, "Attempt number within the run")
+	fs.StringVar(&meta.AttemptID, "attempt-id", "", "Provider attempt ID, numbered by the server when --run-attempt is unset")
+	fs.StringVar(&meta.RunNumber, "run-number", "", "Provider run number for display")
+	fs.StringVar(&meta.RunURL, "run-url", "", "Run URL")
+	fs.StringVar(&meta.SHA, "sha", "", "Commit SHA")
+	fs.StringVar(&meta.Branch, "branch", "", "Branch")
+	fs.StringVar(&meta.Event, "event", "", "Triggering event (push, pull_request, schedule, ...)")
+	fs.Int64Var(&prNumber, "pr", 0, "Pull/merge request number")
+	fs.StringVar(&meta.JobName, "job", "", "Job name")
+	fs.StringVar(&meta.StartedAt, "started-at", "", "Job start time, RFC3339 (default: oldest report modification time)")
+	fs.StringVar(&meta.CompletedAt, "completed-at", "", "Job completion time, RFC3339 (default: now)")
+	fs.IntVar(&retries, "retries", uploader.DefaultMaxRetries, "Retries on network errors, 429 and 5xx responses")
+	fs.BoolVar(&wait, "wait", false, "Wait until the upload has been processed")
+	fs.DurationVar(&waitTimeout, "wait-timeout", 5*time.Minute, "Maximum time to wait with --wait")
+	fs.BoolVar(&dryRun, "dry-run", false, "Print the metadata and matched files without uploading")
+	fs.StringVar(&codeowners, "codeowners", "", "CODEOWNERS file to send with the reports, e.g. .github/CODEOWNERS")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if apiKey == "" {
+		apiKey = strings.TrimSpace(os.Getenv("FLAKEGUARD_API_KEY"))
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "At least one report path or glob is required")
+		return 2
+	}
+	if !dryRun && (baseURL == "" || apiKey == "") {
+		fmt.Fprintln(os.Stderr, "--url and --api-key are required (or set FLAKEGUARD_URL and FLAKEGUARD_API_KEY)")
+		return 2
+	}
+
+	detected := uploader.DetectProvider(os.Getenv)
+	if provider != "auto" {
+		detected = uploader.Provider(provider)
+	}
+	envMeta, err := uploader.MetadataFromEnv(detected, os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --provider: %v\n", err)
+		return 2
+	}
+
+	files, err := uploader.ExpandGlobs(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find reports: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		// Matches the GitHub Action: a job without reports is not an error
+		fmt.Fprintf(os.Stderr, "Warning: no report files matched %s\n", strings.Join(fs.Args(), " "))
+		return 0
+	}
+
+	// Flags win over detected values
+	mergeMetadata(&meta, envMeta)
+	if prNumber > 0 {
+		meta.PRNumber = &prNumber
+	}
+	if meta.StartedAt == "" {
+		meta.StartedAt = oldestModTime(files).UTC().Format(time.RFC3339)
+	}
+	if meta.CompletedAt == "" {
+		meta.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if err := meta.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Incomplete run metadata (provider %s): %v\n", detected, err)
+		return 2
+	}
+
+	if codeowners != "" {
+		if _, err := os.Stat(codeowners); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --codeowners: %v\n", err)
+			return 2
+		}
+	}
+
+	if dryRun {
+		out, _ := json.MarshalIndent(&meta, "", "  ")
+		fmt.Printf("Provider: %s\nMeta: %s\nFiles:\n", detected, out)
+		for _, f := range files {
+			fmt.Printf("  %s\n", f)
+		}
+		if codeowners != "" {
+			fmt.Printf("CODEOWNERS: %s\n", codeowners)
+		}
+		return 0
+	}
+
+	ctx := context.Background()
+	client := uploader.NewClient(baseURL, apiKey)
+	client.MaxRetries = retries
+	client.Codeowners = codeowners
+
+	status, err := client.Upload(ctx, &meta, files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Upload failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("FlakeGuard: uploaded %d report file(s), ingestion %s %s\n", len(files), status.IngestionID, status.Status)
+
+	if !wait {
+		return 0
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	status, err = client.WaitForIngestion(waitCtx, status.IngestionID, 2*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed waiting for ingestion: %v\n", err)
+		return 1
+	}
+	if status.Status == ingest.StatusFailed {
+		message := ""
+		if status.Error != nil {
+			message = *status.Error
+		}
+		fmt.Fprintf(os.Stderr, "Ingestion failed: %s\n", message)
+		return 1
+	}
+	fmt.Printf("FlakeGuard: stored %d test results, %d flake events\n", status.Stored.TestResults, status.FlakeEventsCreated)
+	return 0
+}
+
+// mergeMetadata fills fields of meta left unset by flags from detected
+func mergeMetadata(meta *ingest.IngestionMetadata, detected ingest.IngestionMetadata) {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&meta.Provider, detected.Provider)
+	fill(&meta.RepoFullName, detected.RepoFullName)
+	fill(&meta.WorkflowName, detected.WorkflowName)
+	fill(&meta.WorkflowRef, detected.WorkflowRef)
+	fill(&meta.RunURL, detected.RunURL)
+	fill(&meta.SHA, detected.SHA)
+	fill(&meta.Branch, detected.Branch)
+	fill(&meta.Event, detected.Event)
+	fill(&meta.JobName, detected.JobName)
+	fill(&meta.RunNumber, detected.RunNumber)
+
+	// A detected attempt only belongs to the detected run
+	if meta.RunID == "" {
+		meta.RunID = detected.RunID
+		if meta.RunAttempt == 0 && meta.AttemptID == "" {
+			meta.RunAttempt = detected.RunAttempt
+			meta.AttemptID = detected.AttemptID
+		}
+	}
+	meta.PRNumber = detected.PRNumber
+}
+
+func oldestModTime(files []string) time.Time {
+	oldest := time.Now()
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+	}
+	return oldest
+}
+
+func joinProviders() string {
+	names := make([]string, len(uploader.Providers))
+	for i, p := range uploader.Providers {
+		names[i] = string(p)
+	}
+	return strings.Join(names, ", ")
+}
@@ -9,10 +9,16 @@ import (
 	"github.com/aliuyar1234/flakeguard/internal/audit"
 	"github.com/aliuyar1234/flakeguard/internal/auth"
 	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/dashboard"
+	"github.com/aliuyar1234/flakeguard/internal/digest"
 	"github.com/aliuyar1234/flakeguard/internal/flake"
 	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
 	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
 	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
+	"github.com/aliuyar1234/flakeguard/internal/signatures"
 	"github.com/aliuyar1234/flakeguard/internal/web"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -88,6 +94,9 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 		r.Put("/{org_id}/members/{user_id}", orgs.HandleUpdateMemberRole(pool, auditor))
 		r.Delete("/{org_id}/members/{user_id}", orgs.HandleRemoveMember(pool, auditor))
 
+		// Org-wide flake dashboard
+		r.Get("/{org_id}/dashboard", dashboard.HandleGetOrg(pool))
+
 		// Organization audit log (OWNER/ADMIN)
 		r.Get("/{org_id}/audit", orgs.HandleListAudit(pool))
 
@@ -102,6 +111,15 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 		r.Get("/{org_id}/projects", projects.HandleList(pool))
 	})
 
+	// API routes - Dashboard across the user's orgs (require authentication)
+	r.Route("/api/v1/dashboard", func(r chi.Router) {
+		r.Use(ContentTypeJSON)
+		r.Use(CSRFMiddleware(isProduction))
+		r.Use(auth.RequireAuth)
+
+		r.Get("/", dashboard.HandleGet(pool))
+	})
+
 	// API routes - Projects (require authentication)
 	r.Route("/api/v1/projects", func(r chi.Router) {
 		r.Use(ContentTypeJSON)
@@ -112,15 +130,56 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 		r.Put("/{project_id}/slack", projects.HandleConfigureSlack(pool, auditor))
 		r.Delete("/{project_id}/slack", projects.HandleRemoveSlack(pool, auditor))
 
+		// GitHub pull request reporting
+		r.Put("/{project_id}/github", projects.HandleConfigureGitHub(pool, auditor))
+
+		// Notification channels and settings
+		r.Put("/{project_id}/notifications", projects.HandleConfigureNotifications(pool, auditor))
+		r.Post("/{project_id}/notification-channels", notify.HandleCreate(pool, cfg, auditor))
+		r.Get("/{project_id}/notification-channels", notify.HandleList(pool))
+		r.Put("/{project_id}/notification-channels/{channel_id}", notify.HandleUpdate(pool, cfg, auditor))
+		r.Delete("/{project_id}/notification-channels/{channel_id}", notify.HandleRemove(pool, auditor))
+		r.Post("/{project_id}/notifications/test", notify.HandleSendTest(pool))
+		r.Get("/{project_id}/notification-deliveries", notify.HandleListDeliveries(pool))
+
+		// Flake digests
+		r.Put("/{project_id}/digest", projects.HandleConfigureDigest(pool, auditor))
+		r.Get("/{project_id}/digests", digest.HandleList(pool))
+		r.Get("/{project_id}/digests/preview", digest.HandlePreview(pool))
+		r.Get("/{project_id}/digests/{digest_id}", digest.HandleGet(pool))
+
 		// API keys
 		r.Post("/{project_id}/api-keys", apikeys.HandleCreate(pool, auditor))
 		r.Get("/{project_id}/api-keys", apikeys.HandleList(pool))
 		r.Delete("/{project_id}/api-keys/{api_key_id}", apikeys.HandleRevoke(pool, auditor))
 		r.Post("/{project_id}/api-keys/{api_key_id}/rotate", apikeys.HandleRotate(pool, auditor))
 
+		// Quarantine rules
+		r.Post("/{project_id}/quarantine", quarantine.HandleCreate(pool, auditor))
+		r.Get("/{project_id}/quarantine", quarantine.HandleList(pool))
+		r.Put("/{project_id}/quarantine/{rule_id}", quarantine.HandleUpdate(pool, auditor))
+		r.Delete("/{project_id}/quarantine/{rule_id}", quarantine.HandleRemove(pool, auditor))
+
 		// Flakes
 		r.Get("/{project_id}/flakes", flake.HandleListFlakes(pool))
 		r.Get("/{project_id}/flakes/{test_case_id}", flake.HandleGetFlakeDetail(pool))
+		r.Get("/{project_id}/flakes/{test_case_id}/trend", flake.HandleGetFlakeTrend(pool))
+		r.Put("/{project_id}/flakes/{test_case_id}/status", flake.HandleChangeFlakeStatus(pool, auditor))
+		r.Put("/{project_id}/flake-lifecycle", projects.HandleConfigureFlakeLifecycle(pool, auditor))
+		r.Get("/{project_id}/trend", flake.HandleGetProjectTrend(pool))
+
+		// Broken tests
+		r.Put("/{project_id}/broken-detection", projects.HandleConfigureBrokenDetection(pool, auditor))
+		r.Get("/{project_id}/broken-tests", flake.HandleListBrokenTests(pool))
+
+		// Test ownership
+		r.Get("/{project_id}/codeowners", ownership.HandleGetCodeowners(pool))
+		r.Put("/{project_id}/codeowners", ownership.HandleConfigureCodeowners(pool, auditor))
+		r.Get("/{project_id}/owners", ownership.HandleListOwners(pool))
+
+		// Failure signatures
+		r.Get("/{project_id}/failure-signatures", signatures.HandleList(pool))
+		r.Get("/{project_id}/failure-signatures/{signature_id}", signatures.HandleGet(pool))
 	})
 
 	// API routes - Ingestion (require API key authentication)
@@ -133,6 +192,20 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 			apikey.RequireAPIKey(pool, apikeys.ScopeIngestWrite),
 			apikey.RateLimitByAPIKey(cfg.RateLimitRPM),
 		).Post("/junit", ingest.HandleJUnitUpload(pool, cfg, uploadLimits))
+
+		// Status of a queued ingestion
+		r.With(
+			apikey.RequireAPIKey(pool, apikeys.ScopeIngestWrite),
+			apikey.RateLimitByAPIKey(cfg.RateLimitRPM),
+		).Get("/{ingestion_id}", ingest.HandleGetIngestion(pool))
+	})
+
+	// API routes - Quarantine manifest for CI (require API key authentication)
+	r.Route("/api/v1/quarantine", func(r chi.Router) {
+		r.With(
+			apikey.RequireAPIKey(pool, apikeys.ScopeReadProject),
+			apikey.RateLimitByAPIKey(cfg.RateLimitRPM),
+		).Get("/manifest", quarantine.HandleManifest(pool))
 	})
 
 	// Protected routes - require authentication
@@ -140,10 +213,14 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 		r.Use(auth.RequireAuthPage)
 		r.Use(NoCacheMiddleware)
 
+		// Flake dashboard across the user's orgs
+		r.Get("/dashboard", web.HandleDashboardPage(pool, isProduction))
+
 		// Organizations
 		r.Get("/orgs", web.HandleOrgsPage(pool, isProduction))
 		r.Get("/orgs/new", web.HandleOrgCreatePage(isProduction))
 		r.Get("/orgs/{org_id}/settings", web.HandleOrgSettingsPage(pool, isProduction))
+		r.Get("/orgs/{org_id}/dashboard", web.HandleOrgDashboardPage(pool, isProduction))
 
 		// Projects
 		r.Get("/orgs/{org_id}/projects", web.HandleProjectsPage(pool, isProduction))
@@ -156,6 +233,13 @@ func NewRouter(pool *pgxpool.Pool, cfg *config.Config) *chi.Mux {
 		// Flakes Dashboard (using slug-based URLs)
 		r.Get("/orgs/{org_slug}/projects/{project_slug}/flakes", web.HandleFlakesListPage(pool, isProduction))
 		r.Get("/orgs/{org_slug}/projects/{project_slug}/flakes/{test_case_id}", web.HandleFlakeDetailPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/broken-tests", web.HandleBrokenTestsPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/owners", web.HandleOwnersPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/digests", web.HandleDigestsPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/digests/preview", web.HandleDigestPreviewPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/digests/{digest_id}", web.HandleDigestPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/failure-signatures", web.HandleSignaturesPage(pool, isProduction))
+		r.Get("/orgs/{org_slug}/projects/{project_slug}/failure-signatures/{signature_id}", web.HandleSignaturePage(pool, isProduction))
 	})
 
 	return r
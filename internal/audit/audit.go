@@ -11,20 +11,33 @@ import (
 )
 
 const (
-	EventUserSignup           = "user.signup"
-	EventLoginFailed          = "auth.login_failed"
-	EventOrgCreated           = "org.created"
-	EventOrgInviteCreated     = "org.invite_created"
-	EventOrgInviteRevoked     = "org.invite_revoked"
-	EventOrgInviteAccepted    = "org.invite_accepted"
-	EventOrgMemberRoleUpdated = "org.member_role_updated"
-	EventOrgMemberRemoved     = "org.member_removed"
-	EventProjectCreated       = "project.created"
-	EventAPIKeyCreated        = "apikey.created"
-	EventAPIKeyRevoked        = "apikey.revoked"
-	EventAPIKeyRotated        = "apikey.rotated"
-	EventSlackConfigured      = "slack.configured"
-	EventSlackCleared         = "slack.cleared"
+	EventUserSignup                = "user.signup"
+	EventLoginFailed               = "auth.login_failed"
+	EventOrgCreated                = "org.created"
+	EventOrgInviteCreated          = "org.invite_created"
+	EventOrgInviteRevoked          = "org.invite_revoked"
+	EventOrgInviteAccepted         = "org.invite_accepted"
+	EventOrgMemberRoleUpdated      = "org.member_role_updated"
+	EventOrgMemberRemoved          = "org.member_removed"
+	EventProjectCreated            = "project.created"
+	EventAPIKeyCreated             = "apikey.created"
+	EventAPIKeyRevoked             = "apikey.revoked"
+	EventAPIKeyRotated             = "apikey.rotated"
+	EventSlackConfigured           = "slack.configured"
+	EventSlackCleared              = "slack.cleared"
+	EventGitHubConfigured          = "github.configured"
+	EventNotificationsConfigured   = "notifications.configured"
+	EventDigestConfigured          = "digest.configured"
+	EventBrokenDetectionConfigured = "broken_detection.configured"
+	EventFlakeLifecycleConfigured  = "flake_lifecycle.configured"
+	EventFlakeStatusChanged        = "flake.status_changed"
+	EventCodeownersConfigured      = "codeowners.configured"
+	EventQuarantineCreated         = "quarantine.rule_created"
+	EventQuarantineUpdated         = "quarantine.rule_updated"
+	EventQuarantineRemoved         = "quarantine.rule_removed"
+	EventChannelCreated            = "notification_channel.created"
+	EventChannelUpdated            = "notification_channel.updated"
+	EventChannelRemoved            = "notification_channel.removed"
 )
 
 // Event represents an audit log entry.
@@ -261,6 +274,184 @@ func (w *Writer) LogSlackCleared(ctx context.Context, orgID, projectID, userID u
 	})
 }
 
+func (w *Writer) LogGitHubConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, reportMode string) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventGitHubConfigured,
+		Meta: map[string]interface{}{
+			"report_mode": reportMode,
+		},
+	})
+}
+
+func (w *Writer) LogNotificationsConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, cooldownHours int) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventNotificationsConfigured,
+		Meta: map[string]interface{}{
+			"cooldown_hours": cooldownHours,
+		},
+	})
+}
+
+func (w *Writer) LogDigestConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, frequency string) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventDigestConfigured,
+		Meta: map[string]interface{}{
+			"frequency": frequency,
+		},
+	})
+}
+
+func (w *Writer) LogBrokenDetectionConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, consecutiveRuns int) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventBrokenDetectionConfigured,
+		Meta: map[string]interface{}{
+			"consecutive_runs": consecutiveRuns,
+		},
+	})
+}
+
+func (w *Writer) LogFlakeLifecycleConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, resolveAfterRuns int) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventFlakeLifecycleConfigured,
+		Meta: map[string]interface{}{
+			"resolve_after_runs": resolveAfterRuns,
+		},
+	})
+}
+
+func (w *Writer) LogFlakeStatusChanged(ctx context.Context, orgID, projectID, testCaseID, userID uuid.UUID, fromStatus, toStatus, note string) error {
+	meta := map[string]interface{}{
+		"test_case_id": testCaseID.String(),
+		"from_status":  fromStatus,
+		"to_status":    toStatus,
+	}
+	if note != "" {
+		meta["note"] = note
+	}
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventFlakeStatusChanged,
+		Meta:        meta,
+	})
+}
+
+func (w *Writer) LogCodeownersConfigured(ctx context.Context, orgID, projectID, userID uuid.UUID, rules, pathRules, reassignedTests int) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventCodeownersConfigured,
+		Meta: map[string]interface{}{
+			"rules":            rules,
+			"path_rules":       pathRules,
+			"reassigned_tests": reassignedTests,
+		},
+	})
+}
+
+func (w *Writer) LogQuarantineCreated(ctx context.Context, orgID, projectID, ruleID, userID uuid.UUID, matchType, pattern, owner, reason string, expiresAt *time.Time) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventQuarantineCreated,
+		Meta: map[string]interface{}{
+			"rule_id":    ruleID.String(),
+			"match_type": matchType,
+			"pattern":    pattern,
+			"owner":      owner,
+			"reason":     reason,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+func (w *Writer) LogQuarantineUpdated(ctx context.Context, orgID, projectID, ruleID, userID uuid.UUID, pattern string, changes map[string]interface{}) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventQuarantineUpdated,
+		Meta: map[string]interface{}{
+			"rule_id": ruleID.String(),
+			"pattern": pattern,
+			"changes": changes,
+		},
+	})
+}
+
+func (w *Writer) LogQuarantineRemoved(ctx context.Context, orgID, projectID, ruleID, userID uuid.UUID, pattern string) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventQuarantineRemoved,
+		Meta: map[string]interface{}{
+			"rule_id": ruleID.String(),
+			"pattern": pattern,
+		},
+	})
+}
+
+func (w *Writer) LogChannelCreated(ctx context.Context, orgID, projectID, channelID, userID uuid.UUID, channelType, name string) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventChannelCreated,
+		Meta: map[string]interface{}{
+			"channel_id": channelID.String(),
+			"type":       channelType,
+			"name":       name,
+		},
+	})
+}
+
+func (w *Writer) LogChannelUpdated(ctx context.Context, orgID, projectID, channelID, userID uuid.UUID, name string, changes map[string]interface{}) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventChannelUpdated,
+		Meta: map[string]interface{}{
+			"channel_id": channelID.String(),
+			"name":       name,
+			"changes":    changes,
+		},
+	})
+}
+
+func (w *Writer) LogChannelRemoved(ctx context.Context, orgID, projectID, channelID, userID uuid.UUID, channelType, name string) error {
+	return w.Log(ctx, LogParams{
+		OrgID:       &orgID,
+		ProjectID:   &projectID,
+		ActorUserID: &userID,
+		Action:      EventChannelRemoved,
+		Meta: map[string]interface{}{
+			"channel_id": channelID.String(),
+			"type":       channelType,
+			"name":       name,
+		},
+	})
+}
+
 // LogSlackRemoved is kept for backward compatibility.
 func (w *Writer) LogSlackRemoved(ctx context.Context, orgID, projectID, userID uuid.UUID) error {
 	return w.LogSlackCleared(ctx, orgID, projectID, userID)
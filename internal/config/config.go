@@ -5,6 +5,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // Config holds all application configuration.
@@ -26,6 +28,30 @@ type Config struct {
 
 	SlackTimeoutMS int
 	SessionDays    int
+
+	// NotifyQuietSeconds is how long a run's flake notification waits for
+	// the run's other jobs before it is sent
+	NotifyQuietSeconds int
+
+	// NotifyAllowPrivateURLs lets notification channels post to plain http
+	// and to private, loopback and link-local addresses
+	NotifyAllowPrivateURLs bool
+
+	IngestWorkers int
+
+	// GitHub App used to report flakes on pull requests; disabled when
+	// GitHubAppID is 0
+	GitHubAppID         int64
+	GitHubAppPrivateKey string // PEM
+	GitHubAPIURL        string
+
+	// SMTP relay for email notification channels; disabled when SMTPHost is
+	// empty
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
 }
 
 // Load reads configuration from environment variables.
@@ -96,14 +122,96 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("FG_SLACK_TIMEOUT_MS must be between 1 and 30000 (got: %d)", cfg.SlackTimeoutMS)
 	}
 
+	cfg.NotifyQuietSeconds, err = getEnvIntOrDefault("FG_NOTIFY_QUIET_SECONDS", 120)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.NotifyQuietSeconds < 0 || cfg.NotifyQuietSeconds > 3600 {
+		return nil, fmt.Errorf("FG_NOTIFY_QUIET_SECONDS must be between 0 and 3600 (got: %d)", cfg.NotifyQuietSeconds)
+	}
+
+	cfg.NotifyAllowPrivateURLs, err = getEnvBoolOrDefault("FG_NOTIFY_ALLOW_PRIVATE_URLS", false)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg.SessionDays, err = getEnvIntOrDefault("FG_SESSION_DAYS", 7)
 	if err != nil {
 		return nil, err
 	}
 
+	cfg.IngestWorkers, err = getEnvIntOrDefault("FG_INGEST_WORKERS", 2)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.IngestWorkers < 0 || cfg.IngestWorkers > 64 {
+		return nil, fmt.Errorf("FG_INGEST_WORKERS must be between 0 and 64 (got: %d)", cfg.IngestWorkers)
+	}
+
+	cfg.GitHubAppID, err = getEnvInt64OrDefault("FG_GITHUB_APP_ID", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GitHubAppPrivateKey, err = loadGitHubAppPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if (cfg.GitHubAppID != 0) != (cfg.GitHubAppPrivateKey != "") {
+		return nil, fmt.Errorf("FG_GITHUB_APP_ID and FG_GITHUB_APP_PRIVATE_KEY must be set together")
+	}
+	if cfg.GitHubAppPrivateKey != "" {
+		if _, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cfg.GitHubAppPrivateKey)); err != nil {
+			return nil, fmt.Errorf("FG_GITHUB_APP_PRIVATE_KEY must be a PEM-encoded RSA private key: %w", err)
+		}
+	}
+	cfg.GitHubAPIURL = strings.TrimRight(getEnvOrDefault("FG_GITHUB_API_URL", "https://api.github.com"), "/")
+
+	cfg.SMTPHost = strings.TrimSpace(os.Getenv("FG_SMTP_HOST"))
+	cfg.SMTPPort, err = getEnvIntOrDefault("FG_SMTP_PORT", 587)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SMTPPort <= 0 || cfg.SMTPPort > 65535 {
+		return nil, fmt.Errorf("FG_SMTP_PORT must be between 1 and 65535 (got: %d)", cfg.SMTPPort)
+	}
+	cfg.SMTPUsername = strings.TrimSpace(os.Getenv("FG_SMTP_USERNAME"))
+	cfg.SMTPPassword = os.Getenv("FG_SMTP_PASSWORD")
+	cfg.SMTPFrom = strings.TrimSpace(os.Getenv("FG_SMTP_FROM"))
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("FG_SMTP_FROM is required when FG_SMTP_HOST is set")
+	}
+
 	return cfg, nil
 }
 
+// loadGitHubAppPrivateKey reads the App key from FG_GITHUB_APP_PRIVATE_KEY or
+// the file named by FG_GITHUB_APP_PRIVATE_KEY_FILE. Escaped newlines are
+// accepted so the PEM fits in a single-line variable.
+func loadGitHubAppPrivateKey() (string, error) {
+	key := strings.TrimSpace(os.Getenv("FG_GITHUB_APP_PRIVATE_KEY"))
+	if path := strings.TrimSpace(os.Getenv("FG_GITHUB_APP_PRIVATE_KEY_FILE")); path != "" {
+		if key != "" {
+			return "", fmt.Errorf("set only one of FG_GITHUB_APP_PRIVATE_KEY and FG_GITHUB_APP_PRIVATE_KEY_FILE")
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("FG_GITHUB_APP_PRIVATE_KEY_FILE: %w", err)
+		}
+		key = strings.TrimSpace(string(content))
+	}
+	return strings.ReplaceAll(key, `\n`, "\n"), nil
+}
+
+// GitHubAppEnabled returns true if a GitHub App is configured.
+func (c *Config) GitHubAppEnabled() bool {
+	return c.GitHubAppID != 0 && c.GitHubAppPrivateKey != ""
+}
+
+// SMTPEnabled returns true if an SMTP relay is configured for email notifications.
+func (c *Config) SMTPEnabled() bool {
+	return c.SMTPHost != ""
+}
+
 // IsDev returns true if running in development mode.
 func (c *Config) IsDev() bool {
 	return c.Env == "dev"
@@ -112,21 +220,39 @@ func (c *Config) IsDev() bool {
 // RedactedValues returns a map of config values with secrets redacted.
 func (c *Config) RedactedValues() map[string]string {
 	return map[string]string{
-		"FG_ENV":              c.Env,
-		"FG_HTTP_ADDR":        c.HTTPAddr,
-		"FG_BASE_URL":         c.BaseURL,
-		"FG_DB_DSN":           redactDSN(c.DBDSN),
-		"FG_JWT_SECRET":       "[REDACTED]",
-		"FG_LOG_LEVEL":        c.LogLevel,
-		"FG_RATE_LIMIT_RPM":   fmt.Sprintf("%d", c.RateLimitRPM),
-		"FG_MAX_UPLOAD_BYTES": fmt.Sprintf("%d", c.MaxUploadBytes),
-		"FG_MAX_UPLOAD_FILES": fmt.Sprintf("%d", c.MaxUploadFiles),
-		"FG_MAX_FILE_BYTES":   fmt.Sprintf("%d", c.MaxFileBytes),
-		"FG_SLACK_TIMEOUT_MS": fmt.Sprintf("%d", c.SlackTimeoutMS),
-		"FG_SESSION_DAYS":     fmt.Sprintf("%d", c.SessionDays),
+		"FG_ENV":                       c.Env,
+		"FG_HTTP_ADDR":                 c.HTTPAddr,
+		"FG_BASE_URL":                  c.BaseURL,
+		"FG_DB_DSN":                    redactDSN(c.DBDSN),
+		"FG_JWT_SECRET":                "[REDACTED]",
+		"FG_LOG_LEVEL":                 c.LogLevel,
+		"FG_RATE_LIMIT_RPM":            fmt.Sprintf("%d", c.RateLimitRPM),
+		"FG_MAX_UPLOAD_BYTES":          fmt.Sprintf("%d", c.MaxUploadBytes),
+		"FG_MAX_UPLOAD_FILES":          fmt.Sprintf("%d", c.MaxUploadFiles),
+		"FG_MAX_FILE_BYTES":            fmt.Sprintf("%d", c.MaxFileBytes),
+		"FG_SLACK_TIMEOUT_MS":          fmt.Sprintf("%d", c.SlackTimeoutMS),
+		"FG_NOTIFY_QUIET_SECONDS":      fmt.Sprintf("%d", c.NotifyQuietSeconds),
+		"FG_NOTIFY_ALLOW_PRIVATE_URLS": strconv.FormatBool(c.NotifyAllowPrivateURLs),
+		"FG_SESSION_DAYS":              fmt.Sprintf("%d", c.SessionDays),
+		"FG_INGEST_WORKERS":            fmt.Sprintf("%d", c.IngestWorkers),
+		"FG_GITHUB_APP_ID":             fmt.Sprintf("%d", c.GitHubAppID),
+		"FG_GITHUB_APP_PRIVATE_KEY":    redactSecret(c.GitHubAppPrivateKey),
+		"FG_GITHUB_API_URL":            c.GitHubAPIURL,
+		"FG_SMTP_HOST":                 c.SMTPHost,
+		"FG_SMTP_PORT":                 fmt.Sprintf("%d", c.SMTPPort),
+		"FG_SMTP_USERNAME":             c.SMTPUsername,
+		"FG_SMTP_PASSWORD":             redactSecret(c.SMTPPassword),
+		"FG_SMTP_FROM":                 c.SMTPFrom,
 	}
 }
 
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
 func redactDSN(dsn string) string {
 	if start := strings.Index(dsn, "://"); start != -1 {
 		if end := strings.Index(dsn[start+3:], "@"); end != -1 {
@@ -167,3 +293,15 @@ func getEnvInt64OrDefault(key string, defaultValue int64) (int64, error) {
 	}
 	return parsed, nil
 }
+
+func getEnvBoolOrDefault(key string, defaultValue bool) (bool, error) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false (got: %q)", key, value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,92 @@
+package dashboard
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleGet handles GET /api/v1/dashboard?days=30, covering every org of the
+// user
+func HandleGet(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		service := NewService(pool)
+		orgList, err := service.UserOrgs(ctx, userID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list user orgs")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve organizations")
+			return
+		}
+
+		dashboard, err := service.Get(ctx, orgList, ParseDays(r), time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to build dashboard")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve dashboard")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"dashboard": dashboard,
+		})
+	}
+}
+
+// HandleGetOrg handles GET /api/v1/orgs/{org_id}/dashboard?days=30, the
+// dashboard narrowed to one org
+func HandleGetOrg(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		orgID, err := uuid.Parse(chi.URLParam(r, "org_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid organization ID")
+			return
+		}
+
+		service := NewService(pool)
+		org, err := service.MemberOrg(ctx, userID, orgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Organization not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org membership")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		dashboard, err := service.Get(ctx, []Org{org}, ParseDays(r), time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("org_id", orgID.String()).Msg("Failed to build org dashboard")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve dashboard")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"dashboard": dashboard,
+		})
+	}
+}
+
+// ParseDays reads the days query parameter, flake.DefaultTrendDays when
+// missing or invalid
+func ParseDays(r *http.Request) int {
+	if days, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && days > 0 {
+		return min(days, flake.MaxTrendDays)
+	}
+	return flake.DefaultTrendDays
+}
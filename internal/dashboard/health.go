@@ -0,0 +1,82 @@
+package dashboard
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// RegressionDays is the recent window a regression is detected in
+	RegressionDays = 7
+	// RegressionBaselineDays is the window before it that it is compared to
+	RegressionBaselineDays = 28
+
+	// trendTolerance is the relative change of a flake rate, and
+	// minTrendChange the absolute one, below which it counts as stable
+	trendTolerance = 0.2
+	minTrendChange = 0.001
+)
+
+// healthScore returns the percentage of CI runs without a flaky test, or nil
+// without runs
+func healthScore(runs, flakyRuns int) *int {
+	if runs == 0 {
+		return nil
+	}
+	score := int(math.Round(100 * float64(runs-min(flakyRuns, runs)) / float64(runs)))
+	return &score
+}
+
+// flakeRate returns flakyRuns/runs, 0 without runs
+func flakeRate(flakyRuns, runs int) float64 {
+	if runs == 0 {
+		return 0
+	}
+	return min(float64(flakyRuns)/float64(runs), 1)
+}
+
+// classifyTrend compares a flake rate to the one of the period before. It is
+// empty when either period had no runs.
+func classifyTrend(rate, previousRate float64, runs, previousRuns int) string {
+	if runs == 0 || previousRuns == 0 {
+		return ""
+	}
+	change := rate - previousRate
+	if math.Abs(change) < max(previousRate*trendTolerance, minTrendChange) {
+		return TrendStable
+	}
+	if change > 0 {
+		return TrendWorsening
+	}
+	return TrendImproving
+}
+
+// selectRegressions keeps the tests whose recent flake rate exceeds their
+// baseline rate, largest increase first, up to limit
+func selectRegressions(candidates []Regression, limit int) []Regression {
+	regressions := make([]Regression, 0, len(candidates))
+	for _, r := range candidates {
+		r.FlakeRate = flakeRate(r.FlakyRuns, r.Runs)
+		r.BaselineFlakeRate = flakeRate(r.BaselineFlakyRuns, r.BaselineRuns)
+		if r.FlakyRuns > 0 && r.FlakeRate > r.BaselineFlakeRate {
+			regressions = append(regressions, r)
+		}
+	}
+
+	sort.SliceStable(regressions, func(i, j int) bool {
+		a, b := &regressions[i], &regressions[j]
+		da, db := a.FlakeRate-a.BaselineFlakeRate, b.FlakeRate-b.BaselineFlakeRate
+		if da != db {
+			return da > db
+		}
+		if a.FlakyRuns != b.FlakyRuns {
+			return a.FlakyRuns > b.FlakyRuns
+		}
+		return a.TestIdentifier < b.TestIdentifier
+	})
+
+	if len(regressions) > limit {
+		regressions = regressions[:limit]
+	}
+	return regressions
+}
@@ -0,0 +1,42 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthScore(t *testing.T) {
+	require.Nil(t, healthScore(0, 0))
+	require.Equal(t, 100, *healthScore(40, 0))
+	require.Equal(t, 67, *healthScore(3, 1))
+	require.Equal(t, 0, *healthScore(2, 5), "flaky runs are capped at the runs")
+}
+
+func TestClassifyTrend(t *testing.T) {
+	require.Equal(t, "", classifyTrend(0.1, 0, 10, 0), "no previous runs")
+	require.Equal(t, "", classifyTrend(0, 0.1, 0, 10), "no current runs")
+	require.Equal(t, TrendStable, classifyTrend(0.11, 0.10, 100, 100), "within 20% of the previous rate")
+	require.Equal(t, TrendStable, classifyTrend(0.0005, 0, 100, 100), "below the absolute minimum change")
+	require.Equal(t, TrendWorsening, classifyTrend(0.15, 0.10, 100, 100))
+	require.Equal(t, TrendImproving, classifyTrend(0.05, 0.10, 100, 100))
+}
+
+func TestSelectRegressions(t *testing.T) {
+	candidates := []Regression{
+		{TestIdentifier: "steady", Runs: 10, FlakyRuns: 1, BaselineRuns: 40, BaselineFlakyRuns: 4},
+		{TestIdentifier: "new", Runs: 10, FlakyRuns: 2},
+		{TestIdentifier: "worse", Runs: 10, FlakyRuns: 5, BaselineRuns: 40, BaselineFlakyRuns: 4},
+		{TestIdentifier: "better", Runs: 10, FlakyRuns: 1, BaselineRuns: 40, BaselineFlakyRuns: 20},
+		{TestIdentifier: "clean", Runs: 10},
+	}
+
+	regressions := selectRegressions(candidates, 10)
+	require.Len(t, regressions, 2)
+	require.Equal(t, "worse", regressions[0].TestIdentifier)
+	require.InDelta(t, 0.5, regressions[0].FlakeRate, 1e-9)
+	require.InDelta(t, 0.1, regressions[0].BaselineFlakeRate, 1e-9)
+	require.Equal(t, "new", regressions[1].TestIdentifier)
+
+	require.Len(t, selectRegressions(candidates, 1), 1)
+}
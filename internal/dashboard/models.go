@@ -0,0 +1,83 @@
+package dashboard
+
+import (
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/google/uuid"
+)
+
+// Trend directions of a project's flake rate against the period before
+const (
+	TrendImproving = "improving"
+	TrendWorsening = "worsening"
+	TrendStable    = "stable"
+)
+
+// Org is an organization a dashboard covers
+type Org struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
+
+// Dashboard aggregates the flakiness of every project of its orgs over the
+// last Days
+type Dashboard struct {
+	Orgs        []Org                     `json:"orgs"`
+	Days        int                       `json:"days"`
+	Projects    []ProjectHealth           `json:"projects"`
+	TopFlakes   []OrgFlake                `json:"top_flakes"`
+	Regressions []Regression              `json:"regressions"`
+	Trend       []flake.ProjectTrendPoint `json:"trend"`
+}
+
+// ProjectHealth summarizes one project. HealthScore is the percentage of
+// its CI runs in the period without a flaky test, nil without runs. Flake
+// rates are those of the project's test runs, in the period and in the
+// equally long period before.
+type ProjectHealth struct {
+	OrgID             uuid.UUID `json:"org_id"`
+	OrgSlug           string    `json:"org_slug"`
+	ProjectID         uuid.UUID `json:"project_id"`
+	Name              string    `json:"name"`
+	Slug              string    `json:"slug"`
+	HealthScore       *int      `json:"health_score"`
+	CIRuns            int       `json:"ci_runs"`
+	FlakyCIRuns       int       `json:"flaky_ci_runs"`
+	FlakyTests        int       `json:"flaky_tests"`
+	TestRuns          int       `json:"test_runs"`
+	FlakyTestRuns     int       `json:"flaky_test_runs"`
+	FlakeRate         float64   `json:"flake_rate"`
+	PreviousFlakeRate float64   `json:"previous_flake_rate"`
+	Trend             string    `json:"trend"`
+}
+
+// OrgFlake is a flaky test in the org-wide ranking, with its project
+type OrgFlake struct {
+	flake.FlakeListItem
+	OrgID       uuid.UUID `json:"org_id"`
+	OrgSlug     string    `json:"org_slug"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	ProjectSlug string    `json:"project_slug"`
+}
+
+// Regression is a test that flaked more in the last RegressionDays than in
+// the RegressionBaselineDays before
+type Regression struct {
+	TestCaseID        uuid.UUID `json:"test_case_id"`
+	OrgID             uuid.UUID `json:"org_id"`
+	OrgSlug           string    `json:"org_slug"`
+	ProjectID         uuid.UUID `json:"project_id"`
+	ProjectName       string    `json:"project_name"`
+	ProjectSlug       string    `json:"project_slug"`
+	RepoFullName      string    `json:"repo_full_name"`
+	JobName           string    `json:"job_name"`
+	JobVariant        string    `json:"job_variant"`
+	TestIdentifier    string    `json:"test_identifier"`
+	Runs              int       `json:"runs"`
+	FlakyRuns         int       `json:"flaky_runs"`
+	FlakeRate         float64   `json:"flake_rate"`
+	BaselineRuns      int       `json:"baseline_runs"`
+	BaselineFlakyRuns int       `json:"baseline_flaky_runs"`
+	BaselineFlakeRate float64   `json:"baseline_flake_rate"`
+}
@@ -0,0 +1,364 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MaxTopFlakes bounds the org-wide ranking of flaky tests
+	MaxTopFlakes = 20
+	// MaxRegressions bounds the list of recently regressed tests
+	MaxRegressions = 20
+)
+
+// Service builds org dashboards
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new dashboard service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+// UserOrgs returns every org the user is a member of
+func (s *Service) UserOrgs(ctx context.Context, userID uuid.UUID) ([]Org, error) {
+	memberships, err := orgs.NewService(s.pool).ListUserOrgs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgList := make([]Org, len(memberships))
+	for i, m := range memberships {
+		orgList[i] = Org{ID: m.ID, Name: m.Name, Slug: m.Slug}
+	}
+	return orgList, nil
+}
+
+// MemberOrg returns an org the user is a member of, orgs.ErrNotMember
+// otherwise
+func (s *Service) MemberOrg(ctx context.Context, userID, orgID uuid.UUID) (Org, error) {
+	orgService := orgs.NewService(s.pool)
+	if _, err := orgService.RequireOrgMember(ctx, userID, orgID); err != nil {
+		return Org{}, err
+	}
+
+	org, err := orgService.GetByID(ctx, orgID)
+	if err != nil {
+		return Org{}, err
+	}
+	return Org{ID: org.ID, Name: org.Name, Slug: org.Slug}, nil
+}
+
+// Get builds the dashboard of orgs over the last days. Every member of an
+// org sees all of its projects, so the caller only checks membership.
+func (s *Service) Get(ctx context.Context, orgList []Org, days int, now time.Time) (*Dashboard, error) {
+	start, end := flake.TrendRange(days, now)
+	days = int(end.Sub(start).Hours() / 24)
+
+	dashboard := &Dashboard{
+		Orgs: orgList,
+		Days: days,
+	}
+	orgIDs := make([]uuid.UUID, len(orgList))
+	for i, o := range orgList {
+		orgIDs[i] = o.ID
+	}
+
+	var err error
+	if dashboard.Projects, err = s.projectHealth(ctx, orgList, start, end); err != nil {
+		return nil, err
+	}
+	if dashboard.TopFlakes, err = s.topFlakes(ctx, orgIDs, start); err != nil {
+		return nil, err
+	}
+	if dashboard.Regressions, err = s.regressions(ctx, orgIDs, now); err != nil {
+		return nil, err
+	}
+	if dashboard.Trend, err = flake.NewService(s.pool).GetOrgsTrend(ctx, orgIDs, days, now); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+// projectHealth summarizes every project of the orgs over [start, end), with
+// the test flake rate of the equally long period before for its trend
+func (s *Service) projectHealth(ctx context.Context, orgList []Org, start, end time.Time) ([]ProjectHealth, error) {
+	projectService := projects.NewService(s.pool)
+	orgIDs := make([]uuid.UUID, 0, len(orgList))
+	health := []ProjectHealth{}
+	for _, o := range orgList {
+		projectList, err := projectService.ListByOrg(ctx, o.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects: %w", err)
+		}
+		for _, p := range projectList {
+			health = append(health, ProjectHealth{OrgID: o.ID, OrgSlug: o.Slug, ProjectID: p.ID, Name: p.Name, Slug: p.Slug})
+		}
+		orgIDs = append(orgIDs, o.ID)
+	}
+
+	byID := make(map[uuid.UUID]*ProjectHealth, len(health))
+	for i := range health {
+		byID[health[i].ProjectID] = &health[i]
+	}
+
+	runsQuery := `
+		SELECT
+			cr.project_id,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM flake_events fe WHERE fe.ci_run_id = cr.id))
+		FROM ci_runs cr
+		JOIN projects p ON p.id = cr.project_id
+		WHERE p.org_id = ANY($1)
+		  AND cr.first_seen_at >= $2 AND cr.first_seen_at < $3
+		GROUP BY cr.project_id
+	`
+
+	rows, err := s.pool.Query(ctx, runsQuery, orgIDs, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CI runs: %w", err)
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		var runs, flakyRuns int
+		if err := rows.Scan(&projectID, &runs, &flakyRuns); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan CI runs: %w", err)
+		}
+		if h := byID[projectID]; h != nil {
+			h.CIRuns, h.FlakyCIRuns = runs, flakyRuns
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count CI runs: %w", err)
+	}
+
+	previousStart := start.Add(-end.Sub(start))
+	testsQuery := `
+		SELECT
+			ds.project_id,
+			COALESCE(SUM(ds.runs) FILTER (WHERE ds.day >= $2), 0),
+			COALESCE(SUM(ds.flaky_runs) FILTER (WHERE ds.day >= $2), 0),
+			COUNT(DISTINCT ds.test_case_id) FILTER (WHERE ds.day >= $2 AND ds.flaky_runs > 0),
+			COALESCE(SUM(ds.runs) FILTER (WHERE ds.day < $2), 0),
+			COALESCE(SUM(ds.flaky_runs) FILTER (WHERE ds.day < $2), 0)
+		FROM test_daily_stats ds
+		JOIN projects p ON p.id = ds.project_id
+		WHERE p.org_id = ANY($1)
+		  AND ds.day >= $3 AND ds.day < $4
+		GROUP BY ds.project_id
+	`
+
+	previousRuns := make(map[uuid.UUID][2]int, len(health))
+	rows, err = s.pool.Query(ctx, testsQuery, orgIDs, start, previousStart, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum test runs: %w", err)
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		var runs, flakyRuns, flakyTests, prevRuns, prevFlakyRuns int
+		if err := rows.Scan(&projectID, &runs, &flakyRuns, &flakyTests, &prevRuns, &prevFlakyRuns); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan test runs: %w", err)
+		}
+		if h := byID[projectID]; h != nil {
+			h.TestRuns, h.FlakyTestRuns, h.FlakyTests = runs, flakyRuns, flakyTests
+			previousRuns[projectID] = [2]int{prevRuns, prevFlakyRuns}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to sum test runs: %w", err)
+	}
+
+	for i := range health {
+		h := &health[i]
+		prev := previousRuns[h.ProjectID]
+		h.HealthScore = healthScore(h.CIRuns, h.FlakyCIRuns)
+		h.FlakeRate = flakeRate(h.FlakyTestRuns, h.TestRuns)
+		h.PreviousFlakeRate = flakeRate(prev[1], prev[0])
+		h.Trend = classifyTrend(h.FlakeRate, h.PreviousFlakeRate, h.TestRuns, prev[0])
+	}
+
+	return health, nil
+}
+
+// topFlakes ranks the flaky tests of all of the orgs' projects seen since
+// start, like a project's flake list
+func (s *Service) topFlakes(ctx context.Context, orgIDs []uuid.UUID, start time.Time) ([]OrgFlake, error) {
+	query := `
+		SELECT
+			fs.test_case_id,
+			tc.repo_full_name,
+			tc.job_name,
+			tc.job_variant,
+			tc.test_identifier,
+			fs.flake_score,
+			fs.flake_score_lower,
+			fs.flake_score_upper,
+			fs.mixed_outcome_runs,
+			fs.total_runs_seen,
+			fs.first_seen_at,
+			fs.last_seen_at,
+			o.id,
+			o.slug,
+			p.id,
+			p.name,
+			p.slug
+		FROM flake_stats fs
+		JOIN test_cases tc ON tc.id = fs.test_case_id
+		JOIN projects p ON p.id = tc.project_id
+		JOIN orgs o ON o.id = p.org_id
+		WHERE p.org_id = ANY($1)
+		  AND fs.last_seen_at >= $2
+		ORDER BY fs.flake_score_lower DESC, fs.flake_score DESC, fs.last_seen_at DESC
+		LIMIT $3
+	`
+
+	rows, err := s.pool.Query(ctx, query, orgIDs, start, MaxTopFlakes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top flakes: %w", err)
+	}
+	defer rows.Close()
+
+	flakes := []OrgFlake{}
+	for rows.Next() {
+		var f OrgFlake
+		if err := rows.Scan(
+			&f.TestCaseID,
+			&f.RepoFullName,
+			&f.JobName,
+			&f.JobVariant,
+			&f.TestIdentifier,
+			&f.FlakeScore,
+			&f.FlakeScoreLower,
+			&f.FlakeScoreUpper,
+			&f.MixedOutcomeRuns,
+			&f.TotalRunsSeen,
+			&f.FirstSeenAt,
+			&f.LastSeenAt,
+			&f.OrgID,
+			&f.OrgSlug,
+			&f.ProjectID,
+			&f.ProjectName,
+			&f.ProjectSlug,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan top flake: %w", err)
+		}
+		flakes = append(flakes, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list top flakes: %w", err)
+	}
+	rows.Close()
+
+	// Quarantine rules are per project
+	ruleSets := make(map[uuid.UUID]*quarantine.RuleSet)
+	quarantineService := quarantine.NewService(s.pool)
+	for i := range flakes {
+		rules, ok := ruleSets[flakes[i].ProjectID]
+		if !ok {
+			rules, err = quarantineService.ActiveRuleSet(ctx, flakes[i].ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			ruleSets[flakes[i].ProjectID] = rules
+		}
+		if rule := rules.Match(flakes[i].TestIdentifier); rule != nil {
+			flakes[i].Quarantined = true
+			flakes[i].QuarantineRuleID = &rule.ID
+		}
+	}
+
+	return flakes, nil
+}
+
+// regressions returns the orgs' tests that flaked more in the last
+// RegressionDays than in the RegressionBaselineDays before
+func (s *Service) regressions(ctx context.Context, orgIDs []uuid.UUID, now time.Time) ([]Regression, error) {
+	start, end := flake.TrendRange(RegressionDays, now)
+	baselineStart := start.AddDate(0, 0, -RegressionBaselineDays)
+
+	query := `
+		WITH windows AS (
+			SELECT
+				ds.test_case_id,
+				COALESCE(SUM(ds.runs) FILTER (WHERE ds.day >= $2), 0) AS runs,
+				COALESCE(SUM(ds.flaky_runs) FILTER (WHERE ds.day >= $2), 0) AS flaky_runs,
+				COALESCE(SUM(ds.runs) FILTER (WHERE ds.day < $2), 0) AS baseline_runs,
+				COALESCE(SUM(ds.flaky_runs) FILTER (WHERE ds.day < $2), 0) AS baseline_flaky_runs
+			FROM test_daily_stats ds
+			JOIN projects p ON p.id = ds.project_id
+			WHERE p.org_id = ANY($1)
+			  AND ds.day >= $3 AND ds.day < $4
+			GROUP BY ds.test_case_id
+			HAVING COALESCE(SUM(ds.flaky_runs) FILTER (WHERE ds.day >= $2), 0) > 0
+		)
+		SELECT
+			w.test_case_id,
+			o.id,
+			o.slug,
+			p.id,
+			p.name,
+			p.slug,
+			tc.repo_full_name,
+			tc.job_name,
+			tc.job_variant,
+			tc.test_identifier,
+			w.runs,
+			w.flaky_runs,
+			w.baseline_runs,
+			w.baseline_flaky_runs
+		FROM windows w
+		JOIN test_cases tc ON tc.id = w.test_case_id
+		JOIN projects p ON p.id = tc.project_id
+		JOIN orgs o ON o.id = p.org_id
+	`
+
+	rows, err := s.pool.Query(ctx, query, orgIDs, start, baselineStart, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list regressions: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Regression
+	for rows.Next() {
+		var r Regression
+		if err := rows.Scan(
+			&r.TestCaseID,
+			&r.OrgID,
+			&r.OrgSlug,
+			&r.ProjectID,
+			&r.ProjectName,
+			&r.ProjectSlug,
+			&r.RepoFullName,
+			&r.JobName,
+			&r.JobVariant,
+			&r.TestIdentifier,
+			&r.Runs,
+			&r.FlakyRuns,
+			&r.BaselineRuns,
+			&r.BaselineFlakyRuns,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan regression: %w", err)
+		}
+		candidates = append(candidates, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list regressions: %w", err)
+	}
+
+	return selectRegressions(candidates, MaxRegressions), nil
+}
@@ -0,0 +1,172 @@
+package digest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleList handles GET /api/v1/projects/{project_id}/digests
+func HandleList(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		limit := 20
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+				limit = min(v, MaxDigestsListed)
+			}
+		}
+
+		digests, err := NewService(pool).ListByProject(ctx, project.ID, limit)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list digests")
+			apperrors.WriteInternalError(w, r, "Failed to list digests")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"digests": digests,
+		})
+	}
+}
+
+// HandleGet handles GET /api/v1/projects/{project_id}/digests/{digest_id}
+func HandleGet(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		digestID, err := uuid.Parse(chi.URLParam(r, "digest_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid digest ID")
+			return
+		}
+
+		d, err := NewService(pool).GetByID(ctx, project.ID, digestID)
+		if err != nil {
+			if errors.Is(err, ErrDigestNotFound) {
+				apperrors.WriteNotFound(w, r, "Digest not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get digest")
+			apperrors.WriteInternalError(w, r, "Failed to get digest")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"digest": d,
+		})
+	}
+}
+
+// HandlePreview handles GET /api/v1/projects/{project_id}/digests/preview.
+// It computes the digest of the last complete period without storing or
+// sending it. frequency defaults to the project setting, or weekly when off.
+func HandlePreview(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		frequency, ok := previewFrequency(r.URL.Query().Get("frequency"), project)
+		if !ok {
+			apperrors.WriteBadRequest(w, r, "frequency must be one of: daily, weekly")
+			return
+		}
+
+		preview, err := NewService(pool).Preview(ctx, project.ID, frequency, time.Now())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to compute digest preview")
+			apperrors.WriteInternalError(w, r, "Failed to compute digest preview")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"preview": preview,
+		})
+	}
+}
+
+// previewFrequency resolves the requested preview frequency
+func previewFrequency(raw string, project *projects.Project) (string, bool) {
+	switch raw {
+	case projects.DigestDaily, projects.DigestWeekly:
+		return raw, true
+	case "":
+		if project.DigestFrequency == projects.DigestDaily {
+			return projects.DigestDaily, true
+		}
+		return projects.DigestWeekly, true
+	}
+	return "", false
+}
+
+// requireProject loads the {project_id} project and checks org membership
+// (or OWNER/ADMIN when mutate is true). Writes the error response on failure.
+func requireProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool, mutate bool) (*projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid project ID")
+		return nil, false
+	}
+
+	projectService := projects.NewService(pool)
+	project, err := projectService.GetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get project")
+		apperrors.WriteInternalError(w, r, "Failed to get project")
+		return nil, false
+	}
+
+	orgService := orgs.NewService(pool)
+	if mutate {
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+	} else {
+		_, err = orgService.RequireOrgMember(ctx, userID, project.OrgID)
+	}
+	if err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		if errors.Is(err, orgs.ErrInsufficientPermissions) {
+			apperrors.WriteForbidden(w, r, "Insufficient permissions")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org permissions")
+		apperrors.WriteInternalError(w, r, "Failed to check permissions")
+		return nil, false
+	}
+
+	return project, true
+}
@@ -0,0 +1,108 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// SendHour is the UTC hour from which the digest of a period that just ended
+// is generated, so it arrives at the start of the working day
+const SendHour = 7
+
+// dueFrequencies returns the frequencies whose last complete period ended at
+// least SendHour hours before now
+func dueFrequencies(now time.Time) []string {
+	var due []string
+	for _, frequency := range []string{projects.DigestDaily, projects.DigestWeekly} {
+		_, end := Period(frequency, now)
+		if !now.Before(end.Add(SendHour * time.Hour)) {
+			due = append(due, frequency)
+		}
+	}
+	return due
+}
+
+// RunDigestJob generates the digests that are due and not generated yet.
+// It is safe to run repeatedly and from several instances: each period is
+// generated once, and a missed run catches up on the next one.
+func RunDigestJob(ctx context.Context, pool *pgxpool.Pool, baseURL string, now time.Time) error {
+	due := dueFrequencies(now)
+	if len(due) == 0 {
+		return nil
+	}
+	dailyStart, _ := Period(projects.DigestDaily, now)
+	weeklyStart, _ := Period(projects.DigestWeekly, now)
+
+	query := `
+		SELECT p.id, o.slug
+		FROM projects p
+		JOIN orgs o ON o.id = p.org_id
+		WHERE p.digest_frequency::text = ANY($1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM flake_digests d
+			WHERE d.project_id = p.id
+			  AND d.frequency = p.digest_frequency
+			  AND d.period_start = CASE p.digest_frequency WHEN 'daily' THEN $2::timestamptz ELSE $3::timestamptz END
+		  )
+	`
+
+	rows, err := pool.Query(ctx, query, due, dailyStart, weeklyStart)
+	if err != nil {
+		return fmt.Errorf("failed to list projects due a digest: %w", err)
+	}
+	type dueProject struct {
+		id      uuid.UUID
+		orgSlug string
+	}
+	var pending []dueProject
+	for rows.Next() {
+		var p dueProject
+		if err := rows.Scan(&p.id, &p.orgSlug); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan project: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list projects due a digest: %w", err)
+	}
+
+	projectService := projects.NewService(pool)
+	service := NewService(pool)
+	generated := 0
+	for _, p := range pending {
+		project, err := projectService.GetByID(ctx, p.id)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", p.id.String()).Msg("Failed to load project for digest")
+			continue
+		}
+		if project.DigestFrequency == projects.DigestOff {
+			continue
+		}
+
+		d, err := service.Generate(ctx, project, project.DigestFrequency, now, ProjectURL(baseURL, p.orgSlug, project.Slug))
+		if err != nil {
+			// One failing project must not hold back the digests of the others
+			log.Error().Err(err).Str("project_id", p.id.String()).Msg("Failed to generate digest")
+			continue
+		}
+		if d != nil {
+			generated++
+			log.Info().
+				Str("project_id", p.id.String()).
+				Str("frequency", d.Frequency).
+				Int("flaky_tests", d.Report.FlakyTests).
+				Msg("Digest generated")
+		}
+	}
+
+	log.Info().Int("due", len(pending)).Int("generated", generated).Msg("Digest job completed")
+	return nil
+}
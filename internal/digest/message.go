@@ -0,0 +1,53 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aliuyar1234/flakeguard/internal/notify"
+)
+
+// ProjectURL is the dashboard URL of a project, which digest and test links
+// are relative to
+func ProjectURL(baseURL, orgSlug, projectSlug string) string {
+	base := strings.TrimRight(baseURL, "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return fmt.Sprintf("%s/orgs/%s/projects/%s", base, orgSlug, projectSlug)
+}
+
+// Message renders a digest as a notification
+func Message(d *Digest, projectName, projectURL string) *notify.Message {
+	return &notify.Message{
+		Event:       notify.EventDigest,
+		ProjectName: projectName,
+		Flakes:      []notify.Flake{},
+		Digest: &notify.Digest{
+			Frequency:          d.Frequency,
+			PeriodStart:        d.PeriodStart,
+			PeriodEnd:          d.PeriodEnd,
+			URL:                projectURL + "/digests/" + d.ID.String(),
+			FlakyTests:         d.Report.FlakyTests,
+			FlakeEvents:        d.Report.FlakeEvents,
+			RetryMinutesWasted: d.Report.RetryMinutesWasted,
+			NewFlakes:          messageEntries(d.Report.NewFlakes, projectURL),
+			ScoreIncreases:     messageEntries(d.Report.ScoreIncreases, projectURL),
+			Stabilized:         messageEntries(d.Report.Stabilized, projectURL),
+		},
+	}
+}
+
+func messageEntries(entries []Entry, projectURL string) []notify.DigestEntry {
+	out := make([]notify.DigestEntry, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		out[i] = notify.DigestEntry{
+			TestID:       e.TestIdentifier,
+			Job:          e.JobLabel(),
+			Detail:       e.Detail,
+			DashboardURL: projectURL + "/flakes/" + e.TestCaseID.String(),
+		}
+	}
+	return out
+}
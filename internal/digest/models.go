@@ -0,0 +1,73 @@
+package digest
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest is a generated flake digest of a project
+type Digest struct {
+	ID          uuid.UUID `json:"id"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	Frequency   string    `json:"frequency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"` // exclusive
+	Report      Report    `json:"report"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LastDay returns the last day the digest covers; PeriodEnd is exclusive
+func (d *Digest) LastDay() time.Time {
+	return d.PeriodEnd.AddDate(0, 0, -1)
+}
+
+// Preview is a digest computed on request and not stored
+type Preview struct {
+	Frequency   string    `json:"frequency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Report      Report    `json:"report"`
+}
+
+// Report is the content of a digest. It is stored as JSON so past digests
+// keep showing what was sent even after flake events expire.
+type Report struct {
+	// FlakyTests is the number of tests that flaked during the period
+	FlakyTests  int `json:"flaky_tests"`
+	FlakeEvents int `json:"flake_events"`
+	// RetryMinutesWasted is the CI time of attempts that failed only because
+	// of a flaky test and had to be retried
+	RetryMinutesWasted float64 `json:"retry_minutes_wasted"`
+	NewFlakes          []Entry `json:"new_flakes"`
+	ScoreIncreases     []Entry `json:"score_increases"`
+	Stabilized         []Entry `json:"stabilized"`
+}
+
+// Entry is a test listed in a report with its flakiness during the period
+// and the period before
+type Entry struct {
+	TestCaseID        uuid.UUID `json:"test_case_id"`
+	RepoFullName      string    `json:"repo_full_name"`
+	JobName           string    `json:"job_name"`
+	JobVariant        string    `json:"job_variant"`
+	TestIdentifier    string    `json:"test_identifier"`
+	FlakyRuns         int       `json:"flaky_runs"`
+	Runs              int       `json:"runs"`
+	PreviousFlakyRuns int       `json:"previous_flaky_runs"`
+	PreviousRuns      int       `json:"previous_runs"`
+	FlakeRate         float64   `json:"flake_rate"`
+	PreviousFlakeRate float64   `json:"previous_flake_rate"`
+	FlakeScore        float64   `json:"flake_score"`
+	FirstSeenAt       time.Time `json:"first_seen_at"`
+	// Detail describes why the test is listed, e.g. "Flake rate 5% → 20%"
+	Detail string `json:"detail"`
+}
+
+// JobLabel names the job of the test, including its variant
+func (e *Entry) JobLabel() string {
+	if e.JobVariant == "" {
+		return e.JobName
+	}
+	return e.JobName + " (" + e.JobVariant + ")"
+}
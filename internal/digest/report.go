@@ -0,0 +1,120 @@
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+)
+
+const (
+	// MaxEntries bounds each list of a report
+	MaxEntries = 10
+
+	// minStableRuns is how many runs without a flake a previously flaky test
+	// needs during the period to be reported as stable
+	minStableRuns = 3
+)
+
+// Period returns the period a digest generated at now covers: the previous
+// UTC day for daily digests, and the previous Monday-to-Monday week for
+// weekly digests. The end is exclusive.
+func Period(frequency string, now time.Time) (start, end time.Time) {
+	now = now.UTC()
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if frequency == projects.DigestWeekly {
+		// Days since Monday; Sunday is the last day of the week
+		end = end.AddDate(0, 0, -((int(end.Weekday()) + 6) % 7))
+		return end.AddDate(0, 0, -7), end
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// testStats is the flakiness of one test during the period and the period
+// before, as loaded for building a report
+type testStats struct {
+	Entry
+	// FlakeEvents counts the flake events of the period
+	FlakeEvents int
+}
+
+// buildReport ranks the tests that flaked during the period or the period
+// before into the lists of a report
+func buildReport(stats []testStats, start, end time.Time, retryMinutes float64) Report {
+	report := Report{
+		RetryMinutesWasted: retryMinutes,
+		NewFlakes:          []Entry{},
+		ScoreIncreases:     []Entry{},
+		Stabilized:         []Entry{},
+	}
+
+	for i := range stats {
+		e := stats[i].Entry
+		e.FlakeRate = flakeRate(e.FlakyRuns, e.Runs)
+		e.PreviousFlakeRate = flakeRate(e.PreviousFlakyRuns, e.PreviousRuns)
+
+		if e.FlakyRuns > 0 {
+			report.FlakyTests++
+			report.FlakeEvents += stats[i].FlakeEvents
+		}
+
+		switch {
+		case e.FlakyRuns > 0 && !e.FirstSeenAt.Before(start) && e.FirstSeenAt.Before(end):
+			e.Detail = fmt.Sprintf("Flaked in %d of %d runs", e.FlakyRuns, max(e.Runs, e.FlakyRuns))
+			report.NewFlakes = append(report.NewFlakes, e)
+		case e.FlakyRuns > 0 && e.FlakeRate > e.PreviousFlakeRate:
+			e.Detail = fmt.Sprintf("Flake rate %.0f%% → %.0f%%", e.PreviousFlakeRate*100, e.FlakeRate*100)
+			report.ScoreIncreases = append(report.ScoreIncreases, e)
+		case e.PreviousFlakyRuns > 0 && e.FlakyRuns == 0 && e.Runs >= minStableRuns:
+			e.Detail = fmt.Sprintf("No flakes in %d runs, after %d flaky runs the period before", e.Runs, e.PreviousFlakyRuns)
+			report.Stabilized = append(report.Stabilized, e)
+		}
+	}
+
+	sort.SliceStable(report.NewFlakes, func(i, j int) bool {
+		a, b := report.NewFlakes[i], report.NewFlakes[j]
+		if a.FlakyRuns != b.FlakyRuns {
+			return a.FlakyRuns > b.FlakyRuns
+		}
+		if a.FlakeRate != b.FlakeRate {
+			return a.FlakeRate > b.FlakeRate
+		}
+		return a.TestIdentifier < b.TestIdentifier
+	})
+	sort.SliceStable(report.ScoreIncreases, func(i, j int) bool {
+		a, b := report.ScoreIncreases[i], report.ScoreIncreases[j]
+		da, db := a.FlakeRate-a.PreviousFlakeRate, b.FlakeRate-b.PreviousFlakeRate
+		if da != db {
+			return da > db
+		}
+		if a.FlakyRuns != b.FlakyRuns {
+			return a.FlakyRuns > b.FlakyRuns
+		}
+		return a.TestIdentifier < b.TestIdentifier
+	})
+	sort.SliceStable(report.Stabilized, func(i, j int) bool {
+		a, b := report.Stabilized[i], report.Stabilized[j]
+		if a.PreviousFlakyRuns != b.PreviousFlakyRuns {
+			return a.PreviousFlakyRuns > b.PreviousFlakyRuns
+		}
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.TestIdentifier < b.TestIdentifier
+	})
+
+	report.NewFlakes = report.NewFlakes[:min(len(report.NewFlakes), MaxEntries)]
+	report.ScoreIncreases = report.ScoreIncreases[:min(len(report.ScoreIncreases), MaxEntries)]
+	report.Stabilized = report.Stabilized[:min(len(report.Stabilized), MaxEntries)]
+	return report
+}
+
+// flakeRate is the share of runs with a flake. Flaky runs whose results were
+// not all retained still count as runs.
+func flakeRate(flakyRuns, runs int) float64 {
+	if flakyRuns == 0 {
+		return 0
+	}
+	return float64(flakyRuns) / float64(max(runs, flakyRuns))
+}
@@ -0,0 +1,115 @@
+package digest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriod(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 10, d, h, 30, 0, 0, time.UTC) }
+	midnight := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		frequency string
+		now       time.Time
+		start     time.Time
+		end       time.Time
+	}{
+		{"daily covers yesterday", projects.DigestDaily, day(14, 9), midnight(13), midnight(14)},
+		{"weekly on Monday covers last week", projects.DigestWeekly, day(12, 7), midnight(5), midnight(12)},
+		{"weekly on Sunday covers the week before", projects.DigestWeekly, day(11, 23), midnight(28).AddDate(0, -1, 0), midnight(5)},
+		{"weekly on Wednesday", projects.DigestWeekly, day(14, 9), midnight(5), midnight(12)},
+		{"converts to UTC", projects.DigestDaily, time.Date(2026, 10, 14, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)), midnight(12), midnight(13)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Period(tt.frequency, tt.now)
+			require.Equal(t, tt.start, start)
+			require.Equal(t, tt.end, end)
+		})
+	}
+}
+
+func TestDueFrequencies(t *testing.T) {
+	require.Equal(t, []string{projects.DigestDaily, projects.DigestWeekly}, dueFrequencies(time.Date(2026, 10, 12, SendHour, 0, 0, 0, time.UTC)))
+	require.Empty(t, dueFrequencies(time.Date(2026, 10, 12, SendHour-1, 59, 0, 0, time.UTC)), "Monday before the send hour")
+	require.Equal(t, []string{projects.DigestWeekly}, dueFrequencies(time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC)),
+		"a weekly digest missed on Monday is caught up later in the week")
+}
+
+func TestBuildReport(t *testing.T) {
+	start := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	before := start.AddDate(0, -1, 0)
+
+	stat := func(id string, flaky, runs, prevFlaky, prevRuns int, firstSeen time.Time) testStats {
+		return testStats{
+			Entry: Entry{
+				TestCaseID:        uuid.New(),
+				JobName:           "test",
+				TestIdentifier:    id,
+				FlakyRuns:         flaky,
+				Runs:              runs,
+				PreviousFlakyRuns: prevFlaky,
+				PreviousRuns:      prevRuns,
+				FirstSeenAt:       firstSeen,
+			},
+			FlakeEvents: flaky,
+		}
+	}
+
+	report := buildReport([]testStats{
+		stat("new.few", 1, 10, 0, 0, start.Add(time.Hour)),
+		stat("new.many", 4, 10, 0, 0, end.Add(-time.Hour)),
+		stat("worse.slightly", 2, 10, 1, 10, before),
+		stat("worse.much", 5, 10, 1, 10, before),
+		stat("better", 1, 10, 4, 10, before),
+		stat("stable.long", 0, 20, 2, 10, before),
+		stat("stable.short", 0, 2, 3, 10, before),
+		stat("stable.most", 0, 5, 6, 10, before),
+		// Flaky runs whose results expired count as runs
+		stat("worse.unknown.runs", 3, 0, 0, 0, before),
+	}, start, end, 42.5)
+
+	ids := func(entries []Entry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.TestIdentifier)
+		}
+		return out
+	}
+
+	require.Equal(t, 6, report.FlakyTests)
+	require.Equal(t, 16, report.FlakeEvents)
+	require.Equal(t, 42.5, report.RetryMinutesWasted)
+	require.Equal(t, []string{"new.many", "new.few"}, ids(report.NewFlakes))
+	require.Equal(t, []string{"worse.unknown.runs", "worse.much", "worse.slightly"}, ids(report.ScoreIncreases))
+	require.Equal(t, []string{"stable.most", "stable.long"}, ids(report.Stabilized), "stable.short ran too few times")
+
+	require.Equal(t, "Flaked in 4 of 10 runs", report.NewFlakes[0].Detail)
+	require.Equal(t, "Flake rate 10% → 50%", report.ScoreIncreases[1].Detail)
+	require.Equal(t, 1.0, report.ScoreIncreases[0].FlakeRate)
+	require.Equal(t, "No flakes in 5 runs, after 6 flaky runs the period before", report.Stabilized[0].Detail)
+}
+
+func TestBuildReport_LimitsEntries(t *testing.T) {
+	start := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	var stats []testStats
+	for i := 0; i < MaxEntries+5; i++ {
+		stats = append(stats, testStats{Entry: Entry{TestIdentifier: fmt.Sprintf("pkg.Test%02d", i), FlakyRuns: 1, Runs: 2, FirstSeenAt: start}})
+	}
+
+	report := buildReport(stats, start, start.AddDate(0, 0, 1), 0)
+	require.Equal(t, MaxEntries+5, report.FlakyTests)
+	require.Len(t, report.NewFlakes, MaxEntries)
+	require.Equal(t, "pkg.Test00", report.NewFlakes[0].TestIdentifier)
+	require.NotNil(t, report.ScoreIncreases, "empty lists encode as []")
+	require.Empty(t, report.ScoreIncreases)
+}
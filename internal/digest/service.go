@@ -0,0 +1,278 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/notify"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrDigestNotFound = errors.New("digest not found")
+)
+
+// MaxDigestsListed bounds the digests returned by the API
+const MaxDigestsListed = 100
+
+// Service handles flake digest operations
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new digest service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+const digestColumns = `
+	id, project_id, frequency::text, period_start, period_end, report, created_at
+`
+
+func scanDigest(row pgx.Row) (*Digest, error) {
+	var d Digest
+	var report []byte
+	err := row.Scan(
+		&d.ID,
+		&d.ProjectID,
+		&d.Frequency,
+		&d.PeriodStart,
+		&d.PeriodEnd,
+		&report,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(report, &d.Report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+	return &d, nil
+}
+
+// Compute builds the report of a project for the period [start, end),
+// comparing against the period of the same length before it
+func (s *Service) Compute(ctx context.Context, projectID uuid.UUID, start, end time.Time) (*Report, error) {
+	previousStart := start.Add(-end.Sub(start))
+
+	// Tests that flaked in either period, with their flaky and total runs per
+	// period. A run counts once however many attempts or events it had.
+	query := `
+		WITH flakes AS (
+			SELECT fe.test_case_id,
+				COUNT(DISTINCT fe.ci_run_id) FILTER (WHERE fe.created_at >= $3) AS flaky_runs,
+				COUNT(DISTINCT fe.ci_run_id) FILTER (WHERE fe.created_at < $3) AS previous_flaky_runs,
+				COUNT(*) FILTER (WHERE fe.created_at >= $3) AS events
+			FROM flake_events fe
+			JOIN test_cases tc ON tc.id = fe.test_case_id
+			WHERE tc.project_id = $1
+			  AND fe.created_at >= $2
+			  AND fe.created_at < $4
+			GROUP BY fe.test_case_id
+		),
+		runs AS (
+			SELECT tr.test_case_id,
+				COUNT(DISTINCT a.ci_run_id) FILTER (WHERE tr.created_at >= $3) AS runs,
+				COUNT(DISTINCT a.ci_run_id) FILTER (WHERE tr.created_at < $3) AS previous_runs
+			FROM test_results tr
+			JOIN ci_jobs j ON j.id = tr.ci_job_id
+			JOIN ci_run_attempts a ON a.id = j.ci_run_attempt_id
+			WHERE tr.test_case_id IN (SELECT test_case_id FROM flakes)
+			  AND tr.created_at >= $2
+			  AND tr.created_at < $4
+			GROUP BY tr.test_case_id
+		)
+		SELECT tc.id, tc.repo_full_name, tc.job_name, tc.job_variant, tc.test_identifier,
+			f.flaky_runs, COALESCE(r.runs, 0), f.previous_flaky_runs, COALESCE(r.previous_runs, 0), f.events,
+			COALESCE(fs.flake_score, 0), COALESCE(fs.first_seen_at, tc.first_seen_at)
+		FROM flakes f
+		JOIN test_cases tc ON tc.id = f.test_case_id
+		LEFT JOIN runs r ON r.test_case_id = f.test_case_id
+		LEFT JOIN flake_stats fs ON fs.test_case_id = f.test_case_id
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID, previousStart, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []testStats
+	for rows.Next() {
+		var t testStats
+		err := rows.Scan(
+			&t.TestCaseID,
+			&t.RepoFullName,
+			&t.JobName,
+			&t.JobVariant,
+			&t.TestIdentifier,
+			&t.FlakyRuns,
+			&t.Runs,
+			&t.PreviousFlakyRuns,
+			&t.PreviousRuns,
+			&t.FlakeEvents,
+			&t.FlakeScore,
+			&t.FirstSeenAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan digest stats: %w", err)
+		}
+		stats = append(stats, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query digest stats: %w", err)
+	}
+
+	retryMinutes, err := s.retryMinutesWasted(ctx, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildReport(stats, start, end, retryMinutes)
+	return &report, nil
+}
+
+// retryMinutesWasted sums the duration of the attempts that failed because of
+// a flaky test and were retried, either as a new attempt or as a rerun of the
+// commit. Each attempt counts once however many tests flaked in it. Flakes
+// absorbed by test framework reruns cost no CI retry and are not counted.
+func (s *Service) retryMinutesWasted(ctx context.Context, projectID uuid.UUID, start, end time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (a.completed_at - a.started_at))), 0) / 60
+		FROM ci_run_attempts a
+		WHERE a.completed_at > a.started_at
+		  AND (a.ci_run_id, a.attempt_number) IN (
+			SELECT fe.ci_run_id, fe.failed_attempt_number
+			FROM flake_events fe
+			JOIN test_cases tc ON tc.id = fe.test_case_id
+			WHERE tc.project_id = $1
+			  AND fe.kind IN ('retry_attempt', 'same_sha_rerun')
+			  AND fe.created_at >= $2
+			  AND fe.created_at < $3
+		  )
+	`
+
+	var minutes float64
+	if err := s.pool.QueryRow(ctx, query, projectID, start, end).Scan(&minutes); err != nil {
+		return 0, fmt.Errorf("failed to query retry minutes: %w", err)
+	}
+	return minutes, nil
+}
+
+// Generate computes and stores the digest of a project for the last complete
+// period before now, and queues it for every notification channel of the
+// project in the same transaction. Returns nil when the digest of the period
+// was already generated, e.g. by another instance.
+func (s *Service) Generate(ctx context.Context, project *projects.Project, frequency string, now time.Time, projectURL string) (*Digest, error) {
+	start, end := Period(frequency, now)
+
+	report, err := s.Compute(ctx, project.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	channels, err := notify.NewDispatcherWithOptions(s.pool, notify.Options{}).Channels(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		INSERT INTO flake_digests (project_id, frequency, period_start, period_end, report)
+		VALUES ($1, $2::digest_frequency, $3, $4, $5::jsonb)
+		ON CONFLICT (project_id, frequency, period_start) DO NOTHING
+		RETURNING ` + digestColumns
+
+	d, err := scanDigest(tx.QueryRow(ctx, query, project.ID, frequency, start, end, string(reportJSON)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store digest: %w", err)
+	}
+
+	if len(channels) > 0 {
+		msg := Message(d, project.Name, projectURL)
+		if _, err := notify.NewOutbox(s.pool).Enqueue(ctx, tx, project.ID, nil, channels, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit digest: %w", err)
+	}
+	return d, nil
+}
+
+// Preview computes the digest of a project for the last complete period
+// before now without storing or sending it
+func (s *Service) Preview(ctx context.Context, projectID uuid.UUID, frequency string, now time.Time) (*Preview, error) {
+	start, end := Period(frequency, now)
+	report, err := s.Compute(ctx, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &Preview{Frequency: frequency, PeriodStart: start, PeriodEnd: end, Report: *report}, nil
+}
+
+// GetByID returns a digest of a project
+func (s *Service) GetByID(ctx context.Context, projectID, digestID uuid.UUID) (*Digest, error) {
+	query := `SELECT ` + digestColumns + ` FROM flake_digests WHERE id = $1 AND project_id = $2`
+
+	d, err := scanDigest(s.pool.QueryRow(ctx, query, digestID, projectID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDigestNotFound
+		}
+		return nil, fmt.Errorf("failed to get digest: %w", err)
+	}
+	return d, nil
+}
+
+// ListByProject returns the most recent digests of a project, newest first
+func (s *Service) ListByProject(ctx context.Context, projectID uuid.UUID, limit int) ([]Digest, error) {
+	query := `
+		SELECT ` + digestColumns + `
+		FROM flake_digests
+		WHERE project_id = $1
+		ORDER BY period_end DESC, frequency
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digests: %w", err)
+	}
+	defer rows.Close()
+
+	digests := []Digest{}
+	for rows.Next() {
+		d, err := scanDigest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan digest: %w", err)
+		}
+		digests = append(digests, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list digests: %w", err)
+	}
+
+	return digests, nil
+}
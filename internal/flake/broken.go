@@ -0,0 +1,546 @@
+package flake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliuyar1234/flakeguard/internal/notify"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxBrokenHistoryRuns bounds the default-branch runs of a test walked to
+	// find its failing streak and last passing run
+	maxBrokenHistoryRuns = 100
+	// MaxBrokenTestsListed bounds the broken tests returned by a list
+	MaxBrokenTestsListed = 200
+)
+
+// branchRunOutcome is a test's outcome on one default-branch run, over all
+// attempts of the run
+type branchRunOutcome struct {
+	TestCaseID uuid.UUID
+	CIRunID    uuid.UUID
+	SHA        string
+	Passed     bool // passed on any attempt
+	Failed     bool // failed or errored on any attempt
+	FailureMsg *string
+}
+
+// brokenStreak is the failing streak at the head of a test's default-branch
+// history
+type brokenStreak struct {
+	FailingRuns int
+	FirstBad    *branchRunOutcome // earliest run of the streak
+	LastFailed  *branchRunOutcome // latest run of the streak
+	LastGood    *branchRunOutcome // passing run before the streak, nil if none was seen
+	Passing     *branchRunOutcome // latest run, when the test passed on it
+}
+
+// findBrokenStreak walks a test's default-branch outcomes, newest first, and
+// counts the runs it failed on every attempt of until the last run it passed
+// on. Runs where the test was only skipped are ignored.
+func findBrokenStreak(outcomes []branchRunOutcome) brokenStreak {
+	var streak brokenStreak
+	for i := range outcomes {
+		o := &outcomes[i]
+		switch {
+		case o.Passed:
+			if streak.FailingRuns == 0 {
+				streak.Passing = o
+			} else {
+				streak.LastGood = o
+			}
+			return streak
+		case o.Failed:
+			streak.FailingRuns++
+			streak.FirstBad = o
+			if streak.LastFailed == nil {
+				streak.LastFailed = o
+			}
+		}
+	}
+	return streak
+}
+
+// SuspectRange names the commits that may have broken the test, e.g.
+// "1a2b3c4..5d6e7f8", or only the first failing commit when the test never
+// passed
+func (b BrokenTest) SuspectRange() string {
+	if b.LastGoodSHA == nil {
+		return "up to " + shortSHA(b.FirstBadSHA)
+	}
+	return shortSHA(*b.LastGoodSHA) + ".." + shortSHA(b.FirstBadSHA)
+}
+
+// CompareLink returns the URL of the suspect commits, or ""
+func (b BrokenTest) CompareLink() string {
+	if b.CompareURL == nil {
+		return ""
+	}
+	return *b.CompareURL
+}
+
+// FailureSummary is the first line of the last failure message, or ""
+func (b BrokenTest) FailureSummary() string {
+	if b.LastFailureMessage == nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(*b.LastFailureMessage), "\n")
+	if runes := []rune(line); len(runes) > 200 {
+		return string(runes[:200]) + "..."
+	}
+	return line
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// brokenRun is the CI run broken test detection runs for
+type brokenRun struct {
+	DefaultBranch bool
+	Threshold     int
+	Provider      string
+	RunURL        string
+	Repo          string
+}
+
+// pendingBroken is a breakage opened by detection, to be notified about
+type pendingBroken struct {
+	testCaseID uuid.UUID
+	streak     brokenStreak
+}
+
+// detectBroken updates the broken tests of a project after a default-branch
+// run: tests that failed on every attempt of the project's threshold of
+// consecutive runs are opened as broken, and open breakages of tests that
+// passed are fixed. Runs of other branches are ignored. Returns the newly
+// broken tests.
+func (d *Detector) detectBroken(ctx context.Context, tx pgx.Tx, projectID, ciRunID uuid.UUID) ([]pendingBroken, *brokenRun, error) {
+	run, err := d.getBrokenRun(ctx, tx, ciRunID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !run.DefaultBranch {
+		return nil, run, nil
+	}
+
+	candidates, err := d.getBrokenCandidates(ctx, tx, ciRunID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, run, nil
+	}
+
+	outcomes, err := d.getDefaultBranchOutcomes(ctx, tx, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outcomesByTest := make(map[uuid.UUID][]branchRunOutcome)
+	for _, o := range outcomes {
+		outcomesByTest[o.TestCaseID] = append(outcomesByTest[o.TestCaseID], o)
+	}
+
+	var opened []pendingBroken
+	for _, testCaseID := range candidates {
+		streak := findBrokenStreak(outcomesByTest[testCaseID])
+
+		if streak.Passing != nil {
+			fixed, err := fixBrokenTest(ctx, tx, testCaseID, streak.Passing)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fixed {
+				log.Info().
+					Str("test_case_id", testCaseID.String()).
+					Str("ci_run_id", streak.Passing.CIRunID.String()).
+					Msg("Broken test fixed")
+			}
+			continue
+		}
+
+		if streak.FailingRuns < run.Threshold {
+			continue
+		}
+
+		inserted, err := openBrokenTest(ctx, tx, projectID, testCaseID, streak)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inserted {
+			log.Info().
+				Str("test_case_id", testCaseID.String()).
+				Str("first_bad_ci_run_id", streak.FirstBad.CIRunID.String()).
+				Int("failing_runs", streak.FailingRuns).
+				Msg("Broken test detected")
+			opened = append(opened, pendingBroken{testCaseID: testCaseID, streak: streak})
+		}
+	}
+
+	return opened, run, nil
+}
+
+// getBrokenRun retrieves the run's branch class and the project's threshold
+func (d *Detector) getBrokenRun(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) (*brokenRun, error) {
+	query := `
+		SELECT
+			` + branchClassSQL + ` = 'default',
+			p.broken_test_runs,
+			cr.provider::text,
+			cr.run_url,
+			cr.repo_full_name
+		FROM ci_runs cr
+		JOIN projects p ON p.id = cr.project_id
+		WHERE cr.id = $1
+	`
+
+	var run brokenRun
+	err := tx.QueryRow(ctx, query, ciRunID).Scan(&run.DefaultBranch, &run.Threshold, &run.Provider, &run.RunURL, &run.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run branch: %w", err)
+	}
+	return &run, nil
+}
+
+// getBrokenCandidates returns the tests of a run whose broken state may have
+// changed: those that failed on every attempt, and those that passed while
+// having an open breakage
+func (d *Detector) getBrokenCandidates(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT tr.test_case_id
+		FROM test_results tr
+		JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+		JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+		WHERE cra.ci_run_id = $1
+		GROUP BY tr.test_case_id
+		HAVING (BOOL_OR(tr.status IN ('failed', 'error')) AND NOT BOOL_OR(tr.status = 'passed'))
+		    OR (BOOL_OR(tr.status = 'passed') AND EXISTS (
+				SELECT 1 FROM broken_tests bt
+				WHERE bt.test_case_id = tr.test_case_id AND bt.fixed_at IS NULL
+			))
+		ORDER BY tr.test_case_id
+	`
+
+	rows, err := tx.Query(ctx, query, ciRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broken test candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan broken test candidate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// getDefaultBranchOutcomes retrieves the latest default-branch run outcomes of
+// the given tests, newest run first per test
+func (d *Detector) getDefaultBranchOutcomes(ctx context.Context, tx pgx.Tx, testCaseIDs []uuid.UUID) ([]branchRunOutcome, error) {
+	query := `
+		SELECT test_case_id, ci_run_id, sha, passed, failed, failure_message
+		FROM (
+			SELECT
+				tr.test_case_id,
+				cr.id AS ci_run_id,
+				cr.sha,
+				BOOL_OR(tr.status = 'passed') AS passed,
+				BOOL_OR(tr.status IN ('failed', 'error')) AS failed,
+				(ARRAY_AGG(tr.failure_message ORDER BY cra.attempt_number DESC)
+					FILTER (WHERE tr.status IN ('failed', 'error')))[1] AS failure_message,
+				ROW_NUMBER() OVER (PARTITION BY tr.test_case_id ORDER BY cr.first_seen_at DESC, cr.id DESC) AS position
+			FROM test_results tr
+			JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+			JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+			JOIN ci_runs cr ON cr.id = cra.ci_run_id
+			JOIN projects p ON p.id = cr.project_id
+			WHERE tr.test_case_id = ANY($1)
+			  AND ` + branchClassSQL + ` = 'default'
+			GROUP BY tr.test_case_id, cr.id, cr.sha, cr.first_seen_at
+		) history
+		WHERE position <= $2
+		ORDER BY test_case_id, position
+	`
+
+	rows, err := tx.Query(ctx, query, testCaseIDs, maxBrokenHistoryRuns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query default-branch outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []branchRunOutcome
+	for rows.Next() {
+		var o branchRunOutcome
+		if err := rows.Scan(&o.TestCaseID, &o.CIRunID, &o.SHA, &o.Passed, &o.Failed, &o.FailureMsg); err != nil {
+			return nil, fmt.Errorf("failed to scan default-branch outcome: %w", err)
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+// openBrokenTest records a breakage, or extends the test's open one. Returns
+// inserted=false if the test was already broken.
+func openBrokenTest(ctx context.Context, tx pgx.Tx, projectID, testCaseID uuid.UUID, streak brokenStreak) (bool, error) {
+	var lastGoodRunID *uuid.UUID
+	var lastGoodSHA *string
+	if streak.LastGood != nil {
+		lastGoodRunID = &streak.LastGood.CIRunID
+		lastGoodSHA = &streak.LastGood.SHA
+	}
+	message := truncateMessage(streak.LastFailed.FailureMsg)
+
+	insert := `
+		INSERT INTO broken_tests (
+			test_case_id,
+			project_id,
+			failing_runs,
+			first_bad_ci_run_id,
+			first_bad_sha,
+			last_good_ci_run_id,
+			last_good_sha,
+			last_failure_message
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (test_case_id) WHERE fixed_at IS NULL DO NOTHING
+		RETURNING id
+	`
+
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, insert,
+		testCaseID,
+		projectID,
+		streak.FailingRuns,
+		streak.FirstBad.CIRunID,
+		streak.FirstBad.SHA,
+		lastGoodRunID,
+		lastGoodSHA,
+		message,
+	).Scan(&id)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("failed to insert broken test: %w", err)
+	}
+
+	// Already broken; the suspect range stays the one first detected
+	update := `
+		UPDATE broken_tests
+		SET failing_runs = GREATEST(failing_runs, $2),
+		    last_failure_message = $3,
+		    last_failed_at = NOW()
+		WHERE test_case_id = $1 AND fixed_at IS NULL
+	`
+	if _, err := tx.Exec(ctx, update, testCaseID, streak.FailingRuns, message); err != nil {
+		return false, fmt.Errorf("failed to update broken test: %w", err)
+	}
+	return false, nil
+}
+
+// fixBrokenTest closes the open breakage of a test that passed on a
+// default-branch run. Returns false if the test was not broken.
+func fixBrokenTest(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID, passing *branchRunOutcome) (bool, error) {
+	query := `
+		UPDATE broken_tests
+		SET fixed_at = NOW(),
+		    fixed_ci_run_id = $2,
+		    fixed_sha = $3
+		WHERE test_case_id = $1 AND fixed_at IS NULL
+	`
+
+	tag, err := tx.Exec(ctx, query, testCaseID, passing.CIRunID, passing.SHA)
+	if err != nil {
+		return false, fmt.Errorf("failed to fix broken test: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// enqueueBrokenNotifications queues a single message about the tests a run
+// found broken for each of the project's channels. Quarantined tests are left
+// out. A breakage is only notified when it is opened, so the flake
+// notification cooldown does not apply.
+func (d *Detector) enqueueBrokenNotifications(ctx context.Context, tx pgx.Tx, projectID, ciRunID uuid.UUID, run *brokenRun, broken []pendingBroken) error {
+	target, err := d.loadNotificationTarget(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+
+	msgRun, err := d.getRunInfo(ctx, tx, ciRunID)
+	if err != nil {
+		return err
+	}
+	msgRun.DashboardURL = d.buildBrokenTestsURL(target.org.Slug, target.project.Slug)
+
+	rules, err := quarantine.NewService(d.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to load quarantine rules: %w", err)
+	}
+
+	var tests []notify.BrokenTest
+	for _, b := range broken {
+		info, err := d.getFlakeInfo(ctx, tx, ciRunID, b.testCaseID)
+		if err != nil {
+			return err
+		}
+		if rule := rules.Match(info.TestIdentifier); rule != nil {
+			log.Debug().
+				Str("test_case_id", b.testCaseID.String()).
+				Str("quarantine_rule_id", rule.ID.String()).
+				Msg("Test is quarantined, skipping broken test notification")
+			continue
+		}
+
+		test := notify.BrokenTest{
+			Repo:         info.RepoFullName,
+			Workflow:     info.WorkflowName,
+			Job:          info.JobName,
+			JobVariant:   info.JobVariant,
+			TestID:       info.TestIdentifier,
+			FailingRuns:  b.streak.FailingRuns,
+			FirstBadSHA:  b.streak.FirstBad.SHA,
+			DashboardURL: msgRun.DashboardURL + "#" + b.testCaseID.String(),
+		}
+		if b.streak.LastGood != nil {
+			test.LastGoodSHA = b.streak.LastGood.SHA
+			test.CompareURL = CompareURL(run.Provider, run.RunURL, run.Repo, test.LastGoodSHA, test.FirstBadSHA)
+		}
+		if msg := b.streak.LastFailed.FailureMsg; msg != nil {
+			test.FailureMessage = *truncateMessage(msg)
+		}
+		tests = append(tests, test)
+	}
+	if len(tests) == 0 {
+		return nil
+	}
+
+	msg := &notify.Message{
+		Event:       notify.EventTestBroken,
+		ProjectName: target.project.Name,
+		Run:         msgRun,
+		BrokenTests: tests,
+	}
+	if _, err := d.outbox.Enqueue(ctx, tx, projectID, &ciRunID, target.channels, msg); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("ci_run_id", ciRunID.String()).
+		Int("broken_tests", len(tests)).
+		Int("channels", len(target.channels)).
+		Msg("Broken test notification queued")
+	return nil
+}
+
+// buildBrokenTestsURL constructs the dashboard URL of the project's broken tests
+func (d *Detector) buildBrokenTestsURL(orgSlug, projectSlug string) string {
+	return fmt.Sprintf("%s/orgs/%s/projects/%s/broken-tests", d.dashboardBase(), orgSlug, projectSlug)
+}
+
+// ListBrokenTests returns the broken tests of a project with the given
+// status, most recently detected first
+func (s *Service) ListBrokenTests(ctx context.Context, projectID uuid.UUID, status BrokenStatus, limit int) ([]BrokenTest, error) {
+	query := `
+		SELECT
+			bt.id,
+			bt.test_case_id,
+			tc.repo_full_name,
+			tc.job_name,
+			tc.job_variant,
+			tc.test_identifier,
+			bt.failing_runs,
+			bt.first_bad_ci_run_id,
+			bt.first_bad_sha,
+			bt.last_good_ci_run_id,
+			bt.last_good_sha,
+			COALESCE(cr.provider::text, ''),
+			COALESCE(cr.run_url, ''),
+			bt.last_failure_message,
+			bt.detected_at,
+			bt.last_failed_at,
+			bt.fixed_sha,
+			bt.fixed_at
+		FROM broken_tests bt
+		JOIN test_cases tc ON tc.id = bt.test_case_id
+		LEFT JOIN ci_runs cr ON cr.id = bt.first_bad_ci_run_id
+		WHERE bt.project_id = $1
+		  AND ($2 = 'all' OR ($2 = 'open') = (bt.fixed_at IS NULL))
+		ORDER BY bt.fixed_at IS NULL DESC, bt.detected_at DESC, tc.test_identifier
+		LIMIT $3
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID, string(status), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list broken tests: %w", err)
+	}
+	defer rows.Close()
+
+	broken := []BrokenTest{}
+	for rows.Next() {
+		var b BrokenTest
+		var provider, runURL string
+		if err := rows.Scan(
+			&b.ID,
+			&b.TestCaseID,
+			&b.RepoFullName,
+			&b.JobName,
+			&b.JobVariant,
+			&b.TestIdentifier,
+			&b.FailingRuns,
+			&b.FirstBadCIRunID,
+			&b.FirstBadSHA,
+			&b.LastGoodCIRunID,
+			&b.LastGoodSHA,
+			&provider,
+			&runURL,
+			&b.LastFailureMessage,
+			&b.DetectedAt,
+			&b.LastFailedAt,
+			&b.FixedSHA,
+			&b.FixedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan broken test: %w", err)
+		}
+		if b.LastGoodSHA != nil {
+			if compareURL := CompareURL(provider, runURL, b.RepoFullName, *b.LastGoodSHA, b.FirstBadSHA); compareURL != "" {
+				b.CompareURL = &compareURL
+			}
+		}
+		broken = append(broken, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list broken tests: %w", err)
+	}
+	rows.Close()
+
+	rules, err := quarantine.NewService(s.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range broken {
+		if rule := rules.Match(broken[i].TestIdentifier); rule != nil {
+			broken[i].Quarantined = true
+			broken[i].QuarantineRuleID = &rule.ID
+		}
+	}
+
+	return broken, nil
+}
@@ -0,0 +1,55 @@
+package flake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBrokenStreak_CountsFailuresUntilLastPass(t *testing.T) {
+	msg := "expected 1, got 2"
+	outcomes := []branchRunOutcome{
+		{SHA: "ccc", Failed: true, FailureMsg: &msg},
+		{SHA: "skipped"},
+		{SHA: "bbb", Failed: true},
+		{SHA: "aaa", Passed: true},
+		{SHA: "older", Failed: true},
+	}
+
+	streak := findBrokenStreak(outcomes)
+	require.Equal(t, 2, streak.FailingRuns)
+	require.Equal(t, "ccc", streak.LastFailed.SHA)
+	require.Equal(t, &msg, streak.LastFailed.FailureMsg)
+	require.Equal(t, "bbb", streak.FirstBad.SHA)
+	require.Equal(t, "aaa", streak.LastGood.SHA)
+	require.Nil(t, streak.Passing)
+}
+
+func TestFindBrokenStreak_PassOnAnyAttemptEndsStreak(t *testing.T) {
+	streak := findBrokenStreak([]branchRunOutcome{
+		{SHA: "bbb", Failed: true, Passed: true},
+		{SHA: "aaa", Failed: true},
+	})
+	require.Zero(t, streak.FailingRuns)
+	require.Equal(t, "bbb", streak.Passing.SHA)
+	require.Nil(t, streak.FirstBad)
+}
+
+func TestFindBrokenStreak_NeverPassed(t *testing.T) {
+	streak := findBrokenStreak([]branchRunOutcome{
+		{SHA: "bbb", Failed: true},
+		{SHA: "aaa", Failed: true},
+	})
+	require.Equal(t, 2, streak.FailingRuns)
+	require.Equal(t, "aaa", streak.FirstBad.SHA)
+	require.Nil(t, streak.LastGood)
+}
+
+func TestBrokenTest_SuspectRange(t *testing.T) {
+	good := "1111111aaaaaaa"
+	b := BrokenTest{FirstBadSHA: "2222222bbbbbbb", LastGoodSHA: &good}
+	require.Equal(t, "1111111..2222222", b.SuspectRange())
+
+	b.LastGoodSHA = nil
+	require.Equal(t, "up to 2222222", b.SuspectRange())
+}
@@ -5,23 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
 	"github.com/aliuyar1234/flakeguard/internal/orgs"
 	"github.com/aliuyar1234/flakeguard/internal/projects"
-	"github.com/aliuyar1234/flakeguard/internal/slack"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
 // Detector handles flake detection logic
 type Detector struct {
-	pool        *pgxpool.Pool
-	slackClient *slack.Client
-	baseURL     string
+	pool       *pgxpool.Pool
+	dispatcher *notify.Dispatcher
+	outbox     *notify.Outbox
+	baseURL    string
+
+	// quietPeriod delays a run's flake notification so later jobs of the
+	// run are merged into it
+	quietPeriod time.Duration
 }
 
 // NewDetector creates a new flake detector
@@ -31,12 +37,15 @@ func NewDetector(pool *pgxpool.Pool) *Detector {
 	}
 }
 
-// NewDetectorWithSlack creates a new flake detector with Slack notifications enabled
-func NewDetectorWithSlack(pool *pgxpool.Pool, cfg *config.Config) *Detector {
+// NewDetectorWithNotifications creates a new flake detector that queues
+// notifications of new flakes for the project's channels
+func NewDetectorWithNotifications(pool *pgxpool.Pool, cfg *config.Config) *Detector {
 	return &Detector{
 		pool:        pool,
-		slackClient: slack.NewClient(cfg.SlackTimeoutMS),
+		dispatcher:  notify.NewDispatcher(pool, cfg),
+		outbox:      notify.NewOutbox(pool),
 		baseURL:     cfg.BaseURL,
+		quietPeriod: time.Duration(cfg.NotifyQuietSeconds) * time.Second,
 	}
 }
 
@@ -48,7 +57,7 @@ type testAttempt struct {
 	FailureMsg    *string
 }
 
-// DetectFlakes detects flaky tests within a CI run
+// DetectFlakes detects flaky tests within a CI run, and across other CI runs of the same SHA
 // Returns number of flake events created
 func (d *Detector) DetectFlakes(ctx context.Context, projectID, ciRunID uuid.UUID) (int, error) {
 	log.Debug().
@@ -77,15 +86,10 @@ func (d *Detector) DetectFlakes(ctx context.Context, projectID, ciRunID uuid.UUI
 		attemptsByTest[attempt.TestCaseID] = append(attemptsByTest[attempt.TestCaseID], attempt)
 	}
 
-	type notification struct {
-		testCaseID    uuid.UUID
-		failedAttempt int
-		passedAttempt int
-	}
-
 	flakeEventsCreated := 0
 	statsService := NewStatsService(d.pool)
-	var notifications []notification
+	scored := make(map[uuid.UUID]bool)
+	var notifications []pendingNotification
 
 	// Detect flakes for each test
 	for testCaseID, testAttempts := range attemptsByTest {
@@ -93,18 +97,18 @@ func (d *Detector) DetectFlakes(ctx context.Context, projectID, ciRunID uuid.UUI
 
 		if flakeDetected {
 			// Create flake event
-			eventID, err := d.insertFlakeEvent(ctx, tx, testCaseID, ciRunID, failedAttempt, passedAttempt)
+			eventID, inserted, err := d.insertFlakeEvent(ctx, tx, testCaseID, ciRunID, EventKindRetryAttempt, failedAttempt, passedAttempt)
 			if err != nil {
-				// Log but don't fail on duplicate constraint violations (idempotency)
-				if isDuplicateKeyError(err) {
-					log.Debug().
-						Str("test_case_id", testCaseID.String()).
-						Str("ci_run_id", ciRunID.String()).
-						Msg("Flake event already exists (duplicate ingestion)")
-					continue
-				}
 				return 0, fmt.Errorf("failed to insert flake event: %w", err)
 			}
+			if !inserted {
+				// Don't fail on existing events (idempotency)
+				log.Debug().
+					Str("test_case_id", testCaseID.String()).
+					Str("ci_run_id", ciRunID.String()).
+					Msg("Flake event already exists (duplicate ingestion)")
+				continue
+			}
 
 			log.Info().
 				Str("event_id", eventID.String()).
@@ -118,9 +122,12 @@ func (d *Detector) DetectFlakes(ctx context.Context, projectID, ciRunID uuid.UUI
 			if err := statsService.UpdateStats(ctx, tx, testCaseID, ciRunID, failureMsg); err != nil {
 				return 0, fmt.Errorf("failed to update stats: %w", err)
 			}
+			scored[testCaseID] = true
 
 			flakeEventsCreated++
-			notifications = append(notifications, notification{
+			notifications = append(notifications, pendingNotification{
+				kind:          EventKindRetryAttempt,
+				ciRunID:       ciRunID,
 				testCaseID:    testCaseID,
 				failedAttempt: failedAttempt,
 				passedAttempt: passedAttempt,
@@ -128,19 +135,129 @@ func (d *Detector) DetectFlakes(ctx context.Context, projectID, ciRunID uuid.UUI
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	// Detect flakes retried by the test framework within a single attempt. A test that
+	// already has a retry_attempt event for this run is skipped by the unique constraint.
+	inJobRetries, err := d.getInJobRetries(ctx, tx, ciRunID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get in-job retries: %w", err)
+	}
+
+	for _, retry := range inJobRetries {
+		eventID, inserted, err := d.insertFlakeEvent(ctx, tx, retry.TestCaseID, ciRunID, EventKindInJobRetry, retry.AttemptNumber, retry.AttemptNumber)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert in-job retry flake event: %w", err)
+		}
+		if !inserted {
+			continue
+		}
+
+		log.Info().
+			Str("event_id", eventID.String()).
+			Str("test_case_id", retry.TestCaseID.String()).
+			Str("ci_run_id", ciRunID.String()).
+			Int("attempt", retry.AttemptNumber).
+			Int("rerun_failures", retry.RerunFailures).
+			Msg("In-job retry flake detected")
+
+		if err := statsService.UpdateStats(ctx, tx, retry.TestCaseID, ciRunID, retry.FailureMsg); err != nil {
+			return 0, fmt.Errorf("failed to update stats: %w", err)
+		}
+		scored[retry.TestCaseID] = true
+
+		flakeEventsCreated++
+		notifications = append(notifications, pendingNotification{
+			kind:          EventKindInJobRetry,
+			ciRunID:       ciRunID,
+			testCaseID:    retry.TestCaseID,
+			failedAttempt: retry.AttemptNumber,
+			passedAttempt: retry.AttemptNumber,
+		})
+	}
+
+	// Detect flakes across separate runs of the same commit
+	outcomes, err := d.getSameSHARunOutcomes(ctx, tx, ciRunID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get same-SHA run outcomes: %w", err)
+	}
+
+	outcomesByTest := make(map[uuid.UUID][]runOutcome)
+	for _, outcome := range outcomes {
+		outcomesByTest[outcome.TestCaseID] = append(outcomesByTest[outcome.TestCaseID], outcome)
+	}
+
+	for testCaseID, testOutcomes := range outcomesByTest {
+		for _, f := range d.detectCrossRunPattern(ciRunID, testOutcomes) {
+			eventID, inserted, err := d.insertCrossRunFlakeEvent(ctx, tx, testCaseID, f)
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert cross-run flake event: %w", err)
+			}
+			if !inserted {
+				log.Debug().
+					Str("test_case_id", testCaseID.String()).
+					Str("failed_ci_run_id", f.FailedRunID.String()).
+					Msg("Flake event already exists for failing run")
+				continue
+			}
+
+			log.Info().
+				Str("event_id", eventID.String()).
+				Str("test_case_id", testCaseID.String()).
+				Str("failed_ci_run_id", f.FailedRunID.String()).
+				Str("passed_ci_run_id", f.PassedRunID.String()).
+				Msg("Cross-run flake detected")
+
+			if err := statsService.UpdateStats(ctx, tx, testCaseID, f.FailedRunID, f.FailureMsg); err != nil {
+				return 0, fmt.Errorf("failed to update stats: %w", err)
+			}
+			scored[testCaseID] = true
+
+			flakeEventsCreated++
+			notifications = append(notifications, pendingNotification{
+				kind:          EventKindSameSHARerun,
+				ciRunID:       f.FailedRunID,
+				testCaseID:    testCaseID,
+				failedAttempt: f.FailedAttempt,
+				passedAttempt: f.PassedAttempt,
+			})
+		}
 	}
 
-	// Send Slack notifications asynchronously after transaction commit
-	// Only send if Slack client is configured
-	if d.slackClient != nil {
-		for _, n := range notifications {
-			go d.notifySlackAsync(projectID, ciRunID, n.testCaseID, n.failedAttempt, n.passedAttempt)
+	// Queue the other flaky tests that ran for rescoring, so tests that
+	// stopped flaking sink in the rankings
+	if _, err := statsService.MarkRunTestsStale(ctx, tx, ciRunID, scored); err != nil {
+		return 0, fmt.Errorf("failed to mark flaky tests stale: %w", err)
+	}
+
+	// Resolve flakes that stayed clean for long enough
+	if err := d.resolveCleanTests(ctx, tx, ciRunID); err != nil {
+		return 0, fmt.Errorf("failed to resolve clean flakes: %w", err)
+	}
+
+	// Detect tests that fail consistently on the default branch. They have no
+	// pass to pair a failure with, so they are never flake events.
+	broken, brokenRun, err := d.detectBroken(ctx, tx, projectID, ciRunID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to detect broken tests: %w", err)
+	}
+
+	// Queue one batched notification for the run in the outbox, so it is sent
+	// if and only if the flake events are committed
+	if d.dispatcher != nil && len(notifications) > 0 {
+		if err := d.enqueueNotifications(ctx, tx, projectID, ciRunID, notifications); err != nil {
+			return 0, fmt.Errorf("failed to enqueue notifications: %w", err)
+		}
+	}
+	if d.dispatcher != nil && len(broken) > 0 {
+		if err := d.enqueueBrokenNotifications(ctx, tx, projectID, ciRunID, brokenRun, broken); err != nil {
+			return 0, fmt.Errorf("failed to enqueue broken test notifications: %w", err)
 		}
 	}
 
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	log.Info().
 		Str("ci_run_id", ciRunID.String()).
 		Int("flake_events_created", flakeEventsCreated).
@@ -213,17 +330,219 @@ func (d *Detector) detectFlakePattern(attempts []testAttempt) (bool, int, int, *
 	return false, 0, 0, nil
 }
 
-// insertFlakeEvent creates a flake event record
-func (d *Detector) insertFlakeEvent(ctx context.Context, tx pgx.Tx, testCaseID, ciRunID uuid.UUID, failedAttempt, passedAttempt int) (uuid.UUID, error) {
+// insertFlakeEvent creates a flake event record within a single CI run (retry_attempt or in_job_retry).
+// Uses ON CONFLICT DO NOTHING rather than surfacing a unique violation, which would
+// abort the surrounding transaction. Returns inserted=false if the event already exists.
+func (d *Detector) insertFlakeEvent(ctx context.Context, tx pgx.Tx, testCaseID, ciRunID uuid.UUID, kind EventKind, failedAttempt, passedAttempt int) (uuid.UUID, bool, error) {
 	query := `
-		INSERT INTO flake_events (test_case_id, ci_run_id, failed_attempt_number, passed_attempt_number)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO flake_events (test_case_id, ci_run_id, kind, failed_attempt_number, passed_attempt_number)
+		VALUES ($1, $2, $3::flake_event_kind, $4, $5)
+		ON CONFLICT (test_case_id, ci_run_id) DO NOTHING
 		RETURNING id
 	`
 
 	var eventID uuid.UUID
-	err := tx.QueryRow(ctx, query, testCaseID, ciRunID, failedAttempt, passedAttempt).Scan(&eventID)
-	return eventID, err
+	err := tx.QueryRow(ctx, query, testCaseID, ciRunID, string(kind), failedAttempt, passedAttempt).Scan(&eventID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return eventID, true, nil
+}
+
+// inJobRetry is a passed test result that needed framework-level reruns
+type inJobRetry struct {
+	TestCaseID    uuid.UUID
+	AttemptNumber int
+	RerunFailures int
+	FailureMsg    *string
+}
+
+// getInJobRetries retrieves passed test results of a CI run that failed at least once
+// on a test framework rerun within the same attempt (earliest attempt per test)
+func (d *Detector) getInJobRetries(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) ([]inJobRetry, error) {
+	query := `
+		SELECT DISTINCT ON (tr.test_case_id)
+			tr.test_case_id,
+			cra.attempt_number,
+			tr.rerun_failures,
+			tr.rerun_failure_message
+		FROM test_results tr
+		JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+		JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+		WHERE cra.ci_run_id = $1
+		  AND tr.status = 'passed'
+		  AND tr.rerun_failures > 0
+		ORDER BY tr.test_case_id, cra.attempt_number
+	`
+
+	rows, err := tx.Query(ctx, query, ciRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var retries []inJobRetry
+	for rows.Next() {
+		var r inJobRetry
+		if err := rows.Scan(&r.TestCaseID, &r.AttemptNumber, &r.RerunFailures, &r.FailureMsg); err != nil {
+			return nil, err
+		}
+		retries = append(retries, r)
+	}
+
+	return retries, rows.Err()
+}
+
+// runOutcome summarizes a test's attempts within one CI run
+type runOutcome struct {
+	TestCaseID         uuid.UUID
+	CIRunID            uuid.UUID
+	LastFailedAttempt  int // 0 if the test never failed in this run
+	FirstPassedAttempt int // 0 if the test never passed in this run
+	FailureMsg         *string
+}
+
+// crossRunFlake is a fail on one run paired with a pass on another run of the same SHA
+type crossRunFlake struct {
+	FailedRunID   uuid.UUID
+	PassedRunID   uuid.UUID
+	FailedAttempt int
+	PassedAttempt int
+	FailureMsg    *string
+}
+
+// getSameSHARunOutcomes retrieves per-run outcomes for every test in this CI run across
+// all runs of the same project, repo and SHA. Job name and variant are part of the
+// test case identity, so grouping by test_case_id keeps jobs and variants apart.
+// Outcomes are ordered by the run's first_seen_at so pairing is deterministic.
+func (d *Detector) getSameSHARunOutcomes(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) ([]runOutcome, error) {
+	query := `
+		WITH current_run AS (
+			SELECT project_id, repo_full_name, sha
+			FROM ci_runs
+			WHERE id = $1
+		),
+		current_tests AS (
+			SELECT DISTINCT tr.test_case_id
+			FROM test_results tr
+			JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+			JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+			WHERE cra.ci_run_id = $1
+		)
+		SELECT
+			tr.test_case_id,
+			cr.id,
+			COALESCE(MAX(cra.attempt_number) FILTER (WHERE tr.status IN ('failed', 'error')), 0),
+			COALESCE(MIN(cra.attempt_number) FILTER (WHERE tr.status = 'passed'), 0),
+			(ARRAY_AGG(tr.failure_message ORDER BY cra.attempt_number DESC)
+				FILTER (WHERE tr.status IN ('failed', 'error')))[1]
+		FROM test_results tr
+		JOIN current_tests ct ON ct.test_case_id = tr.test_case_id
+		JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+		JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+		JOIN ci_runs cr ON cr.id = cra.ci_run_id
+		JOIN current_run cur
+		  ON cr.project_id = cur.project_id
+		 AND cr.repo_full_name = cur.repo_full_name
+		 AND cr.sha = cur.sha
+		GROUP BY tr.test_case_id, cr.id, cr.first_seen_at
+		ORDER BY tr.test_case_id, cr.first_seen_at, cr.id
+	`
+
+	rows, err := tx.Query(ctx, query, ciRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []runOutcome
+	for rows.Next() {
+		var o runOutcome
+		if err := rows.Scan(&o.TestCaseID, &o.CIRunID, &o.LastFailedAttempt, &o.FirstPassedAttempt, &o.FailureMsg); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+
+	return outcomes, rows.Err()
+}
+
+// detectCrossRunPattern pairs failing runs with passing runs of the same SHA.
+// A run counts as failing only if the test never passed in it; runs that failed and
+// then passed on retry are already covered by detectFlakePattern. Only pairs involving
+// the current run are returned, so each ingestion reports just the evidence it added.
+func (d *Detector) detectCrossRunPattern(currentRunID uuid.UUID, outcomes []runOutcome) []crossRunFlake {
+	var current *runOutcome
+	for i := range outcomes {
+		if outcomes[i].CIRunID == currentRunID {
+			current = &outcomes[i]
+			break
+		}
+	}
+	if current == nil || len(outcomes) < 2 {
+		return nil
+	}
+
+	failedOnly := func(o *runOutcome) bool {
+		return o.LastFailedAttempt > 0 && o.FirstPassedAttempt == 0
+	}
+
+	var flakes []crossRunFlake
+	switch {
+	case failedOnly(current):
+		// Pair with the earliest run that passed
+		for i := range outcomes {
+			o := &outcomes[i]
+			if o.CIRunID != currentRunID && o.FirstPassedAttempt > 0 {
+				flakes = append(flakes, newCrossRunFlake(current, o))
+				break
+			}
+		}
+	case current.FirstPassedAttempt > 0:
+		// Every earlier failing run is now contradicted by this pass
+		for i := range outcomes {
+			o := &outcomes[i]
+			if o.CIRunID != currentRunID && failedOnly(o) {
+				flakes = append(flakes, newCrossRunFlake(o, current))
+			}
+		}
+	}
+
+	return flakes
+}
+
+func newCrossRunFlake(failed, passed *runOutcome) crossRunFlake {
+	return crossRunFlake{
+		FailedRunID:   failed.CIRunID,
+		PassedRunID:   passed.CIRunID,
+		FailedAttempt: failed.LastFailedAttempt,
+		PassedAttempt: passed.FirstPassedAttempt,
+		FailureMsg:    failed.FailureMsg,
+	}
+}
+
+// insertCrossRunFlakeEvent creates a same_sha_rerun flake event anchored on the failing run.
+// Returns inserted=false if the failing run already has a flake event for this test.
+func (d *Detector) insertCrossRunFlakeEvent(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID, f crossRunFlake) (uuid.UUID, bool, error) {
+	query := `
+		INSERT INTO flake_events (test_case_id, ci_run_id, kind, passed_ci_run_id, failed_attempt_number, passed_attempt_number)
+		VALUES ($1, $2, $3::flake_event_kind, $4, $5, $6)
+		ON CONFLICT (test_case_id, ci_run_id) DO NOTHING
+		RETURNING id
+	`
+
+	var eventID uuid.UUID
+	err := tx.QueryRow(ctx, query, testCaseID, f.FailedRunID, string(EventKindSameSHARerun), f.PassedRunID, f.FailedAttempt, f.PassedAttempt).Scan(&eventID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return eventID, true, nil
 }
 
 // isFailed checks if status represents a failure
@@ -236,110 +555,278 @@ func isPassed(status string) bool {
 	return status == "passed"
 }
 
-// isDuplicateKeyError checks if error is a unique constraint violation
-func isDuplicateKeyError(err error) bool {
-	if err == nil {
-		return false
+// pendingNotification is a flake event created by detection, to be notified
+// about
+type pendingNotification struct {
+	kind          EventKind
+	ciRunID       uuid.UUID
+	testCaseID    uuid.UUID
+	failedAttempt int
+	passedAttempt int
+}
+
+// enqueueNotifications queues a single message about the flakes detected in a
+// CI run for each of the project's channels. Quarantined tests and tests still
+// within the project's notification cooldown are left out. The outbox workers
+// send the message once tx commits.
+func (d *Detector) enqueueNotifications(ctx context.Context, tx pgx.Tx, projectID, ciRunID uuid.UUID, notifications []pendingNotification) error {
+	target, err := d.loadNotificationTarget(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+	project, org, channels := target.project, target.org, target.channels
+
+	run, err := d.getRunInfo(ctx, tx, ciRunID)
+	if err != nil {
+		return err
+	}
+	run.DashboardURL = d.buildFlakesURL(org.Slug, project.Slug)
+
+	// Skip quarantined tests; the event is still recorded and counted in stats
+	rules, err := quarantine.NewService(d.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to load quarantine rules: %w", err)
+	}
+
+	// A test can have several events in one run (e.g. a retry and a same-SHA
+	// rerun); it is listed once, with the first event's evidence
+	seen := make(map[uuid.UUID]bool, len(notifications))
+	candidates := make(map[uuid.UUID]notify.Flake, len(notifications))
+	var testCaseIDs []uuid.UUID
+	for _, n := range notifications {
+		if seen[n.testCaseID] {
+			continue
+		}
+		seen[n.testCaseID] = true
+
+		flakeInfo, err := d.getFlakeInfo(ctx, tx, n.ciRunID, n.testCaseID)
+		if err != nil {
+			return err
+		}
+		if rule := rules.Match(flakeInfo.TestIdentifier); rule != nil {
+			log.Debug().
+				Str("test_case_id", n.testCaseID.String()).
+				Str("quarantine_rule_id", rule.ID.String()).
+				Msg("Test is quarantined, skipping notification")
+			continue
+		}
+
+		flake := notify.Flake{
+			Repo:          flakeInfo.RepoFullName,
+			Workflow:      flakeInfo.WorkflowName,
+			Job:           flakeInfo.JobName,
+			JobVariant:    flakeInfo.JobVariant,
+			TestID:        flakeInfo.TestIdentifier,
+			CrossRun:      n.kind == EventKindSameSHARerun,
+			InJobRetry:    n.kind == EventKindInJobRetry,
+			FailedAttempt: n.failedAttempt,
+			PassedAttempt: n.passedAttempt,
+			DashboardURL:  d.buildDashboardURL(org.Slug, project.Slug, n.testCaseID),
+			FlakeScore:    flakeInfo.FlakeScore,
+			FlakyRuns:     flakeInfo.MixedOutcomeRuns,
+			TotalRuns:     flakeInfo.TotalRunsSeen,
+		}
+		if flakeInfo.FirstSeenAt != nil {
+			flake.FirstSeenAt = *flakeInfo.FirstSeenAt
+		}
+		candidates[n.testCaseID] = flake
+		testCaseIDs = append(testCaseIDs, n.testCaseID)
 	}
-	var pgErr *pgconn.PgError
-	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+	if len(testCaseIDs) == 0 {
+		return nil
+	}
+
+	// Claimed in the detection transaction, so that concurrent ingestions
+	// notify about a test at most once per cooldown
+	claimed, err := d.claimNotifications(ctx, tx, testCaseIDs, project.NotificationCooldownHours)
+	if err != nil {
+		return err
+	}
+	if len(claimed) == 0 {
+		log.Debug().
+			Str("ci_run_id", ciRunID.String()).
+			Int("flakes", len(testCaseIDs)).
+			Msg("All flakes are within the notification cooldown, skipping notification")
+		return nil
+	}
+
+	flakes := make([]notify.Flake, 0, len(claimed))
+	for _, id := range testCaseIDs {
+		if claimed[id] {
+			flakes = append(flakes, candidates[id])
+		}
+	}
+	notify.SortFlakes(flakes)
+
+	// Other jobs of the run merge into the same message until it goes quiet
+	msg := &notify.Message{
+		Event:       notify.EventFlakeDetected,
+		ProjectName: project.Name,
+		Run:         run,
+		Flakes:      flakes,
+	}
+	if err := d.outbox.EnqueueRunFlakes(ctx, tx, projectID, ciRunID, channels, msg, d.quietPeriod); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("ci_run_id", ciRunID.String()).
+		Int("flakes", len(flakes)).
+		Int("channels", len(channels)).
+		Msg("Flake notification queued")
+	return nil
 }
 
-// notifySlackAsync sends a Slack notification for a flake event
-// This runs in a goroutine and uses a background context to ensure it completes
-// even if the original request context is cancelled
-func (d *Detector) notifySlackAsync(projectID, ciRunID, testCaseID uuid.UUID, failedAttempt, passedAttempt int) {
-	// Use background context (not the request context) to ensure notification completes
-	ctx := context.Background()
+// notificationTarget is a project with the channels its notifications are
+// sent to
+type notificationTarget struct {
+	project  *projects.Project
+	org      *orgs.Org
+	channels []notify.Channel
+}
 
-	// Load project Slack settings
+// loadNotificationTarget loads a project, its org for dashboard URLs and its
+// channels. Returns nil if no channel is configured.
+func (d *Detector) loadNotificationTarget(ctx context.Context, projectID uuid.UUID) (*notificationTarget, error) {
+	// Load project settings
 	projectService := projects.NewService(d.pool)
 	project, err := projectService.GetByID(ctx, projectID)
 	if err != nil {
-		log.Warn().
-			Err(err).
-			Str("project_id", projectID.String()).
-			Msg("Failed to load project for Slack notification")
-		return
+		return nil, fmt.Errorf("failed to load project: %w", err)
 	}
 
-	// Skip if Slack not enabled
-	if !project.SlackEnabled || !project.SlackWebhookURL.Valid || project.SlackWebhookURL.String == "" {
+	// Skip if no channel is configured
+	channels, err := d.dispatcher.Channels(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	if len(channels) == 0 {
 		log.Debug().
 			Str("project_id", projectID.String()).
-			Bool("slack_enabled", project.SlackEnabled).
-			Msg("Slack not configured, skipping notification")
-		return
+			Msg("No notification channels configured, skipping notification")
+		return nil, nil
 	}
 
 	// Load org slug for dashboard URL
 	orgService := orgs.NewService(d.pool)
 	org, err := orgService.GetByID(ctx, project.OrgID)
 	if err != nil {
-		log.Warn().
-			Err(err).
-			Str("org_id", project.OrgID.String()).
-			Msg("Failed to load org for Slack notification")
-		return
+		return nil, fmt.Errorf("failed to load org: %w", err)
 	}
 
-	// Get flake details for the message
-	flakeInfo, err := d.getFlakeInfo(ctx, ciRunID, testCaseID)
+	return &notificationTarget{project: project, org: org, channels: channels}, nil
+}
+
+// claimNotifications marks the tests that are outside the cooldown as
+// notified and returns them. A cooldown of 0 claims every test.
+func (d *Detector) claimNotifications(ctx context.Context, tx pgx.Tx, testCaseIDs []uuid.UUID, cooldownHours int) (map[uuid.UUID]bool, error) {
+	query := `
+		UPDATE flake_stats
+		SET last_notified_at = NOW()
+		WHERE test_case_id = ANY($1)
+		  AND ($2 = 0 OR last_notified_at IS NULL OR last_notified_at < NOW() - make_interval(hours => $2))
+		RETURNING test_case_id
+	`
+
+	rows, err := tx.Query(ctx, query, testCaseIDs, cooldownHours)
 	if err != nil {
-		log.Warn().
-			Err(err).
-			Str("test_case_id", testCaseID.String()).
-			Str("ci_run_id", ciRunID.String()).
-			Msg("Failed to get flake info for Slack notification")
-		return
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	claimed := make(map[uuid.UUID]bool, len(testCaseIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan claimed test case: %w", err)
+		}
+		claimed[id] = true
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	return claimed, nil
+}
 
-	// Build dashboard URL
-	dashboardURL := d.buildDashboardURL(org.Slug, project.Slug, testCaseID)
+// getRunInfo retrieves the CI run a notification is sent for
+func (d *Detector) getRunInfo(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) (*notify.Run, error) {
+	query := `
+		SELECT repo_full_name, workflow_name, branch, sha, provider::text, run_id, run_number, run_url
+		FROM ci_runs
+		WHERE id = $1
+	`
 
-	// Build and send Slack message
-	msg := slack.FlakeMessage{
-		Repo:          flakeInfo.RepoFullName,
-		Workflow:      flakeInfo.WorkflowName,
-		Job:           flakeInfo.JobName,
-		TestID:        flakeInfo.TestIdentifier,
-		FailedAttempt: failedAttempt,
-		PassedAttempt: passedAttempt,
-		DashboardURL:  dashboardURL,
+	var run notify.Run
+	var provider, runID, runNumber, runURL string
+	err := tx.QueryRow(ctx, query, ciRunID).Scan(
+		&run.Repo,
+		&run.Workflow,
+		&run.Branch,
+		&run.SHA,
+		&provider,
+		&runID,
+		&runNumber,
+		&runURL,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run info: %w", err)
 	}
 
-	// Send notification (errors are logged inside PostFlakeNotification)
-	d.slackClient.PostFlakeNotification(ctx, project.SlackWebhookURL.String, msg)
+	run.Label = RunLabel(provider, runID, runNumber)
+	if isWebURL(runURL) {
+		run.URL = runURL
+	}
+	return &run, nil
 }
 
-// flakeInfo contains information needed for Slack notifications
+// flakeInfo contains information needed for notifications
 type flakeInfo struct {
-	RepoFullName   string
-	WorkflowName   string
-	JobName        string
-	TestIdentifier string
+	RepoFullName     string
+	WorkflowName     string
+	JobName          string
+	JobVariant       string
+	TestIdentifier   string
+	MixedOutcomeRuns int
+	TotalRunsSeen    int
+	FlakeScore       float64
+	FirstSeenAt      *time.Time
 }
 
-// getFlakeInfo retrieves flake information for Slack notifications
-func (d *Detector) getFlakeInfo(ctx context.Context, ciRunID, testCaseID uuid.UUID) (*flakeInfo, error) {
+// getFlakeInfo retrieves flake information for notifications
+func (d *Detector) getFlakeInfo(ctx context.Context, tx pgx.Tx, ciRunID, testCaseID uuid.UUID) (*flakeInfo, error) {
 	query := `
 		SELECT
 			cr.repo_full_name,
 			cr.workflow_name,
 			tc.job_name,
-			tc.test_identifier
+			tc.job_variant,
+			tc.test_identifier,
+			COALESCE(fs.mixed_outcome_runs, 0),
+			COALESCE(fs.total_runs_seen, 0),
+			COALESCE(fs.flake_score, 0),
+			fs.first_seen_at
 		FROM ci_runs cr
 		JOIN test_cases tc ON tc.id = $2
+		LEFT JOIN flake_stats fs ON fs.test_case_id = tc.id
 		WHERE cr.id = $1
 		LIMIT 1
 	`
 
 	var info flakeInfo
-	err := d.pool.QueryRow(ctx, query, ciRunID, testCaseID).Scan(
+	err := tx.QueryRow(ctx, query, ciRunID, testCaseID).Scan(
 		&info.RepoFullName,
 		&info.WorkflowName,
 		&info.JobName,
+		&info.JobVariant,
 		&info.TestIdentifier,
+		&info.MixedOutcomeRuns,
+		&info.TotalRunsSeen,
+		&info.FlakeScore,
+		&info.FirstSeenAt,
 	)
 
 	if err != nil {
@@ -349,11 +836,20 @@ func (d *Detector) getFlakeInfo(ctx context.Context, ciRunID, testCaseID uuid.UU
 	return &info, nil
 }
 
+// buildFlakesURL constructs the dashboard URL of the project's flaky tests
+func (d *Detector) buildFlakesURL(orgSlug, projectSlug string) string {
+	return fmt.Sprintf("%s/orgs/%s/projects/%s/flakes", d.dashboardBase(), orgSlug, projectSlug)
+}
+
 // buildDashboardURL constructs the dashboard URL for a flake
 func (d *Detector) buildDashboardURL(orgSlug, projectSlug string, testCaseID uuid.UUID) string {
+	return fmt.Sprintf("%s/orgs/%s/projects/%s/flakes/%s", d.dashboardBase(), orgSlug, projectSlug, testCaseID.String())
+}
+
+func (d *Detector) dashboardBase() string {
 	base := strings.TrimRight(d.baseURL, "/")
 	if base == "" {
 		base = "http://localhost:8080"
 	}
-	return fmt.Sprintf("%s/orgs/%s/projects/%s/flakes/%s", base, orgSlug, projectSlug, testCaseID.String())
+	return base
 }
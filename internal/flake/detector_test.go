@@ -65,3 +65,89 @@ func TestDetector_detectFlakePattern_PassThenFailNoFlake(t *testing.T) {
 	detected, _, _, _ := d.detectFlakePattern(attempts)
 	require.False(t, detected)
 }
+
+func TestDetector_detectCrossRunPattern_CurrentRunPassesAfterEarlierFailure(t *testing.T) {
+	d := &Detector{}
+	msg := "boom"
+	testCaseID := uuid.New()
+	failedRun := uuid.New()
+	currentRun := uuid.New()
+
+	outcomes := []runOutcome{
+		{TestCaseID: testCaseID, CIRunID: failedRun, LastFailedAttempt: 1, FailureMsg: &msg},
+		{TestCaseID: testCaseID, CIRunID: currentRun, FirstPassedAttempt: 1},
+	}
+
+	flakes := d.detectCrossRunPattern(currentRun, outcomes)
+	require.Len(t, flakes, 1)
+	require.Equal(t, failedRun, flakes[0].FailedRunID)
+	require.Equal(t, currentRun, flakes[0].PassedRunID)
+	require.Equal(t, 1, flakes[0].FailedAttempt)
+	require.Equal(t, 1, flakes[0].PassedAttempt)
+	require.NotNil(t, flakes[0].FailureMsg)
+	require.Equal(t, msg, *flakes[0].FailureMsg)
+}
+
+func TestDetector_detectCrossRunPattern_CurrentRunFailsAfterEarlierPass(t *testing.T) {
+	d := &Detector{}
+	testCaseID := uuid.New()
+	passedRun := uuid.New()
+	currentRun := uuid.New()
+
+	outcomes := []runOutcome{
+		{TestCaseID: testCaseID, CIRunID: passedRun, FirstPassedAttempt: 1},
+		{TestCaseID: testCaseID, CIRunID: currentRun, LastFailedAttempt: 2},
+	}
+
+	flakes := d.detectCrossRunPattern(currentRun, outcomes)
+	require.Len(t, flakes, 1)
+	require.Equal(t, currentRun, flakes[0].FailedRunID)
+	require.Equal(t, passedRun, flakes[0].PassedRunID)
+	require.Equal(t, 2, flakes[0].FailedAttempt)
+}
+
+func TestDetector_detectCrossRunPattern_RetriedRunIsNotAFailingRun(t *testing.T) {
+	d := &Detector{}
+	testCaseID := uuid.New()
+	retriedRun := uuid.New()
+	currentRun := uuid.New()
+
+	outcomes := []runOutcome{
+		{TestCaseID: testCaseID, CIRunID: retriedRun, LastFailedAttempt: 1, FirstPassedAttempt: 2},
+		{TestCaseID: testCaseID, CIRunID: currentRun, FirstPassedAttempt: 1},
+	}
+
+	require.Empty(t, d.detectCrossRunPattern(currentRun, outcomes))
+}
+
+func TestDetector_detectCrossRunPattern_IgnoresPairsWithoutCurrentRun(t *testing.T) {
+	d := &Detector{}
+	testCaseID := uuid.New()
+	currentRun := uuid.New()
+
+	outcomes := []runOutcome{
+		{TestCaseID: testCaseID, CIRunID: uuid.New(), LastFailedAttempt: 1},
+		{TestCaseID: testCaseID, CIRunID: uuid.New(), FirstPassedAttempt: 1},
+		{TestCaseID: testCaseID, CIRunID: currentRun, LastFailedAttempt: 1},
+	}
+
+	flakes := d.detectCrossRunPattern(currentRun, outcomes)
+	require.Len(t, flakes, 1)
+	require.Equal(t, currentRun, flakes[0].FailedRunID)
+}
+
+func TestDetector_detectCrossRunPattern_SingleRunNoFlake(t *testing.T) {
+	d := &Detector{}
+	currentRun := uuid.New()
+
+	outcomes := []runOutcome{
+		{TestCaseID: uuid.New(), CIRunID: currentRun, LastFailedAttempt: 1},
+	}
+
+	require.Empty(t, d.detectCrossRunPattern(currentRun, outcomes))
+}
+
+func TestDetector_buildFlakesURL_DefaultsToLocalhost(t *testing.T) {
+	require.Equal(t, "http://localhost:8080/orgs/acme/projects/api/flakes", (&Detector{}).buildFlakesURL("acme", "api"))
+	require.Equal(t, "https://fg.example.com/orgs/acme/projects/api/flakes", (&Detector{baseURL: "https://fg.example.com/"}).buildFlakesURL("acme", "api"))
+}
@@ -1,11 +1,19 @@
 package flake
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/audit"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,11 +22,15 @@ import (
 
 // ListFlakesRequest represents query parameters for listing flakes (internal pagination used by UI).
 type ListFlakesRequest struct {
-	Days    int
-	Repo    string
-	JobName string
-	Limit   int
-	Offset  int
+	Days        int
+	Repo        string
+	JobName     string
+	JobVariant  *string // nil means any variant; "" matches jobs without a variant
+	BranchClass BranchClass
+	Statuses    []FlakeStatus // nil means any status
+	Owner       string        // a CODEOWNERS owner, or "unowned" for tests without owners
+	Limit       int
+	Offset      int
 }
 
 // HandleListFlakes handles GET /api/v1/projects/{project_id}/flakes.
@@ -33,7 +45,11 @@ func HandleListFlakes(pool *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
-		req := parseListFlakesRequest(r)
+		req, err := parseListFlakesRequest(r)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
 
 		service := NewService(pool)
 		flakes, _, err := service.ListFlakes(ctx, projectID, req)
@@ -96,7 +112,95 @@ func HandleGetFlakeDetail(pool *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-func parseListFlakesRequest(r *http.Request) ListFlakesRequest {
+// HandleGetProjectTrend handles GET /api/v1/projects/{project_id}/trend?days=30&repo=...&job_name=...&job_variant=...
+func HandleGetProjectTrend(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project_id")
+			return
+		}
+
+		service := NewService(pool)
+		trend, err := service.GetProjectTrend(ctx, projectID, parseTrendRequest(r), time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to get project trend")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve trend")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"trend": trend,
+		})
+	}
+}
+
+// HandleGetFlakeTrend handles GET /api/v1/projects/{project_id}/flakes/{test_case_id}/trend?days=30.
+func HandleGetFlakeTrend(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project_id")
+			return
+		}
+
+		testCaseIDStr := chi.URLParam(r, "test_case_id")
+		testCaseID, err := uuid.Parse(testCaseIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid test_case_id")
+			return
+		}
+
+		service := NewService(pool)
+		trend, err := service.GetTestTrend(ctx, projectID, testCaseID, parseTrendRequest(r).Days, time.Now())
+		if err != nil {
+			if errors.Is(err, ErrFlakeNotFound) {
+				apperrors.WriteNotFound(w, r, "Test not found")
+				return
+			}
+			log.Error().Err(err).
+				Str("project_id", projectID.String()).
+				Str("test_case_id", testCaseID.String()).
+				Msg("Failed to get flake trend")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve trend")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"trend": trend,
+		})
+	}
+}
+
+// parseTrendRequest reads the days and test filters of a trend request.
+// days defaults to DefaultTrendDays and is capped at MaxTrendDays.
+func parseTrendRequest(r *http.Request) TrendRequest {
+	req := TrendRequest{Days: DefaultTrendDays}
+
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days > 0 {
+			req.Days = min(days, MaxTrendDays)
+		}
+	}
+
+	req.Repo = r.URL.Query().Get("repo")
+	req.JobName = r.URL.Query().Get("job_name")
+
+	if r.URL.Query().Has("job_variant") {
+		jobVariant := r.URL.Query().Get("job_variant")
+		req.JobVariant = &jobVariant
+	}
+
+	return req
+}
+
+func parseListFlakesRequest(r *http.Request) (ListFlakesRequest, error) {
 	req := ListFlakesRequest{
 		Days:   30,
 		Limit:  100,
@@ -117,5 +221,176 @@ func parseListFlakesRequest(r *http.Request) ListFlakesRequest {
 		req.JobName = jobName
 	}
 
-	return req
+	if r.URL.Query().Has("job_variant") {
+		jobVariant := r.URL.Query().Get("job_variant")
+		req.JobVariant = &jobVariant
+	}
+
+	if branchClass := r.URL.Query().Get("branch_class"); branchClass != "" {
+		parsed, ok := ParseBranchClass(branchClass)
+		if !ok {
+			return req, errors.New("branch_class must be one of: default, pr, other")
+		}
+		req.BranchClass = parsed
+	}
+
+	statuses, err := ParseFlakeStatusFilter(r.URL.Query().Get("status"))
+	if err != nil {
+		return req, err
+	}
+	req.Statuses = statuses
+
+	req.Owner = strings.TrimSpace(r.URL.Query().Get("owner"))
+
+	return req, nil
+}
+
+// HandleListBrokenTests handles GET /api/v1/projects/{project_id}/broken-tests?status=open.
+func HandleListBrokenTests(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project_id")
+			return
+		}
+
+		status, err := ParseBrokenStatusQuery(r)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
+
+		service := NewService(pool)
+		broken, err := service.ListBrokenTests(ctx, projectID, status, MaxBrokenTestsListed)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to list broken tests")
+			apperrors.WriteInternalError(w, r, "Failed to retrieve broken tests")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"broken_tests": broken,
+		})
+	}
+}
+
+// ParseBrokenStatusQuery reads the status filter of a broken test list,
+// defaulting to open breakages
+func ParseBrokenStatusQuery(r *http.Request) (BrokenStatus, error) {
+	value := r.URL.Query().Get("status")
+	if value == "" {
+		return BrokenStatusOpen, nil
+	}
+	status, ok := ParseBrokenStatus(value)
+	if !ok {
+		return "", errors.New("status must be one of: open, fixed, all")
+	}
+	return status, nil
+}
+
+// MaxStatusNoteLength bounds the note of a manual status change
+const MaxStatusNoteLength = 1000
+
+// ChangeStatusRequest represents the request to change a flake's status
+type ChangeStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// HandleChangeFlakeStatus handles PUT /api/v1/projects/{project_id}/flakes/{test_case_id}/status.
+// Any member but a VIEWER can triage flakes.
+func HandleChangeFlakeStatus(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project_id")
+			return
+		}
+
+		testCaseID, err := uuid.Parse(chi.URLParam(r, "test_case_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid test_case_id")
+			return
+		}
+
+		project, err := projects.NewService(pool).GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, projects.ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		_, err = orgs.NewService(pool).CheckOrgRole(ctx, userID, project.OrgID, orgs.RoleMember)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		var req ChangeStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		status, ok := ParseFlakeStatus(req.Status)
+		if !ok {
+			apperrors.WriteBadRequest(w, r, "status must be one of: new, active, acknowledged, fixed_pending, resolved, regressed")
+			return
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if utf8.RuneCountInString(req.Note) > MaxStatusNoteLength {
+			apperrors.WriteBadRequest(w, r, "note must be at most 1000 characters")
+			return
+		}
+		var note *string
+		if req.Note != "" {
+			note = &req.Note
+		}
+
+		change, err := NewService(pool).ChangeFlakeStatus(ctx, projectID, testCaseID, status, userID, note)
+		if err != nil {
+			if errors.Is(err, ErrFlakeNotFound) {
+				apperrors.WriteNotFound(w, r, "Flake not found")
+				return
+			}
+			if errors.Is(err, ErrInvalidStatusTransition) {
+				apperrors.WriteConflict(w, r, "A flake cannot be moved to this status from its current status")
+				return
+			}
+			log.Error().Err(err).
+				Str("project_id", projectID.String()).
+				Str("test_case_id", testCaseID.String()).
+				Msg("Failed to change flake status")
+			apperrors.WriteInternalError(w, r, "Failed to change flake status")
+			return
+		}
+
+		if err := auditor.LogFlakeStatusChanged(ctx, project.OrgID, projectID, testCaseID, userID, string(*change.FromStatus), string(change.ToStatus), req.Note); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"status_change": change,
+		})
+	}
 }
@@ -0,0 +1,44 @@
+package flake
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListFlakesRequest_BranchClassAndVariant(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/projects/x/flakes?branch_class=pr&job_variant=windows", nil)
+
+	req, err := parseListFlakesRequest(r)
+	require.NoError(t, err)
+	require.Equal(t, BranchClassPR, req.BranchClass)
+	require.NotNil(t, req.JobVariant)
+	require.Equal(t, "windows", *req.JobVariant)
+}
+
+func TestParseListFlakesRequest_EmptyVariantMatchesNoVariant(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/projects/x/flakes?job_variant=", nil)
+
+	req, err := parseListFlakesRequest(r)
+	require.NoError(t, err)
+	require.NotNil(t, req.JobVariant)
+	require.Equal(t, "", *req.JobVariant)
+}
+
+func TestParseListFlakesRequest_DefaultsLeaveFiltersUnset(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/projects/x/flakes", nil)
+
+	req, err := parseListFlakesRequest(r)
+	require.NoError(t, err)
+	require.Equal(t, 30, req.Days)
+	require.Nil(t, req.JobVariant)
+	require.Equal(t, BranchClass(""), req.BranchClass)
+}
+
+func TestParseListFlakesRequest_InvalidBranchClass(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/projects/x/flakes?branch_class=main", nil)
+
+	_, err := parseListFlakesRequest(r)
+	require.Error(t, err)
+}
@@ -0,0 +1,337 @@
+package flake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxStatusHistory bounds the status history returned with a flake
+const MaxStatusHistory = 50
+
+// ErrInvalidStatusTransition is returned for manual status changes the
+// lifecycle does not allow
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// manualTransitions lists the statuses a flake can be moved to by hand. New
+// and regressed are only set by detection.
+var manualTransitions = map[FlakeStatus][]FlakeStatus{
+	FlakeStatusNew:          {FlakeStatusAcknowledged, FlakeStatusFixedPending, FlakeStatusResolved},
+	FlakeStatusActive:       {FlakeStatusAcknowledged, FlakeStatusFixedPending, FlakeStatusResolved},
+	FlakeStatusAcknowledged: {FlakeStatusActive, FlakeStatusFixedPending, FlakeStatusResolved},
+	FlakeStatusFixedPending: {FlakeStatusActive, FlakeStatusResolved},
+	FlakeStatusResolved:     {FlakeStatusActive},
+	FlakeStatusRegressed:    {FlakeStatusAcknowledged, FlakeStatusFixedPending, FlakeStatusResolved},
+}
+
+// ManualTargets returns the statuses a flake in this status can be moved to
+// by hand
+func (s FlakeStatus) ManualTargets() []FlakeStatus {
+	return manualTransitions[s]
+}
+
+// CanTransitionTo reports whether a flake can be moved from s to target by hand
+func (s FlakeStatus) CanTransitionTo(target FlakeStatus) bool {
+	for _, t := range manualTransitions[s] {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Label is the display name of the status
+func (s FlakeStatus) Label() string {
+	switch s {
+	case FlakeStatusNew:
+		return "New"
+	case FlakeStatusActive:
+		return "Active"
+	case FlakeStatusAcknowledged:
+		return "Acknowledged"
+	case FlakeStatusFixedPending:
+		return "Fix pending verification"
+	case FlakeStatusResolved:
+		return "Resolved"
+	case FlakeStatusRegressed:
+		return "Regressed"
+	}
+	return string(s)
+}
+
+// ReasonLabel describes why the status changed
+func (c FlakeStatusChange) ReasonLabel() string {
+	switch c.Reason {
+	case StatusReasonDetected:
+		return "First flake detected"
+	case StatusReasonFlaked:
+		return "Flaked again"
+	case StatusReasonCleanRuns:
+		return "Clean runs"
+	case StatusReasonManual:
+		return "Manual"
+	}
+	return string(c.Reason)
+}
+
+// statusAfterFlake is the status of a flake that flaked again. A fix that
+// did not hold reopens the flake; a resolved flake regresses.
+func statusAfterFlake(current FlakeStatus) FlakeStatus {
+	switch current {
+	case FlakeStatusNew, FlakeStatusFixedPending:
+		return FlakeStatusActive
+	case FlakeStatusResolved:
+		return FlakeStatusRegressed
+	}
+	return current
+}
+
+// ParseFlakeStatusFilter reads a flake list status filter: a status, "open"
+// for every status but resolved, or "all" (or "") for any status
+func ParseFlakeStatusFilter(s string) ([]FlakeStatus, error) {
+	if s == "" || s == "all" {
+		return nil, nil
+	}
+	if s == FlakeStatusOpen {
+		return []FlakeStatus{FlakeStatusNew, FlakeStatusActive, FlakeStatusAcknowledged, FlakeStatusFixedPending, FlakeStatusRegressed}, nil
+	}
+	status, ok := ParseFlakeStatus(s)
+	if !ok {
+		return nil, errors.New("status must be one of: open, all, new, active, acknowledged, fixed_pending, resolved, regressed")
+	}
+	return []FlakeStatus{status}, nil
+}
+
+// recordStatusChange appends a status change to a flake's history
+func recordStatusChange(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID, from *FlakeStatus, to FlakeStatus, reason StatusReason, actorUserID, ciRunID *uuid.UUID, note *string) (*FlakeStatusChange, error) {
+	change := FlakeStatusChange{
+		FromStatus:  from,
+		ToStatus:    to,
+		Reason:      reason,
+		ActorUserID: actorUserID,
+		CIRunID:     ciRunID,
+		Note:        note,
+	}
+
+	var fromText *string
+	if from != nil {
+		s := string(*from)
+		fromText = &s
+	}
+
+	query := `
+		INSERT INTO flake_status_history (test_case_id, from_status, to_status, reason, actor_user_id, ci_run_id, note)
+		VALUES ($1, $2::flake_status, $3::flake_status, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, query, testCaseID, fromText, string(to), string(reason), actorUserID, ciRunID, note).Scan(&change.ID, &change.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record status change: %w", err)
+	}
+	return &change, nil
+}
+
+// getFlakeStatus locks a test's flake stats and returns its status, or nil if
+// the test has not flaked before
+func getFlakeStatus(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (*FlakeStatus, error) {
+	var status FlakeStatus
+	err := tx.QueryRow(ctx, `SELECT status::text FROM flake_stats WHERE test_case_id = $1 FOR UPDATE`, testCaseID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flake status: %w", err)
+	}
+	return &status, nil
+}
+
+// resolveCleanTests recounts the clean runs of the unresolved flaky tests of a
+// CI run and resolves those that reached their project's threshold. A clean
+// run is one the test passed on every attempt of, after its last flake and,
+// for a fix pending verification, after the fix was reported.
+func (d *Detector) resolveCleanTests(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID) error {
+	query := `
+		WITH candidates AS (
+			SELECT fs.test_case_id, fs.status, fs.status_changed_at, fs.clean_runs, p.flake_resolve_runs
+			FROM flake_stats fs
+			JOIN test_cases tc ON tc.id = fs.test_case_id
+			JOIN projects p ON p.id = tc.project_id
+			WHERE fs.status <> 'resolved'
+			  AND fs.test_case_id IN (
+				SELECT tr.test_case_id
+				FROM test_results tr
+				JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+				JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+				WHERE cra.ci_run_id = $1
+			  )
+		),
+		last_flake AS (
+			SELECT fe.test_case_id, MAX(GREATEST(cr.first_seen_at, pcr.first_seen_at)) AS at
+			FROM flake_events fe
+			JOIN ci_runs cr ON cr.id = fe.ci_run_id
+			LEFT JOIN ci_runs pcr ON pcr.id = fe.passed_ci_run_id
+			WHERE fe.test_case_id IN (SELECT test_case_id FROM candidates)
+			GROUP BY fe.test_case_id
+		)
+		SELECT c.test_case_id, c.status::text, c.clean_runs, c.flake_resolve_runs, clean.runs
+		FROM candidates c
+		LEFT JOIN last_flake lf ON lf.test_case_id = c.test_case_id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS runs
+			FROM (
+				SELECT cr.id
+				FROM test_results tr
+				JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+				JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+				JOIN ci_runs cr ON cr.id = cra.ci_run_id
+				WHERE tr.test_case_id = c.test_case_id
+				  AND cr.first_seen_at > COALESCE(
+					GREATEST(lf.at, CASE WHEN c.status = 'fixed_pending' THEN c.status_changed_at END),
+					'-infinity'::timestamptz)
+				GROUP BY cr.id
+				HAVING BOOL_OR(tr.status = 'passed') AND NOT BOOL_OR(tr.status IN ('failed', 'error'))
+			) clean_runs
+		) clean
+	`
+
+	rows, err := tx.Query(ctx, query, ciRunID)
+	if err != nil {
+		return fmt.Errorf("failed to count clean runs: %w", err)
+	}
+
+	type cleanCount struct {
+		testCaseID  uuid.UUID
+		status      FlakeStatus
+		stored      int
+		resolveRuns int
+		cleanRuns   int
+	}
+	var counts []cleanCount
+	for rows.Next() {
+		var c cleanCount
+		if err := rows.Scan(&c.testCaseID, &c.status, &c.stored, &c.resolveRuns, &c.cleanRuns); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan clean runs: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range counts {
+		if c.cleanRuns < c.resolveRuns {
+			if c.cleanRuns != c.stored {
+				if _, err := tx.Exec(ctx, `UPDATE flake_stats SET clean_runs = $2 WHERE test_case_id = $1`, c.testCaseID, c.cleanRuns); err != nil {
+					return fmt.Errorf("failed to update clean runs: %w", err)
+				}
+			}
+			continue
+		}
+
+		update := `
+			UPDATE flake_stats
+			SET status = 'resolved', status_changed_at = NOW(), clean_runs = $2
+			WHERE test_case_id = $1
+		`
+		if _, err := tx.Exec(ctx, update, c.testCaseID, c.cleanRuns); err != nil {
+			return fmt.Errorf("failed to resolve flake: %w", err)
+		}
+		from := c.status
+		if _, err := recordStatusChange(ctx, tx, c.testCaseID, &from, FlakeStatusResolved, StatusReasonCleanRuns, nil, &ciRunID, nil); err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("test_case_id", c.testCaseID.String()).
+			Str("ci_run_id", ciRunID.String()).
+			Int("clean_runs", c.cleanRuns).
+			Msg("Flake resolved after clean runs")
+	}
+	return nil
+}
+
+// ChangeFlakeStatus moves a flaky test to a new status by hand and records
+// the change with its actor and note
+func (s *Service) ChangeFlakeStatus(ctx context.Context, projectID, testCaseID uuid.UUID, to FlakeStatus, userID uuid.UUID, note *string) (*FlakeStatusChange, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var from FlakeStatus
+	query := `
+		SELECT fs.status::text
+		FROM flake_stats fs
+		JOIN test_cases tc ON tc.id = fs.test_case_id
+		WHERE tc.project_id = $1
+		  AND tc.id = $2
+		FOR UPDATE OF fs
+	`
+	if err := tx.QueryRow(ctx, query, projectID, testCaseID).Scan(&from); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFlakeNotFound
+		}
+		return nil, fmt.Errorf("failed to get flake status: %w", err)
+	}
+	if !from.CanTransitionTo(to) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	// Clean runs of a fix pending verification count from now
+	update := `
+		UPDATE flake_stats
+		SET status = $2::flake_status,
+		    status_changed_at = NOW(),
+		    clean_runs = CASE WHEN $2 = 'fixed_pending' THEN 0 ELSE clean_runs END
+		WHERE test_case_id = $1
+	`
+	if _, err := tx.Exec(ctx, update, testCaseID, string(to)); err != nil {
+		return nil, fmt.Errorf("failed to update flake status: %w", err)
+	}
+
+	change, err := recordStatusChange(ctx, tx, testCaseID, &from, to, StatusReasonManual, &userID, nil, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return change, nil
+}
+
+// getStatusHistory returns the latest status changes of a flake, newest first
+func (s *Service) getStatusHistory(ctx context.Context, testCaseID uuid.UUID, limit int) ([]FlakeStatusChange, error) {
+	query := `
+		SELECT h.id, h.from_status::text, h.to_status::text, h.reason, h.actor_user_id, u.email::text, h.ci_run_id, h.note, h.created_at
+		FROM flake_status_history h
+		LEFT JOIN users u ON u.id = h.actor_user_id
+		WHERE h.test_case_id = $1
+		ORDER BY h.created_at DESC, h.id
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, query, testCaseID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []FlakeStatusChange
+	for rows.Next() {
+		var c FlakeStatusChange
+		if err := rows.Scan(&c.ID, &c.FromStatus, &c.ToStatus, &c.Reason, &c.ActorUserID, &c.ActorEmail, &c.CIRunID, &c.Note, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,56 @@
+package flake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusAfterFlake(t *testing.T) {
+	for current, want := range map[FlakeStatus]FlakeStatus{
+		FlakeStatusNew:          FlakeStatusActive,
+		FlakeStatusActive:       FlakeStatusActive,
+		FlakeStatusAcknowledged: FlakeStatusAcknowledged,
+		FlakeStatusFixedPending: FlakeStatusActive,
+		FlakeStatusResolved:     FlakeStatusRegressed,
+		FlakeStatusRegressed:    FlakeStatusRegressed,
+	} {
+		require.Equal(t, want, statusAfterFlake(current), current)
+	}
+}
+
+func TestCanTransitionTo(t *testing.T) {
+	require.True(t, FlakeStatusNew.CanTransitionTo(FlakeStatusAcknowledged))
+	require.True(t, FlakeStatusRegressed.CanTransitionTo(FlakeStatusFixedPending))
+	require.True(t, FlakeStatusResolved.CanTransitionTo(FlakeStatusActive))
+
+	// New and regressed are only set by detection
+	for _, from := range FlakeStatuses {
+		require.False(t, from.CanTransitionTo(FlakeStatusNew), from)
+		require.False(t, from.CanTransitionTo(FlakeStatusRegressed), from)
+		require.False(t, from.CanTransitionTo(from), from)
+	}
+	require.False(t, FlakeStatusResolved.CanTransitionTo(FlakeStatusAcknowledged))
+}
+
+func TestParseFlakeStatusFilter(t *testing.T) {
+	statuses, err := ParseFlakeStatusFilter("")
+	require.NoError(t, err)
+	require.Nil(t, statuses)
+
+	statuses, err = ParseFlakeStatusFilter("all")
+	require.NoError(t, err)
+	require.Nil(t, statuses)
+
+	statuses, err = ParseFlakeStatusFilter("open")
+	require.NoError(t, err)
+	require.Len(t, statuses, len(FlakeStatuses)-1)
+	require.NotContains(t, statuses, FlakeStatusResolved)
+
+	statuses, err = ParseFlakeStatusFilter("fixed_pending")
+	require.NoError(t, err)
+	require.Equal(t, []FlakeStatus{FlakeStatusFixedPending}, statuses)
+
+	_, err = ParseFlakeStatusFilter("closed")
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package flake
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// runNouns names each CI provider's unit of run for display
+var runNouns = map[string]string{
+	"github":    "GitHub run",
+	"gitlab":    "GitLab pipeline",
+	"jenkins":   "Jenkins build",
+	"circleci":  "CircleCI workflow",
+	"buildkite": "Buildkite build",
+	"azure":     "Azure Pipelines run",
+	"local":     "Local run",
+}
+
+// RunLabel names a CI run for display, preferring the provider's run number
+// over its opaque run ID (e.g. "GitLab pipeline #55")
+func RunLabel(provider, runID, runNumber string) string {
+	noun, ok := runNouns[provider]
+	if !ok {
+		noun = "Run"
+	}
+	if runNumber != "" {
+		return fmt.Sprintf("%s #%s", noun, runNumber)
+	}
+	return fmt.Sprintf("%s %s", noun, runID)
+}
+
+// AttemptURL links a single attempt of a run, or returns "" when the
+// provider has no per-attempt pages
+func AttemptURL(provider, runURL string, attempt int) string {
+	if provider != "github" || !isWebURL(runURL) || attempt < 1 {
+		return ""
+	}
+	return fmt.Sprintf("%s/attempts/%d", strings.TrimRight(runURL, "/"), attempt)
+}
+
+// CommitURL links a commit on the provider's code host, which is assumed to
+// serve the run URL. Returns "" for providers that do not host code.
+func CommitURL(provider, runURL, repo, sha string) string {
+	if !isWebURL(runURL) || repo == "" || sha == "" {
+		return ""
+	}
+	u, _ := url.Parse(runURL)
+	base := u.Scheme + "://" + u.Host
+
+	switch provider {
+	case "github":
+		return fmt.Sprintf("%s/%s/commit/%s", base, repo, sha)
+	case "gitlab":
+		return fmt.Sprintf("%s/%s/-/commit/%s", base, repo, sha)
+	default:
+		return ""
+	}
+}
+
+// CompareURL links the commits after base up to head on the provider's code
+// host, like CommitURL. Returns "" when either commit is unknown.
+func CompareURL(provider, runURL, repo, base, head string) string {
+	if !isWebURL(runURL) || repo == "" || base == "" || head == "" {
+		return ""
+	}
+	u, _ := url.Parse(runURL)
+	host := u.Scheme + "://" + u.Host
+
+	switch provider {
+	case "github":
+		return fmt.Sprintf("%s/%s/compare/%s...%s", host, repo, base, head)
+	case "gitlab":
+		return fmt.Sprintf("%s/%s/-/compare/%s...%s", host, repo, base, head)
+	default:
+		return ""
+	}
+}
+
+// GitHubRunID returns the numeric GitHub run ID of a run, or nil for other
+// providers and run IDs that are not numeric
+func GitHubRunID(provider, runID string) *int64 {
+	if provider != "github" {
+		return nil
+	}
+	id, err := strconv.ParseInt(runID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// isWebURL reports whether s is an absolute http(s) URL that can be linked
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// RunLink returns the run URL if it can be linked (local runs have none)
+func (e FlakeEvidence) RunLink() string {
+	if !isWebURL(e.RunURL) {
+		return ""
+	}
+	return e.RunURL
+}
+
+// PassedRunLink returns the passing run's URL if it can be linked
+func (e FlakeEvidence) PassedRunLink() string {
+	if e.PassedRunURL == nil || !isWebURL(*e.PassedRunURL) {
+		return ""
+	}
+	return *e.PassedRunURL
+}
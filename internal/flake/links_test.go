@@ -0,0 +1,46 @@
+package flake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLabel(t *testing.T) {
+	require.Equal(t, "GitHub run #42", RunLabel("github", "123456", "42"))
+	require.Equal(t, "Buildkite build 0190a3f2-uuid", RunLabel("buildkite", "0190a3f2-uuid", ""))
+	require.Equal(t, "Run #7", RunLabel("unknown", "x", "7"))
+}
+
+func TestAttemptURL(t *testing.T) {
+	require.Equal(t, "https://github.com/acme/api/actions/runs/123/attempts/2",
+		AttemptURL("github", "https://github.com/acme/api/actions/runs/123", 2))
+	require.Empty(t, AttemptURL("gitlab", "https://gitlab.com/acme/api/-/pipelines/9", 2))
+	require.Empty(t, AttemptURL("github", "", 1))
+}
+
+func TestCommitURL(t *testing.T) {
+	require.Equal(t, "https://github.example.com/acme/api/commit/abc",
+		CommitURL("github", "https://github.example.com/acme/api/actions/runs/1", "acme/api", "abc"))
+	require.Equal(t, "https://gitlab.com/group/api/-/commit/abc",
+		CommitURL("gitlab", "https://gitlab.com/group/api/-/pipelines/9", "group/api", "abc"))
+	require.Empty(t, CommitURL("jenkins", "https://jenkins.example.com/job/api/1/", "acme/api", "abc"))
+	require.Empty(t, CommitURL("local", "local://run/1", "acme/api", "abc"))
+}
+
+func TestCompareURL(t *testing.T) {
+	require.Equal(t, "https://github.com/acme/api/compare/good...bad",
+		CompareURL("github", "https://github.com/acme/api/actions/runs/1", "acme/api", "good", "bad"))
+	require.Equal(t, "https://gitlab.com/group/api/-/compare/good...bad",
+		CompareURL("gitlab", "https://gitlab.com/group/api/-/pipelines/9", "group/api", "good", "bad"))
+	require.Empty(t, CompareURL("github", "https://github.com/acme/api/actions/runs/1", "acme/api", "", "bad"))
+	require.Empty(t, CompareURL("jenkins", "https://jenkins.example.com/job/api/1/", "acme/api", "good", "bad"))
+}
+
+func TestGitHubRunID(t *testing.T) {
+	id := GitHubRunID("github", "123456")
+	require.NotNil(t, id)
+	require.Equal(t, int64(123456), *id)
+	require.Nil(t, GitHubRunID("gitlab", "55"))
+	require.Nil(t, GitHubRunID("github", "not-a-number"))
+}
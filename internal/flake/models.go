@@ -6,14 +6,114 @@ import (
 	"github.com/google/uuid"
 )
 
+// EventKind identifies how a flake event was detected
+type EventKind string
+
+const (
+	// EventKindRetryAttempt is a fail->pass across attempts of the same CI run
+	EventKindRetryAttempt EventKind = "retry_attempt"
+	// EventKindSameSHARerun is a fail on one CI run and a pass on another run of the same SHA
+	EventKindSameSHARerun EventKind = "same_sha_rerun"
+	// EventKindInJobRetry is a fail->pass within one attempt via the test framework's own reruns
+	EventKindInJobRetry EventKind = "in_job_retry"
+)
+
+// BranchClass groups CI runs by the kind of branch they ran on
+type BranchClass string
+
+const (
+	// BranchClassDefault is a run on the project's default branch
+	BranchClassDefault BranchClass = "default"
+	// BranchClassPR is a pull request run
+	BranchClassPR BranchClass = "pr"
+	// BranchClassOther is any other branch (feature branches, tags, ...)
+	BranchClassOther BranchClass = "other"
+)
+
+// ParseBranchClass validates a branch class query value
+func ParseBranchClass(s string) (BranchClass, bool) {
+	switch BranchClass(s) {
+	case BranchClassDefault, BranchClassPR, BranchClassOther:
+		return BranchClass(s), true
+	}
+	return "", false
+}
+
+// FlakeStatus is the lifecycle state of a flaky test
+type FlakeStatus string
+
+const (
+	// FlakeStatusNew is a test that flaked for the first time
+	FlakeStatusNew FlakeStatus = "new"
+	// FlakeStatusActive is a test that flaked again, or was reopened
+	FlakeStatusActive FlakeStatus = "active"
+	// FlakeStatusAcknowledged is a flake someone is looking into
+	FlakeStatusAcknowledged FlakeStatus = "acknowledged"
+	// FlakeStatusFixedPending is a flake with a fix that is being verified
+	FlakeStatusFixedPending FlakeStatus = "fixed_pending"
+	// FlakeStatusResolved is a flake that stopped, or was closed by hand
+	FlakeStatusResolved FlakeStatus = "resolved"
+	// FlakeStatusRegressed is a resolved flake that flaked again
+	FlakeStatusRegressed FlakeStatus = "regressed"
+)
+
+// FlakeStatuses lists the flake statuses in lifecycle order
+var FlakeStatuses = []FlakeStatus{
+	FlakeStatusNew, FlakeStatusActive, FlakeStatusAcknowledged,
+	FlakeStatusFixedPending, FlakeStatusResolved, FlakeStatusRegressed,
+}
+
+// FlakeStatusOpen filters flake lists to every status but resolved
+const FlakeStatusOpen = "open"
+
+// ParseFlakeStatus validates a flake status
+func ParseFlakeStatus(s string) (FlakeStatus, bool) {
+	switch FlakeStatus(s) {
+	case FlakeStatusNew, FlakeStatusActive, FlakeStatusAcknowledged,
+		FlakeStatusFixedPending, FlakeStatusResolved, FlakeStatusRegressed:
+		return FlakeStatus(s), true
+	}
+	return "", false
+}
+
+// StatusReason is why a flake changed status
+type StatusReason string
+
+const (
+	// StatusReasonDetected is the first flake of a test
+	StatusReasonDetected StatusReason = "detected"
+	// StatusReasonFlaked is a later flake
+	StatusReasonFlaked StatusReason = "flaked"
+	// StatusReasonCleanRuns is the project's threshold of clean runs
+	StatusReasonCleanRuns StatusReason = "clean_runs"
+	// StatusReasonManual is a change made through the API or UI
+	StatusReasonManual StatusReason = "manual"
+)
+
+// FlakeStatusChange is one entry of a flake's status history. Automatic
+// changes have no actor; CIRunID is the run that caused them.
+type FlakeStatusChange struct {
+	ID          uuid.UUID    `json:"id"`
+	FromStatus  *FlakeStatus `json:"from_status"`
+	ToStatus    FlakeStatus  `json:"to_status"`
+	Reason      StatusReason `json:"reason"`
+	ActorUserID *uuid.UUID   `json:"actor_user_id,omitempty"`
+	ActorEmail  *string      `json:"actor_email,omitempty"`
+	CIRunID     *uuid.UUID   `json:"ci_run_id,omitempty"`
+	Note        *string      `json:"note,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
 // FlakeEvent represents a detected flaky test event
 type FlakeEvent struct {
-	ID                  uuid.UUID `json:"id"`
-	TestCaseID          uuid.UUID `json:"test_case_id"`
-	CIRunID             uuid.UUID `json:"ci_run_id"`
-	FailedAttemptNumber int       `json:"failed_attempt_number"`
-	PassedAttemptNumber int       `json:"passed_attempt_number"`
-	CreatedAt           time.Time `json:"created_at"`
+	ID                  uuid.UUID  `json:"id"`
+	TestCaseID          uuid.UUID  `json:"test_case_id"`
+	CIRunID             uuid.UUID  `json:"ci_run_id"`
+	Kind                EventKind  `json:"kind"`
+	PassedCIRunID       *uuid.UUID `json:"passed_ci_run_id"`
+	FailedAttemptNumber int        `json:"failed_attempt_number"`
+	PassedAttemptNumber int        `json:"passed_attempt_number"`
+	CreatedAt           time.Time  `json:"created_at"`
 }
 
 // FlakeStats represents aggregated statistics for a flaky test
@@ -22,51 +122,204 @@ type FlakeStats struct {
 	MixedOutcomeRuns   int       `json:"mixed_outcome_runs"`
 	TotalRunsSeen      int       `json:"total_runs_seen"`
 	FlakeScore         float64   `json:"flake_score"`
+	FlakeScoreLower    float64   `json:"flake_score_lower"`
+	FlakeScoreUpper    float64   `json:"flake_score_upper"`
 	LastFailureMessage *string   `json:"last_failure_message"`
 	FirstSeenAt        time.Time `json:"first_seen_at"`
 	LastSeenAt         time.Time `json:"last_seen_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
 
-// FlakeListItem represents a flaky test in the list view
-type FlakeListItem struct {
+// FlakeBranchStats represents flake statistics for a test within one branch class
+type FlakeBranchStats struct {
+	BranchClass      BranchClass `json:"branch_class"`
+	MixedOutcomeRuns int         `json:"mixed_outcome_runs"`
+	TotalRunsSeen    int         `json:"total_runs_seen"`
+	FlakeScore       float64     `json:"flake_score"`
+	FlakeScoreLower  float64     `json:"flake_score_lower"`
+	FlakeScoreUpper  float64     `json:"flake_score_upper"`
+	LastFlakeAt      *time.Time  `json:"last_flake_at"`
+}
+
+// FlakeVariantStats represents flake statistics for the same test under another job variant
+type FlakeVariantStats struct {
 	TestCaseID       uuid.UUID `json:"test_case_id"`
-	RepoFullName     string    `json:"repo_full_name"`
-	JobName          string    `json:"job_name"`
 	JobVariant       string    `json:"job_variant"`
-	TestIdentifier   string    `json:"test_identifier"`
 	FlakeScore       float64   `json:"flake_score"`
+	FlakeScoreLower  float64   `json:"flake_score_lower"`
+	FlakeScoreUpper  float64   `json:"flake_score_upper"`
 	MixedOutcomeRuns int       `json:"mixed_outcome_runs"`
 	TotalRunsSeen    int       `json:"total_runs_seen"`
-	FirstSeenAt      time.Time `json:"first_seen_at"`
-	LastSeenAt       time.Time `json:"last_seen_at"`
+}
+
+// FlakeListItem represents a flaky test in the list view
+type FlakeListItem struct {
+	TestCaseID       uuid.UUID   `json:"test_case_id"`
+	RepoFullName     string      `json:"repo_full_name"`
+	JobName          string      `json:"job_name"`
+	JobVariant       string      `json:"job_variant"`
+	TestIdentifier   string      `json:"test_identifier"`
+	Owners           []string    `json:"owners"`
+	FlakeScore       float64     `json:"flake_score"`
+	FlakeScoreLower  float64     `json:"flake_score_lower"`
+	FlakeScoreUpper  float64     `json:"flake_score_upper"`
+	MixedOutcomeRuns int         `json:"mixed_outcome_runs"`
+	TotalRunsSeen    int         `json:"total_runs_seen"`
+	Status           FlakeStatus `json:"status"`
+	FirstSeenAt      time.Time   `json:"first_seen_at"`
+	LastSeenAt       time.Time   `json:"last_seen_at"`
+	Quarantined      bool        `json:"quarantined"`
+	QuarantineRuleID *uuid.UUID  `json:"quarantine_rule_id,omitempty"`
 }
 
 // FlakeEvidence represents evidence of a single flake event
+// For same_sha_rerun evidence the run is the failing run and the Passed* fields
+// identify the separate run that passed. For in_job_retry evidence both attempts are the
+// same and RerunFailures is the number of failed framework reruns. Labels and links are
+// rendered per CI provider; links a provider does not offer are omitted.
+// GitHubRunID and PassedGitHubRunID are the pre-provider field names, kept for
+// existing API consumers and only set for GitHub runs.
 type FlakeEvidence struct {
-	GitHubRunID   int64      `json:"github_run_id"`
-	RunURL        string     `json:"run_url"`
-	SHA           string     `json:"sha"`
-	AttemptFailed int        `json:"attempt_failed"`
-	AttemptPassed int        `json:"attempt_passed"`
-	FailedAt      *time.Time `json:"failed_at"`
-	PassedAt      *time.Time `json:"passed_at"`
+	Kind           EventKind  `json:"kind"`
+	Provider       string     `json:"provider"`
+	RunID          string     `json:"run_id"`
+	RunLabel       string     `json:"run_label"`
+	RunURL         string     `json:"run_url"`
+	AttemptURL     *string    `json:"attempt_url,omitempty"`
+	SHA            string     `json:"sha"`
+	CommitURL      *string    `json:"commit_url,omitempty"`
+	AttemptFailed  int        `json:"attempt_failed"`
+	AttemptPassed  int        `json:"attempt_passed"`
+	PassedProvider *string    `json:"passed_provider,omitempty"`
+	PassedRunID    *string    `json:"passed_run_id,omitempty"`
+	PassedRunLabel *string    `json:"passed_run_label,omitempty"`
+	PassedRunURL   *string    `json:"passed_run_url,omitempty"`
+	FailedAt       *time.Time `json:"failed_at"`
+	PassedAt       *time.Time `json:"passed_at"`
+	RerunFailures  *int       `json:"rerun_failures,omitempty"`
+
+	// Deprecated: use RunID and PassedRunID
+	GitHubRunID       *int64 `json:"github_run_id,omitempty"`
+	PassedGitHubRunID *int64 `json:"passed_github_run_id,omitempty"`
 }
 
 // FlakeDetail represents the full detail view of a flaky test
 type FlakeDetail struct {
-	TestCaseID         uuid.UUID       `json:"test_case_id"`
-	RepoFullName       string          `json:"repo_full_name"`
-	JobName            string          `json:"job_name"`
-	JobVariant         string          `json:"job_variant"`
-	TestIdentifier     string          `json:"test_identifier"`
-	FlakeScore         float64         `json:"flake_score"`
-	MixedOutcomeRuns   int             `json:"mixed_outcome_runs"`
-	TotalRunsSeen      int             `json:"total_runs_seen"`
-	LastFailureMessage *string         `json:"last_failure_message"`
-	FirstSeenAt        time.Time       `json:"first_seen_at"`
-	LastSeenAt         time.Time       `json:"last_seen_at"`
-	Evidence           []FlakeEvidence `json:"evidence"`
+	TestCaseID         uuid.UUID           `json:"test_case_id"`
+	RepoFullName       string              `json:"repo_full_name"`
+	JobName            string              `json:"job_name"`
+	JobVariant         string              `json:"job_variant"`
+	TestIdentifier     string              `json:"test_identifier"`
+	FilePath           *string             `json:"file_path"`
+	Owners             []string            `json:"owners"`
+	FlakeScore         float64             `json:"flake_score"`
+	FlakeScoreLower    float64             `json:"flake_score_lower"`
+	FlakeScoreUpper    float64             `json:"flake_score_upper"`
+	MixedOutcomeRuns   int                 `json:"mixed_outcome_runs"`
+	TotalRunsSeen      int                 `json:"total_runs_seen"`
+	LastFailureMessage *string             `json:"last_failure_message"`
+	FirstSeenAt        time.Time           `json:"first_seen_at"`
+	LastSeenAt         time.Time           `json:"last_seen_at"`
+	Quarantined        bool                `json:"quarantined"`
+	QuarantineRuleID   *uuid.UUID          `json:"quarantine_rule_id,omitempty"`
+	Status             FlakeStatus         `json:"status"`
+	StatusChangedAt    time.Time           `json:"status_changed_at"`
+	CleanRuns          int                 `json:"clean_runs"`
+	ResolveAfterRuns   int                 `json:"resolve_after_runs"`
+	StatusHistory      []FlakeStatusChange `json:"status_history"`
+	Branches           []FlakeBranchStats  `json:"branches"`
+	Variants           []FlakeVariantStats `json:"variants"`
+	Onset              *FlakeOnset         `json:"onset"`
+	Evidence           []FlakeEvidence     `json:"evidence"`
+}
+
+// FlakeOnset estimates when a test became flaky: the point in its
+// default-branch history where its flake rate shifted up. The commits that
+// may have introduced the flake are those after LastStable up to FirstFlaky.
+type FlakeOnset struct {
+	LastStable      OnsetRun `json:"last_stable"`
+	FirstFlaky      OnsetRun `json:"first_flaky"`
+	CompareURL      *string  `json:"compare_url,omitempty"`
+	RunsBefore      int      `json:"runs_before"`
+	FlakyRunsBefore int      `json:"flaky_runs_before"`
+	FlakeRateBefore float64  `json:"flake_rate_before"`
+	RunsAfter       int      `json:"runs_after"`
+	FlakyRunsAfter  int      `json:"flaky_runs_after"`
+	FlakeRateAfter  float64  `json:"flake_rate_after"`
+}
+
+// OnsetRun is a default-branch run bounding a flake onset
+type OnsetRun struct {
+	SHA       string    `json:"sha"`
+	CommitURL *string   `json:"commit_url,omitempty"`
+	RunLabel  string    `json:"run_label"`
+	RunURL    string    `json:"run_url"`
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// TrendPoint is one UTC day of a test's daily series. Runs are the CI runs
+// the test ran in, FailedRuns those where it failed on any attempt and
+// FlakyRuns those with flake evidence.
+type TrendPoint struct {
+	Date       string  `json:"date"`
+	Runs       int     `json:"runs"`
+	FailedRuns int     `json:"failed_runs"`
+	FlakyRuns  int     `json:"flaky_runs"`
+	FlakeRate  float64 `json:"flake_rate"`
+}
+
+// ProjectTrendPoint is one UTC day of a project's or org's daily series: the
+// counts summed over its tests, and how many tests flaked that day
+type ProjectTrendPoint struct {
+	TrendPoint
+	FlakyTests int `json:"flaky_tests"`
+}
+
+// BrokenStatus filters broken tests by whether they were fixed
+type BrokenStatus string
+
+const (
+	// BrokenStatusOpen is a test that still fails on the default branch
+	BrokenStatusOpen BrokenStatus = "open"
+	// BrokenStatusFixed is a test that passed on the default branch since
+	BrokenStatusFixed BrokenStatus = "fixed"
+	// BrokenStatusAll is any broken test
+	BrokenStatusAll BrokenStatus = "all"
+)
+
+// ParseBrokenStatus validates a broken status query value
+func ParseBrokenStatus(s string) (BrokenStatus, bool) {
+	switch BrokenStatus(s) {
+	case BrokenStatusOpen, BrokenStatusFixed, BrokenStatusAll:
+		return BrokenStatus(s), true
+	}
+	return "", false
+}
+
+// BrokenTest is a test that failed on every attempt of consecutive
+// default-branch runs. The suspect range runs from the last default-branch
+// run where the test passed (nil when it never passed) to the first run of
+// the failing streak; run IDs are nil once retention removed the run.
+type BrokenTest struct {
+	ID                 uuid.UUID  `json:"id"`
+	TestCaseID         uuid.UUID  `json:"test_case_id"`
+	RepoFullName       string     `json:"repo_full_name"`
+	JobName            string     `json:"job_name"`
+	JobVariant         string     `json:"job_variant"`
+	TestIdentifier     string     `json:"test_identifier"`
+	FailingRuns        int        `json:"failing_runs"`
+	FirstBadCIRunID    *uuid.UUID `json:"first_bad_ci_run_id"`
+	FirstBadSHA        string     `json:"first_bad_sha"`
+	LastGoodCIRunID    *uuid.UUID `json:"last_good_ci_run_id"`
+	LastGoodSHA        *string    `json:"last_good_sha"`
+	CompareURL         *string    `json:"compare_url,omitempty"`
+	LastFailureMessage *string    `json:"last_failure_message"`
+	DetectedAt         time.Time  `json:"detected_at"`
+	LastFailedAt       time.Time  `json:"last_failed_at"`
+	FixedSHA           *string    `json:"fixed_sha"`
+	FixedAt            *time.Time `json:"fixed_at"`
+	Quarantined        bool       `json:"quarantined"`
+	QuarantineRuleID   *uuid.UUID `json:"quarantine_rule_id,omitempty"`
 }
 
 // FlakeListFilters represents filtering options for flake list queries
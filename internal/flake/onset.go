@@ -0,0 +1,197 @@
+package flake
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxOnsetRuns bounds the default-branch history searched for an onset
+	maxOnsetRuns = 500
+	// minOnsetSegmentRuns is the fewest runs on either side of an onset
+	minOnsetSegmentRuns = 5
+	// minOnsetLogLikelihood is the log-likelihood ratio a change of flake
+	// rate must reach to be reported (about p < 0.01 for a single split)
+	minOnsetLogLikelihood = 3.3
+)
+
+// onsetRun is one default-branch run of a test, and whether it flaked in it
+type onsetRun struct {
+	Provider  string
+	RunID     string
+	RunNumber string
+	RunURL    string
+	Repo      string
+	SHA       string
+	SeenAt    time.Time
+	Flaky     bool
+}
+
+// findOnset finds the most likely point where the flake rate of a run history,
+// oldest first, rose from one level to a higher one. The change starts at a
+// flaky run; its index is returned, or ok=false when the history is too short
+// or the rate did not shift significantly.
+func findOnset(runs []onsetRun) (int, bool) {
+	n := len(runs)
+	if n < 2*minOnsetSegmentRuns {
+		return 0, false
+	}
+
+	flakyBefore := make([]int, n+1)
+	for i, r := range runs {
+		flakyBefore[i+1] = flakyBefore[i]
+		if r.Flaky {
+			flakyBefore[i+1]++
+		}
+	}
+	total := flakyBefore[n]
+	baseline := bernoulliLogLikelihood(total, n)
+
+	best, bestRatio := 0, 0.0
+	for k := minOnsetSegmentRuns; k <= n-minOnsetSegmentRuns; k++ {
+		if !runs[k].Flaky {
+			continue
+		}
+		before, after := flakyBefore[k], total-flakyBefore[k]
+		if float64(after)/float64(n-k) <= float64(before)/float64(k) {
+			continue
+		}
+		ratio := bernoulliLogLikelihood(before, k) + bernoulliLogLikelihood(after, n-k) - baseline
+		if ratio > bestRatio {
+			best, bestRatio = k, ratio
+		}
+	}
+	if bestRatio < minOnsetLogLikelihood {
+		return 0, false
+	}
+	return best, true
+}
+
+// bernoulliLogLikelihood is the log-likelihood of x flaky runs out of n at
+// their observed rate
+func bernoulliLogLikelihood(x, n int) float64 {
+	if x == 0 || x == n {
+		return 0
+	}
+	p := float64(x) / float64(n)
+	return float64(x)*math.Log(p) + float64(n-x)*math.Log(1-p)
+}
+
+// newFlakeOnset describes the onset at index split of runs
+func newFlakeOnset(runs []onsetRun, split int) *FlakeOnset {
+	onset := &FlakeOnset{
+		LastStable: onsetRunView(runs[split-1]),
+		FirstFlaky: onsetRunView(runs[split]),
+		RunsBefore: split,
+		RunsAfter:  len(runs) - split,
+	}
+	for i, r := range runs {
+		if !r.Flaky {
+			continue
+		}
+		if i < split {
+			onset.FlakyRunsBefore++
+		} else {
+			onset.FlakyRunsAfter++
+		}
+	}
+	onset.FlakeRateBefore = float64(onset.FlakyRunsBefore) / float64(onset.RunsBefore)
+	onset.FlakeRateAfter = float64(onset.FlakyRunsAfter) / float64(onset.RunsAfter)
+
+	last, first := runs[split-1], runs[split]
+	if last.Provider == first.Provider {
+		if u := CompareURL(first.Provider, first.RunURL, first.Repo, last.SHA, first.SHA); u != "" {
+			onset.CompareURL = &u
+		}
+	}
+	return onset
+}
+
+// onsetRunView presents a run of an onset with its provider label and links
+func onsetRunView(r onsetRun) OnsetRun {
+	v := OnsetRun{
+		SHA:      r.SHA,
+		RunLabel: RunLabel(r.Provider, r.RunID, r.RunNumber),
+		RunURL:   r.RunURL,
+		SeenAt:   r.SeenAt,
+	}
+	if u := CommitURL(r.Provider, r.RunURL, r.Repo, r.SHA); u != "" {
+		v.CommitURL = &u
+	}
+	return v
+}
+
+// RunLink returns the run URL if it can be linked (local runs have none)
+func (r OnsetRun) RunLink() string {
+	if !isWebURL(r.RunURL) {
+		return ""
+	}
+	return r.RunURL
+}
+
+// getFlakeOnset estimates when a test became flaky from its latest
+// default-branch runs. Returns nil when no onset was found.
+func (s *Service) getFlakeOnset(ctx context.Context, testCaseID uuid.UUID) (*FlakeOnset, error) {
+	query := `
+		WITH mixed AS (
+			SELECT DISTINCT ci_run_id
+			FROM flake_events
+			WHERE test_case_id = $1
+		)
+		SELECT provider, run_id, run_number, run_url, repo_full_name, sha, first_seen_at, flaky
+		FROM (
+			SELECT
+				cr.provider::text AS provider,
+				cr.run_id,
+				cr.run_number,
+				cr.run_url,
+				cr.repo_full_name,
+				cr.sha,
+				cr.first_seen_at,
+				cr.id,
+				mixed.ci_run_id IS NOT NULL AS flaky
+			FROM ci_runs cr
+			JOIN projects p ON p.id = cr.project_id
+			LEFT JOIN mixed ON mixed.ci_run_id = cr.id
+			WHERE cr.id IN (
+				SELECT cra.ci_run_id
+				FROM test_results tr
+				JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+				JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+				WHERE tr.test_case_id = $1
+			)
+			  AND ` + branchClassSQL + ` = 'default'
+			ORDER BY cr.first_seen_at DESC, cr.id DESC
+			LIMIT $2
+		) history
+		ORDER BY first_seen_at, id
+	`
+
+	rows, err := s.pool.Query(ctx, query, testCaseID, maxOnsetRuns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query default-branch history: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []onsetRun
+	for rows.Next() {
+		var r onsetRun
+		if err := rows.Scan(&r.Provider, &r.RunID, &r.RunNumber, &r.RunURL, &r.Repo, &r.SHA, &r.SeenAt, &r.Flaky); err != nil {
+			return nil, fmt.Errorf("failed to scan default-branch run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	split, ok := findOnset(runs)
+	if !ok {
+		return nil, nil
+	}
+	return newFlakeOnset(runs, split), nil
+}
@@ -0,0 +1,73 @@
+package flake
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// historyOf builds a run history from a pattern, oldest first: "x" is a
+// flaky run and "." a stable one
+func historyOf(pattern string) []onsetRun {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	pattern = strings.ReplaceAll(pattern, " ", "")
+	runs := make([]onsetRun, len(pattern))
+	for i, c := range pattern {
+		runs[i] = onsetRun{
+			Provider:  "github",
+			RunID:     strings.Repeat("1", i+1),
+			RunNumber: strings.Repeat("1", i+1),
+			RunURL:    "https://github.com/acme/api/actions/runs/1",
+			Repo:      "acme/api",
+			SHA:       string(rune('a'+i%26)) + "000000",
+			SeenAt:    start.Add(time.Duration(i) * time.Hour),
+			Flaky:     c == 'x',
+		}
+	}
+	return runs
+}
+
+func TestFindOnset_RateShift(t *testing.T) {
+	runs := historyOf(".......... .......... x.x..xx.x. .xx.x..x.x")
+
+	split, ok := findOnset(runs)
+	require.True(t, ok)
+	require.Equal(t, 20, split, "the first flaky run starts the new rate")
+
+	onset := newFlakeOnset(runs, split)
+	require.Equal(t, runs[19].SHA, onset.LastStable.SHA)
+	require.Equal(t, runs[20].SHA, onset.FirstFlaky.SHA)
+	require.Equal(t, 20, onset.RunsBefore)
+	require.Zero(t, onset.FlakyRunsBefore)
+	require.Equal(t, 20, onset.RunsAfter)
+	require.Equal(t, 10, onset.FlakyRunsAfter)
+	require.InDelta(t, 0.5, onset.FlakeRateAfter, 1e-9)
+	require.NotNil(t, onset.CompareURL)
+	require.Equal(t, "https://github.com/acme/api/compare/"+runs[19].SHA+"..."+runs[20].SHA, *onset.CompareURL)
+	require.Equal(t, "https://github.com/acme/api/actions/runs/1", onset.FirstFlaky.RunLink())
+}
+
+func TestFindOnset_NoShift(t *testing.T) {
+	for name, pattern := range map[string]string{
+		"stable rate":       "..x...x... ...x...x.. .x....x...",
+		"flaky from start":  "x.x.xx..x. x.xx.x..x. .x.x",
+		"rate went down":    "x.xx.x.xx. .......... ..........",
+		"too short":         "....x",
+		"one stray failure": ".......... ......x...",
+	} {
+		_, ok := findOnset(historyOf(pattern))
+		require.False(t, ok, name)
+	}
+}
+
+func TestFindOnset_KeepsMinimumSegments(t *testing.T) {
+	// Flaky only in the last runs: too few to call a new rate
+	_, ok := findOnset(historyOf(".......... .......... ...xx"))
+	require.False(t, ok)
+
+	split, ok := findOnset(historyOf(".......... .......... xxxxx"))
+	require.True(t, ok)
+	require.Equal(t, 20, split)
+}
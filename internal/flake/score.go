@@ -0,0 +1,57 @@
+package flake
+
+import "math"
+
+const (
+	// ScoreHalfLifeDays is how long it takes a run's weight in the flake score to halve
+	ScoreHalfLifeDays = 30
+
+	// scoreZ is the z-value for the 95% Wilson score interval
+	scoreZ = 1.96
+)
+
+// FlakeScore is a time-decayed flake rate with its 95% Wilson score interval.
+// Lower is the conservative estimate used for ranking: a test with 1 flake in 2 runs
+// has a much lower bound than one with 40 flakes in 200.
+type FlakeScore struct {
+	Rate  float64
+	Lower float64
+	Upper float64
+}
+
+// ComputeFlakeScore scores decayed mixed-outcome and total run weights.
+// Each run contributes 0.5^(age/ScoreHalfLifeDays), so the weights may be fractional.
+func ComputeFlakeScore(mixedWeight, totalWeight float64) FlakeScore {
+	if totalWeight <= 0 || mixedWeight <= 0 {
+		return FlakeScore{}
+	}
+	if mixedWeight > totalWeight {
+		mixedWeight = totalWeight
+	}
+
+	rate := mixedWeight / totalWeight
+	lower, upper := wilsonInterval(rate, totalWeight, scoreZ)
+
+	return FlakeScore{
+		Rate:  rate,
+		Lower: lower,
+		Upper: upper,
+	}
+}
+
+// wilsonInterval returns the Wilson score interval for proportion p over n trials
+func wilsonInterval(p, n, z float64) (float64, float64) {
+	z2 := z * z
+	denom := 1 + z2/n
+	center := p + z2/(2*n)
+	margin := z * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+
+	lower := (center - margin) / denom
+	upper := (center + margin) / denom
+
+	return clamp01(lower), clamp01(upper)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
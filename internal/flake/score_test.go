@@ -0,0 +1,44 @@
+package flake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFlakeScore_NoRuns(t *testing.T) {
+	score := ComputeFlakeScore(0, 0)
+	require.Equal(t, FlakeScore{}, score)
+}
+
+func TestComputeFlakeScore_RateAndIntervalContainRate(t *testing.T) {
+	score := ComputeFlakeScore(40, 200)
+	require.InDelta(t, 0.2, score.Rate, 1e-9)
+	require.Less(t, score.Lower, score.Rate)
+	require.Greater(t, score.Upper, score.Rate)
+	require.InDelta(t, 0.1506, score.Lower, 1e-3)
+	require.InDelta(t, 0.2605, score.Upper, 1e-3)
+}
+
+func TestComputeFlakeScore_SmallSampleRanksBelowLargeSample(t *testing.T) {
+	small := ComputeFlakeScore(1, 2)
+	large := ComputeFlakeScore(40, 200)
+
+	require.Greater(t, small.Rate, large.Rate)
+	require.Less(t, small.Lower, large.Lower)
+}
+
+func TestComputeFlakeScore_AllRunsMixedStaysInRange(t *testing.T) {
+	score := ComputeFlakeScore(1, 1)
+	require.Equal(t, 1.0, score.Rate)
+	require.Equal(t, 1.0, score.Upper)
+	require.GreaterOrEqual(t, score.Lower, 0.0)
+	require.Less(t, score.Lower, 1.0)
+}
+
+func TestComputeFlakeScore_FractionalDecayedWeights(t *testing.T) {
+	score := ComputeFlakeScore(0.5, 2.5)
+	require.InDelta(t, 0.2, score.Rate, 1e-9)
+	require.GreaterOrEqual(t, score.Lower, 0.0)
+	require.LessOrEqual(t, score.Upper, 1.0)
+}
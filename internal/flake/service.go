@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -23,9 +25,26 @@ func NewService(pool *pgxpool.Pool) *Service {
 }
 
 // ListFlakes returns a list of flaky tests with filtering and pagination (used by web UI).
+// Results are ranked by the lower confidence bound of the flake score, so well-evidenced
+// flakes outrank tests with only a handful of runs.
 func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListFlakesRequest) ([]FlakeListItem, int, error) {
 	cutoffDate := time.Now().AddDate(0, 0, -req.Days)
 
+	// Scores and counts come from the branch breakdown when filtering by branch class
+	from := `
+		FROM flake_stats fs
+		JOIN test_cases tc ON tc.id = fs.test_case_id
+	`
+	scoreCols := `
+			fs.flake_score,
+			fs.flake_score_lower,
+			fs.flake_score_upper,
+			fs.mixed_outcome_runs,
+			fs.total_runs_seen,
+	`
+	orderBy := `
+		ORDER BY fs.flake_score_lower DESC, fs.flake_score DESC, fs.last_seen_at DESC
+	`
 	where := `
 		WHERE tc.project_id = $1
 		  AND fs.last_seen_at >= $2
@@ -34,6 +53,28 @@ func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListF
 	args := []any{projectID, cutoffDate}
 	argNum := 3
 
+	if req.BranchClass != "" {
+		from += fmt.Sprintf(" JOIN flake_branch_stats fbs ON fbs.test_case_id = fs.test_case_id AND fbs.branch_class = $%d::branch_class", argNum)
+		args = append(args, string(req.BranchClass))
+		argNum++
+
+		scoreCols = `
+			fbs.flake_score,
+			fbs.flake_score_lower,
+			fbs.flake_score_upper,
+			fbs.mixed_outcome_runs,
+			fbs.total_runs_seen,
+		`
+		orderBy = `
+		ORDER BY fbs.flake_score_lower DESC, fbs.flake_score DESC, fbs.last_flake_at DESC
+		`
+		where = `
+		WHERE tc.project_id = $1
+		  AND fbs.mixed_outcome_runs > 0
+		  AND fbs.last_flake_at >= $2
+		`
+	}
+
 	if req.Repo != "" {
 		where += fmt.Sprintf(" AND tc.repo_full_name = $%d", argNum)
 		args = append(args, req.Repo)
@@ -46,11 +87,33 @@ func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListF
 		argNum++
 	}
 
+	if req.JobVariant != nil {
+		where += fmt.Sprintf(" AND tc.job_variant = $%d", argNum)
+		args = append(args, *req.JobVariant)
+		argNum++
+	}
+
+	if req.Statuses != nil {
+		statuses := make([]string, len(req.Statuses))
+		for i, status := range req.Statuses {
+			statuses[i] = string(status)
+		}
+		where += fmt.Sprintf(" AND fs.status = ANY($%d::flake_status[])", argNum)
+		args = append(args, statuses)
+		argNum++
+	}
+
+	if req.Owner == ownership.OwnerUnowned {
+		where += " AND cardinality(tc.owners) = 0"
+	} else if req.Owner != "" {
+		where += fmt.Sprintf(" AND $%d = ANY(tc.owners)", argNum)
+		args = append(args, req.Owner)
+		argNum++
+	}
+
 	countQuery := `
 		SELECT COUNT(*)
-		FROM flake_stats fs
-		JOIN test_cases tc ON tc.id = fs.test_case_id
-	` + where
+	` + from + where
 
 	var total int
 	if err := s.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
@@ -64,16 +127,12 @@ func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListF
 			tc.job_name,
 			tc.job_variant,
 			tc.test_identifier,
-			fs.flake_score,
-			fs.mixed_outcome_runs,
-			fs.total_runs_seen,
+			tc.owners,
+	` + scoreCols + `
+			fs.status::text,
 			fs.first_seen_at,
 			fs.last_seen_at
-		FROM flake_stats fs
-		JOIN test_cases tc ON tc.id = fs.test_case_id
-	` + where + `
-		ORDER BY fs.flake_score DESC, fs.last_seen_at DESC
-	` + fmt.Sprintf(" LIMIT $%d OFFSET $%d", argNum, argNum+1)
+	` + from + where + orderBy + fmt.Sprintf(" LIMIT $%d OFFSET $%d", argNum, argNum+1)
 
 	args = append(args, req.Limit, req.Offset)
 
@@ -92,9 +151,13 @@ func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListF
 			&item.JobName,
 			&item.JobVariant,
 			&item.TestIdentifier,
+			&item.Owners,
 			&item.FlakeScore,
+			&item.FlakeScoreLower,
+			&item.FlakeScoreUpper,
 			&item.MixedOutcomeRuns,
 			&item.TotalRunsSeen,
+			&item.Status,
 			&item.FirstSeenAt,
 			&item.LastSeenAt,
 		); err != nil {
@@ -105,6 +168,18 @@ func (s *Service) ListFlakes(ctx context.Context, projectID uuid.UUID, req ListF
 	if err := rows.Err(); err != nil {
 		return nil, 0, err
 	}
+	rows.Close()
+
+	rules, err := quarantine.NewService(s.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range flakes {
+		if rule := rules.Match(flakes[i].TestIdentifier); rule != nil {
+			flakes[i].Quarantined = true
+			flakes[i].QuarantineRuleID = &rule.ID
+		}
+	}
 
 	return flakes, total, nil
 }
@@ -119,14 +194,23 @@ func (s *Service) GetFlakeDetail(ctx context.Context, projectID, testCaseID uuid
 			tc.job_name,
 			tc.job_variant,
 			tc.test_identifier,
+			tc.file_path,
+			tc.owners,
 			fs.flake_score,
+			fs.flake_score_lower,
+			fs.flake_score_upper,
 			fs.mixed_outcome_runs,
 			fs.total_runs_seen,
 			fs.last_failure_message,
 			fs.first_seen_at,
-			fs.last_seen_at
+			fs.last_seen_at,
+			fs.status::text,
+			fs.status_changed_at,
+			fs.clean_runs,
+			p.flake_resolve_runs
 		FROM flake_stats fs
 		JOIN test_cases tc ON tc.id = fs.test_case_id
+		JOIN projects p ON p.id = tc.project_id
 		WHERE tc.project_id = $1
 		  AND tc.id = $2
 		LIMIT 1
@@ -139,29 +223,56 @@ func (s *Service) GetFlakeDetail(ctx context.Context, projectID, testCaseID uuid
 		&detail.JobName,
 		&detail.JobVariant,
 		&detail.TestIdentifier,
+		&detail.FilePath,
+		&detail.Owners,
 		&detail.FlakeScore,
+		&detail.FlakeScoreLower,
+		&detail.FlakeScoreUpper,
 		&detail.MixedOutcomeRuns,
 		&detail.TotalRunsSeen,
 		&detail.LastFailureMessage,
 		&detail.FirstSeenAt,
 		&detail.LastSeenAt,
+		&detail.Status,
+		&detail.StatusChangedAt,
+		&detail.CleanRuns,
+		&detail.ResolveAfterRuns,
 	); err != nil {
 		return nil, 0, ErrFlakeNotFound
 	}
 
 	evidenceQuery := `
 		SELECT
-			cr.github_run_id,
+			fe.kind::text,
+			cr.provider::text,
+			cr.run_id,
+			cr.run_number,
 			cr.run_url,
+			cr.repo_full_name,
 			cr.sha,
 			fe.failed_attempt_number,
 			fe.passed_attempt_number,
+			pcr.provider::text,
+			pcr.run_id,
+			pcr.run_number,
+			pcr.run_url,
 			failed.completed_at,
-			passed.completed_at
+			passed.completed_at,
+			reruns.rerun_failures
 		FROM flake_events fe
 		JOIN ci_runs cr ON cr.id = fe.ci_run_id
+		LEFT JOIN ci_runs pcr ON pcr.id = fe.passed_ci_run_id
 		LEFT JOIN ci_run_attempts failed ON failed.ci_run_id = cr.id AND failed.attempt_number = fe.failed_attempt_number
-		LEFT JOIN ci_run_attempts passed ON passed.ci_run_id = cr.id AND passed.attempt_number = fe.passed_attempt_number
+		LEFT JOIN ci_run_attempts passed ON passed.ci_run_id = COALESCE(fe.passed_ci_run_id, cr.id) AND passed.attempt_number = fe.passed_attempt_number
+		LEFT JOIN LATERAL (
+			SELECT tr.rerun_failures
+			FROM test_results tr
+			JOIN ci_jobs cj ON cj.id = tr.ci_job_id
+			WHERE fe.kind::text = 'in_job_retry'
+			  AND cj.ci_run_attempt_id = passed.id
+			  AND tr.test_case_id = fe.test_case_id
+			LIMIT 1
+		) reruns ON TRUE
 		WHERE fe.test_case_id = $1
 		  AND fe.created_at >= $2
 		ORDER BY fe.created_at DESC
@@ -177,20 +288,44 @@ func (s *Service) GetFlakeDetail(ctx context.Context, projectID, testCaseID uuid
 	var evidence []FlakeEvidence
 	for rows.Next() {
 		var ev FlakeEvidence
+		var runNumber, repo string
+		var passedRunNumber *string
 		var failedAt sql.NullTime
 		var passedAt sql.NullTime
 
 		if err := rows.Scan(
-			&ev.GitHubRunID,
+			&ev.Kind,
+			&ev.Provider,
+			&ev.RunID,
+			&runNumber,
 			&ev.RunURL,
+			&repo,
 			&ev.SHA,
 			&ev.AttemptFailed,
 			&ev.AttemptPassed,
+			&ev.PassedProvider,
+			&ev.PassedRunID,
+			&passedRunNumber,
+			&ev.PassedRunURL,
 			&failedAt,
 			&passedAt,
+			&ev.RerunFailures,
 		); err != nil {
 			return nil, 0, err
 		}
+		ev.RunLabel = RunLabel(ev.Provider, ev.RunID, runNumber)
+		if u := AttemptURL(ev.Provider, ev.RunURL, ev.AttemptFailed); u != "" {
+			ev.AttemptURL = &u
+		}
+		if u := CommitURL(ev.Provider, ev.RunURL, repo, ev.SHA); u != "" {
+			ev.CommitURL = &u
+		}
+		ev.GitHubRunID = GitHubRunID(ev.Provider, ev.RunID)
+		if ev.PassedRunID != nil {
+			label := RunLabel(*ev.PassedProvider, *ev.PassedRunID, *passedRunNumber)
+			ev.PassedRunLabel = &label
+			ev.PassedGitHubRunID = GitHubRunID(*ev.PassedProvider, *ev.PassedRunID)
+		}
 		if failedAt.Valid {
 			ev.FailedAt = &failedAt.Time
 		}
@@ -205,6 +340,39 @@ func (s *Service) GetFlakeDetail(ctx context.Context, projectID, testCaseID uuid
 
 	detail.Evidence = evidence
 
+	branches, err := s.getBranchStats(ctx, testCaseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	detail.Branches = branches
+
+	variants, err := s.getVariantStats(ctx, projectID, testCaseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	detail.Variants = variants
+
+	history, err := s.getStatusHistory(ctx, testCaseID, MaxStatusHistory)
+	if err != nil {
+		return nil, 0, err
+	}
+	detail.StatusHistory = history
+
+	onset, err := s.getFlakeOnset(ctx, testCaseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	detail.Onset = onset
+
+	rules, err := quarantine.NewService(s.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rule := rules.Match(detail.TestIdentifier); rule != nil {
+		detail.Quarantined = true
+		detail.QuarantineRuleID = &rule.ID
+	}
+
 	var evidenceTotal int
 	countQuery := `
 		SELECT COUNT(*)
@@ -218,3 +386,96 @@ func (s *Service) GetFlakeDetail(ctx context.Context, projectID, testCaseID uuid
 
 	return &detail, evidenceTotal, nil
 }
+
+// getBranchStats returns the per-branch-class breakdown for a test case.
+func (s *Service) getBranchStats(ctx context.Context, testCaseID uuid.UUID) ([]FlakeBranchStats, error) {
+	query := `
+		SELECT
+			branch_class::text,
+			mixed_outcome_runs,
+			total_runs_seen,
+			flake_score,
+			flake_score_lower,
+			flake_score_upper,
+			last_flake_at
+		FROM flake_branch_stats
+		WHERE test_case_id = $1
+		ORDER BY branch_class
+	`
+
+	rows, err := s.pool.Query(ctx, query, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var branches []FlakeBranchStats
+	for rows.Next() {
+		var b FlakeBranchStats
+		if err := rows.Scan(
+			&b.BranchClass,
+			&b.MixedOutcomeRuns,
+			&b.TotalRunsSeen,
+			&b.FlakeScore,
+			&b.FlakeScoreLower,
+			&b.FlakeScoreUpper,
+			&b.LastFlakeAt,
+		); err != nil {
+			return nil, err
+		}
+		branches = append(branches, b)
+	}
+
+	return branches, rows.Err()
+}
+
+// getVariantStats returns flake stats for the same test under other job variants
+// (same repo, job name and test identifier).
+func (s *Service) getVariantStats(ctx context.Context, projectID, testCaseID uuid.UUID) ([]FlakeVariantStats, error) {
+	query := `
+		SELECT
+			other.id,
+			other.job_variant,
+			COALESCE(fs.flake_score, 0),
+			COALESCE(fs.flake_score_lower, 0),
+			COALESCE(fs.flake_score_upper, 0),
+			COALESCE(fs.mixed_outcome_runs, 0),
+			COALESCE(fs.total_runs_seen, 0)
+		FROM test_cases tc
+		JOIN test_cases other
+		  ON other.project_id = tc.project_id
+		 AND other.repo_full_name = tc.repo_full_name
+		 AND other.job_name = tc.job_name
+		 AND other.test_identifier = tc.test_identifier
+		 AND other.id <> tc.id
+		LEFT JOIN flake_stats fs ON fs.test_case_id = other.id
+		WHERE tc.project_id = $1
+		  AND tc.id = $2
+		ORDER BY other.job_variant
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []FlakeVariantStats
+	for rows.Next() {
+		var v FlakeVariantStats
+		if err := rows.Scan(
+			&v.TestCaseID,
+			&v.JobVariant,
+			&v.FlakeScore,
+			&v.FlakeScoreLower,
+			&v.FlakeScoreUpper,
+			&v.MixedOutcomeRuns,
+			&v.TotalRunsSeen,
+		); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	return variants, rows.Err()
+}
@@ -2,7 +2,9 @@ package flake
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -26,29 +28,31 @@ const (
 )
 
 // UpdateStats updates flake statistics for a test case
-// This should be called within a transaction (tx) after detecting a flake
+// This should be called within a transaction (tx) after detecting a flake,
+// once per new flake event; ciRunID is the run the event is recorded on
 func (s *StatsService) UpdateStats(ctx context.Context, tx pgx.Tx, testCaseID, ciRunID uuid.UUID, failureMessage *string) error {
-	// Count total runs this test has appeared in
-	totalRuns, err := s.countTotalRuns(ctx, tx, testCaseID)
+	totalRuns, mixedRuns, score, err := s.computeScore(ctx, tx, testCaseID)
 	if err != nil {
-		return fmt.Errorf("failed to count total runs: %w", err)
+		return err
 	}
 
-	// Count unique runs with mixed outcomes (flakes)
-	mixedRuns, err := s.countMixedOutcomeRuns(ctx, tx, testCaseID)
+	// Truncate failure message to 1KB
+	truncatedMsg := truncateMessage(failureMessage)
+
+	// Move the flake along its lifecycle
+	previous, err := getFlakeStatus(ctx, tx, testCaseID)
 	if err != nil {
-		return fmt.Errorf("failed to count mixed outcome runs: %w", err)
+		return err
 	}
-
-	// Calculate flake score
-	var flakeScore float64
-	if totalRuns > 0 {
-		flakeScore = float64(mixedRuns) / float64(totalRuns)
+	status, reason := FlakeStatusNew, StatusReasonDetected
+	if previous != nil {
+		status, reason = statusAfterFlake(*previous), StatusReasonFlaked
+		// More evidence from the run it first flaked in keeps a flake new
+		if *previous == FlakeStatusNew && mixedRuns <= 1 {
+			status = FlakeStatusNew
+		}
 	}
 
-	// Truncate failure message to 1KB
-	truncatedMsg := truncateMessage(failureMessage)
-
 	// Upsert flake_stats
 	query := `
 		INSERT INTO flake_stats (
@@ -56,68 +60,371 @@ func (s *StatsService) UpdateStats(ctx context.Context, tx pgx.Tx, testCaseID, c
 			mixed_outcome_runs,
 			total_runs_seen,
 			flake_score,
+			flake_score_lower,
+			flake_score_upper,
 			last_failure_message,
+			status,
 			first_seen_at,
-			last_seen_at
-		) VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+			last_seen_at,
+			scored_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8::flake_status, NOW(), NOW(), NOW())
 		ON CONFLICT (test_case_id)
 		DO UPDATE SET
 			mixed_outcome_runs = EXCLUDED.mixed_outcome_runs,
 			total_runs_seen = EXCLUDED.total_runs_seen,
 			flake_score = EXCLUDED.flake_score,
+			flake_score_lower = EXCLUDED.flake_score_lower,
+			flake_score_upper = EXCLUDED.flake_score_upper,
 			last_failure_message = EXCLUDED.last_failure_message,
-			last_seen_at = NOW()
+			status = EXCLUDED.status,
+			status_changed_at = CASE
+				WHEN flake_stats.status <> EXCLUDED.status THEN NOW()
+				ELSE flake_stats.status_changed_at
+			END,
+			clean_runs = 0,
+			last_seen_at = NOW(),
+			scored_at = NOW()
 	`
 
 	_, err = tx.Exec(ctx, query,
 		testCaseID,
 		mixedRuns,
 		totalRuns,
-		flakeScore,
+		score.Rate,
+		score.Lower,
+		score.Upper,
 		truncatedMsg,
+		string(status),
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to upsert flake_stats: %w", err)
 	}
 
+	if previous == nil || *previous != status {
+		if _, err := recordStatusChange(ctx, tx, testCaseID, previous, status, reason, nil, &ciRunID, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := s.updateBranchStats(ctx, tx, testCaseID); err != nil {
+		return fmt.Errorf("failed to update branch stats: %w", err)
+	}
+
+	if err := recordDailyFlake(ctx, tx, testCaseID, ciRunID); err != nil {
+		return err
+	}
+
 	log.Debug().
 		Str("test_case_id", testCaseID.String()).
 		Int("mixed_outcome_runs", mixedRuns).
 		Int("total_runs_seen", totalRuns).
-		Float64("flake_score", flakeScore).
+		Float64("flake_score", score.Rate).
+		Float64("flake_score_lower", score.Lower).
+		Float64("flake_score_upper", score.Upper).
+		Str("status", string(status)).
 		Msg("Updated flake stats")
 
 	return nil
 }
 
-// countTotalRuns counts how many unique CI runs this test has appeared in
-func (s *StatsService) countTotalRuns(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (int, error) {
+// Rescore recomputes the time-decayed score of a flaky test, and of its
+// branch breakdown, without recording a flake. Scores decay as runs age, so
+// tests are rescored periodically by RescoreStale.
+func (s *StatsService) Rescore(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) error {
+	totalRuns, mixedRuns, score, err := s.computeScore(ctx, tx, testCaseID)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		SELECT COUNT(DISTINCT cra.ci_run_id)
-		FROM test_results tr
-		JOIN ci_jobs cj ON tr.ci_job_id = cj.id
-		JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
-		WHERE tr.test_case_id = $1
+		UPDATE flake_stats
+		SET mixed_outcome_runs = $2,
+		    total_runs_seen = $3,
+		    flake_score = $4,
+		    flake_score_lower = $5,
+		    flake_score_upper = $6,
+		    scored_at = NOW()
+		WHERE test_case_id = $1
+	`
+	if _, err := tx.Exec(ctx, query, testCaseID, mixedRuns, totalRuns, score.Rate, score.Lower, score.Upper); err != nil {
+		return fmt.Errorf("failed to rescore flake_stats: %w", err)
+	}
+
+	if err := s.updateBranchStats(ctx, tx, testCaseID); err != nil {
+		return fmt.Errorf("failed to update branch stats: %w", err)
+	}
+	return nil
+}
+
+// MarkRunTestsStale marks the flaky tests that ran in a CI run, except those
+// in skip, whose stats were just updated, to be rescored by RescoreStale. The
+// run changes their run counts and decay, which are recomputed outside the
+// detection transaction. Returns how many tests were marked.
+func (s *StatsService) MarkRunTestsStale(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID, skip map[uuid.UUID]bool) (int64, error) {
+	scored := make([]uuid.UUID, 0, len(skip))
+	for testCaseID := range skip {
+		scored = append(scored, testCaseID)
+	}
+
+	query := `
+		UPDATE flake_stats
+		SET scored_at = '-infinity'
+		WHERE test_case_id IN (
+			SELECT tr.test_case_id
+			FROM test_results tr
+			JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+			JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+			WHERE cra.ci_run_id = $1
+		)
+		  AND NOT (test_case_id = ANY($2))
+	`
+	tag, err := tx.Exec(ctx, query, ciRunID, scored)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark flaky tests of run stale: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// rescoreBatchSize is how many stale tests RescoreStale lists at a time
+const rescoreBatchSize = 500
+
+// RescoreStale rescores flaky tests last scored before the given time, so
+// tests that stopped running or flaking still decay. Each test is rescored in
+// its own transaction, so ingestions are never blocked for long. Returns how
+// many tests were rescored.
+func RescoreStale(ctx context.Context, pool *pgxpool.Pool, before time.Time) (int, error) {
+	s := NewStatsService(pool)
+	rescored := 0
+	after := uuid.Nil
+	for {
+		rows, err := pool.Query(ctx, `
+			SELECT test_case_id
+			FROM flake_stats
+			WHERE scored_at < $1 AND test_case_id > $2
+			ORDER BY test_case_id
+			LIMIT $3
+		`, before, after, rescoreBatchSize)
+		if err != nil {
+			return rescored, fmt.Errorf("failed to list stale flake scores: %w", err)
+		}
+		testCaseIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return rescored, fmt.Errorf("failed to list stale flake scores: %w", err)
+		}
+
+		for _, testCaseID := range testCaseIDs {
+			ok, err := s.rescoreStale(ctx, testCaseID, before)
+			if err != nil {
+				return rescored, err
+			}
+			if ok {
+				rescored++
+			}
+		}
+
+		if len(testCaseIDs) < rescoreBatchSize {
+			return rescored, nil
+		}
+		after = testCaseIDs[len(testCaseIDs)-1]
+	}
+}
+
+// rescoreStale rescores one test unless an ingestion holds or already
+// rescored it
+func (s *StatsService) rescoreStale(ctx context.Context, testCaseID uuid.UUID, before time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var locked uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT test_case_id
+		FROM flake_stats
+		WHERE test_case_id = $1 AND scored_at < $2
+		FOR UPDATE SKIP LOCKED
+	`, testCaseID, before).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock flake stats: %w", err)
+	}
+
+	if err := s.Rescore(ctx, tx, testCaseID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// computeScore counts the runs and flaky runs of a test and scores them
+// with time decay
+func (s *StatsService) computeScore(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (int, int, FlakeScore, error) {
+	// Count total runs this test has appeared in
+	totalRuns, totalWeight, err := s.countTotalRuns(ctx, tx, testCaseID)
+	if err != nil {
+		return 0, 0, FlakeScore{}, fmt.Errorf("failed to count total runs: %w", err)
+	}
+
+	// Count unique runs with mixed outcomes (flakes)
+	mixedRuns, mixedWeight, err := s.countMixedOutcomeRuns(ctx, tx, testCaseID)
+	if err != nil {
+		return 0, 0, FlakeScore{}, fmt.Errorf("failed to count mixed outcome runs: %w", err)
+	}
+
+	// Calculate time-decayed flake score with confidence interval
+	return totalRuns, mixedRuns, ComputeFlakeScore(mixedWeight, totalWeight), nil
+}
+
+// decayedWeightSQL is the per-run weight 0.5^(age_days / half_life_days), where the
+// half-life is bound as $2 and the run's age is taken from cr.first_seen_at.
+const decayedWeightSQL = `POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (NOW() - cr.first_seen_at)), 0) / 86400.0 / $2)`
+
+// countTotalRuns counts how many unique CI runs this test has appeared in,
+// along with the time-decayed weight of those runs
+func (s *StatsService) countTotalRuns(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (int, float64, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(` + decayedWeightSQL + `), 0)
+		FROM ci_runs cr
+		WHERE cr.id IN (
+			SELECT cra.ci_run_id
+			FROM test_results tr
+			JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+			JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+			WHERE tr.test_case_id = $1
+		)
 	`
 
 	var count int
-	err := tx.QueryRow(ctx, query, testCaseID).Scan(&count)
-	return count, err
+	var weight float64
+	err := tx.QueryRow(ctx, query, testCaseID, float64(ScoreHalfLifeDays)).Scan(&count, &weight)
+	return count, weight, err
 }
 
-// countMixedOutcomeRuns counts unique runs where this test had both failures and passes
-func (s *StatsService) countMixedOutcomeRuns(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (int, error) {
+// countMixedOutcomeRuns counts unique runs where this test had both failures and passes,
+// along with the time-decayed weight of those runs
+func (s *StatsService) countMixedOutcomeRuns(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) (int, float64, error) {
 	// Count distinct ci_run_ids that have flake events for this test
 	query := `
-		SELECT COUNT(DISTINCT ci_run_id)
-		FROM flake_events
-		WHERE test_case_id = $1
+		SELECT COUNT(*), COALESCE(SUM(` + decayedWeightSQL + `), 0)
+		FROM ci_runs cr
+		WHERE cr.id IN (
+			SELECT ci_run_id
+			FROM flake_events
+			WHERE test_case_id = $1
+		)
 	`
 
 	var count int
-	err := tx.QueryRow(ctx, query, testCaseID).Scan(&count)
-	return count, err
+	var weight float64
+	err := tx.QueryRow(ctx, query, testCaseID, float64(ScoreHalfLifeDays)).Scan(&count, &weight)
+	return count, weight, err
+}
+
+// branchClassSQL classifies a ci_runs row (cr) against its project's default branch (p)
+const branchClassSQL = `
+	CASE
+		WHEN cr.event = 'pull_request' OR cr.pr_number IS NOT NULL THEN 'pr'
+		WHEN cr.branch = p.default_branch THEN 'default'
+		ELSE 'other'
+	END`
+
+// updateBranchStats recomputes flake_branch_stats for every branch class this test has run on
+func (s *StatsService) updateBranchStats(ctx context.Context, tx pgx.Tx, testCaseID uuid.UUID) error {
+	query := `
+		WITH mixed AS (
+			SELECT DISTINCT ci_run_id
+			FROM flake_events
+			WHERE test_case_id = $1
+		)
+		SELECT
+			` + branchClassSQL + ` AS branch_class,
+			COUNT(*),
+			COALESCE(SUM(` + decayedWeightSQL + `), 0),
+			COUNT(*) FILTER (WHERE mixed.ci_run_id IS NOT NULL),
+			COALESCE(SUM(` + decayedWeightSQL + `) FILTER (WHERE mixed.ci_run_id IS NOT NULL), 0),
+			MAX(cr.first_seen_at) FILTER (WHERE mixed.ci_run_id IS NOT NULL)
+		FROM ci_runs cr
+		JOIN projects p ON p.id = cr.project_id
+		LEFT JOIN mixed ON mixed.ci_run_id = cr.id
+		WHERE cr.id IN (
+			SELECT cra.ci_run_id
+			FROM test_results tr
+			JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+			JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+			WHERE tr.test_case_id = $1
+		)
+		GROUP BY 1
+	`
+
+	rows, err := tx.Query(ctx, query, testCaseID, float64(ScoreHalfLifeDays))
+	if err != nil {
+		return err
+	}
+
+	var breakdown []FlakeBranchStats
+	for rows.Next() {
+		var b FlakeBranchStats
+		var totalWeight, mixedWeight float64
+		if err := rows.Scan(&b.BranchClass, &b.TotalRunsSeen, &totalWeight, &b.MixedOutcomeRuns, &mixedWeight, &b.LastFlakeAt); err != nil {
+			rows.Close()
+			return err
+		}
+		score := ComputeFlakeScore(mixedWeight, totalWeight)
+		b.FlakeScore = score.Rate
+		b.FlakeScoreLower = score.Lower
+		b.FlakeScoreUpper = score.Upper
+		breakdown = append(breakdown, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	upsert := `
+		INSERT INTO flake_branch_stats (
+			test_case_id,
+			branch_class,
+			mixed_outcome_runs,
+			total_runs_seen,
+			flake_score,
+			flake_score_lower,
+			flake_score_upper,
+			last_flake_at
+		) VALUES ($1, $2::branch_class, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (test_case_id, branch_class)
+		DO UPDATE SET
+			mixed_outcome_runs = EXCLUDED.mixed_outcome_runs,
+			total_runs_seen = EXCLUDED.total_runs_seen,
+			flake_score = EXCLUDED.flake_score,
+			flake_score_lower = EXCLUDED.flake_score_lower,
+			flake_score_upper = EXCLUDED.flake_score_upper,
+			last_flake_at = EXCLUDED.last_flake_at
+	`
+
+	for _, b := range breakdown {
+		if _, err := tx.Exec(ctx, upsert,
+			testCaseID,
+			string(b.BranchClass),
+			b.MixedOutcomeRuns,
+			b.TotalRunsSeen,
+			b.FlakeScore,
+			b.FlakeScoreLower,
+			b.FlakeScoreUpper,
+			b.LastFlakeAt,
+		); err != nil {
+			return fmt.Errorf("failed to upsert flake_branch_stats: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // truncateMessage truncates a message to MaxFailureMessageLength (1KB)
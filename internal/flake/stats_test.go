@@ -0,0 +1,42 @@
+package flake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTx counts the statements run in a transaction
+type countingTx struct {
+	pgx.Tx
+	execs int
+}
+
+func (tx *countingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.execs++
+	return pgconn.NewCommandTag("UPDATE 0"), nil
+}
+
+func (tx *countingTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	panic("unexpected query")
+}
+
+func (tx *countingTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	panic("unexpected query")
+}
+
+func TestStatsService_MarkRunTestsStale_SingleStatement(t *testing.T) {
+	skip := make(map[uuid.UUID]bool)
+	for i := 0; i < 1000; i++ {
+		skip[uuid.New()] = true
+	}
+
+	tx := &countingTx{}
+	_, err := NewStatsService(nil).MarkRunTestsStale(context.Background(), tx, uuid.New(), skip)
+	require.NoError(t, err)
+	require.Equal(t, 1, tx.execs)
+}
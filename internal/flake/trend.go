@@ -0,0 +1,319 @@
+package flake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultTrendDays is the length of a trend series when none is requested
+	DefaultTrendDays = 30
+	// MaxTrendDays bounds the length of a trend series
+	MaxTrendDays = 365
+)
+
+// runDaySQL is the UTC day a ci_runs row (cr) is counted on in test_daily_stats
+const runDaySQL = `(cr.first_seen_at AT TIME ZONE 'UTC')::date`
+
+// TrendRequest selects a project's trend series. JobVariant nil means any
+// variant; "" matches jobs without a variant.
+type TrendRequest struct {
+	Days       int
+	Repo       string
+	JobName    string
+	JobVariant *string
+}
+
+// dailyCounts is a test_daily_stats row, or the sum of several
+type dailyCounts struct {
+	runs       int
+	failedRuns int
+	flakyRuns  int
+	flakyTests int
+}
+
+// RecordDailyRuns adds new test results of a CI job to the daily rollup.
+// testCaseIDs are the tests whose result in the job was inserted, not
+// skipped as a duplicate. A test counts once per CI run and once more as
+// failed when it fails on any attempt; results of the run's other attempts
+// are already counted, as ingestions of one run are serialized by the
+// ci_runs row lock the caller's transaction holds.
+func RecordDailyRuns(ctx context.Context, tx pgx.Tx, ciRunID, ciJobID uuid.UUID, testCaseIDs []uuid.UUID) error {
+	if len(testCaseIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO test_daily_stats (test_case_id, project_id, day, runs, failed_runs)
+		SELECT
+			tr.test_case_id,
+			cr.project_id,
+			` + runDaySQL + `,
+			CASE WHEN prior.results = 0 THEN 1 ELSE 0 END,
+			CASE WHEN tr.status IN ('failed', 'error') AND prior.failures = 0 THEN 1 ELSE 0 END
+		FROM test_results tr
+		JOIN ci_runs cr ON cr.id = $1
+		CROSS JOIN LATERAL (
+			SELECT
+				COUNT(*) AS results,
+				COUNT(*) FILTER (WHERE o.status IN ('failed', 'error')) AS failures
+			FROM test_results o
+			JOIN ci_jobs oj ON oj.id = o.ci_job_id
+			JOIN ci_run_attempts oa ON oa.id = oj.ci_run_attempt_id
+			WHERE o.test_case_id = tr.test_case_id
+			  AND oa.ci_run_id = $1
+			  AND o.ci_job_id <> $2
+		) prior
+		WHERE tr.ci_job_id = $2
+		  AND tr.test_case_id = ANY($3)
+		  AND (prior.results = 0 OR (tr.status IN ('failed', 'error') AND prior.failures = 0))
+		ON CONFLICT (test_case_id, day)
+		DO UPDATE SET
+			runs = test_daily_stats.runs + EXCLUDED.runs,
+			failed_runs = test_daily_stats.failed_runs + EXCLUDED.failed_runs
+	`
+
+	if _, err := tx.Exec(ctx, query, ciRunID, ciJobID, testCaseIDs); err != nil {
+		return fmt.Errorf("failed to record daily runs: %w", err)
+	}
+	return nil
+}
+
+// recordDailyFlake counts the CI run of a new flake event as flaky on the
+// run's day. flake_events holds one event per test and run, so each new
+// event is a new flaky run.
+func recordDailyFlake(ctx context.Context, tx pgx.Tx, testCaseID, ciRunID uuid.UUID) error {
+	query := `
+		INSERT INTO test_daily_stats (test_case_id, project_id, day, flaky_runs)
+		SELECT $1, cr.project_id, ` + runDaySQL + `, 1
+		FROM ci_runs cr
+		WHERE cr.id = $2
+		ON CONFLICT (test_case_id, day)
+		DO UPDATE SET flaky_runs = test_daily_stats.flaky_runs + 1
+	`
+
+	if _, err := tx.Exec(ctx, query, testCaseID, ciRunID); err != nil {
+		return fmt.Errorf("failed to record daily flake: %w", err)
+	}
+	return nil
+}
+
+// BackfillDailyStats rebuilds a project's daily rollup from its test results
+// and flake events and returns the number of rows written. Counts are only
+// ever raised, so days whose flake events were removed by retention keep
+// the counts recorded at ingestion. The function is idempotent.
+func BackfillDailyStats(ctx context.Context, pool *pgxpool.Pool, projectID uuid.UUID) (int64, error) {
+	query := `
+		WITH run_results AS (
+			SELECT
+				tr.test_case_id,
+				` + runDaySQL + ` AS day,
+				BOOL_OR(tr.status IN ('failed', 'error')) AS failed
+			FROM test_results tr
+			JOIN ci_jobs cj ON cj.id = tr.ci_job_id
+			JOIN ci_run_attempts cra ON cra.id = cj.ci_run_attempt_id
+			JOIN ci_runs cr ON cr.id = cra.ci_run_id
+			WHERE cr.project_id = $1
+			GROUP BY tr.test_case_id, cr.id
+		),
+		runs AS (
+			SELECT test_case_id, day, COUNT(*) AS runs, COUNT(*) FILTER (WHERE failed) AS failed_runs
+			FROM run_results
+			GROUP BY test_case_id, day
+		),
+		flaky AS (
+			SELECT fe.test_case_id, ` + runDaySQL + ` AS day, COUNT(*) AS flaky_runs
+			FROM flake_events fe
+			JOIN ci_runs cr ON cr.id = fe.ci_run_id
+			WHERE cr.project_id = $1
+			GROUP BY fe.test_case_id, day
+		)
+		INSERT INTO test_daily_stats (test_case_id, project_id, day, runs, failed_runs, flaky_runs)
+		SELECT
+			COALESCE(r.test_case_id, f.test_case_id),
+			$1,
+			COALESCE(r.day, f.day),
+			COALESCE(r.runs, 0),
+			COALESCE(r.failed_runs, 0),
+			COALESCE(f.flaky_runs, 0)
+		FROM runs r
+		FULL JOIN flaky f ON f.test_case_id = r.test_case_id AND f.day = r.day
+		ON CONFLICT (test_case_id, day)
+		DO UPDATE SET
+			runs = GREATEST(test_daily_stats.runs, EXCLUDED.runs),
+			failed_runs = GREATEST(test_daily_stats.failed_runs, EXCLUDED.failed_runs),
+			flaky_runs = GREATEST(test_daily_stats.flaky_runs, EXCLUDED.flaky_runs)
+	`
+
+	tag, err := pool.Exec(ctx, query, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill daily stats: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetTestTrend returns the daily series of a test case over the last days,
+// ending today (UTC).
+func (s *Service) GetTestTrend(ctx context.Context, projectID, testCaseID uuid.UUID, days int, now time.Time) ([]TrendPoint, error) {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM test_cases WHERE id = $1 AND project_id = $2)`, testCaseID, projectID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrFlakeNotFound
+	}
+
+	start, end := TrendRange(days, now)
+	query := `
+		SELECT day, runs, failed_runs, flaky_runs, 0
+		FROM test_daily_stats
+		WHERE test_case_id = $1
+		  AND day >= $2 AND day < $3
+	`
+
+	counts, err := s.queryDailyCounts(ctx, query, testCaseID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	series := trendSeries(counts, start, end)
+	points := make([]TrendPoint, len(series))
+	for i, p := range series {
+		points[i] = p.TrendPoint
+	}
+	return points, nil
+}
+
+// GetOrgsTrend returns the daily series of the tests of all of the orgs'
+// projects over the last days, ending today (UTC).
+func (s *Service) GetOrgsTrend(ctx context.Context, orgIDs []uuid.UUID, days int, now time.Time) ([]ProjectTrendPoint, error) {
+	start, end := TrendRange(days, now)
+
+	query := `
+		SELECT
+			ds.day,
+			SUM(ds.runs),
+			SUM(ds.failed_runs),
+			SUM(ds.flaky_runs),
+			COUNT(*) FILTER (WHERE ds.flaky_runs > 0)
+		FROM test_daily_stats ds
+		JOIN projects p ON p.id = ds.project_id
+		WHERE p.org_id = ANY($1)
+		  AND ds.day >= $2 AND ds.day < $3
+		GROUP BY ds.day
+	`
+
+	counts, err := s.queryDailyCounts(ctx, query, orgIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return trendSeries(counts, start, end), nil
+}
+
+// GetProjectTrend returns the daily series of a project's tests over the
+// last req.Days, ending today (UTC).
+func (s *Service) GetProjectTrend(ctx context.Context, projectID uuid.UUID, req TrendRequest, now time.Time) ([]ProjectTrendPoint, error) {
+	start, end := TrendRange(req.Days, now)
+
+	query := `
+		SELECT
+			ds.day,
+			SUM(ds.runs),
+			SUM(ds.failed_runs),
+			SUM(ds.flaky_runs),
+			COUNT(*) FILTER (WHERE ds.flaky_runs > 0)
+		FROM test_daily_stats ds
+		JOIN test_cases tc ON tc.id = ds.test_case_id
+		WHERE ds.project_id = $1
+		  AND ds.day >= $2 AND ds.day < $3
+	`
+	args := []any{projectID, start, end}
+	argNum := 4
+
+	if req.Repo != "" {
+		query += fmt.Sprintf(" AND tc.repo_full_name = $%d", argNum)
+		args = append(args, req.Repo)
+		argNum++
+	}
+
+	if req.JobName != "" {
+		query += fmt.Sprintf(" AND tc.job_name = $%d", argNum)
+		args = append(args, req.JobName)
+		argNum++
+	}
+
+	if req.JobVariant != nil {
+		query += fmt.Sprintf(" AND tc.job_variant = $%d", argNum)
+		args = append(args, *req.JobVariant)
+	}
+
+	query += " GROUP BY ds.day"
+
+	counts, err := s.queryDailyCounts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return trendSeries(counts, start, end), nil
+}
+
+// queryDailyCounts runs a query returning day, runs, failed runs, flaky runs
+// and flaky tests, keyed by day
+func (s *Service) queryDailyCounts(ctx context.Context, query string, args ...any) (map[time.Time]dailyCounts, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[time.Time]dailyCounts)
+	for rows.Next() {
+		var day time.Time
+		var c dailyCounts
+		if err := rows.Scan(&day, &c.runs, &c.failedRuns, &c.flakyRuns, &c.flakyTests); err != nil {
+			return nil, err
+		}
+		counts[day.UTC()] = c
+	}
+	return counts, rows.Err()
+}
+
+// TrendRange returns the first day and the exclusive end of a series of the
+// last days ending today (UTC), with days clamped to 1..MaxTrendDays
+func TrendRange(days int, now time.Time) (time.Time, time.Time) {
+	if days <= 0 {
+		days = DefaultTrendDays
+	}
+	days = min(days, MaxTrendDays)
+
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	return end.AddDate(0, 0, -days), end
+}
+
+// trendSeries returns one point per day from start to end, with zero counts
+// on days without runs
+func trendSeries(counts map[time.Time]dailyCounts, start, end time.Time) []ProjectTrendPoint {
+	var points []ProjectTrendPoint
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		c := counts[day]
+		p := ProjectTrendPoint{
+			TrendPoint: TrendPoint{
+				Date:       day.Format(time.DateOnly),
+				Runs:       c.runs,
+				FailedRuns: c.failedRuns,
+				FlakyRuns:  c.flakyRuns,
+			},
+			FlakyTests: c.flakyTests,
+		}
+		if c.runs > 0 {
+			p.FlakeRate = min(float64(c.flakyRuns)/float64(c.runs), 1)
+		}
+		points = append(points, p)
+	}
+	return points
+}
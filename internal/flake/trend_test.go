@@ -0,0 +1,40 @@
+package flake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendRange(t *testing.T) {
+	now := time.Date(2026, 10, 14, 23, 30, 0, 0, time.FixedZone("PDT", -7*3600))
+
+	start, end := TrendRange(7, now)
+	require.Equal(t, time.Date(2026, 10, 9, 0, 0, 0, 0, time.UTC), start, "today is Oct 15 in UTC")
+	require.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = TrendRange(0, now)
+	require.Equal(t, DefaultTrendDays, int(end.Sub(start).Hours()/24))
+
+	start, end = TrendRange(10000, now)
+	require.Equal(t, MaxTrendDays, int(end.Sub(start).Hours()/24))
+}
+
+func TestTrendSeries_FillsDaysWithoutRuns(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+
+	points := trendSeries(map[time.Time]dailyCounts{
+		start:                  {runs: 8, failedRuns: 3, flakyRuns: 2, flakyTests: 1},
+		start.AddDate(0, 0, 2): {runs: 1, flakyRuns: 3},
+		// Outside the range
+		end: {runs: 5},
+	}, start, end)
+
+	require.Equal(t, []ProjectTrendPoint{
+		{TrendPoint: TrendPoint{Date: "2026-10-01", Runs: 8, FailedRuns: 3, FlakyRuns: 2, FlakeRate: 0.25}, FlakyTests: 1},
+		{TrendPoint: TrendPoint{Date: "2026-10-02"}},
+		{TrendPoint: TrendPoint{Date: "2026-10-03", Runs: 1, FlakyRuns: 3, FlakeRate: 1}},
+	}, points)
+}
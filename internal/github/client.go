@@ -0,0 +1,404 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// CheckRunName is the name of the check run FlakeGuard maintains per commit
+	CheckRunName = "FlakeGuard"
+
+	// tokenRefreshMargin renews installation tokens this long before they expire
+	tokenRefreshMargin = time.Minute
+)
+
+// Client talks to the GitHub REST API as a GitHub App installation
+type Client struct {
+	appID      int64
+	privateKey *rsa.PrivateKey
+	baseURL    string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]installationToken // by repo full name
+}
+
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewClient creates a client for the App with the given ID and PEM private
+// key. baseURL is the REST API root, e.g. https://api.github.com or the
+// /api/v3 root of GitHub Enterprise Server.
+func NewClient(appID int64, privateKeyPEM, baseURL string) (*Client, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	return &Client{
+		appID:      appID,
+		privateKey: key,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		tokens:     make(map[string]installationToken),
+	}, nil
+}
+
+// APIError is an error response from the GitHub API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitHub API returned HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub API returned HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// appJWT returns a short-lived JWT authenticating as the App itself
+func (c *Client) appJWT() (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		// Backdated to tolerate clock drift, as GitHub recommends
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+		Issuer:    strconv.FormatInt(c.appID, 10),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(c.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign App JWT: %w", err)
+	}
+	return token, nil
+}
+
+// installationToken returns an access token of the App installation covering
+// repo, reusing a cached token until shortly before it expires
+func (c *Client) installationToken(ctx context.Context, repo string) (string, error) {
+	c.mu.Lock()
+	cached, ok := c.tokens[repo]
+	c.mu.Unlock()
+	if ok && time.Until(cached.expiresAt) > tokenRefreshMargin {
+		return cached.token, nil
+	}
+
+	appToken, err := c.appJWT()
+	if err != nil {
+		return "", err
+	}
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(ctx, appToken, http.MethodGet, "/repos/"+repo+"/installation", nil, &installation); err != nil {
+		return "", fmt.Errorf("failed to find App installation for %s: %w", repo, err)
+	}
+
+	var access struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", installation.ID)
+	if err := c.do(ctx, appToken, http.MethodPost, path, nil, &access); err != nil {
+		return "", fmt.Errorf("failed to create installation token: %w", err)
+	}
+
+	c.mu.Lock()
+	c.tokens[repo] = installationToken{token: access.Token, expiresAt: access.ExpiresAt}
+	c.mu.Unlock()
+	return access.Token, nil
+}
+
+// PullRequestHeadSHA returns the head commit of a pull request. Runs triggered
+// by pull_request events report the merge commit, which GitHub does not show
+// check runs for.
+func (c *Client) PullRequestHeadSHA(ctx context.Context, repo string, prNumber int64) (string, error) {
+	token, err := c.installationToken(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+
+	var pr struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := c.do(ctx, token, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", repo, prNumber), nil, &pr); err != nil {
+		return "", fmt.Errorf("failed to get pull request: %w", err)
+	}
+	return pr.Head.SHA, nil
+}
+
+// CheckRun is the content of the FlakeGuard check run of a commit
+type CheckRun struct {
+	HeadSHA     string
+	Conclusion  string // success or neutral
+	DetailsURL  string
+	Title       string
+	Summary     string
+	Annotations []Annotation
+}
+
+// Annotation marks a flaky test on a file of the commit
+type Annotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+// maxAnnotationsPerRequest is GitHub's limit per check run create or update
+const maxAnnotationsPerRequest = 50
+
+// UpsertCheckRun updates the App's FlakeGuard check run of the commit, or
+// creates it when there is none, so each report of a commit replaces the
+// last. GitHub appends the annotations of an update to the existing ones, so
+// only annotations the run does not have yet are sent.
+func (c *Client) UpsertCheckRun(ctx context.Context, repo string, run CheckRun) error {
+	token, err := c.installationToken(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	checkRunID, err := c.findCheckRun(ctx, token, repo, run.HeadSHA)
+	if err != nil {
+		return err
+	}
+
+	annotations := run.Annotations
+	if checkRunID != 0 {
+		existing, err := c.listAnnotations(ctx, token, repo, checkRunID)
+		if err != nil {
+			return err
+		}
+		annotations = nil
+		for _, a := range run.Annotations {
+			if !existing[a] {
+				annotations = append(annotations, a)
+			}
+		}
+	}
+
+	output := func(annotations []Annotation) map[string]any {
+		return map[string]any{
+			"title":       run.Title,
+			"summary":     run.Summary,
+			"annotations": nonNil(annotations),
+		}
+	}
+
+	first := annotations[:min(maxAnnotationsPerRequest, len(annotations))]
+	body := map[string]any{
+		"name":       CheckRunName,
+		"status":     "completed",
+		"conclusion": run.Conclusion,
+		"output":     output(first),
+	}
+	if run.DetailsURL != "" {
+		body["details_url"] = run.DetailsURL
+	}
+
+	if checkRunID != 0 {
+		path := fmt.Sprintf("/repos/%s/check-runs/%d", repo, checkRunID)
+		if err := c.do(ctx, token, http.MethodPatch, path, body, nil); err != nil {
+			return fmt.Errorf("failed to update check run: %w", err)
+		}
+	} else {
+		body["head_sha"] = run.HeadSHA
+		var created struct {
+			ID int64 `json:"id"`
+		}
+		if err := c.do(ctx, token, http.MethodPost, "/repos/"+repo+"/check-runs", body, &created); err != nil {
+			return fmt.Errorf("failed to create check run: %w", err)
+		}
+		checkRunID = created.ID
+	}
+
+	// Annotations beyond the per-request limit are appended by updates
+	for i := maxAnnotationsPerRequest; i < len(annotations); i += maxAnnotationsPerRequest {
+		batch := annotations[i:min(i+maxAnnotationsPerRequest, len(annotations))]
+		path := fmt.Sprintf("/repos/%s/check-runs/%d", repo, checkRunID)
+		if err := c.do(ctx, token, http.MethodPatch, path, map[string]any{"output": output(batch)}, nil); err != nil {
+			return fmt.Errorf("failed to add check run annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+// findCheckRun returns the ID of the latest FlakeGuard check run the App
+// created on a commit, or 0. Check runs of other apps with the same name are
+// ignored.
+func (c *Client) findCheckRun(ctx context.Context, token, repo, headSHA string) (int64, error) {
+	var list struct {
+		CheckRuns []struct {
+			ID  int64 `json:"id"`
+			App struct {
+				ID int64 `json:"id"`
+			} `json:"app"`
+		} `json:"check_runs"`
+	}
+	path := fmt.Sprintf("/repos/%s/commits/%s/check-runs?check_name=%s&filter=latest&per_page=100",
+		repo, url.PathEscape(headSHA), url.QueryEscape(CheckRunName))
+	if err := c.do(ctx, token, http.MethodGet, path, nil, &list); err != nil {
+		return 0, fmt.Errorf("failed to list check runs: %w", err)
+	}
+	for _, run := range list.CheckRuns {
+		if run.App.ID == c.appID {
+			return run.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// maxAnnotationPages bounds the listing of a check run's annotations
+const maxAnnotationPages = 10
+
+// listAnnotations returns the annotations a check run already has
+func (c *Client) listAnnotations(ctx context.Context, token, repo string, checkRunID int64) (map[Annotation]bool, error) {
+	const perPage = 100
+	existing := make(map[Annotation]bool)
+	for page := 1; page <= maxAnnotationPages; page++ {
+		var annotations []Annotation
+		path := fmt.Sprintf("/repos/%s/check-runs/%d/annotations?per_page=%d&page=%d", repo, checkRunID, perPage, page)
+		if err := c.do(ctx, token, http.MethodGet, path, nil, &annotations); err != nil {
+			return nil, fmt.Errorf("failed to list check run annotations: %w", err)
+		}
+		for _, a := range annotations {
+			existing[a] = true
+		}
+		if len(annotations) < perPage {
+			break
+		}
+	}
+	return existing, nil
+}
+
+// maxCommentPages bounds the search for an existing report comment
+const maxCommentPages = 10
+
+// UpsertComment updates the pull request comment containing marker, or
+// creates it when there is none
+func (c *Client) UpsertComment(ctx context.Context, repo string, prNumber int64, marker, body string) error {
+	token, err := c.installationToken(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := c.findComment(ctx, token, repo, prNumber, marker)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{"body": body}
+	if commentID != 0 {
+		path := fmt.Sprintf("/repos/%s/issues/comments/%d", repo, commentID)
+		if err := c.do(ctx, token, http.MethodPatch, path, payload, nil); err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+		return nil
+	}
+
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, prNumber)
+	if err := c.do(ctx, token, http.MethodPost, path, payload, nil); err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	return nil
+}
+
+// findComment returns the ID of the first pull request comment containing
+// marker, or 0
+func (c *Client) findComment(ctx context.Context, token, repo string, prNumber int64, marker string) (int64, error) {
+	const perPage = 100
+	for page := 1; page <= maxCommentPages; page++ {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		path := fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=%d&page=%d", repo, prNumber, perPage, page)
+		if err := c.do(ctx, token, http.MethodGet, path, nil, &comments); err != nil {
+			return 0, fmt.Errorf("failed to list comments: %w", err)
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, marker) {
+				return comment.ID, nil
+			}
+		}
+		if len(comments) < perPage {
+			break
+		}
+	}
+	return 0, nil
+}
+
+// do sends an authenticated API request and decodes a JSON response into out
+func (c *Client) do(ctx context.Context, token, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errBody struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&errBody); err == nil {
+			apiErr.Message = errBody.Message
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// nonNil keeps an empty annotation batch serializing as [] rather than null
+func nonNil(annotations []Annotation) []Annotation {
+	if annotations == nil {
+		return []Annotation{}
+	}
+	return annotations
+}
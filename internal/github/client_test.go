@@ -0,0 +1,275 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGitHub is a minimal GitHub REST API for one App installation
+type stubGitHub struct {
+	t      *testing.T
+	key    *rsa.PrivateKey
+	server *httptest.Server
+
+	mu            sync.Mutex
+	tokensIssued  int
+	checkRuns     []map[string]any
+	checkRunPatch []map[string]any
+	annotations   map[int64][]any // by check run ID
+	foreignRuns   []map[string]any
+	comments      map[int64]string
+	nextCommentID int64
+}
+
+const stubToken = "ghs_installation_token"
+
+func newStubGitHub(t *testing.T) *stubGitHub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s := &stubGitHub{t: t, key: key, comments: make(map[int64]string), nextCommentID: 100, annotations: make(map[int64][]any)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubGitHub) privateKeyPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(s.key)}))
+}
+
+func (s *stubGitHub) client(t *testing.T) *Client {
+	c, err := NewClient(42, s.privateKeyPEM(), s.server.URL+"/")
+	require.NoError(t, err)
+	return c
+}
+
+// requireAppJWT checks the request is signed by the App's key
+func (s *stubGitHub) requireAppJWT(r *http.Request) bool {
+	raw := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token, err := jwt.Parse(raw, func(*jwt.Token) (any, error) { return &s.key.PublicKey, nil },
+		jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer("42"))
+	return err == nil && token.Valid
+}
+
+func (s *stubGitHub) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	decode := func() map[string]any {
+		var body map[string]any
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		return body
+	}
+
+	route := r.Method + " " + r.URL.Path
+	switch {
+	case route == "GET /repos/acme/api/installation":
+		if !s.requireAppJWT(r) {
+			writeJSON(http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		writeJSON(http.StatusOK, map[string]any{"id": 7})
+		return
+	case route == "POST /app/installations/7/access_tokens":
+		if !s.requireAppJWT(r) {
+			writeJSON(http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		s.tokensIssued++
+		writeJSON(http.StatusCreated, map[string]any{"token": stubToken, "expires_at": time.Now().Add(time.Hour)})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+stubToken {
+		writeJSON(http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+
+	switch {
+	case route == "GET /repos/acme/api/pulls/12":
+		writeJSON(http.StatusOK, map[string]any{"head": map[string]any{"sha": "headsha"}})
+	case route == "GET /repos/acme/api/commits/headsha/check-runs":
+		require.Equal(s.t, CheckRunName, r.URL.Query().Get("check_name"))
+		list := append([]map[string]any{}, s.foreignRuns...)
+		for i := len(s.checkRuns) - 1; i >= 0; i-- {
+			list = append(list, map[string]any{"id": 501 + i, "app": map[string]any{"id": 42}})
+		}
+		writeJSON(http.StatusOK, map[string]any{"total_count": len(list), "check_runs": list})
+	case route == "POST /repos/acme/api/check-runs":
+		body := decode()
+		s.checkRuns = append(s.checkRuns, body)
+		id := int64(500 + len(s.checkRuns))
+		s.annotations[id] = append(s.annotations[id], body["output"].(map[string]any)["annotations"].([]any)...)
+		writeJSON(http.StatusCreated, map[string]any{"id": id})
+	case strings.HasPrefix(route, "GET /repos/acme/api/check-runs/") && strings.HasSuffix(route, "/annotations"):
+		var id int64
+		_, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/repos/acme/api/check-runs/"), "%d", &id)
+		require.NoError(s.t, err)
+		list := []any{}
+		if r.URL.Query().Get("page") == "1" {
+			list = s.annotations[id]
+		}
+		writeJSON(http.StatusOK, list)
+	case strings.HasPrefix(route, "PATCH /repos/acme/api/check-runs/"):
+		var id int64
+		_, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/repos/acme/api/check-runs/"), "%d", &id)
+		require.NoError(s.t, err)
+		body := decode()
+		s.checkRunPatch = append(s.checkRunPatch, body)
+		s.annotations[id] = append(s.annotations[id], body["output"].(map[string]any)["annotations"].([]any)...)
+		writeJSON(http.StatusOK, map[string]any{})
+	case route == "GET /repos/acme/api/issues/12/comments":
+		var list []map[string]any
+		if r.URL.Query().Get("page") == "1" {
+			for id, body := range s.comments {
+				list = append(list, map[string]any{"id": id, "body": body})
+			}
+		}
+		writeJSON(http.StatusOK, list)
+	case route == "POST /repos/acme/api/issues/12/comments":
+		s.nextCommentID++
+		s.comments[s.nextCommentID] = decode()["body"].(string)
+		writeJSON(http.StatusCreated, map[string]any{"id": s.nextCommentID})
+	case strings.HasPrefix(route, "PATCH /repos/acme/api/issues/comments/"):
+		var id int64
+		_, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/repos/acme/api/issues/comments/"), "%d", &id)
+		require.NoError(s.t, err)
+		if _, ok := s.comments[id]; !ok {
+			writeJSON(http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		s.comments[id] = decode()["body"].(string)
+		writeJSON(http.StatusOK, map[string]any{"id": id})
+	default:
+		writeJSON(http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func TestClient_UpsertCheckRunCreatesOnPullRequestHead(t *testing.T) {
+	stub := newStubGitHub(t)
+	client := stub.client(t)
+	ctx := context.Background()
+
+	report := &Report{
+		Repo:          "acme/api",
+		PRNumber:      12,
+		DefaultBranch: "main",
+		WorkflowPath:  ".github/workflows/ci.yml",
+		DashboardURL:  "https://fg.example.com/orgs/acme/projects/api/flakes",
+	}
+	for i := 0; i < 60; i++ {
+		report.Tests = append(report.Tests, FlakedTest{TestIdentifier: fmt.Sprintf("pkg.Test%02d", i), JobName: "test", FlakeEvents: 1})
+	}
+
+	headSHA, err := client.PullRequestHeadSHA(ctx, "acme/api", 12)
+	require.NoError(t, err)
+	require.Equal(t, "headsha", headSHA)
+
+	require.NoError(t, client.UpsertCheckRun(ctx, "acme/api", report.CheckRun(headSHA)))
+
+	require.Len(t, stub.checkRuns, 1)
+	created := stub.checkRuns[0]
+	require.Equal(t, CheckRunName, created["name"])
+	require.Equal(t, "headsha", created["head_sha"])
+	require.Equal(t, "neutral", created["conclusion"])
+	require.Equal(t, report.DashboardURL, created["details_url"])
+	output := created["output"].(map[string]any)
+	require.Equal(t, "60 flaky tests", output["title"])
+	require.Len(t, output["annotations"], maxAnnotationsPerRequest)
+
+	// The rest of the annotations are appended to the created run
+	require.Len(t, stub.checkRunPatch, 1)
+	require.Len(t, stub.checkRunPatch[0]["output"].(map[string]any)["annotations"], 10)
+
+	// The installation token is cached across calls
+	require.Equal(t, 1, stub.tokensIssued)
+}
+
+func TestClient_UpsertCheckRunUpdatesExistingRun(t *testing.T) {
+	stub := newStubGitHub(t)
+	client := stub.client(t)
+	ctx := context.Background()
+
+	// A check run of the same name from another app is left alone
+	stub.foreignRuns = []map[string]any{{"id": 900, "app": map[string]any{"id": 1}}}
+
+	report := &Report{Repo: "acme/api", PRNumber: 12, DefaultBranch: "main", WorkflowPath: ".github/workflows/ci.yml", Tests: []FlakedTest{
+		{TestIdentifier: "pkg.TestA", JobName: "test", FlakeEvents: 1},
+	}}
+	require.NoError(t, client.UpsertCheckRun(ctx, "acme/api", report.CheckRun("headsha")))
+	require.Len(t, stub.checkRuns, 1)
+
+	report.Tests = append(report.Tests, FlakedTest{TestIdentifier: "pkg.TestB", JobName: "test", FlakeEvents: 2})
+	require.NoError(t, client.UpsertCheckRun(ctx, "acme/api", report.CheckRun("headsha")))
+
+	// Updated in place rather than created again
+	require.Len(t, stub.checkRuns, 1)
+	require.Len(t, stub.checkRunPatch, 1)
+	patch := stub.checkRunPatch[0]
+	require.NotContains(t, patch, "head_sha")
+	require.Equal(t, "2 flaky tests", patch["output"].(map[string]any)["title"])
+
+	// Only the new test's annotation is appended
+	require.Len(t, patch["output"].(map[string]any)["annotations"], 1)
+	require.Len(t, stub.annotations[501], 2)
+	require.Empty(t, stub.annotations[900])
+}
+
+func TestClient_UpsertCommentUpdatesExistingReport(t *testing.T) {
+	stub := newStubGitHub(t)
+	client := stub.client(t)
+	ctx := context.Background()
+
+	stub.comments[1] = "LGTM"
+
+	report := &Report{Repo: "acme/api", PRNumber: 12, DefaultBranch: "main", Tests: []FlakedTest{
+		{TestIdentifier: "pkg.TestA", JobName: "test", FlakeEvents: 1},
+	}}
+	require.NoError(t, client.UpsertComment(ctx, "acme/api", 12, CommentMarker, report.CommentBody()))
+	require.Len(t, stub.comments, 2)
+
+	report.Tests = append(report.Tests, FlakedTest{TestIdentifier: "pkg.TestB", JobName: "test", FlakeEvents: 2})
+	require.NoError(t, client.UpsertComment(ctx, "acme/api", 12, CommentMarker, report.CommentBody()))
+
+	// Updated in place rather than commented again
+	require.Len(t, stub.comments, 2)
+	require.Equal(t, "LGTM", stub.comments[1])
+	body := stub.comments[stub.nextCommentID]
+	require.Contains(t, body, CommentMarker)
+	require.Contains(t, body, "pkg.TestB")
+}
+
+func TestClient_ReportsAPIErrors(t *testing.T) {
+	stub := newStubGitHub(t)
+	client := stub.client(t)
+
+	_, err := client.PullRequestHeadSHA(context.Background(), "acme/api", 99)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, "Not Found", apiErr.Message)
+}
+
+func TestNewClient_RejectsInvalidKey(t *testing.T) {
+	_, err := NewClient(1, "not a key", "https://api.github.com")
+	require.Error(t, err)
+}
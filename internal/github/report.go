@@ -0,0 +1,193 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommentMarker identifies the FlakeGuard report comment of a pull request
+const CommentMarker = "<!-- flakeguard:pr-report -->"
+
+// maxReportedTests bounds the tests listed in a comment or check run summary;
+// GitHub rejects bodies over 65536 characters
+const maxReportedTests = 100
+
+// Report lists the tests that flaked on the runs of one pull request
+type Report struct {
+	Repo          string
+	PRNumber      int64
+	DefaultBranch string
+	WorkflowPath  string // workflow file that annotations are placed on, may be empty
+	DashboardURL  string
+	Tests         []FlakedTest
+}
+
+// FlakedTest is a test that flaked at least once on the pull request
+type FlakedTest struct {
+	TestIdentifier string
+	JobName        string
+	JobVariant     string
+	FlakeEvents    int    // on runs of this pull request
+	FailureMessage string // most recent failure, may be empty
+	Quarantined    bool
+	DashboardURL   string
+
+	// KnownOnDefault is set when the test has also flaked on the default
+	// branch, i.e. the flake predates this pull request
+	KnownOnDefault     bool
+	DefaultBranchScore float64
+}
+
+func (t *FlakedTest) job() string {
+	if t.JobVariant == "" {
+		return t.JobName
+	}
+	return t.JobName + " (" + t.JobVariant + ")"
+}
+
+func (t *FlakedTest) knownLabel(defaultBranch string) string {
+	if t.KnownOnDefault {
+		return fmt.Sprintf("Yes, %.0f%% flake score on %s", t.DefaultBranchScore*100, defaultBranch)
+	}
+	return "No, new in this PR"
+}
+
+// newTests counts the flaked tests not known flaky on the default branch
+func (r *Report) newTests() int {
+	n := 0
+	for i := range r.Tests {
+		if !r.Tests[i].KnownOnDefault {
+			n++
+		}
+	}
+	return n
+}
+
+// Title is a one-line summary of the report
+func (r *Report) Title() string {
+	switch len(r.Tests) {
+	case 0:
+		return "No flaky tests"
+	case 1:
+		return "1 flaky test"
+	}
+	return fmt.Sprintf("%d flaky tests", len(r.Tests))
+}
+
+// Markdown renders the report as a table of flaked tests
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	if len(r.Tests) == 0 {
+		b.WriteString("No tests flaked on the CI runs of this pull request.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s flaked on the CI runs of this pull request", pluralTests(len(r.Tests)))
+	if n := r.newTests(); n > 0 {
+		fmt.Fprintf(&b, "; %d not known to be flaky on `%s`", n, r.DefaultBranch)
+	}
+	b.WriteString(".\n\n")
+
+	b.WriteString("| Test | Job | Flakes in PR | Known flaky on default branch |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for i, t := range r.Tests {
+		if i == maxReportedTests {
+			fmt.Fprintf(&b, "\n...and %d more.\n", len(r.Tests)-maxReportedTests)
+			break
+		}
+		name := "`" + escapeCode(t.TestIdentifier) + "`"
+		if t.DashboardURL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, t.DashboardURL)
+		}
+		if t.Quarantined {
+			name += " (quarantined)"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", name, escapeCell(t.job()), t.FlakeEvents, t.knownLabel(r.DefaultBranch))
+	}
+
+	if r.DashboardURL != "" {
+		fmt.Fprintf(&b, "\n[View all flaky tests in FlakeGuard](%s)\n", r.DashboardURL)
+	}
+	return b.String()
+}
+
+// CommentBody renders the pull request comment, including the marker used to
+// find it again
+func (r *Report) CommentBody() string {
+	return CommentMarker + "\n### FlakeGuard: " + r.Title() + "\n\n" + r.Markdown()
+}
+
+// CheckRun renders the report as a check run on headSHA. Flakes do not fail
+// the check; annotations are placed on the workflow file when it is known.
+func (r *Report) CheckRun(headSHA string) CheckRun {
+	run := CheckRun{
+		HeadSHA:    headSHA,
+		Conclusion: "success",
+		DetailsURL: r.DashboardURL,
+		Title:      r.Title(),
+		Summary:    r.Markdown(),
+	}
+	if len(r.Tests) > 0 {
+		run.Conclusion = "neutral"
+	}
+	if r.WorkflowPath == "" {
+		return run
+	}
+
+	for i := range r.Tests {
+		t := &r.Tests[i]
+		level := "warning"
+		message := fmt.Sprintf("Flaked %d time(s) in job %s on this pull request. Not known to be flaky on %s.", t.FlakeEvents, t.job(), r.DefaultBranch)
+		if t.KnownOnDefault {
+			level = "notice"
+			message = fmt.Sprintf("Flaked %d time(s) in job %s on this pull request. Already flaky on %s (%.0f%% flake score).", t.FlakeEvents, t.job(), r.DefaultBranch, t.DefaultBranchScore*100)
+		}
+		if t.FailureMessage != "" {
+			message += "\n\nLast failure: " + truncate(t.FailureMessage, 500)
+		}
+		run.Annotations = append(run.Annotations, Annotation{
+			Path:            r.WorkflowPath,
+			StartLine:       1,
+			EndLine:         1,
+			AnnotationLevel: level,
+			Title:           "Flaky test: " + truncate(t.TestIdentifier, 200),
+			Message:         message,
+		})
+	}
+	return run
+}
+
+// WorkflowPath returns the repository path of a workflow_ref when it names a
+// GitHub Actions workflow file, or "" otherwise
+func WorkflowPath(workflowRef string) string {
+	path, _, _ := strings.Cut(workflowRef, "@")
+	if idx := strings.Index(path, ".github/workflows/"); idx >= 0 {
+		return path[idx:]
+	}
+	return ""
+}
+
+func pluralTests(n int) string {
+	if n == 1 {
+		return "1 test"
+	}
+	return fmt.Sprintf("%d tests", n)
+}
+
+func escapeCode(s string) string {
+	return strings.ReplaceAll(escapeCell(s), "`", "'")
+}
+
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func truncate(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + "..."
+}
@@ -0,0 +1,62 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_MarkdownMarksKnownFlakes(t *testing.T) {
+	report := &Report{
+		DefaultBranch: "main",
+		DashboardURL:  "https://fg.example.com/orgs/acme/projects/api/flakes",
+		Tests: []FlakedTest{
+			{TestIdentifier: "pkg.TestNew", JobName: "test", JobVariant: "go-1.22", FlakeEvents: 2, DashboardURL: "https://fg.example.com/t/1"},
+			{TestIdentifier: "pkg.TestOld|pipe", JobName: "test", FlakeEvents: 1, KnownOnDefault: true, DefaultBranchScore: 0.125, Quarantined: true},
+		},
+	}
+
+	md := report.Markdown()
+	require.Contains(t, md, "2 tests flaked on the CI runs of this pull request; 1 not known to be flaky on `main`.")
+	require.Contains(t, md, "| [`pkg.TestNew`](https://fg.example.com/t/1) | test (go-1.22) | 2 | No, new in this PR |")
+	require.Contains(t, md, "| `pkg.TestOld\\|pipe` (quarantined) | test | 1 | Yes, 12% flake score on main |")
+	require.Contains(t, md, "[View all flaky tests in FlakeGuard]("+report.DashboardURL+")")
+
+	body := report.CommentBody()
+	require.True(t, strings.HasPrefix(body, CommentMarker+"\n### FlakeGuard: 2 flaky tests"))
+}
+
+func TestReport_CheckRun(t *testing.T) {
+	report := &Report{DefaultBranch: "main", WorkflowPath: ".github/workflows/ci.yml"}
+
+	run := report.CheckRun("abc")
+	require.Equal(t, "success", run.Conclusion)
+	require.Equal(t, "No flaky tests", run.Title)
+	require.Empty(t, run.Annotations)
+
+	report.Tests = []FlakedTest{
+		{TestIdentifier: "pkg.TestNew", JobName: "test", FlakeEvents: 1, FailureMessage: "timeout"},
+		{TestIdentifier: "pkg.TestOld", JobName: "test", FlakeEvents: 1, KnownOnDefault: true, DefaultBranchScore: 0.5},
+	}
+	run = report.CheckRun("abc")
+	require.Equal(t, "neutral", run.Conclusion)
+	require.Len(t, run.Annotations, 2)
+	require.Equal(t, "warning", run.Annotations[0].AnnotationLevel)
+	require.Equal(t, ".github/workflows/ci.yml", run.Annotations[0].Path)
+	require.Contains(t, run.Annotations[0].Message, "Not known to be flaky on main")
+	require.Contains(t, run.Annotations[0].Message, "Last failure: timeout")
+	require.Equal(t, "notice", run.Annotations[1].AnnotationLevel)
+	require.Contains(t, run.Annotations[1].Message, "Already flaky on main (50% flake score)")
+
+	// Without a workflow file the report is summary-only
+	report.WorkflowPath = ""
+	require.Empty(t, report.CheckRun("abc").Annotations)
+}
+
+func TestWorkflowPath(t *testing.T) {
+	require.Equal(t, ".github/workflows/ci.yml", WorkflowPath(".github/workflows/ci.yml"))
+	require.Equal(t, ".github/workflows/ci.yml", WorkflowPath("acme/api/.github/workflows/ci.yml@refs/heads/main"))
+	require.Equal(t, "", WorkflowPath("CI"))
+	require.Equal(t, "", WorkflowPath(".gitlab-ci.yml"))
+}
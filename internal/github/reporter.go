@@ -0,0 +1,217 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// Reporter reports the flakes of pull request runs back to GitHub according
+// to each project's report mode
+type Reporter struct {
+	pool    *pgxpool.Pool
+	client  *Client
+	baseURL string
+}
+
+// NewReporter creates a reporter using the GitHub App of cfg. Returns nil when
+// no App is configured.
+func NewReporter(pool *pgxpool.Pool, cfg *config.Config) *Reporter {
+	if !cfg.GitHubAppEnabled() {
+		return nil
+	}
+	client, err := NewClient(cfg.GitHubAppID, cfg.GitHubAppPrivateKey, cfg.GitHubAPIURL)
+	if err != nil {
+		// Load validates the key, so this only happens with a hand-built config
+		log.Error().Err(err).Msg("GitHub App misconfigured, pull request reports disabled")
+		return nil
+	}
+	return &Reporter{pool: pool, client: client, baseURL: cfg.BaseURL}
+}
+
+// NewReporterWithClient creates a reporter using an existing client
+func NewReporterWithClient(pool *pgxpool.Pool, client *Client, baseURL string) *Reporter {
+	return &Reporter{pool: pool, client: client, baseURL: baseURL}
+}
+
+// prRun is the pull request a CI run belongs to
+type prRun struct {
+	Repo        string
+	PRNumber    int64
+	WorkflowRef string
+}
+
+// ReportRun publishes the flake report of the pull request a CI run belongs
+// to. Runs that are not GitHub pull_request runs, and projects with reporting
+// off, are skipped. Returns whether a report was published.
+func (r *Reporter) ReportRun(ctx context.Context, projectID, ciRunID uuid.UUID) (bool, error) {
+	project, err := projects.NewService(r.pool).GetByID(ctx, projectID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load project: %w", err)
+	}
+	if project.GitHubReportMode == projects.GitHubReportOff {
+		return false, nil
+	}
+
+	run, err := r.getPRRun(ctx, projectID, ciRunID)
+	if err != nil {
+		return false, err
+	}
+	if run == nil {
+		return false, nil
+	}
+
+	org, err := orgs.NewService(r.pool).GetByID(ctx, project.OrgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load org: %w", err)
+	}
+
+	report := &Report{
+		Repo:          run.Repo,
+		PRNumber:      run.PRNumber,
+		DefaultBranch: project.DefaultBranch,
+		WorkflowPath:  WorkflowPath(run.WorkflowRef),
+		DashboardURL:  fmt.Sprintf("%s/orgs/%s/projects/%s/flakes", r.dashboardBase(), org.Slug, project.Slug),
+	}
+	report.Tests, err = r.getFlakedTests(ctx, projectID, run, report.DashboardURL)
+	if err != nil {
+		return false, err
+	}
+
+	switch project.GitHubReportMode {
+	case projects.GitHubReportCheckRun:
+		headSHA, err := r.client.PullRequestHeadSHA(ctx, run.Repo, run.PRNumber)
+		if err != nil {
+			return false, err
+		}
+		if err := r.client.UpsertCheckRun(ctx, run.Repo, report.CheckRun(headSHA)); err != nil {
+			return false, err
+		}
+	case projects.GitHubReportComment:
+		// Clean pull requests get no comment
+		if len(report.Tests) == 0 {
+			return false, nil
+		}
+		if err := r.client.UpsertComment(ctx, run.Repo, run.PRNumber, CommentMarker, report.CommentBody()); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("unknown GitHub report mode %q", project.GitHubReportMode)
+	}
+
+	log.Info().
+		Str("project_id", projectID.String()).
+		Str("repo_full_name", run.Repo).
+		Int64("pr_number", run.PRNumber).
+		Str("mode", project.GitHubReportMode).
+		Int("flaky_tests", len(report.Tests)).
+		Msg("GitHub pull request report published")
+	return true, nil
+}
+
+// getPRRun returns the pull request of a GitHub pull_request run, or nil for
+// any other run
+func (r *Reporter) getPRRun(ctx context.Context, projectID, ciRunID uuid.UUID) (*prRun, error) {
+	query := `
+		SELECT repo_full_name, pr_number, workflow_ref
+		FROM ci_runs
+		WHERE id = $1
+		  AND project_id = $2
+		  AND provider = 'github'
+		  AND event = 'pull_request'
+		  AND pr_number IS NOT NULL
+	`
+
+	var run prRun
+	err := r.pool.QueryRow(ctx, query, ciRunID, projectID).Scan(&run.Repo, &run.PRNumber, &run.WorkflowRef)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CI run: %w", err)
+	}
+	return &run, nil
+}
+
+// getFlakedTests lists the tests with flake events on any run of the pull
+// request, known-flaky tests last, linking each to its page under dashboardURL
+func (r *Reporter) getFlakedTests(ctx context.Context, projectID uuid.UUID, run *prRun, dashboardURL string) ([]FlakedTest, error) {
+	query := `
+		SELECT
+			tc.id,
+			tc.test_identifier,
+			tc.job_name,
+			tc.job_variant,
+			COUNT(DISTINCT fe.id),
+			COALESCE(fbs.mixed_outcome_runs, 0) > 0,
+			COALESCE(fbs.flake_score, 0),
+			COALESCE(fs.last_failure_message, '')
+		FROM flake_events fe
+		JOIN ci_runs cr ON cr.id = fe.ci_run_id
+		JOIN test_cases tc ON tc.id = fe.test_case_id
+		LEFT JOIN flake_stats fs ON fs.test_case_id = tc.id
+		LEFT JOIN flake_branch_stats fbs ON fbs.test_case_id = tc.id AND fbs.branch_class = 'default'
+		WHERE cr.project_id = $1
+		  AND cr.provider = 'github'
+		  AND cr.repo_full_name = $2
+		  AND cr.pr_number = $3
+		GROUP BY tc.id, fbs.mixed_outcome_runs, fbs.flake_score, fs.last_failure_message
+		ORDER BY COALESCE(fbs.mixed_outcome_runs, 0) > 0, tc.test_identifier, tc.job_name, tc.job_variant
+	`
+
+	rows, err := r.pool.Query(ctx, query, projectID, run.Repo, run.PRNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flaked tests: %w", err)
+	}
+	defer rows.Close()
+
+	var tests []FlakedTest
+	for rows.Next() {
+		var t FlakedTest
+		var testCaseID uuid.UUID
+		if err := rows.Scan(
+			&testCaseID,
+			&t.TestIdentifier,
+			&t.JobName,
+			&t.JobVariant,
+			&t.FlakeEvents,
+			&t.KnownOnDefault,
+			&t.DefaultBranchScore,
+			&t.FailureMessage,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan flaked test: %w", err)
+		}
+		t.DashboardURL = dashboardURL + "/" + testCaseID.String()
+		tests = append(tests, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flaked tests: %w", err)
+	}
+
+	rules, err := quarantine.NewService(r.pool).ActiveRuleSet(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quarantine rules: %w", err)
+	}
+	for i := range tests {
+		tests[i].Quarantined = rules.Match(tests[i].TestIdentifier) != nil
+	}
+	return tests, nil
+}
+
+func (r *Reporter) dashboardBase() string {
+	base := strings.TrimRight(r.baseURL, "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return base
+}
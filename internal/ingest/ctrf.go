@@ -0,0 +1,89 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ctrfReport is a Common Test Report Format (CTRF) JSON document
+type ctrfReport struct {
+	Results *struct {
+		Tool struct {
+			Name string `json:"name"`
+		} `json:"tool"`
+		Tests []ctrfTest `json:"tests"`
+	} `json:"results"`
+}
+
+type ctrfTest struct {
+	Name     string          `json:"name"`
+	Status   string          `json:"status"`
+	Duration float64         `json:"duration"`
+	Message  string          `json:"message"`
+	Trace    string          `json:"trace"`
+	Suite    json.RawMessage `json:"suite"`
+	FilePath string          `json:"filePath"`
+}
+
+// ParseCTRF parses a CTRF JSON report. The classname is the test's suite
+// (joined with " > " when given as a list), falling back to its file path.
+// Durations are in milliseconds.
+func ParseCTRF(r io.Reader) ([]TestResult, error) {
+	var report ctrfReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to parse CTRF JSON: %w", err)
+	}
+	if report.Results == nil {
+		return nil, fmt.Errorf("failed to parse CTRF JSON: missing results")
+	}
+
+	results := make([]TestResult, 0, len(report.Results.Tests))
+	for _, test := range report.Results.Tests {
+		classname := ctrfSuite(test.Suite)
+		if classname == "" {
+			classname = test.FilePath
+		}
+
+		result := TestResult{
+			Classname:      classname,
+			Name:           test.Name,
+			TestIdentifier: deriveTestIdentifier(classname, test.Name),
+			DurationMS:     int(test.Duration),
+			FilePath:       strings.TrimSpace(test.FilePath),
+		}
+
+		switch test.Status {
+		case "passed":
+			result.Status = "passed"
+		case "failed":
+			result.Status = "failed"
+			result.FailureMessage = truncateString(strings.TrimSpace(test.Message), 1024)
+			result.FailureOutput = truncateString(strings.TrimSpace(test.Trace), 8192)
+		default:
+			// skipped, pending, other
+			result.Status = "skipped"
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ctrfSuite decodes a suite given either as a string or a list of names
+func ctrfSuite(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []string
+	if err := json.Unmarshal(raw, &parts); err == nil {
+		return strings.Join(parts, " > ")
+	}
+	return ""
+}
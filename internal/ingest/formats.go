@@ -0,0 +1,249 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportFormat identifies a test report format accepted by ingestion
+type ReportFormat string
+
+const (
+	FormatJUnit  ReportFormat = "junit"
+	FormatGoTest ReportFormat = "gotest"
+	FormatTRX    ReportFormat = "trx"
+	FormatXUnit  ReportFormat = "xunit"
+	FormatNUnit  ReportFormat = "nunit"
+	FormatTAP    ReportFormat = "tap"
+	FormatCTRF   ReportFormat = "ctrf"
+)
+
+// ReportParser normalizes one report format into test results
+type ReportParser struct {
+	Format      ReportFormat
+	Description string
+	// ErrorCode is reported as the ingestion's error_code when a file fails
+	// to parse
+	ErrorCode string
+	// XMLRoots lists the root element names that identify the format (XML formats only)
+	XMLRoots []string
+	// Sniff reports whether non-XML content looks like this format (optional)
+	Sniff func(content []byte) bool
+	Parse func(r io.Reader) ([]TestResult, error)
+	// Stream emits results while reading (optional; formats without it are
+	// parsed whole by StreamReport)
+	Stream func(r io.Reader, emit func(TestResult) error) error
+}
+
+// sniffBytes is how much of a report StreamReport reads ahead to detect its format
+const sniffBytes = 64 * 1024
+
+var parsers []ReportParser
+
+// RegisterParser adds a report parser to the registry. Parsers are sniffed in
+// registration order.
+func RegisterParser(p ReportParser) {
+	parsers = append(parsers, p)
+}
+
+func init() {
+	RegisterParser(ReportParser{
+		Format:      FormatJUnit,
+		Description: "JUnit XML",
+		ErrorCode:   "invalid_junit_xml",
+		XMLRoots:    []string{"testsuites", "testsuite"},
+		Parse:       ParseAndExtract,
+		Stream:      StreamJUnitXML,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatGoTest,
+		Description: "go test -json output",
+		ErrorCode:   "invalid_gotest_json",
+		Sniff:       sniffGoTestJSON,
+		Parse:       ParseGoTestJSON,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatTRX,
+		Description: "TRX",
+		ErrorCode:   "invalid_trx",
+		XMLRoots:    []string{"TestRun"},
+		Parse:       ParseTRX,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatXUnit,
+		Description: "xUnit v2 XML",
+		ErrorCode:   "invalid_xunit",
+		XMLRoots:    []string{"assemblies", "assembly"},
+		Parse:       ParseXUnit,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatNUnit,
+		Description: "NUnit XML",
+		ErrorCode:   "invalid_nunit",
+		XMLRoots:    []string{"test-run", "test-results"},
+		Parse:       ParseNUnit,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatTAP,
+		Description: "TAP",
+		ErrorCode:   "invalid_tap",
+		Sniff:       sniffTAP,
+		Parse:       ParseTAP,
+	})
+	RegisterParser(ReportParser{
+		Format:      FormatCTRF,
+		Description: "CTRF JSON",
+		ErrorCode:   "invalid_ctrf",
+		Sniff:       sniffCTRF,
+		Parse:       ParseCTRF,
+	})
+}
+
+// LookupParser returns the parser registered for a format
+func LookupParser(format ReportFormat) (ReportParser, bool) {
+	for _, p := range parsers {
+		if p.Format == format {
+			return p, true
+		}
+	}
+	return ReportParser{}, false
+}
+
+// SupportedFormats returns the registered format names
+func SupportedFormats() []string {
+	formats := make([]string, len(parsers))
+	for i, p := range parsers {
+		formats[i] = string(p.Format)
+	}
+	return formats
+}
+
+// DetectFormat identifies the report format of content: XML reports by their
+// root element, other formats by sniffing. Unrecognized content is treated as
+// JUnit XML, the historical default.
+func DetectFormat(content []byte) ReportFormat {
+	if root := xmlRootElement(content); root != "" {
+		for _, p := range parsers {
+			for _, name := range p.XMLRoots {
+				if name == root {
+					return p.Format
+				}
+			}
+		}
+		return FormatJUnit
+	}
+
+	for _, p := range parsers {
+		if p.Sniff != nil && p.Sniff(content) {
+			return p.Format
+		}
+	}
+	return FormatJUnit
+}
+
+// ParseReport parses content with the parser for format, detecting the format
+// when it is empty. Returns the parser used so callers can report errors.
+func ParseReport(format ReportFormat, content []byte) ([]TestResult, ReportParser, error) {
+	if format == "" {
+		format = DetectFormat(content)
+	}
+	p, ok := LookupParser(format)
+	if !ok {
+		return nil, ReportParser{}, fmt.Errorf("unsupported report format: %s", format)
+	}
+	results, err := p.Parse(bytes.NewReader(content))
+	return results, p, err
+}
+
+// StreamReport parses r with the parser for format and calls emit for each
+// result, detecting the format from the first sniffBytes when it is empty.
+// Formats with a Stream function never hold the whole report in memory.
+func StreamReport(format ReportFormat, r io.Reader, emit func(TestResult) error) (ReportParser, error) {
+	br := bufio.NewReaderSize(r, sniffBytes)
+	if format == "" {
+		// A short read just means the whole report fits in the sniff window
+		head, _ := br.Peek(sniffBytes)
+		format = DetectFormat(head)
+	}
+	p, ok := LookupParser(format)
+	if !ok {
+		return ReportParser{}, fmt.Errorf("unsupported report format: %s", format)
+	}
+
+	if p.Stream != nil {
+		return p, p.Stream(br, emit)
+	}
+
+	results, err := p.Parse(br)
+	if err != nil {
+		return p, err
+	}
+	for _, result := range results {
+		if err := emit(result); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// xmlRootElement returns the local name of the first element, or "" if the
+// content does not start like an XML document.
+func xmlRootElement(content []byte) string {
+	trimmed := bytes.TrimLeft(content, "\xef\xbb\xbf \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '<' {
+		return ""
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// firstLine returns the first non-empty line of content
+func firstLine(content []byte) []byte {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			return line
+		}
+	}
+	return nil
+}
+
+func sniffGoTestJSON(content []byte) bool {
+	line := firstLine(content)
+	if len(line) == 0 || line[0] != '{' {
+		return false
+	}
+	var ev map[string]json.RawMessage
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return false
+	}
+	_, ok := ev["Action"]
+	return ok
+}
+
+func sniffCTRF(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && trimmed[0] == '{' && bytes.Contains(trimmed, []byte(`"results"`))
+}
+
+func sniffTAP(content []byte) bool {
+	line := string(firstLine(content))
+	return strings.HasPrefix(line, "TAP version") ||
+		tapPlanRe.MatchString(line) ||
+		tapTestLineRe.MatchString(line)
+}
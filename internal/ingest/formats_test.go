@@ -0,0 +1,216 @@
+package ingest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readReportFixture(t *testing.T, dir, name string) []byte {
+	t.Helper()
+
+	_, currentFile, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(currentFile), "..", "..", "testdata", dir, name))
+	require.NoError(t, err)
+	return b
+}
+
+func resultsByID(results []TestResult) map[string]TestResult {
+	byID := make(map[string]TestResult, len(results))
+	for _, r := range results {
+		byID[r.TestIdentifier] = r
+	}
+	return byID
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		dir, name string
+		want      ReportFormat
+	}{
+		{"junit", "mixed.xml", FormatJUnit},
+		{"gotest", "mixed.jsonl", FormatGoTest},
+		{"trx", "mixed.trx", FormatTRX},
+		{"xunit", "mixed.xml", FormatXUnit},
+		{"nunit", "nunit3.xml", FormatNUnit},
+		{"nunit", "nunit2.xml", FormatNUnit},
+		{"tap", "mixed.tap", FormatTAP},
+		{"ctrf", "mixed.json", FormatCTRF},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, DetectFormat(readReportFixture(t, tc.dir, tc.name)), "%s/%s", tc.dir, tc.name)
+	}
+
+	require.Equal(t, FormatJUnit, DetectFormat([]byte("not a report")))
+}
+
+func TestParsers_FormatSpecificErrorCodes(t *testing.T) {
+	want := map[ReportFormat]string{
+		FormatJUnit:  "invalid_junit_xml",
+		FormatGoTest: "invalid_gotest_json",
+		FormatTRX:    "invalid_trx",
+		FormatXUnit:  "invalid_xunit",
+		FormatNUnit:  "invalid_nunit",
+		FormatTAP:    "invalid_tap",
+		FormatCTRF:   "invalid_ctrf",
+	}
+
+	require.Len(t, parsers, len(want))
+	for format, code := range want {
+		p, ok := LookupParser(format)
+		require.True(t, ok, format)
+		require.Equal(t, code, p.ErrorCode, format)
+	}
+}
+
+func TestParseReport_UnsupportedFormat(t *testing.T) {
+	_, _, err := ParseReport("bogus", []byte("<testsuites/>"))
+	require.Error(t, err)
+}
+
+func TestStreamReport_DetectsFormat(t *testing.T) {
+	for _, tc := range []struct{ dir, name string }{{"junit", "mixed.xml"}, {"tap", "mixed.tap"}} {
+		content := readReportFixture(t, tc.dir, tc.name)
+
+		want, _, err := ParseReport("", content)
+		require.NoError(t, err)
+
+		var got []TestResult
+		_, err = StreamReport("", bytes.NewReader(content), func(r TestResult) error {
+			got = append(got, r)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, want, got, "%s/%s", tc.dir, tc.name)
+	}
+
+	_, err := StreamReport("bogus", strings.NewReader("<testsuites/>"), func(TestResult) error { return nil })
+	require.Error(t, err)
+}
+
+func TestParseTRX(t *testing.T) {
+	results, err := ParseTRX(bytes.NewReader(readReportFixture(t, "trx", "mixed.trx")))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	byID := resultsByID(results)
+
+	totals := byID["Acme.Billing.Tests.InvoiceTests#Totals"]
+	require.Equal(t, "passed", totals.Status)
+	require.Equal(t, 125, totals.DurationMS)
+
+	refund := byID["Acme.Billing.Tests.InvoiceTests#Refund"]
+	require.Equal(t, "failed", refund.Status)
+	require.Equal(t, 1500, refund.DurationMS)
+	require.Equal(t, "Assert.Equal() Failure: Expected 10, Actual 0", refund.FailureMessage)
+	require.Contains(t, refund.FailureOutput, "InvoiceTests.cs:line 42")
+
+	require.Equal(t, "passed", byID["Acme.Billing.Tests.MathTests#Add(a: 1, b: 2)"].Status)
+	require.Equal(t, "skipped", byID["Acme.Billing.Tests.InvoiceTests#Export"].Status)
+}
+
+func TestParseXUnit(t *testing.T) {
+	results, err := ParseXUnit(bytes.NewReader(readReportFixture(t, "xunit", "mixed.xml")))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	byID := resultsByID(results)
+
+	require.Equal(t, "passed", byID["Acme.Orders.Tests.CartTests#AddsItem"].Status)
+	require.Equal(t, "passed", byID["Acme.Orders.Tests.CartTests#Total(items: 3)"].Status)
+	require.Equal(t, "skipped", byID["Acme.Orders.Tests.CartTests#Discounts"].Status)
+
+	checkout := byID["Acme.Orders.Tests.CartTests#Checkout"]
+	require.Equal(t, "failed", checkout.Status)
+	require.Equal(t, 480, checkout.DurationMS)
+	require.Equal(t, "System.TimeoutException : payment gateway did not respond", checkout.FailureMessage)
+	require.Contains(t, checkout.FailureOutput, "CartTests.cs:line 31")
+}
+
+func TestParseNUnit_V3(t *testing.T) {
+	results, err := ParseNUnit(bytes.NewReader(readReportFixture(t, "nunit", "nunit3.xml")))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	byID := resultsByID(results)
+
+	require.Equal(t, "passed", byID["Acme.Search.Tests.IndexTests#Builds"].Status)
+	require.Equal(t, "passed", byID[`Acme.Search.Tests.IndexTests#Query("a.b")`].Status)
+	require.Equal(t, "skipped", byID["Acme.Search.Tests.IndexTests#Shards"].Status)
+
+	reindex := byID["Acme.Search.Tests.IndexTests#Reindex"]
+	require.Equal(t, "error", reindex.Status)
+	require.Equal(t, 750, reindex.DurationMS)
+	require.Equal(t, "System.IO.IOException : index locked", reindex.FailureMessage)
+}
+
+func TestParseNUnit_V2(t *testing.T) {
+	results, err := ParseNUnit(bytes.NewReader(readReportFixture(t, "nunit", "nunit2.xml")))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	byID := resultsByID(results)
+
+	require.Equal(t, "passed", byID["Acme.Legacy.Tests.ParserTests#ParsesHeader"].Status)
+	require.Equal(t, "skipped", byID["Acme.Legacy.Tests.ParserTests#ParsesFooter"].Status)
+
+	body := byID["Acme.Legacy.Tests.ParserTests#ParsesBody"]
+	require.Equal(t, "failed", body.Status)
+	require.Equal(t, 250, body.DurationMS)
+	require.Equal(t, "Expected: 3 But was: 2", body.FailureMessage)
+}
+
+func TestParseTAP(t *testing.T) {
+	results, err := ParseTAP(bytes.NewReader(readReportFixture(t, "tap", "mixed.tap")))
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	byID := resultsByID(results)
+
+	require.Equal(t, "passed", byID["cart#adds items"].Status)
+	require.Equal(t, "skipped", byID["checkout#charges card"].Status)
+	require.Equal(t, "skipped", byID["checkout#sends receipt"].Status)
+	require.Equal(t, "passed", byID["checkout#clears cart"].Status)
+
+	total := byID["cart#computes total"]
+	require.Equal(t, "failed", total.Status)
+	require.Equal(t, "should be equal", total.FailureMessage)
+	require.Equal(t, 12, total.DurationMS)
+	require.Contains(t, total.FailureOutput, "expected: 30")
+}
+
+func TestParseTAP_NotTAP(t *testing.T) {
+	_, err := ParseTAP(strings.NewReader("hello\nworld\n"))
+	require.Error(t, err)
+}
+
+func TestParseCTRF(t *testing.T) {
+	results, err := ParseCTRF(bytes.NewReader(readReportFixture(t, "ctrf", "mixed.json")))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	byID := resultsByID(results)
+
+	require.Equal(t, "passed", byID["Header#renders header"].Status)
+	require.Equal(t, "passed", byID["src/Menu.test.tsx#closes menu"].Status)
+	require.Equal(t, "skipped", byID["Menu#animates"].Status)
+
+	open := byID["Menu > interactions#opens menu"]
+	require.Equal(t, "failed", open.Status)
+	require.Equal(t, 230, open.DurationMS)
+	require.Equal(t, "Timed out waiting for element", open.FailureMessage)
+	require.Contains(t, open.FailureOutput, "Menu.test.tsx:12:5")
+}
+
+func TestParseCTRF_MissingResults(t *testing.T) {
+	_, err := ParseCTRF(strings.NewReader(`{"foo": 1}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// GoTestEvent is a single event from `go test -json` (cmd/test2json)
+type GoTestEvent struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// goTestState accumulates events for a single test
+type goTestState struct {
+	pkg     string
+	name    string
+	action  string
+	elapsed float64
+	output  strings.Builder
+}
+
+// ParseGoTestJSON parses a `go test -json` stream into test results.
+// Tests are identified as package#TestName; subtests keep their full
+// slash-separated name (package#TestFoo/case_1). Lines that are not JSON
+// (e.g. build errors captured with 2>&1) are ignored. Tests that started
+// but never reported a result (e.g. the binary panicked or timed out) are
+// recorded as failed if their package failed.
+func ParseGoTestJSON(r io.Reader) ([]TestResult, error) {
+	reader := bufio.NewReader(r)
+
+	tests := make(map[string]*goTestState)
+	var order []string
+	failedPackages := make(map[string]bool)
+	events := 0
+
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return nil, fmt.Errorf("failed to read go test output: %w", readErr)
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] == '{' {
+			var ev GoTestEvent
+			if err := json.Unmarshal(line, &ev); err != nil {
+				return nil, fmt.Errorf("invalid go test -json event on line %d: %w", lineNum, err)
+			}
+			events++
+
+			if ev.Test == "" {
+				if ev.Action == "fail" {
+					failedPackages[ev.Package] = true
+				}
+			} else {
+				key := ev.Package + "#" + ev.Test
+				state, ok := tests[key]
+				if !ok {
+					state = &goTestState{pkg: ev.Package, name: ev.Test}
+					tests[key] = state
+					order = append(order, key)
+				}
+
+				switch ev.Action {
+				case "output":
+					state.output.WriteString(ev.Output)
+				case "pass", "fail", "skip":
+					state.action = ev.Action
+					state.elapsed = ev.Elapsed
+				}
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+	}
+
+	if events == 0 {
+		return nil, errors.New("no go test -json events found")
+	}
+
+	results := make([]TestResult, 0, len(order))
+	for _, key := range order {
+		state := tests[key]
+
+		action := state.action
+		if action == "" {
+			if !failedPackages[state.pkg] {
+				// Still running (truncated stream) or only benchmark output; nothing to record
+				continue
+			}
+			action = "fail"
+		}
+
+		result := TestResult{
+			Classname:      state.pkg,
+			Name:           state.name,
+			TestIdentifier: deriveTestIdentifier(state.pkg, state.name),
+			DurationMS:     int(state.elapsed * 1000), // Convert seconds to milliseconds
+		}
+
+		switch action {
+		case "pass":
+			result.Status = "passed"
+		case "skip":
+			result.Status = "skipped"
+		case "fail":
+			output := state.output.String()
+			result.Status = "failed"
+			result.FailureMessage = truncateString(goTestFailureMessage(output, state.action == ""), 1024)
+			result.FailureOutput = truncateString(output, 8192)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// goTestFailureMessage picks the first line of test output that is not a
+// framework marker (=== RUN, --- FAIL, ...) as the failure message.
+func goTestFailureMessage(output string, incomplete bool) string {
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		return trimmed
+	}
+	if incomplete {
+		return "test did not complete"
+	}
+	return "test failed"
+}
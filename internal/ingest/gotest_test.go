@@ -0,0 +1,76 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func goTestFixturePath(t *testing.T, name string) string {
+	t.Helper()
+
+	_, currentFile, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	return filepath.Join(filepath.Dir(currentFile), "..", "..", "testdata", "gotest", name)
+}
+
+func TestParseGoTestJSON_Mixed(t *testing.T) {
+	f, err := os.Open(goTestFixturePath(t, "mixed.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	results, err := ParseGoTestJSON(f)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	byID := make(map[string]TestResult)
+	for _, r := range results {
+		byID[r.TestIdentifier] = r
+	}
+
+	create := byID["github.com/acme/api/users#TestCreate"]
+	require.Equal(t, "passed", create.Status)
+	require.Equal(t, 120, create.DurationMS)
+	require.Equal(t, "github.com/acme/api/users", create.Classname)
+	require.Equal(t, "TestCreate", create.Name)
+	require.Empty(t, create.FailureOutput)
+
+	sub := byID["github.com/acme/api/users#TestLogin/expired_session"]
+	require.Equal(t, "failed", sub.Status)
+	require.Equal(t, 320, sub.DurationMS)
+	require.Equal(t, "login_test.go:42: expected status 401, got 500", sub.FailureMessage)
+	require.Contains(t, sub.FailureOutput, "expected status 401")
+
+	require.Equal(t, "passed", byID["github.com/acme/api/users#TestLogin/valid_password"].Status)
+	require.Equal(t, "failed", byID["github.com/acme/api/users#TestLogin"].Status)
+	require.Equal(t, "skipped", byID["github.com/acme/api/users#TestExport"].Status)
+}
+
+func TestParseGoTestJSON_IncompleteTestInFailedPackage(t *testing.T) {
+	f, err := os.Open(goTestFixturePath(t, "panic.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	results, err := ParseGoTestJSON(f)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	require.Equal(t, "github.com/acme/api/billing#TestInvoice", results[0].TestIdentifier)
+	require.Equal(t, "failed", results[0].Status)
+	require.True(t, strings.HasPrefix(results[0].FailureMessage, "panic: runtime error"))
+}
+
+func TestParseGoTestJSON_NoEvents(t *testing.T) {
+	_, err := ParseGoTestJSON(strings.NewReader("ok  \tgithub.com/acme/api\t0.01s\n"))
+	require.Error(t, err)
+}
+
+func TestParseGoTestJSON_InvalidEvent(t *testing.T) {
+	_, err := ParseGoTestJSON(strings.NewReader(`{"Action":"run","Test":` + "\n"))
+	require.Error(t, err)
+}
@@ -2,7 +2,6 @@ package ingest
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,13 +12,28 @@ import (
 	"github.com/aliuyar1234/flakeguard/internal/apikey"
 	"github.com/aliuyar1234/flakeguard/internal/apperrors"
 	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
 	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
-const maxStoredJUnitContentBytes = 64 * 1024
+// multipartMemoryBytes caps how much of an upload is buffered in memory
+const multipartMemoryBytes = 8 * 1024 * 1024
+
+// reportFields lists the multipart fields that carry report files. Each may
+// be repeated and fields may be mixed in a single upload. An empty format
+// means meta.format, or auto-detection when that is unset.
+var reportFields = []struct {
+	field  string
+	format ReportFormat
+}{
+	{field: "junit"},
+	{field: "report"},
+	{field: "gotest", format: FormatGoTest},
+}
 
 // HandleJUnitUpload handles POST /api/v1/ingest/junit.
 func HandleJUnitUpload(pool *pgxpool.Pool, cfg *config.Config, limits UploadLimits) http.HandlerFunc {
@@ -32,7 +46,8 @@ func HandleJUnitUpload(pool *pgxpool.Pool, cfg *config.Config, limits UploadLimi
 			return
 		}
 
-		if err := r.ParseMultipartForm(limits.MaxTotalBytes); err != nil {
+		// Files beyond the in-memory budget are spooled to temporary files
+		if err := r.ParseMultipartForm(min(limits.MaxTotalBytes, multipartMemoryBytes)); err != nil {
 			if err == multipart.ErrMessageTooLarge {
 				apperrors.WritePayloadTooLarge(w, r, fmt.Sprintf("Upload exceeds maximum size of %d bytes", limits.MaxTotalBytes))
 				return
@@ -77,9 +92,20 @@ func HandleJUnitUpload(pool *pgxpool.Pool, cfg *config.Config, limits UploadLimi
 			return
 		}
 
-		files := r.MultipartForm.File["junit"]
+		var files []*multipart.FileHeader
+		fileFormats := make(map[*multipart.FileHeader]ReportFormat)
+		for _, f := range reportFields {
+			format := f.format
+			if format == "" {
+				format = ReportFormat(meta.Format)
+			}
+			for _, fileHeader := range r.MultipartForm.File[f.field] {
+				files = append(files, fileHeader)
+				fileFormats[fileHeader] = format
+			}
+		}
 		if len(files) == 0 {
-			apperrors.WriteBadRequest(w, r, "No JUnit files provided")
+			apperrors.WriteBadRequest(w, r, "No report files provided")
 			return
 		}
 		if err := limits.ValidateFileCount(len(files)); err != nil {
@@ -104,9 +130,9 @@ func HandleJUnitUpload(pool *pgxpool.Pool, cfg *config.Config, limits UploadLimi
 			return
 		}
 
-		var allTestResults []TestResult
-		junitFiles := make([]JUnitFile, 0, len(files))
-
+		// Reports are streamed into the queue from the parsed form, which
+		// spooled large files to disk
+		queued := make([]QueuedFile, 0, len(files))
 		for _, fileHeader := range files {
 			file, err := fileHeader.Open()
 			if err != nil {
@@ -114,74 +140,86 @@ func HandleJUnitUpload(pool *pgxpool.Pool, cfg *config.Config, limits UploadLimi
 				apperrors.WriteInternalError(w, r, "Failed to process uploaded files")
 				return
 			}
+			defer file.Close()
 
-			buf := new(bytes.Buffer)
-			size, err := io.Copy(buf, file)
-			file.Close()
-
-			if err != nil {
-				log.Error().Err(err).Str("filename", fileHeader.Filename).Msg("Failed to read uploaded file")
-				apperrors.WriteInternalError(w, r, "Failed to read uploaded files")
-				return
-			}
-
-			results, err := ParseAndExtract(bytes.NewReader(buf.Bytes()))
-			if err != nil {
-				log.Error().Err(err).Str("filename", fileHeader.Filename).Msg("Failed to parse JUnit XML")
-				apperrors.WriteError(w, r, http.StatusBadRequest, "invalid_junit_xml", fmt.Sprintf("Failed to parse JUnit XML in file '%s': %v", fileHeader.Filename, err))
-				return
-			}
-
-			allTestResults = append(allTestResults, results...)
-
-			sum := sha256.Sum256(buf.Bytes())
+			queued = append(queued, QueuedFile{
+				Filename: fileHeader.Filename,
+				Format:   fileFormats[fileHeader],
+				Content:  file,
+			})
+		}
 
-			storedContent := buf.Bytes()
-			contentTruncated := false
-			if len(storedContent) > maxStoredJUnitContentBytes {
-				storedContent = storedContent[:maxStoredJUnitContentBytes]
-				contentTruncated = true
-			}
+		// A CODEOWNERS file sent along replaces the project's when the
+		// worker stores the results
+		var codeowners *string
+		if content, ok, err := readCodeowners(r); err != nil {
+			apperrors.WriteError(w, r, http.StatusBadRequest, "invalid_codeowners", err.Error())
+			return
+		} else if ok {
+			codeowners = &content
+		}
 
-			junitFiles = append(junitFiles, JUnitFile{
-				Filename:         fileHeader.Filename,
-				SHA256:           fmt.Sprintf("%x", sum),
-				SizeBytes:        int(size),
-				ContentTruncated: contentTruncated,
-				Content:          append([]byte(nil), storedContent...),
-			})
+		queue := NewQueue(pool)
+		ingestionID, err := queue.Enqueue(ctx, project.ID, key.ID, &meta, queued, codeowners)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to enqueue ingestion")
+			apperrors.WriteInternalError(w, r, "Failed to store ingestion data")
+			return
 		}
 
-		persistence := NewPersistenceService(pool, cfg)
-		result, err := persistence.PersistIngestion(ctx, project.ID, key.ID, &meta, junitFiles, allTestResults)
+		status, err := queue.Get(ctx, project.ID, ingestionID)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to persist ingestion")
+			log.Error().Err(err).Str("ingestion_id", ingestionID.String()).Msg("Failed to load queued ingestion")
 			apperrors.WriteInternalError(w, r, "Failed to store ingestion data")
 			return
 		}
 
 		log.Info().
-			Str("ingestion_id", result.IngestionID.String()).
+			Str("ingestion_id", ingestionID.String()).
 			Str("project_id", project.ID.String()).
 			Str("project_slug", meta.ProjectSlug).
 			Str("repo_full_name", meta.RepoFullName).
-			Int64("github_run_id", meta.GitHubRunID).
-			Int("github_run_attempt", meta.GitHubRunAttempt).
+			Str("provider", meta.Provider).
+			Str("run_id", meta.RunID).
+			Int("run_attempt", meta.RunAttempt).
 			Str("job_name", meta.JobName).
 			Str("job_variant", meta.JobVariant).
-			Int("stored_test_results", result.TestResultsCount).
-			Int("stored_junit_files", result.JUnitFilesCount).
-			Int("flake_events_created", result.FlakeEventsCount).
-			Msg("Ingestion successful")
-
-		apperrors.WriteSuccess(w, r, http.StatusAccepted, IngestionResponse{
-			IngestionID: result.IngestionID,
-			Stored: IngestionStoredCounts{
-				JUnitFiles:  result.JUnitFilesCount,
-				TestResults: result.TestResultsCount,
-			},
-			FlakeEventsCreated: result.FlakeEventsCount,
-		})
+			Int("report_files", len(queued)).
+			Msg("Ingestion queued")
+
+		apperrors.WriteSuccess(w, r, http.StatusAccepted, status)
+	}
+}
+
+// HandleGetIngestion handles GET /api/v1/ingest/{ingestion_id}.
+func HandleGetIngestion(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		key := apikey.GetAPIKey(ctx)
+		if key == nil {
+			apperrors.WriteUnauthorized(w, r, "API key required")
+			return
+		}
+
+		ingestionID, err := uuid.Parse(chi.URLParam(r, "ingestion_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid ingestion ID")
+			return
+		}
+
+		status, err := NewQueue(pool).Get(ctx, key.ProjectID, ingestionID)
+		if err != nil {
+			if errors.Is(err, ErrIngestionNotFound) {
+				apperrors.WriteNotFound(w, r, "Ingestion not found")
+				return
+			}
+			log.Error().Err(err).Str("ingestion_id", ingestionID.String()).Msg("Failed to get ingestion")
+			apperrors.WriteInternalError(w, r, "Failed to get ingestion")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, status)
 	}
 }
 
@@ -190,12 +228,6 @@ type IngestionStoredCounts struct {
 	TestResults int `json:"test_results"`
 }
 
-type IngestionResponse struct {
-	IngestionID        uuid.UUID             `json:"ingestion_id"`
-	Stored             IngestionStoredCounts `json:"stored"`
-	FlakeEventsCreated int                   `json:"flake_events_created"`
-}
-
 func readMetaBytes(r *http.Request) ([]byte, error) {
 	if meta := r.FormValue("meta"); meta != "" {
 		return []byte(meta), nil
@@ -217,3 +249,39 @@ func readMetaBytes(r *http.Request) ([]byte, error) {
 	}
 	return nil, errors.New("meta is required")
 }
+
+// readCodeowners reads the optional CODEOWNERS file of an upload, which may
+// be gzip-compressed, and checks that it parses
+func readCodeowners(r *http.Request) (string, bool, error) {
+	files := r.MultipartForm.File["codeowners"]
+	if len(files) == 0 {
+		return "", false, nil
+	}
+	if len(files) > 1 {
+		return "", false, errors.New("only one codeowners file is allowed")
+	}
+
+	f, err := files[0].Open()
+	if err != nil {
+		return "", false, errors.New("failed to read codeowners file")
+	}
+	raw, err := io.ReadAll(io.LimitReader(f, ownership.MaxCodeownersBytes+1))
+	f.Close()
+	if err != nil {
+		return "", false, errors.New("failed to read codeowners file")
+	}
+
+	reader, err := openReport(bytes.NewReader(raw), ownership.MaxCodeownersBytes)
+	if err != nil {
+		return "", false, errors.New("failed to decompress codeowners file")
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil || len(content) > ownership.MaxCodeownersBytes {
+		return "", false, fmt.Errorf("codeowners file must be at most %d bytes", ownership.MaxCodeownersBytes)
+	}
+
+	if _, err := ownership.ParseCodeowners(string(content)); err != nil {
+		return "", false, fmt.Errorf("invalid codeowners file: %w", err)
+	}
+	return string(content), true, nil
+}
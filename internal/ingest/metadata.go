@@ -2,27 +2,70 @@ package ingest
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// CI providers accepted in meta.provider
+const (
+	ProviderGitHub    = "github"
+	ProviderGitLab    = "gitlab"
+	ProviderJenkins   = "jenkins"
+	ProviderCircleCI  = "circleci"
+	ProviderBuildkite = "buildkite"
+	ProviderAzure     = "azure"
+	ProviderLocal     = "local"
+)
+
+var ciProviders = []string{
+	ProviderGitHub,
+	ProviderGitLab,
+	ProviderJenkins,
+	ProviderCircleCI,
+	ProviderBuildkite,
+	ProviderAzure,
+	ProviderLocal,
+}
+
+// SupportedProviders returns the accepted meta.provider values
+func SupportedProviders() []string {
+	return append([]string(nil), ciProviders...)
+}
+
 // IngestionMetadata contains all meta fields required for a JUnit ingestion (SSOT 8.9.1).
+//
+// A run is identified by provider and the opaque run_id. Attempts within a run
+// are numbered by run_attempt; providers without an attempt counter send an
+// opaque attempt_id instead and attempts are numbered in order of arrival.
+// The github_run_* fields are the pre-provider contract and are still
+// accepted: Validate maps them onto the provider-neutral fields.
 type IngestionMetadata struct {
-	ProjectSlug      string `json:"project_slug"`
-	RepoFullName     string `json:"repo_full_name"`
-	WorkflowName     string `json:"workflow_name"`
-	WorkflowRef      string `json:"workflow_ref"`
-	GitHubRunID      int64  `json:"github_run_id"`
-	GitHubRunAttempt int    `json:"github_run_attempt"`
-	GitHubRunNumber  int64  `json:"github_run_number"`
-	RunURL           string `json:"run_url"`
-	SHA              string `json:"sha"`
-	Branch           string `json:"branch"`
-	Event            string `json:"event"`
-	PRNumber         *int64 `json:"pr_number"`
-	JobName          string `json:"job_name"`
-	JobVariant       string `json:"job_variant"`
-	StartedAt        string `json:"started_at"`
-	CompletedAt      string `json:"completed_at"`
+	ProjectSlug  string `json:"project_slug"`
+	RepoFullName string `json:"repo_full_name"`
+	WorkflowName string `json:"workflow_name"`
+	WorkflowRef  string `json:"workflow_ref"`
+	Provider     string `json:"provider"`
+	RunID        string `json:"run_id"`
+	RunAttempt   int    `json:"run_attempt"`          // 0 = numbered from attempt_id
+	AttemptID    string `json:"attempt_id,omitempty"` // only used when run_attempt is unset
+	RunNumber    string `json:"run_number,omitempty"`
+	RunURL       string `json:"run_url"`
+	SHA          string `json:"sha"`
+	Branch       string `json:"branch"`
+	Event        string `json:"event"`
+	PRNumber     *int64 `json:"pr_number"`
+	JobName      string `json:"job_name"`
+	JobVariant   string `json:"job_variant"`
+	StartedAt    string `json:"started_at"`
+	CompletedAt  string `json:"completed_at"`
+	Format       string `json:"format,omitempty"` // Report format for junit/report files; empty = auto-detect
+
+	// Deprecated: GitHub-only run identity, use Provider/RunID/RunAttempt/RunNumber
+	GitHubRunID      int64 `json:"github_run_id,omitempty"`
+	GitHubRunAttempt int   `json:"github_run_attempt,omitempty"`
+	GitHubRunNumber  int64 `json:"github_run_number,omitempty"`
 
 	startedAtTime   time.Time
 	completedAtTime time.Time
@@ -37,6 +80,8 @@ func (e *InvalidMetaError) Error() string {
 }
 
 func (m *IngestionMetadata) Validate() error {
+	m.normalizeRunIdentity()
+
 	switch {
 	case m.ProjectSlug == "":
 		return &InvalidMetaError{Message: "meta.project_slug is required"}
@@ -46,12 +91,12 @@ func (m *IngestionMetadata) Validate() error {
 		return &InvalidMetaError{Message: "meta.workflow_name is required"}
 	case m.WorkflowRef == "":
 		return &InvalidMetaError{Message: "meta.workflow_ref is required"}
-	case m.GitHubRunID <= 0:
-		return &InvalidMetaError{Message: "meta.github_run_id is required"}
-	case m.GitHubRunAttempt < 1:
-		return &InvalidMetaError{Message: "meta.github_run_attempt is required"}
-	case m.GitHubRunNumber <= 0:
-		return &InvalidMetaError{Message: "meta.github_run_number is required"}
+	case !slices.Contains(ciProviders, m.Provider):
+		return &InvalidMetaError{Message: fmt.Sprintf("meta.provider must be one of: %s", strings.Join(ciProviders, ", "))}
+	case m.RunID == "":
+		return &InvalidMetaError{Message: "meta.run_id is required"}
+	case m.RunAttempt < 0:
+		return &InvalidMetaError{Message: "meta.run_attempt must be positive"}
 	case m.RunURL == "":
 		return &InvalidMetaError{Message: "meta.run_url is required"}
 	case m.SHA == "":
@@ -68,6 +113,12 @@ func (m *IngestionMetadata) Validate() error {
 		return &InvalidMetaError{Message: "meta.completed_at is required"}
 	}
 
+	if m.Format != "" {
+		if _, ok := LookupParser(ReportFormat(m.Format)); !ok {
+			return &InvalidMetaError{Message: fmt.Sprintf("meta.format must be one of: %s", strings.Join(SupportedFormats(), ", "))}
+		}
+	}
+
 	startedAt, err := parseRFC3339("meta.started_at", m.StartedAt)
 	if err != nil {
 		return err
@@ -83,6 +134,30 @@ func (m *IngestionMetadata) Validate() error {
 	return nil
 }
 
+// normalizeRunIdentity fills the provider-neutral run identity from the
+// legacy github_run_* fields and applies defaults
+func (m *IngestionMetadata) normalizeRunIdentity() {
+	m.Provider = strings.ToLower(strings.TrimSpace(m.Provider))
+	m.RunID = strings.TrimSpace(m.RunID)
+	m.AttemptID = strings.TrimSpace(m.AttemptID)
+
+	if m.Provider == "" {
+		m.Provider = ProviderGitHub
+	}
+	if m.RunID == "" && m.GitHubRunID > 0 {
+		m.RunID = strconv.FormatInt(m.GitHubRunID, 10)
+	}
+	if m.RunAttempt == 0 && m.GitHubRunAttempt > 0 {
+		m.RunAttempt = m.GitHubRunAttempt
+	}
+	if m.RunNumber == "" && m.GitHubRunNumber > 0 {
+		m.RunNumber = strconv.FormatInt(m.GitHubRunNumber, 10)
+	}
+	if m.RunAttempt == 0 && m.AttemptID == "" {
+		m.RunAttempt = 1
+	}
+}
+
 func (m *IngestionMetadata) StartedAtTime() time.Time {
 	return m.startedAtTime
 }
@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validMeta() IngestionMetadata {
+	return IngestionMetadata{
+		ProjectSlug:  "proj",
+		RepoFullName: "acme/api",
+		WorkflowName: "CI",
+		WorkflowRef:  ".github/workflows/ci.yml",
+		RunURL:       "https://github.com/acme/api/actions/runs/123",
+		SHA:          "abc",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "test",
+		StartedAt:    "2026-01-01T12:00:00Z",
+		CompletedAt:  "2026-01-01T12:05:00Z",
+	}
+}
+
+func TestValidate_MapsLegacyGitHubRunFields(t *testing.T) {
+	m := validMeta()
+	m.GitHubRunID = 123
+	m.GitHubRunAttempt = 2
+	m.GitHubRunNumber = 7
+
+	require.NoError(t, m.Validate())
+	require.Equal(t, ProviderGitHub, m.Provider)
+	require.Equal(t, "123", m.RunID)
+	require.Equal(t, 2, m.RunAttempt)
+	require.Equal(t, "7", m.RunNumber)
+}
+
+func TestValidate_ProviderRunIdentity(t *testing.T) {
+	m := validMeta()
+	m.Provider = "GitLab"
+	m.RunID = "9001"
+	m.AttemptID = "1002"
+	require.NoError(t, m.Validate())
+	require.Equal(t, ProviderGitLab, m.Provider)
+	require.Equal(t, 0, m.RunAttempt, "attempt is numbered from attempt_id")
+
+	m = validMeta()
+	m.Provider = "buildkite"
+	m.RunID = "0190a3f2-build"
+	require.NoError(t, m.Validate())
+	require.Equal(t, 1, m.RunAttempt)
+
+	m = validMeta()
+	m.Provider = "teamcity"
+	m.RunID = "1"
+	require.ErrorContains(t, m.Validate(), "meta.provider must be one of")
+
+	m = validMeta()
+	m.Provider = "gitlab"
+	require.ErrorContains(t, m.Validate(), "meta.run_id is required")
+}
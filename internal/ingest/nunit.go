@@ -0,0 +1,128 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// nunitTestCase covers the <test-case> element of both NUnit 3 (<test-run>)
+// and NUnit 2 (<test-results>) reports.
+type nunitTestCase struct {
+	// NUnit 3
+	Name      string  `xml:"name,attr"`
+	FullName  string  `xml:"fullname,attr"`
+	ClassName string  `xml:"classname,attr"`
+	Result    string  `xml:"result,attr"`
+	Label     string  `xml:"label,attr"`
+	Duration  float64 `xml:"duration,attr"`
+	// NUnit 2
+	Executed string  `xml:"executed,attr"`
+	Time     float64 `xml:"time,attr"`
+
+	Failure *struct {
+		Message    string `xml:"message"`
+		StackTrace string `xml:"stack-trace"`
+	} `xml:"failure"`
+}
+
+// ParseNUnit parses an NUnit 3 or NUnit 2 XML report. Test cases are
+// collected from any depth of nested <test-suite> elements.
+func ParseNUnit(r io.Reader) ([]TestResult, error) {
+	decoder := xml.NewDecoder(r)
+
+	var results []TestResult
+	sawRoot := false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse NUnit XML: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "test-run" || start.Name.Local == "test-results" {
+			sawRoot = true
+			continue
+		}
+		if start.Name.Local != "test-case" {
+			continue
+		}
+
+		var tc nunitTestCase
+		if err := decoder.DecodeElement(&tc, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse NUnit XML: %w", err)
+		}
+		results = append(results, nunitTestResult(&tc))
+	}
+
+	if !sawRoot {
+		return nil, fmt.Errorf("failed to parse NUnit XML: expected <test-run> or <test-results> root")
+	}
+
+	return results, nil
+}
+
+func nunitTestResult(tc *nunitTestCase) TestResult {
+	classname, name := tc.ClassName, tc.Name
+	if classname == "" {
+		// NUnit 2 only has the full name: Namespace.Class.Method(args)
+		full := tc.Name
+		if tc.FullName != "" {
+			full = tc.FullName
+		}
+		classname, name = splitQualifiedTestName(full)
+	}
+
+	result := TestResult{
+		Classname:      classname,
+		Name:           name,
+		TestIdentifier: deriveTestIdentifier(classname, name),
+		DurationMS:     int((tc.Duration + tc.Time) * 1000), // Only one of the two is set
+	}
+
+	switch tc.Result {
+	case "Passed", "Success", "Warning":
+		result.Status = "passed"
+	case "Failed", "Failure":
+		result.Status = "failed"
+		if tc.Label == "Error" || tc.Label == "Cancelled" || tc.Label == "Invalid" {
+			result.Status = "error"
+		}
+	case "Error", "NotRunnable", "Cancelled":
+		result.Status = "error"
+	default:
+		// Skipped, Ignored, Inconclusive, NotRun, Explicit
+		result.Status = "skipped"
+	}
+	if tc.Executed == "False" {
+		result.Status = "skipped"
+	}
+
+	if (result.Status == "failed" || result.Status == "error") && tc.Failure != nil {
+		result.FailureMessage = truncateString(strings.TrimSpace(tc.Failure.Message), 1024)
+		result.FailureOutput = truncateString(strings.TrimSpace(tc.Failure.StackTrace), 8192)
+	}
+
+	return result
+}
+
+// splitQualifiedTestName splits "Namespace.Class.Method(args)" into
+// ("Namespace.Class", "Method(args)"), ignoring dots inside the arguments.
+func splitQualifiedTestName(full string) (string, string) {
+	base := full
+	if i := strings.Index(full, "("); i >= 0 {
+		base = full[:i]
+	}
+	i := strings.LastIndex(base, ".")
+	if i < 0 {
+		return "", full
+	}
+	return full[:i], full[i+1:]
+}
@@ -13,27 +13,58 @@ type JUnitTestSuites struct {
 	TestSuites []JUnitTestSuite `xml:"testsuite"`
 }
 
-// JUnitTestSuite represents a single test suite
+// JUnitTestSuite represents a single test suite. Suites may be nested
+// (Jest, Mocha, Ant).
 type JUnitTestSuite struct {
-	XMLName   xml.Name        `xml:"testsuite"`
-	Name      string          `xml:"name,attr"`
-	Tests     int             `xml:"tests,attr"`
-	Failures  int             `xml:"failures,attr"`
-	Errors    int             `xml:"errors,attr"`
-	Skipped   int             `xml:"skipped,attr"`
-	Time      float64         `xml:"time,attr"`
-	TestCases []JUnitTestCase `xml:"testcase"`
+	XMLName    xml.Name         `xml:"testsuite"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       float64          `xml:"time,attr"`
+	File       string           `xml:"file,attr"`
+	Properties []JUnitProperty  `xml:"properties>property"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitProperty is a name/value pair from a <properties> block
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
-// JUnitTestCase represents a single test case
+// JUnitTestCase represents a single test case.
+// Flaky* elements are failed reruns of a test that eventually passed; Rerun*
+// elements are further failed reruns of a test that failed (Maven Surefire
+// rerunFailingTestsCount and compatible reporters).
 type JUnitTestCase struct {
-	XMLName   xml.Name      `xml:"testcase"`
-	Classname string        `xml:"classname,attr"`
-	Name      string        `xml:"name,attr"`
-	Time      float64       `xml:"time,attr"`
-	Failure   *JUnitFailure `xml:"failure"`
-	Error     *JUnitError   `xml:"error"`
-	Skipped   *JUnitSkipped `xml:"skipped"`
+	XMLName       xml.Name          `xml:"testcase"`
+	Classname     string            `xml:"classname,attr"`
+	Name          string            `xml:"name,attr"`
+	Time          float64           `xml:"time,attr"`
+	File          string            `xml:"file,attr"`
+	Failure       *JUnitFailure     `xml:"failure"`
+	Error         *JUnitError       `xml:"error"`
+	Skipped       *JUnitSkipped     `xml:"skipped"`
+	Properties    []JUnitProperty   `xml:"properties>property"`
+	SystemOut     string            `xml:"system-out"`
+	SystemErr     string            `xml:"system-err"`
+	FlakyFailures []JUnitRerunEntry `xml:"flakyFailure"`
+	FlakyErrors   []JUnitRerunEntry `xml:"flakyError"`
+	RerunFailures []JUnitRerunEntry `xml:"rerunFailure"`
+	RerunErrors   []JUnitRerunEntry `xml:"rerunError"`
+}
+
+// JUnitRerunEntry is one failed framework-level rerun of a test case
+type JUnitRerunEntry struct {
+	Message    string `xml:"message,attr"`
+	Type       string `xml:"type,attr"`
+	StackTrace string `xml:"stackTrace"`
+	SystemOut  string `xml:"system-out"`
+	SystemErr  string `xml:"system-err"`
+	Content    string `xml:",chardata"`
 }
 
 // JUnitFailure represents a test failure
@@ -67,34 +98,113 @@ type TestResult struct {
 	DurationMS     int
 	FailureMessage string
 	FailureOutput  string
+	SystemOut      string
+	SystemErr      string
+	Properties     map[string]string
+	RerunFailures  int    // Failed in-job reruns; on a passed result this is a flake within one attempt
+	RerunMessage   string // Failure message of the first failed rerun
+	FilePath       string // Source file of the test, when the report has it
 }
 
-// ParseJUnitXML parses JUnit XML from a reader
+// ParseJUnitXML parses JUnit XML from a reader. The root may be either
+// <testsuites> or a single <testsuite> (Maven Surefire, pytest); a bare suite
+// is wrapped so callers always see a JUnitTestSuites.
 func ParseJUnitXML(r io.Reader) (*JUnitTestSuites, error) {
-	var suites JUnitTestSuites
 	decoder := xml.NewDecoder(r)
 
-	if err := decoder.Decode(&suites); err != nil {
-		return nil, fmt.Errorf("failed to parse JUnit XML: %w", err)
-	}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to parse JUnit XML: %w", err)
+		}
 
-	return &suites, nil
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "testsuites":
+			var suites JUnitTestSuites
+			if err := decoder.DecodeElement(&suites, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse JUnit XML: %w", err)
+			}
+			return &suites, nil
+		case "testsuite":
+			var suite JUnitTestSuite
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse JUnit XML: %w", err)
+			}
+			return &JUnitTestSuites{TestSuites: []JUnitTestSuite{suite}}, nil
+		default:
+			return nil, fmt.Errorf("failed to parse JUnit XML: unexpected root element <%s>", start.Name.Local)
+		}
+	}
 }
 
-// ExtractTestResults extracts all test results from parsed JUnit XML
+// ExtractTestResults extracts all test results from parsed JUnit XML.
+// Nested suites are flattened: test cases below a top-level suite get the
+// names of their enclosing nested suites prefixed to the test name
+// (e.g. "Parser/nested/handles empty input"), so identically named tests in
+// different nested suites stay distinct. Suite names equal to the test's
+// classname are not repeated.
 func ExtractTestResults(suites *JUnitTestSuites) []TestResult {
 	var results []TestResult
 
-	for _, suite := range suites.TestSuites {
-		for _, testCase := range suite.TestCases {
-			result := extractTestResult(&testCase)
-			results = append(results, result)
+	for i := range suites.TestSuites {
+		results = appendSuiteResults(results, &suites.TestSuites[i], nil, "")
+	}
+
+	return results
+}
+
+// appendSuiteResults appends the results of a suite and its nested suites.
+// file is the source file of the enclosing suite, if it has one.
+func appendSuiteResults(results []TestResult, suite *JUnitTestSuite, path []string, file string) []TestResult {
+	if suite.File != "" {
+		file = suite.File
+	}
+
+	for i := range suite.TestCases {
+		result := suiteTestResult(&suite.TestCases[i], suite.Properties, path)
+		if result.FilePath == "" {
+			result.FilePath = strings.TrimSpace(file)
 		}
+		results = append(results, result)
+	}
+
+	for i := range suite.TestSuites {
+		nested := &suite.TestSuites[i]
+		nestedPath := append(append([]string(nil), path...), nested.Name)
+		results = appendSuiteResults(results, nested, nestedPath, file)
 	}
 
 	return results
 }
 
+// suiteTestResult converts a test case of a suite into a TestResult. path holds
+// the names of the nested suites enclosing the test below its top-level suite.
+func suiteTestResult(tc *JUnitTestCase, suiteProps []JUnitProperty, path []string) TestResult {
+	result := extractTestResult(tc)
+	result.Properties = mergeProperties(suiteProps, tc.Properties)
+
+	var prefix []string
+	for _, name := range path {
+		if name != "" && name != result.Classname {
+			prefix = append(prefix, name)
+		}
+	}
+	if len(prefix) > 0 {
+		result.Name = strings.Join(prefix, "/") + "/" + result.Name
+		result.TestIdentifier = deriveTestIdentifier(result.Classname, result.Name)
+	}
+
+	return result
+}
+
 // extractTestResult converts a JUnit test case to a TestResult
 func extractTestResult(tc *JUnitTestCase) TestResult {
 	result := TestResult{
@@ -102,6 +212,7 @@ func extractTestResult(tc *JUnitTestCase) TestResult {
 		Name:           tc.Name,
 		TestIdentifier: deriveTestIdentifier(tc.Classname, tc.Name),
 		DurationMS:     int(tc.Time * 1000), // Convert seconds to milliseconds
+		FilePath:       strings.TrimSpace(tc.File),
 	}
 
 	// Determine status and extract failure information
@@ -119,9 +230,42 @@ func extractTestResult(tc *JUnitTestCase) TestResult {
 		result.Status = "passed"
 	}
 
+	result.SystemOut = truncateString(strings.TrimSpace(tc.SystemOut), 8192)
+	result.SystemErr = truncateString(strings.TrimSpace(tc.SystemErr), 8192)
+
+	var reruns []JUnitRerunEntry
+	reruns = append(reruns, tc.FlakyFailures...)
+	reruns = append(reruns, tc.FlakyErrors...)
+	reruns = append(reruns, tc.RerunFailures...)
+	reruns = append(reruns, tc.RerunErrors...)
+	result.RerunFailures = len(reruns)
+	if len(reruns) > 0 {
+		message := reruns[0].Message
+		if message == "" {
+			message = reruns[0].Type
+		}
+		result.RerunMessage = truncateString(strings.TrimSpace(message), 1024)
+	}
+
 	return result
 }
 
+// mergeProperties combines suite and test case properties; test case values win.
+// Returns nil when there are none.
+func mergeProperties(suite, testCase []JUnitProperty) map[string]string {
+	if len(suite) == 0 && len(testCase) == 0 {
+		return nil
+	}
+	props := make(map[string]string, len(suite)+len(testCase))
+	for _, p := range suite {
+		props[p.Name] = p.Value
+	}
+	for _, p := range testCase {
+		props[p.Name] = p.Value
+	}
+	return props
+}
+
 // deriveTestIdentifier creates a test identifier from classname and name
 // Format: classname#name
 func deriveTestIdentifier(classname, name string) string {
@@ -145,14 +289,125 @@ func truncateString(s string, maxBytes int) string {
 	return truncated + indicator
 }
 
+// streamSuite is an open <testsuite> element while streaming
+type streamSuite struct {
+	name       string
+	file       string // source file, inherited by nested suites
+	properties []JUnitProperty
+}
+
+// StreamJUnitXML decodes JUnit XML token by token and calls emit for each test
+// case as soon as it has been read, so memory is bounded by the largest single
+// test case rather than the whole report. Results match ExtractTestResults but
+// come in document order, and suite <properties> only apply to the test cases
+// that follow them (reporters write them first). Decoding stops at the
+// end of the root element; an error from emit aborts parsing and is returned.
+func StreamJUnitXML(r io.Reader, emit func(TestResult) error) error {
+	decoder := xml.NewDecoder(r)
+
+	var suites []streamSuite
+	rootSeen := false
+	bareSuite := false
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("failed to parse JUnit XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !rootSeen {
+				rootSeen = true
+				switch t.Name.Local {
+				case "testsuites":
+					continue
+				case "testsuite":
+					bareSuite = true
+					suites = append(suites, streamSuite{name: xmlAttr(t, "name"), file: xmlAttr(t, "file")})
+					continue
+				default:
+					return fmt.Errorf("failed to parse JUnit XML: unexpected root element <%s>", t.Name.Local)
+				}
+			}
+
+			// Every other element is skipped whole, so t is a direct child of
+			// the root or of the innermost open suite.
+			switch {
+			case t.Name.Local == "testsuite":
+				file := xmlAttr(t, "file")
+				if file == "" && len(suites) > 0 {
+					file = suites[len(suites)-1].file
+				}
+				suites = append(suites, streamSuite{name: xmlAttr(t, "name"), file: file})
+			case t.Name.Local == "properties" && len(suites) > 0:
+				var props struct {
+					Property []JUnitProperty `xml:"property"`
+				}
+				if err := decoder.DecodeElement(&props, &t); err != nil {
+					return fmt.Errorf("failed to parse JUnit XML: %w", err)
+				}
+				suites[len(suites)-1].properties = props.Property
+			case t.Name.Local == "testcase" && len(suites) > 0:
+				var tc JUnitTestCase
+				if err := decoder.DecodeElement(&tc, &t); err != nil {
+					return fmt.Errorf("failed to parse JUnit XML: %w", err)
+				}
+
+				path := make([]string, 0, len(suites)-1)
+				for _, suite := range suites[1:] {
+					path = append(path, suite.name)
+				}
+				result := suiteTestResult(&tc, suites[len(suites)-1].properties, path)
+				if result.FilePath == "" {
+					result.FilePath = strings.TrimSpace(suites[len(suites)-1].file)
+				}
+				if err := emit(result); err != nil {
+					return err
+				}
+			default:
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("failed to parse JUnit XML: %w", err)
+				}
+			}
+		case xml.EndElement:
+			// Only the root and open suites are still unclosed here
+			if t.Name.Local != "testsuite" {
+				return nil
+			}
+			suites = suites[:len(suites)-1]
+			if bareSuite && len(suites) == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// xmlAttr returns the value of the named attribute of an element
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
 // ParseAndExtract is a convenience function that parses JUnit XML and extracts results
 func ParseAndExtract(r io.Reader) ([]TestResult, error) {
-	suites, err := ParseJUnitXML(r)
+	var results []TestResult
+	err := StreamJUnitXML(r, func(result TestResult) error {
+		results = append(results, result)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return ExtractTestResults(suites), nil
+	return results, nil
 }
 
 // NormalizeEventType converts event_type to CI event enum
@@ -2,6 +2,7 @@ package ingest
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -93,3 +94,160 @@ func TestExtractTestResult_Truncation(t *testing.T) {
 	require.Contains(t, results[0].FailureOutput, "[truncated]")
 	require.Equal(t, 100, results[0].DurationMS)
 }
+
+func TestParseAndExtract_BareTestsuiteRoot(t *testing.T) {
+	b, err := os.ReadFile(junitFixturePath(t, "bare_testsuite.xml"))
+	require.NoError(t, err)
+
+	results, err := ParseAndExtract(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, "com.example.CheckoutTest#testTotal", results[0].TestIdentifier)
+	require.Equal(t, "passed", results[0].Status)
+	require.Equal(t, "com.example.CheckoutTest#testPayment", results[1].TestIdentifier)
+	require.Equal(t, "failed", results[1].Status)
+	require.Equal(t, "expected:<200> but was:<500>", results[1].FailureMessage)
+}
+
+func TestParseAndExtract_NestedSuites(t *testing.T) {
+	b, err := os.ReadFile(junitFixturePath(t, "nested.xml"))
+	require.NoError(t, err)
+
+	results, err := ParseAndExtract(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.TestIdentifier
+	}
+	require.ElementsMatch(t, []string{
+		"App#boots",
+		"Parser#parses numbers",
+		"Parser strings#Parser/strings/handles empty input",
+		"Parser strings#Parser/strings/handles unicode",
+	}, ids)
+
+	for _, r := range results {
+		if r.Name == "Parser/strings/handles unicode" {
+			require.Equal(t, "failed", r.Status)
+		}
+	}
+}
+
+func TestParseAndExtract_FileAttributes(t *testing.T) {
+	xmlDoc := `<testsuites>
+  <testsuite name="api" file="tests/api/test_users.py">
+    <testcase classname="tests.api.test_users" name="test_login"/>
+    <testcase classname="tests.api.test_users" name="test_logout" file="tests/api/test_session.py"/>
+    <testsuite name="nested">
+      <testcase classname="tests.api.nested" name="test_inherits"/>
+    </testsuite>
+  </testsuite>
+  <testsuite name="web">
+    <testcase classname="web.LoginTest" name="test_form"/>
+  </testsuite>
+</testsuites>`
+
+	results, err := ParseAndExtract(strings.NewReader(xmlDoc))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	files := make(map[string]string, len(results))
+	for _, r := range results {
+		files[r.Name] = r.FilePath
+	}
+	require.Equal(t, map[string]string{
+		"test_login":           "tests/api/test_users.py",
+		"test_logout":          "tests/api/test_session.py",
+		"nested/test_inherits": "tests/api/test_users.py",
+		"test_form":            "",
+	}, files)
+
+	suites, err := ParseJUnitXML(strings.NewReader(xmlDoc))
+	require.NoError(t, err)
+	tree := ExtractTestResults(suites)
+	require.Len(t, tree, 4)
+	for _, r := range tree {
+		require.Equal(t, files[r.Name], r.FilePath, r.Name)
+	}
+}
+
+func TestParseAndExtract_UnexpectedRoot(t *testing.T) {
+	_, err := ParseAndExtract(strings.NewReader(`<report><testcase name="x"/></report>`))
+	require.Error(t, err)
+
+	_, err = ParseAndExtract(strings.NewReader(``))
+	require.Error(t, err)
+}
+
+func TestParseAndExtract_SurefireRerunsOutputAndProperties(t *testing.T) {
+	b, err := os.ReadFile(junitFixturePath(t, "surefire_reruns.xml"))
+	require.NoError(t, err)
+
+	results, err := ParseAndExtract(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	place := results[0]
+	require.Equal(t, "com.example.OrderServiceTest#testPlaceOrder", place.TestIdentifier)
+	require.Equal(t, "passed", place.Status)
+	require.Equal(t, 1, place.RerunFailures)
+	require.Equal(t, "Connection refused", place.RerunMessage)
+	require.Equal(t, "attempt 2: connected", place.SystemOut)
+	require.Equal(t, map[string]string{
+		"surefire.rerunFailingTestsCount": "2",
+		"owner":                           "team-orders",
+	}, place.Properties)
+
+	cancel := results[1]
+	require.Equal(t, "failed", cancel.Status)
+	require.Equal(t, 2, cancel.RerunFailures)
+	require.Equal(t, "WARN order 42 locked", cancel.SystemErr)
+
+	list := results[2]
+	require.Equal(t, "passed", list.Status)
+	require.Zero(t, list.RerunFailures)
+	require.Empty(t, list.SystemOut)
+	require.Equal(t, map[string]string{"surefire.rerunFailingTestsCount": "2"}, list.Properties)
+}
+
+func TestStreamJUnitXML_MatchesTreeParse(t *testing.T) {
+	for _, name := range []string{"passing.xml", "failing.xml", "bare_testsuite.xml", "nested.xml", "surefire_reruns.xml"} {
+		b, err := os.ReadFile(junitFixturePath(t, name))
+		require.NoError(t, err)
+
+		suites, err := ParseJUnitXML(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		var streamed []TestResult
+		err = StreamJUnitXML(bytes.NewReader(b), func(r TestResult) error {
+			streamed = append(streamed, r)
+			return nil
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, ExtractTestResults(suites), streamed, name)
+	}
+}
+
+func TestStreamJUnitXML_EmitErrorStopsParsing(t *testing.T) {
+	b, err := os.ReadFile(junitFixturePath(t, "passing.xml"))
+	require.NoError(t, err)
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = StreamJUnitXML(bytes.NewReader(b), func(TestResult) error {
+		calls++
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, 1, calls)
+}
+
+func TestStreamJUnitXML_TruncatedDocument(t *testing.T) {
+	err := StreamJUnitXML(strings.NewReader(`<testsuites><testsuite name="s"><testcase classname="c" name="a"/>`), func(TestResult) error {
+		return nil
+	})
+	require.ErrorContains(t, err, "unexpected EOF")
+}
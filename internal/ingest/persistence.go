@@ -3,11 +3,13 @@ package ingest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/aliuyar1234/flakeguard/internal/config"
 	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
+	"github.com/aliuyar1234/flakeguard/internal/signatures"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -29,130 +31,189 @@ func NewPersistenceService(pool *pgxpool.Pool, cfg *config.Config) *PersistenceS
 
 type IngestionResult struct {
 	IngestionID      uuid.UUID
+	CIRunID          uuid.UUID
 	TestResultsCount int
 	JUnitFilesCount  int
-	FlakeEventsCount int
 }
 
-func (s *PersistenceService) PersistIngestion(
-	ctx context.Context,
-	projectID uuid.UUID,
-	apiKeyID uuid.UUID,
-	metadata *IngestionMetadata,
-	files []JUnitFile,
-	testResults []TestResult,
-) (*IngestionResult, error) {
+// testResultBatchSize is how many test results are buffered before they are
+// written with one batched round trip per table.
+const testResultBatchSize = 1000
+
+// IngestionWriter stores the results of a queued ingestion in a single
+// transaction while its reports are being parsed. Test results are buffered
+// and written in batches of testResultBatchSize.
+type IngestionWriter struct {
+	s           *PersistenceService
+	tx          pgx.Tx
+	projectID   uuid.UUID
+	metadata    *IngestionMetadata
+	ingestionID uuid.UUID
+	ciRunID     uuid.UUID
+	ciJobID     uuid.UUID
+	owners      *ownership.Matcher
+
+	// Failures are recorded to their signatures once, at Commit, so the
+	// signature rows stay locked only briefly and always in the same order
+	failures *signatures.Counts
+
+	pending             []TestResult
+	filesStored         int
+	testResultsInserted int
+}
+
+// BeginIngestion opens the transaction and records the CI run, attempt and
+// job of a claimed ingestion, applying the CODEOWNERS sent with it. Callers
+// must Commit or Rollback the returned writer.
+func (s *PersistenceService) BeginIngestion(ctx context.Context, job *Job) (*IngestionWriter, error) {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer func() {
+
+	w := &IngestionWriter{
+		s:           s,
+		tx:          tx,
+		projectID:   job.ProjectID,
+		metadata:    &job.Meta,
+		ingestionID: job.ID,
+		failures:    signatures.NewCounts(),
+		pending:     make([]TestResult, 0, testResultBatchSize),
+	}
+	if err := w.begin(ctx, job); err != nil {
 		_ = tx.Rollback(ctx)
-	}()
+		return nil, err
+	}
+	return w, nil
+}
 
-	metaJSON, err := json.Marshal(metadata)
+func (w *IngestionWriter) begin(ctx context.Context, job *Job) error {
+	var err error
+	w.ciRunID, err = w.s.upsertCIRun(ctx, w.tx, w.projectID, w.metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal meta: %w", err)
+		return fmt.Errorf("failed to upsert CI run: %w", err)
 	}
 
-	ingestionID, err := s.createIngestion(ctx, tx, projectID, apiKeyID, string(metaJSON), len(files))
+	ciRunAttemptID, err := w.s.upsertCIRunAttempt(ctx, w.tx, w.ciRunID, w.metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ingestion: %w", err)
+		return fmt.Errorf("failed to upsert CI run attempt: %w", err)
 	}
 
-	ciRunID, err := s.upsertCIRun(ctx, tx, projectID, metadata)
+	w.ciJobID, err = w.s.upsertCIJob(ctx, w.tx, ciRunAttemptID, w.metadata.JobName, w.metadata.JobVariant)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upsert CI run: %w", err)
+		return fmt.Errorf("failed to upsert CI job: %w", err)
+	}
+
+	if job.Codeowners != nil {
+		reassigned, err := ownership.UpdateFromUpload(ctx, w.tx, w.projectID, *job.Codeowners, job.ReceivedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update CODEOWNERS: %w", err)
+		}
+		if reassigned > 0 {
+			log.Info().Str("project_id", w.projectID.String()).Int("reassigned_tests", reassigned).Msg("CODEOWNERS updated from upload")
+		}
 	}
 
-	ciRunAttemptID, err := s.upsertCIRunAttempt(ctx, tx, ciRunID, metadata.GitHubRunAttempt, metadata.StartedAtTime(), metadata.CompletedAtTime())
+	w.owners, err = ownership.LoadMatcher(ctx, w.tx, w.projectID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upsert CI run attempt: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddFile stores an uploaded report file
+func (w *IngestionWriter) AddFile(ctx context.Context, file JUnitFile) error {
+	if err := w.s.storeJUnitFile(ctx, w.tx, w.ingestionID, file); err != nil {
+		return fmt.Errorf("failed to store JUnit file: %w", err)
+	}
+	w.filesStored++
+	return nil
+}
+
+// AddTestResult buffers a test result, flushing the buffer when it is full
+func (w *IngestionWriter) AddTestResult(ctx context.Context, result TestResult) error {
+	w.pending = append(w.pending, result)
+	if len(w.pending) >= testResultBatchSize {
+		return w.flush(ctx)
 	}
+	return nil
+}
 
-	ciJobID, err := s.upsertCIJob(ctx, tx, ciRunAttemptID, metadata.JobName, metadata.JobVariant)
+// flush upserts the test cases of the buffered results in one batch, then
+// inserts the results in a second. Statements in a batch run in order, so a
+// test reported twice in one job keeps its first result as before.
+func (w *IngestionWriter) flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	testCaseIDs, err := w.s.upsertTestCases(ctx, w.tx, w.projectID, w.metadata, w.owners, w.pending)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upsert CI job: %w", err)
+		return fmt.Errorf("failed to upsert test cases: %w", err)
 	}
 
-	for _, file := range files {
-		if err := s.storeJUnitFile(ctx, tx, ingestionID, file); err != nil {
-			return nil, fmt.Errorf("failed to store JUnit file: %w", err)
-		}
+	insertedIdx, err := w.s.insertTestResults(ctx, w.tx, testCaseIDs, w.ciJobID, w.pending)
+	if err != nil {
+		return fmt.Errorf("failed to insert test results: %w", err)
 	}
 
-	testResultsInserted := 0
-	for _, result := range testResults {
-		testCaseID, err := s.upsertTestCase(ctx, tx, projectID, metadata, result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to upsert test case: %w", err)
+	inserted := make([]uuid.UUID, len(insertedIdx))
+	for i, idx := range insertedIdx {
+		inserted[i] = testCaseIDs[idx]
+		if failure, ok := resultFailure(testCaseIDs[idx], &w.pending[idx]); ok {
+			w.failures.Add(failure)
 		}
+	}
 
-		inserted, err := s.insertTestResult(ctx, tx, testCaseID, ciJobID, result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to insert test result: %w", err)
-		}
-		if inserted {
-			testResultsInserted++
-		}
+	if err := flake.RecordDailyRuns(ctx, w.tx, w.ciRunID, w.ciJobID, inserted); err != nil {
+		return err
 	}
 
-	if err := s.updateIngestionTestResultsCount(ctx, tx, ingestionID, testResultsInserted); err != nil {
-		return nil, fmt.Errorf("failed to update ingestion counts: %w", err)
+	w.testResultsInserted += len(inserted)
+	w.pending = w.pending[:0]
+	return nil
+}
+
+// Commit flushes buffered results, records the counts and CI run on the
+// ingestion and commits the transaction. Flake detection is left to the caller.
+func (w *IngestionWriter) Commit(ctx context.Context) (*IngestionResult, error) {
+	if err := w.flush(ctx); err != nil {
+		return nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	if err := signatures.Record(ctx, w.tx, w.projectID, w.failures); err != nil {
+		return nil, err
 	}
 
-	flakeEventsCount := 0
-	detector := flake.NewDetectorWithSlack(s.pool, s.config)
-	if detector != nil {
-		n, err := detector.DetectFlakes(ctx, projectID, ciRunID)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("project_id", projectID.String()).
-				Str("ci_run_id", ciRunID.String()).
-				Msg("Flake detection failed")
-		} else {
-			flakeEventsCount = n
-		}
+	if err := w.s.recordIngestionResults(ctx, w.tx, w.ingestionID, w.ciRunID, w.filesStored, w.testResultsInserted); err != nil {
+		return nil, fmt.Errorf("failed to update ingestion counts: %w", err)
+	}
+
+	if err := w.tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return &IngestionResult{
-		IngestionID:      ingestionID,
-		TestResultsCount: testResultsInserted,
-		JUnitFilesCount:  len(files),
-		FlakeEventsCount: flakeEventsCount,
+		IngestionID:      w.ingestionID,
+		CIRunID:          w.ciRunID,
+		TestResultsCount: w.testResultsInserted,
+		JUnitFilesCount:  w.filesStored,
 	}, nil
 }
 
-func (s *PersistenceService) createIngestion(
-	ctx context.Context,
-	tx pgx.Tx,
-	projectID uuid.UUID,
-	apiKeyID uuid.UUID,
-	metaJSON string,
-	junitFilesCount int,
-) (uuid.UUID, error) {
-	var ingestionID uuid.UUID
-	query := `
-		INSERT INTO ingestions (project_id, api_key_id, meta, junit_files_count, test_results_count)
-		VALUES ($1, $2, $3::jsonb, $4, 0)
-		RETURNING id
-	`
-	err := tx.QueryRow(ctx, query, projectID, apiKeyID, metaJSON, junitFilesCount).Scan(&ingestionID)
-	return ingestionID, err
+// Rollback discards the stored results. It is a no-op after Commit.
+func (w *IngestionWriter) Rollback(ctx context.Context) {
+	_ = w.tx.Rollback(ctx)
 }
 
-func (s *PersistenceService) updateIngestionTestResultsCount(ctx context.Context, tx pgx.Tx, ingestionID uuid.UUID, testResultsCount int) error {
+func (s *PersistenceService) recordIngestionResults(ctx context.Context, tx pgx.Tx, ingestionID, ciRunID uuid.UUID, junitFilesCount, testResultsCount int) error {
 	query := `
 		UPDATE ingestions
-		SET test_results_count = $2
+		SET ci_run_id = $2, junit_files_count = $3, test_results_count = $4
 		WHERE id = $1
 	`
-	_, err := tx.Exec(ctx, query, ingestionID, testResultsCount)
+	_, err := tx.Exec(ctx, query, ingestionID, ciRunID, junitFilesCount, testResultsCount)
 	return err
 }
 
@@ -160,18 +221,21 @@ func (s *PersistenceService) upsertCIRun(ctx context.Context, tx pgx.Tx, project
 	var ciRunID uuid.UUID
 	query := `
 		INSERT INTO ci_runs (
-			project_id, repo_full_name, workflow_name, workflow_ref,
-			github_run_id, github_run_number, run_url, sha, branch, event, pr_number,
+			project_id, provider, repo_full_name, workflow_name, workflow_ref,
+			run_id, run_number, run_url, sha, branch, event, pr_number,
+			github_run_id, github_run_number,
 			first_seen_at, last_seen_at
 		) VALUES (
-			$1, $2, $3, $4,
-			$5, $6, $7, $8, $9, $10::ci_event, $11,
+			$1, $2::ci_provider, $3, $4, $5,
+			$6, $7, $8, $9, $10, $11::ci_event, $12,
+			$13, $14,
 			NOW(), NOW()
 		)
-		ON CONFLICT (project_id, repo_full_name, github_run_id)
+		ON CONFLICT (project_id, provider, repo_full_name, run_id)
 		DO UPDATE SET
 			workflow_name = EXCLUDED.workflow_name,
 			workflow_ref = EXCLUDED.workflow_ref,
+			run_number = EXCLUDED.run_number,
 			github_run_number = EXCLUDED.github_run_number,
 			run_url = EXCLUDED.run_url,
 			sha = EXCLUDED.sha,
@@ -185,38 +249,64 @@ func (s *PersistenceService) upsertCIRun(ctx context.Context, tx pgx.Tx, project
 	event := NormalizeEventType(meta.Event)
 	err := tx.QueryRow(ctx, query,
 		projectID,
+		meta.Provider,
 		meta.RepoFullName,
 		meta.WorkflowName,
 		meta.WorkflowRef,
-		meta.GitHubRunID,
-		meta.GitHubRunNumber,
+		meta.RunID,
+		meta.RunNumber,
 		meta.RunURL,
 		meta.SHA,
 		meta.Branch,
 		event,
 		meta.PRNumber,
+		// Deprecated columns, kept for GitHub runs
+		flake.GitHubRunID(meta.Provider, meta.RunID),
+		flake.GitHubRunID(meta.Provider, meta.RunNumber),
 	).Scan(&ciRunID)
 
 	return ciRunID, err
 }
 
-func (s *PersistenceService) upsertCIRunAttempt(
-	ctx context.Context,
-	tx pgx.Tx,
-	ciRunID uuid.UUID,
-	attemptNumber int,
-	startedAt, completedAt time.Time,
-) (uuid.UUID, error) {
+// upsertCIRunAttempt records the attempt of an ingestion. Without run_attempt
+// the attempt is looked up by attempt_id, or numbered after the run's latest
+// attempt. The ci_runs row locked by upsertCIRun keeps concurrent ingestions
+// of the same run from taking the same number.
+func (s *PersistenceService) upsertCIRunAttempt(ctx context.Context, tx pgx.Tx, ciRunID uuid.UUID, meta *IngestionMetadata) (uuid.UUID, error) {
 	var ciRunAttemptID uuid.UUID
+	startedAt, completedAt := meta.StartedAtTime(), meta.CompletedAtTime()
+
+	if meta.RunAttempt > 0 {
+		query := `
+			INSERT INTO ci_run_attempts (ci_run_id, attempt_number, started_at, completed_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (ci_run_id, attempt_number)
+			DO UPDATE SET started_at = EXCLUDED.started_at, completed_at = EXCLUDED.completed_at
+			RETURNING id
+		`
+		err := tx.QueryRow(ctx, query, ciRunID, meta.RunAttempt, startedAt, completedAt).Scan(&ciRunAttemptID)
+		return ciRunAttemptID, err
+	}
+
 	query := `
-		INSERT INTO ci_run_attempts (ci_run_id, attempt_number, started_at, completed_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (ci_run_id, attempt_number)
-		DO UPDATE SET started_at = EXCLUDED.started_at, completed_at = EXCLUDED.completed_at
+		UPDATE ci_run_attempts
+		SET started_at = $3, completed_at = $4
+		WHERE ci_run_id = $1 AND attempt_id = $2
 		RETURNING id
 	`
+	err := tx.QueryRow(ctx, query, ciRunID, meta.AttemptID, startedAt, completedAt).Scan(&ciRunAttemptID)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return ciRunAttemptID, err
+	}
 
-	err := tx.QueryRow(ctx, query, ciRunID, attemptNumber, startedAt, completedAt).Scan(&ciRunAttemptID)
+	query = `
+		INSERT INTO ci_run_attempts (ci_run_id, attempt_number, attempt_id, started_at, completed_at)
+		SELECT $1, COALESCE(MAX(attempt_number), 0) + 1, $2, $3, $4
+		FROM ci_run_attempts
+		WHERE ci_run_id = $1
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query, ciRunID, meta.AttemptID, startedAt, completedAt).Scan(&ciRunAttemptID)
 	return ciRunAttemptID, err
 }
 
@@ -243,46 +333,118 @@ func (s *PersistenceService) storeJUnitFile(ctx context.Context, tx pgx.Tx, inge
 	return err
 }
 
-func (s *PersistenceService) upsertTestCase(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, meta *IngestionMetadata, result TestResult) (uuid.UUID, error) {
-	var testCaseID uuid.UUID
+// upsertTestCases upserts the test cases of results and assigns their
+// owners. A test reported without a file path keeps the path, and so the
+// owners, it was last reported with.
+func (s *PersistenceService) upsertTestCases(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, meta *IngestionMetadata, owners *ownership.Matcher, results []TestResult) ([]uuid.UUID, error) {
 	query := `
-		INSERT INTO test_cases (project_id, repo_full_name, job_name, job_variant, test_identifier)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO test_cases (project_id, repo_full_name, job_name, job_variant, test_identifier, file_path, owners)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (project_id, repo_full_name, job_name, job_variant, test_identifier)
-		DO UPDATE SET last_seen_at = NOW()
+		DO UPDATE SET
+			last_seen_at = NOW(),
+			file_path = COALESCE(EXCLUDED.file_path, test_cases.file_path),
+			owners = CASE
+				WHEN EXCLUDED.file_path IS NULL AND test_cases.file_path IS NOT NULL THEN test_cases.owners
+				ELSE EXCLUDED.owners
+			END
 		RETURNING id
 	`
 
-	err := tx.QueryRow(ctx, query,
-		projectID,
-		meta.RepoFullName,
-		meta.JobName,
-		meta.JobVariant,
-		result.TestIdentifier,
-	).Scan(&testCaseID)
+	batch := &pgx.Batch{}
+	for _, result := range results {
+		filePath := ownership.NormalizePath(result.FilePath)
+		batch.Queue(query,
+			projectID,
+			meta.RepoFullName,
+			meta.JobName,
+			meta.JobVariant,
+			result.TestIdentifier,
+			nullString(filePath),
+			owners.Owners(filePath, result.Classname),
+		)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
 
-	return testCaseID, err
+	testCaseIDs := make([]uuid.UUID, len(results))
+	for i := range results {
+		if err := br.QueryRow().Scan(&testCaseIDs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return testCaseIDs, br.Close()
 }
 
-func (s *PersistenceService) insertTestResult(ctx context.Context, tx pgx.Tx, testCaseID, ciJobID uuid.UUID, result TestResult) (bool, error) {
+// resultFailure returns the failure of a result to cluster by signature: the
+// failure of a failed or errored result, or the first failed in-job rerun of
+// a passed one
+func resultFailure(testCaseID uuid.UUID, result *TestResult) (signatures.Failure, bool) {
+	switch {
+	case result.Status == "failed" || result.Status == "error":
+		return signatures.Failure{TestCaseID: testCaseID, Message: result.FailureMessage, Output: result.FailureOutput}, true
+	case result.RerunFailures > 0 && result.RerunMessage != "":
+		return signatures.Failure{TestCaseID: testCaseID, Message: result.RerunMessage}, true
+	}
+	return signatures.Failure{}, false
+}
+
+// insertTestResults inserts results[i] for testCaseIDs[i] and returns the
+// indexes of the results whose row was new.
+func (s *PersistenceService) insertTestResults(ctx context.Context, tx pgx.Tx, testCaseIDs []uuid.UUID, ciJobID uuid.UUID, results []TestResult) ([]int, error) {
 	query := `
-		INSERT INTO test_results (test_case_id, ci_job_id, status, duration_ms, failure_message, failure_output)
-		VALUES ($1, $2, $3::test_status, $4, $5, $6)
+		INSERT INTO test_results (
+			test_case_id, ci_job_id, status, duration_ms, failure_message, failure_output,
+			system_out, system_err, properties, rerun_failures, rerun_failure_message
+		)
+		VALUES ($1, $2, $3::test_status, $4, $5, $6, $7, $8, $9::jsonb, $10, $11)
 		ON CONFLICT (test_case_id, ci_job_id) DO NOTHING
 	`
 
-	tag, err := tx.Exec(ctx, query,
-		testCaseID,
-		ciJobID,
-		result.Status,
-		nullInt(result.DurationMS),
-		nullString(result.FailureMessage),
-		nullString(result.FailureOutput),
-	)
-	if err != nil {
-		return false, err
+	batch := &pgx.Batch{}
+	for i, result := range results {
+		var properties *string
+		if len(result.Properties) > 0 {
+			b, err := json.Marshal(result.Properties)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal properties: %w", err)
+			}
+			p := string(b)
+			properties = &p
+		}
+
+		batch.Queue(query,
+			testCaseIDs[i],
+			ciJobID,
+			result.Status,
+			nullInt(result.DurationMS),
+			nullString(result.FailureMessage),
+			nullString(result.FailureOutput),
+			nullString(result.SystemOut),
+			nullString(result.SystemErr),
+			properties,
+			result.RerunFailures,
+			nullString(result.RerunMessage),
+		)
 	}
-	return tag.RowsAffected() > 0, nil
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	var inserted []int
+	for i := range results {
+		tag, err := br.Exec()
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() > 0 {
+			inserted = append(inserted, i)
+		}
+	}
+
+	return inserted, br.Close()
 }
 
 func nullString(s string) *string {
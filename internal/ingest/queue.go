@@ -0,0 +1,386 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/jobqueue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxLastErrorBytes = 2048
+
+	// payloadChunkBytes is the size of the rows a report file is split into
+	payloadChunkBytes = 256 * 1024
+)
+
+// Ingestion statuses
+const (
+	StatusQueued     = "queued"
+	StatusProcessing = "processing"
+	StatusSucceeded  = "succeeded"
+	StatusFailed     = "failed"
+)
+
+var ErrIngestionNotFound = errors.New("ingestion not found")
+
+// ingestionJobs leases ingestions to workers. A claimed ingestion may stay
+// processing for 15 minutes before another worker assumes its worker died.
+var ingestionJobs = &jobqueue.Table{
+	Name:     "ingestions",
+	Noun:     "ingestion",
+	Pending:  StatusQueued,
+	Running:  StatusProcessing,
+	Failed:   StatusFailed,
+	Lease:    15 * time.Minute,
+	Backoff:  jobqueue.Backoff{Base: 30 * time.Second, Max: 15 * time.Minute},
+	ClaimSet: "started_at = COALESCE(started_at, NOW())",
+}
+
+// Queue is the Postgres-backed ingestion job queue. Each queued ingestion
+// keeps its raw report files in ingestion_payloads, split into
+// ingestion_payload_chunks, until it succeeds.
+type Queue struct {
+	pool *pgxpool.Pool
+}
+
+func NewQueue(pool *pgxpool.Pool) *Queue {
+	return &Queue{pool: pool}
+}
+
+// QueuedFile is a raw report file of an upload
+type QueuedFile struct {
+	Filename string
+	Format   ReportFormat // empty = auto-detect
+	Content  io.Reader
+}
+
+// Job is a claimed ingestion
+type Job struct {
+	ID          uuid.UUID
+	ProjectID   uuid.UUID
+	Meta        IngestionMetadata
+	Attempts    int // including the current one
+	MaxAttempts int
+	CIRunID     *uuid.UUID // set once results are stored
+	ReceivedAt  time.Time
+	Codeowners  *string // CODEOWNERS sent with the upload, applied with its results
+}
+
+// payloadFile identifies a stored report file without its content
+type payloadFile struct {
+	ID       uuid.UUID
+	Filename string
+	Format   ReportFormat
+}
+
+// IngestionStatus is the API representation of a queued or processed ingestion
+type IngestionStatus struct {
+	IngestionID        uuid.UUID             `json:"ingestion_id"`
+	Status             string                `json:"status"`
+	Attempts           int                   `json:"attempts"`
+	ReceivedAt         time.Time             `json:"received_at"`
+	StartedAt          *time.Time            `json:"started_at"`
+	CompletedAt        *time.Time            `json:"completed_at"`
+	NextAttemptAt      *time.Time            `json:"next_attempt_at,omitempty"`
+	Error              *string               `json:"error,omitempty"`
+	ErrorCode          *string               `json:"error_code,omitempty"`
+	Stored             IngestionStoredCounts `json:"stored"`
+	FlakeEventsCreated int                   `json:"flake_events_created"`
+}
+
+// Enqueue stores an upload as a queued ingestion and returns its ID.
+// codeowners is the CODEOWNERS file sent with the upload, if any.
+func (q *Queue) Enqueue(ctx context.Context, projectID, apiKeyID uuid.UUID, meta *IngestionMetadata, files []QueuedFile, codeowners *string) (uuid.UUID, error) {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal meta: %w", err)
+	}
+
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var ingestionID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ingestions (project_id, api_key_id, meta, status, codeowners)
+		VALUES ($1, $2, $3::jsonb, 'queued', $4)
+		RETURNING id
+	`, projectID, apiKeyID, string(metaJSON), codeowners).Scan(&ingestionID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create ingestion: %w", err)
+	}
+
+	buf := make([]byte, payloadChunkBytes)
+	for i, file := range files {
+		var payloadID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO ingestion_payloads (ingestion_id, position, filename, format)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, ingestionID, i, file.Filename, string(file.Format)).Scan(&payloadID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to store payload: %w", err)
+		}
+
+		if err := storePayloadChunks(ctx, tx, payloadID, file.Content, buf); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to store payload %s: %w", file.Filename, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ingestionID, nil
+}
+
+// storePayloadChunks copies a report file into ingestion_payload_chunks one
+// buffer at a time
+func storePayloadChunks(ctx context.Context, tx pgx.Tx, payloadID uuid.UUID, content io.Reader, buf []byte) error {
+	for seq := 0; ; seq++ {
+		n, err := io.ReadFull(content, buf)
+		if n > 0 {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO ingestion_payload_chunks (payload_id, seq, data)
+				VALUES ($1, $2, $3)
+			`, payloadID, seq, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Claim takes the oldest due ingestion, or one whose worker lease expired, and
+// marks it processing. Returns nil when nothing is due.
+func (q *Queue) Claim(ctx context.Context) (*Job, error) {
+	row, err := ingestionJobs.Claim(ctx, q.pool, "id, project_id, meta, attempts, max_attempts, ci_run_id, received_at, codeowners")
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	var metaJSON []byte
+	err = row.Scan(
+		&job.ID,
+		&job.ProjectID,
+		&metaJSON,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.CIRunID,
+		&job.ReceivedAt,
+		&job.Codeowners,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim ingestion: %w", err)
+	}
+
+	if err := json.Unmarshal(metaJSON, &job.Meta); err != nil {
+		return &job, fmt.Errorf("failed to decode meta: %w", err)
+	}
+	// Re-parses the timestamps, which are not serialized
+	if err := job.Meta.Validate(); err != nil {
+		return &job, fmt.Errorf("invalid stored meta: %w", err)
+	}
+
+	return &job, nil
+}
+
+// Succeed marks a job succeeded and drops its raw payloads and CODEOWNERS
+func (q *Queue) Succeed(ctx context.Context, ingestionID uuid.UUID, flakeEventsCount int) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `
+		UPDATE ingestions
+		SET status = 'succeeded',
+		    completed_at = NOW(),
+		    locked_at = NULL,
+		    last_error = NULL,
+		    error_code = NULL,
+		    flake_events_count = $2,
+		    codeowners = NULL
+		WHERE id = $1
+	`, ingestionID, flakeEventsCount)
+	if err != nil {
+		return fmt.Errorf("failed to mark ingestion succeeded: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM ingestion_payloads WHERE ingestion_id = $1`, ingestionID); err != nil {
+		return fmt.Errorf("failed to delete payloads: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Fail records a failed attempt. The job is retried with exponential backoff
+// unless the failure is permanent or it was the last attempt, in which case it
+// is dead-lettered as failed with its payloads kept. Returns whether the job
+// will be retried. code is recorded as the job's error_code, empty for none.
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error, code string, permanent bool) (bool, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	message := truncateString(cause.Error(), maxLastErrorBytes)
+	retrying, err := ingestionJobs.Fail(ctx, tx, job.ID, job.Attempts, job.MaxAttempts, message, permanent)
+	if err != nil {
+		return false, err
+	}
+
+	var errorCode *string
+	if code != "" {
+		errorCode = &code
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ingestions SET error_code = $2 WHERE id = $1`, job.ID, errorCode); err != nil {
+		return false, fmt.Errorf("failed to record error code: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return retrying, nil
+}
+
+// Release puts a job back in the queue without counting the attempt, for
+// workers that stop mid-job
+func (q *Queue) Release(ctx context.Context, ingestionID uuid.UUID) error {
+	return ingestionJobs.Release(ctx, q.pool, ingestionID)
+}
+
+// Get returns the status of an ingestion of a project
+func (q *Queue) Get(ctx context.Context, projectID, ingestionID uuid.UUID) (*IngestionStatus, error) {
+	query := `
+		SELECT id, status::text, attempts, received_at, started_at, completed_at,
+		       next_attempt_at, last_error, error_code, junit_files_count, test_results_count, flake_events_count
+		FROM ingestions
+		WHERE id = $1 AND project_id = $2
+	`
+
+	var st IngestionStatus
+	var nextAttemptAt time.Time
+	err := q.pool.QueryRow(ctx, query, ingestionID, projectID).Scan(
+		&st.IngestionID,
+		&st.Status,
+		&st.Attempts,
+		&st.ReceivedAt,
+		&st.StartedAt,
+		&st.CompletedAt,
+		&nextAttemptAt,
+		&st.Error,
+		&st.ErrorCode,
+		&st.Stored.JUnitFiles,
+		&st.Stored.TestResults,
+		&st.FlakeEventsCreated,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIngestionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Status == StatusQueued && st.Attempts > 0 {
+		st.NextAttemptAt = &nextAttemptAt
+	}
+	return &st, nil
+}
+
+// payloadFiles lists the stored report files of an ingestion in upload order
+func (q *Queue) payloadFiles(ctx context.Context, ingestionID uuid.UUID) ([]payloadFile, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT id, filename, format
+		FROM ingestion_payloads
+		WHERE ingestion_id = $1
+		ORDER BY position
+	`, ingestionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []payloadFile
+	for rows.Next() {
+		var f payloadFile
+		var format string
+		if err := rows.Scan(&f.ID, &f.Filename, &format); err != nil {
+			return nil, err
+		}
+		f.Format = ReportFormat(format)
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// payloadReader streams the content of one stored report file, loading one
+// chunk at a time. Load errors are kept in err so the worker can tell them
+// apart from reports that fail to parse.
+type payloadReader struct {
+	ctx       context.Context
+	pool      *pgxpool.Pool
+	payloadID uuid.UUID
+	seq       int
+	chunk     []byte
+	done      bool
+	err       error
+}
+
+func (q *Queue) payloadReader(ctx context.Context, payloadID uuid.UUID) *payloadReader {
+	return &payloadReader{ctx: ctx, pool: q.pool, payloadID: payloadID}
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	for len(p.chunk) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+		if p.done {
+			return 0, io.EOF
+		}
+
+		err := p.pool.QueryRow(p.ctx, `
+			SELECT data FROM ingestion_payload_chunks WHERE payload_id = $1 AND seq = $2
+		`, p.payloadID, p.seq).Scan(&p.chunk)
+		if errors.Is(err, pgx.ErrNoRows) {
+			p.done = true
+			continue
+		}
+		if err != nil {
+			p.err = fmt.Errorf("failed to load payload chunk %d: %w", p.seq, err)
+			return 0, p.err
+		}
+		p.seq++
+	}
+
+	n := copy(b, p.chunk)
+	p.chunk = p.chunk[n:]
+	return n, nil
+}
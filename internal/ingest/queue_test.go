@@ -0,0 +1,53 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 30*time.Second, ingestionJobs.Backoff.Delay(1))
+	require.Equal(t, time.Minute, ingestionJobs.Backoff.Delay(2))
+	require.Equal(t, 2*time.Minute, ingestionJobs.Backoff.Delay(3))
+	require.Equal(t, 15*time.Minute, ingestionJobs.Backoff.Delay(6))
+	require.Equal(t, 15*time.Minute, ingestionJobs.Backoff.Delay(100))
+}
+
+func TestOpenReport_DecompressesGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("<testsuite/>"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	r, err := openReport(bytes.NewReader(buf.Bytes()), 1024)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "<testsuite/>", string(content))
+
+	r, err = openReport(bytes.NewReader(buf.Bytes()), 4)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.ErrorContains(t, err, "exceeds 4 bytes")
+
+	r, err = openReport(strings.NewReader("<testsuite/>"), 4)
+	require.NoError(t, err)
+	content, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "<testsuite/>", string(content))
+}
+
+func TestOpenReport_ShortContent(t *testing.T) {
+	r, err := openReport(strings.NewReader("x"), 4)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "x", string(content))
+}
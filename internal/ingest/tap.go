@@ -0,0 +1,152 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tapPlanRe     = regexp.MustCompile(`^1\.\.\d+`)
+	tapTestLineRe = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(.*))?$`)
+	tapSummaryRe  = regexp.MustCompile(`^#\s*(tests|pass|fail|skip|todo|ok)\b`)
+)
+
+// ParseTAP parses a TAP (Test Anything Protocol) stream. Only top-level test
+// lines are recorded; indented subtest output is ignored. TAP has no class
+// name, so the most recent top-level comment (the test group header emitted
+// by tape and similar runners) is used as the classname. "# SKIP" and
+// failing "# TODO" tests are recorded as skipped. The YAML diagnostics block
+// after a failing test becomes its failure output.
+func ParseTAP(r io.Reader) ([]TestResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var results []TestResult
+	group := ""
+	sawTAP := false
+
+	// Index of the last failing test, which owns a following YAML diagnostics block
+	diag := -1
+	inYAML := false
+	var yaml strings.Builder
+
+	flushYAML := func() {
+		if diag >= 0 && yaml.Len() > 0 {
+			output := yaml.String()
+			results[diag].FailureOutput = truncateString(output, 8192)
+			if msg := tapYAMLValue(output, "message"); msg != "" {
+				results[diag].FailureMessage = truncateString(msg, 1024)
+			}
+			if ms := tapYAMLValue(output, "duration_ms"); ms != "" {
+				if f, err := strconv.ParseFloat(ms, 64); err == nil {
+					results[diag].DurationMS = int(f)
+				}
+			}
+		}
+		yaml.Reset()
+		inYAML = false
+	}
+
+	for scanner.Scan() {
+		raw := scanner.Text()
+
+		if inYAML {
+			if strings.TrimSpace(raw) == "..." {
+				flushYAML()
+			} else {
+				yaml.WriteString(strings.TrimPrefix(strings.TrimPrefix(raw, "  "), "\t"))
+				yaml.WriteString("\n")
+			}
+			continue
+		}
+
+		indented := strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")
+		line := strings.TrimSpace(raw)
+
+		if indented {
+			if line == "---" && diag >= 0 {
+				inYAML = true
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "TAP version"), tapPlanRe.MatchString(line):
+			sawTAP = true
+		case strings.HasPrefix(line, "Bail out!"):
+			sawTAP = true
+			diag = -1
+		case strings.HasPrefix(line, "#"):
+			if !tapSummaryRe.MatchString(line) && !strings.HasPrefix(line, "# Subtest") {
+				group = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			}
+		default:
+			m := tapTestLineRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			sawTAP = true
+
+			ok := m[1] == ""
+			name := strings.TrimSpace(m[3])
+			if name == "" {
+				name = "test " + m[2]
+			}
+			directive := strings.ToUpper(strings.TrimSpace(m[4]))
+
+			result := TestResult{
+				Classname:      group,
+				Name:           name,
+				TestIdentifier: deriveTestIdentifier(group, name),
+			}
+			switch {
+			case strings.HasPrefix(directive, "SKIP"):
+				result.Status = "skipped"
+			case strings.HasPrefix(directive, "TODO"):
+				// Failing TODO tests are expected failures
+				result.Status = "skipped"
+				if ok {
+					result.Status = "passed"
+				}
+			case ok:
+				result.Status = "passed"
+			default:
+				result.Status = "failed"
+				result.FailureMessage = truncateString(name, 1024)
+			}
+
+			results = append(results, result)
+			diag = -1
+			if result.Status == "failed" {
+				diag = len(results) - 1
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read TAP: %w", err)
+	}
+	flushYAML()
+
+	if !sawTAP {
+		return nil, fmt.Errorf("failed to parse TAP: no plan or test lines found")
+	}
+
+	return results, nil
+}
+
+// tapYAMLValue returns the scalar value of a top-level key in a TAP YAML block
+func tapYAMLValue(block, key string) string {
+	for _, line := range strings.Split(block, "\n") {
+		if strings.HasPrefix(line, key+":") {
+			value := strings.TrimSpace(strings.TrimPrefix(line, key+":"))
+			return strings.Trim(value, `'"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// trxTestRun is the root of a Visual Studio TRX report (dotnet test --logger trx)
+type trxTestRun struct {
+	XMLName     xml.Name            `xml:"TestRun"`
+	Definitions []trxUnitTest       `xml:"TestDefinitions>UnitTest"`
+	Results     []trxUnitTestResult `xml:"Results>UnitTestResult"`
+}
+
+type trxUnitTest struct {
+	ID         string `xml:"id,attr"`
+	Name       string `xml:"name,attr"`
+	TestMethod struct {
+		ClassName string `xml:"className,attr"`
+		Name      string `xml:"name,attr"`
+	} `xml:"TestMethod"`
+}
+
+type trxUnitTestResult struct {
+	TestID   string `xml:"testId,attr"`
+	TestName string `xml:"testName,attr"`
+	Duration string `xml:"duration,attr"`
+	Outcome  string `xml:"outcome,attr"`
+	Message  string `xml:"Output>ErrorInfo>Message"`
+	Stack    string `xml:"Output>ErrorInfo>StackTrace"`
+}
+
+// ParseTRX parses a TRX report. The class comes from the matching
+// TestDefinitions entry; data-driven rows keep their display name
+// (e.g. "Add(a: 1, b: 2)") so each row is a distinct test.
+func ParseTRX(r io.Reader) ([]TestResult, error) {
+	var run trxTestRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, fmt.Errorf("failed to parse TRX: %w", err)
+	}
+
+	classByTestID := make(map[string]string, len(run.Definitions))
+	for _, def := range run.Definitions {
+		className := def.TestMethod.ClassName
+		// Older TRX files use an assembly-qualified class name
+		if i := strings.Index(className, ","); i >= 0 {
+			className = className[:i]
+		}
+		classByTestID[def.ID] = strings.TrimSpace(className)
+	}
+
+	results := make([]TestResult, 0, len(run.Results))
+	for _, res := range run.Results {
+		classname := classByTestID[res.TestID]
+		name := strings.TrimPrefix(res.TestName, classname+".")
+
+		result := TestResult{
+			Classname:      classname,
+			Name:           name,
+			TestIdentifier: deriveTestIdentifier(classname, name),
+			DurationMS:     parseTRXDuration(res.Duration),
+		}
+
+		switch res.Outcome {
+		case "Passed", "PassedButRunAborted", "Warning":
+			result.Status = "passed"
+		case "Failed":
+			result.Status = "failed"
+		case "Error", "Timeout", "Aborted":
+			result.Status = "error"
+		default:
+			// NotExecuted, Inconclusive, Pending, Disconnected, ...
+			result.Status = "skipped"
+		}
+
+		if result.Status == "failed" || result.Status == "error" {
+			result.FailureMessage = truncateString(strings.TrimSpace(res.Message), 1024)
+			result.FailureOutput = truncateString(strings.TrimSpace(res.Stack), 8192)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// parseTRXDuration converts a TRX "hh:mm:ss.fffffff" duration to milliseconds
+func parseTRXDuration(d string) int {
+	parts := strings.Split(d, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0
+	}
+	return int((float64(hours*3600+minutes*60) + seconds) * 1000)
+}
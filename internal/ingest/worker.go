@@ -0,0 +1,365 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/github"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultPollInterval is how often an idle worker checks the queue
+	DefaultPollInterval = 2 * time.Second
+
+	// githubReportTimeout bounds reporting a pull request run back to GitHub
+	githubReportTimeout = 30 * time.Second
+
+	maxStoredJUnitContentBytes = 64 * 1024
+
+	// maxGzipExpansion bounds how much larger than FG_MAX_FILE_BYTES a
+	// gzip-compressed report may be once decompressed
+	maxGzipExpansion = 100
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// permanentError marks a job failure that retrying cannot fix
+type permanentError struct {
+	err error
+	// code is the ingestion's error_code, if the failure has one
+	code string
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Worker processes queued ingestions: it parses the stored reports, persists
+// the results and runs flake detection.
+type Worker struct {
+	queue        *Queue
+	persistence  *PersistenceService
+	detector     *flake.Detector
+	PollInterval time.Duration
+
+	// GitHubReporter reports pull request flakes back to GitHub; nil when no
+	// GitHub App is configured
+	GitHubReporter *github.Reporter
+
+	maxReportBytes int64 // decompressed size limit of gzip reports
+}
+
+func NewWorker(pool *pgxpool.Pool, cfg *config.Config) *Worker {
+	return &Worker{
+		queue:        NewQueue(pool),
+		persistence:  NewPersistenceService(pool, cfg),
+		detector:     flake.NewDetectorWithNotifications(pool, cfg),
+		PollInterval: DefaultPollInterval,
+
+		GitHubReporter: github.NewReporter(pool, cfg),
+
+		maxReportBytes: cfg.MaxFileBytes * maxGzipExpansion,
+	}
+}
+
+// Run processes jobs until ctx is cancelled, sleeping PollInterval whenever
+// the queue is empty. A job interrupted by cancellation is put back.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		processed, err := w.processNextRecovered(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Ingestion worker error")
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// processNextRecovered keeps a panicking job from killing the worker. The job
+// stays processing and is reclaimed when its lease expires.
+func (w *Worker) processNextRecovered(ctx context.Context) (processed bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			processed, err = true, fmt.Errorf("ingestion worker panicked: %v", r)
+		}
+	}()
+	return w.ProcessNext(ctx)
+}
+
+// ProcessNext claims and processes one job. Returns false when no job was due.
+// Job failures are recorded on the job; the returned error only reports
+// failures to talk to the queue.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := w.queue.Claim(ctx)
+	if job == nil {
+		return false, err
+	}
+	if err == nil {
+		err = w.process(ctx, job)
+	} else {
+		// The stored job itself is unusable
+		err = &permanentError{err: err}
+	}
+
+	if err == nil {
+		return true, nil
+	}
+
+	if ctx.Err() != nil {
+		if releaseErr := w.queue.Release(context.WithoutCancel(ctx), job.ID); releaseErr != nil {
+			return true, fmt.Errorf("failed to release ingestion %s: %w", job.ID, releaseErr)
+		}
+		return true, nil
+	}
+
+	var perm *permanentError
+	permanent := errors.As(err, &perm)
+	code := ""
+	if permanent {
+		code = perm.code
+	}
+	retrying, failErr := w.queue.Fail(ctx, job, err, code, permanent)
+	if failErr != nil {
+		return true, failErr
+	}
+
+	log.Warn().
+		Err(err).
+		Str("ingestion_id", job.ID.String()).
+		Int("attempt", job.Attempts).
+		Bool("retrying", retrying).
+		Msg("Ingestion failed")
+	return true, nil
+}
+
+func (w *Worker) process(ctx context.Context, job *Job) error {
+	result := &IngestionResult{IngestionID: job.ID}
+	if job.CIRunID != nil {
+		// Results were stored by an earlier attempt that failed in detection
+		result.CIRunID = *job.CIRunID
+	} else {
+		var err error
+		result, err = w.store(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	flakeEventsCount, err := w.detector.DetectFlakes(ctx, job.ProjectID, result.CIRunID)
+	if err != nil {
+		return fmt.Errorf("flake detection failed: %w", err)
+	}
+
+	if err := w.queue.Succeed(ctx, job.ID, flakeEventsCount); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("ingestion_id", job.ID.String()).
+		Str("project_id", job.ProjectID.String()).
+		Str("project_slug", job.Meta.ProjectSlug).
+		Str("repo_full_name", job.Meta.RepoFullName).
+		Str("provider", job.Meta.Provider).
+		Str("run_id", job.Meta.RunID).
+		Int("run_attempt", job.Meta.RunAttempt).
+		Str("job_name", job.Meta.JobName).
+		Str("job_variant", job.Meta.JobVariant).
+		Int("attempt", job.Attempts).
+		Int("stored_test_results", result.TestResultsCount).
+		Int("stored_junit_files", result.JUnitFilesCount).
+		Int("flake_events_created", flakeEventsCount).
+		Msg("Ingestion successful")
+
+	if w.GitHubReporter != nil {
+		w.reportToGitHub(ctx, job, result.CIRunID)
+	}
+
+	return nil
+}
+
+// reportToGitHub publishes the pull request report of a processed run. The
+// ingestion already succeeded, so failures are only logged.
+func (w *Worker) reportToGitHub(ctx context.Context, job *Job, ciRunID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), githubReportTimeout)
+	defer cancel()
+
+	if _, err := w.GitHubReporter.ReportRun(ctx, job.ProjectID, ciRunID); err != nil {
+		log.Warn().
+			Err(err).
+			Str("ingestion_id", job.ID.String()).
+			Str("project_id", job.ProjectID.String()).
+			Str("repo_full_name", job.Meta.RepoFullName).
+			Msg("Failed to report flakes to GitHub")
+	}
+}
+
+// store parses the job's reports into one transaction. Reports that fail to
+// parse fail the job permanently.
+func (w *Worker) store(ctx context.Context, job *Job) (*IngestionResult, error) {
+	files, err := w.queue.payloadFiles(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payloads: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, &permanentError{err: errors.New("ingestion has no report files")}
+	}
+
+	writer, err := w.persistence.BeginIngestion(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	defer writer.Rollback(ctx)
+
+	for _, f := range files {
+		content := w.queue.payloadReader(ctx, f.ID)
+
+		report, err := openReport(content, w.maxReportBytes)
+		if content.err != nil {
+			return nil, fmt.Errorf("failed to load payload %s: %w", f.Filename, content.err)
+		}
+		if err != nil {
+			return nil, &permanentError{err: fmt.Errorf("failed to decompress file '%s': %w", f.Filename, err)}
+		}
+
+		junitFile, parser, err := streamReportFile(ctx, writer, report, f.Format)
+		if content.err != nil {
+			return nil, fmt.Errorf("failed to load payload %s: %w", f.Filename, content.err)
+		}
+		var storeErr *storeError
+		if errors.As(err, &storeErr) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, &permanentError{
+				err:  fmt.Errorf("failed to parse %s in file '%s': %w", parser.Description, f.Filename, err),
+				code: parser.ErrorCode,
+			}
+		}
+
+		junitFile.Filename = f.Filename
+		if err := writer.AddFile(ctx, junitFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return writer.Commit(ctx)
+}
+
+// openReport returns a reader over a stored report, transparently
+// decompressing gzip uploads
+func openReport(content io.Reader, maxBytes int64) (io.Reader, error) {
+	br := bufio.NewReader(content)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &cappedReader{r: gz, remaining: maxBytes, max: maxBytes}, nil
+}
+
+// cappedReader fails once more than max bytes are read, unlike
+// io.LimitReader which silently truncates
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		// Only an error if there is more to read
+		var probe [1]byte
+		if n, _ := c.r.Read(probe[:]); n > 0 {
+			return 0, fmt.Errorf("report exceeds %d bytes when decompressed", c.max)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// storeError marks a failure to persist results while a report was streaming,
+// as opposed to the report failing to parse
+type storeError struct {
+	err error
+}
+
+func (e *storeError) Error() string { return e.err.Error() }
+func (e *storeError) Unwrap() error { return e.err }
+
+// reportCapture hashes and measures a report as it is read and keeps its
+// first maxStoredJUnitContentBytes for storage
+type reportCapture struct {
+	hash    hash.Hash
+	size    int64
+	content []byte
+}
+
+func (c *reportCapture) Write(b []byte) (int, error) {
+	c.hash.Write(b)
+	c.size += int64(len(b))
+	if room := maxStoredJUnitContentBytes - len(c.content); room > 0 {
+		c.content = append(c.content, b[:min(room, len(b))]...)
+	}
+	return len(b), nil
+}
+
+// streamReportFile parses one uploaded report straight into the ingestion
+// writer without buffering it whole. Persistence failures are returned as
+// *storeError. The returned file has no Filename set.
+func streamReportFile(ctx context.Context, writer *IngestionWriter, f io.Reader, format ReportFormat) (JUnitFile, ReportParser, error) {
+	capture := &reportCapture{hash: sha256.New()}
+	tee := io.TeeReader(f, capture)
+
+	parser, err := StreamReport(format, tee, func(result TestResult) error {
+		if err := writer.AddTestResult(ctx, result); err != nil {
+			return &storeError{err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return JUnitFile{}, parser, err
+	}
+
+	// Hash trailing content the parser did not need to read
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return JUnitFile{}, parser, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	return JUnitFile{
+		SHA256:           fmt.Sprintf("%x", capture.hash.Sum(nil)),
+		SizeBytes:        int(capture.size),
+		ContentTruncated: capture.size > maxStoredJUnitContentBytes,
+		Content:          capture.content,
+	}, parser, nil
+}
@@ -0,0 +1,103 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xunitAssembly is an <assembly> element of an xUnit.net v2 XML report
+type xunitAssembly struct {
+	Name        string            `xml:"name,attr"`
+	Collections []xunitCollection `xml:"collection"`
+}
+
+type xunitCollection struct {
+	Tests []xunitTest `xml:"test"`
+}
+
+type xunitTest struct {
+	Name    string  `xml:"name,attr"`
+	Type    string  `xml:"type,attr"`
+	Method  string  `xml:"method,attr"`
+	Time    float64 `xml:"time,attr"`
+	Result  string  `xml:"result,attr"`
+	Failure *struct {
+		ExceptionType string `xml:"exception-type,attr"`
+		Message       string `xml:"message"`
+		StackTrace    string `xml:"stack-trace"`
+	} `xml:"failure"`
+}
+
+// ParseXUnit parses an xUnit.net v2 XML report with either an <assemblies>
+// or a single <assembly> root. Theory rows keep their display name
+// (e.g. "Add(a: 1, b: 2)") so each row is a distinct test.
+func ParseXUnit(r io.Reader) ([]TestResult, error) {
+	decoder := xml.NewDecoder(r)
+
+	var assemblies []xunitAssembly
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse xUnit XML: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "assembly" {
+			continue
+		}
+
+		var assembly xunitAssembly
+		if err := decoder.DecodeElement(&assembly, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse xUnit XML: %w", err)
+		}
+		assemblies = append(assemblies, assembly)
+	}
+
+	if len(assemblies) == 0 {
+		return nil, fmt.Errorf("failed to parse xUnit XML: no <assembly> elements")
+	}
+
+	var results []TestResult
+	for _, assembly := range assemblies {
+		for _, collection := range assembly.Collections {
+			for _, test := range collection.Tests {
+				name := strings.TrimPrefix(test.Name, test.Type+".")
+
+				result := TestResult{
+					Classname:      test.Type,
+					Name:           name,
+					TestIdentifier: deriveTestIdentifier(test.Type, name),
+					DurationMS:     int(test.Time * 1000), // Convert seconds to milliseconds
+				}
+
+				switch test.Result {
+				case "Pass":
+					result.Status = "passed"
+				case "Fail":
+					result.Status = "failed"
+				default:
+					// Skip, NotRun
+					result.Status = "skipped"
+				}
+
+				if result.Status == "failed" && test.Failure != nil {
+					message := strings.TrimSpace(test.Failure.Message)
+					if message == "" {
+						message = test.Failure.ExceptionType
+					}
+					result.FailureMessage = truncateString(message, 1024)
+					result.FailureOutput = truncateString(strings.TrimSpace(test.Failure.StackTrace), 8192)
+				}
+
+				results = append(results, result)
+			}
+		}
+	}
+
+	return results, nil
+}
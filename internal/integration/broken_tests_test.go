@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_BrokenTestsOnDefaultBranch(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrf := newCSRFClient(t, srv.URL)
+	userID := signupAndLogin(t, client, srv.URL, csrf, "owner@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrf, "Acme", "acme")
+	project, err := projects.NewService(pool).Create(ctx, orgID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	projectBase := srv.URL + "/api/v1/projects/" + project.ID.String()
+	errEnv := doJSONExpectError(t, client, http.MethodPut, projectBase+"/broken-detection", csrf, http.StatusBadRequest, map[string]any{"consecutive_runs": 1})
+	require.Equal(t, "consecutive_runs must be between 2 and 50", errEnv.Error.Message)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunURL:       "https://github.com/acme/repo/actions/runs/1",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		RunAttempt:   1,
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+	ingestRun := func(runID, sha, branch, fixture string) {
+		meta.RunID, meta.RunNumber, meta.SHA, meta.Branch = runID, runID, sha, branch
+		ingestJUnit(t, srv.URL, token, meta, fixture)
+	}
+	listBroken := func(status string) []flake.BrokenTest {
+		var list struct {
+			BrokenTests []flake.BrokenTest `json:"broken_tests"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodGet, projectBase+"/broken-tests?status="+status, csrf, http.StatusOK, nil)
+		require.NoError(t, json.Unmarshal(env.Data, &list))
+		return list.BrokenTests
+	}
+
+	ingestRun("100", "aaa", "main", "flaky_attempt2.xml")
+	ingestRun("101", "bbb", "main", "flaky_attempt1.xml")
+	ingestRun("102", "ccc", "main", "flaky_attempt1.xml")
+	// Runs of other branches neither count towards nor break the streak
+	ingestRun("103", "fff", "feature", "flaky_attempt2.xml")
+	require.Empty(t, listBroken("all"), "two failing runs are below the default threshold")
+
+	ingestRun("104", "ddd", "main", "flaky_attempt1.xml")
+	open := listBroken("open")
+	require.Len(t, open, 1)
+	broken := open[0]
+	require.Equal(t, "com.example.FlakyTest#testFlaky", broken.TestIdentifier)
+	require.Equal(t, 3, broken.FailingRuns)
+	require.Equal(t, "bbb", broken.FirstBadSHA)
+	require.NotNil(t, broken.LastGoodSHA)
+	require.Equal(t, "aaa", *broken.LastGoodSHA)
+	require.NotNil(t, broken.CompareURL)
+	require.Equal(t, "https://github.com/acme/repo/compare/aaa...bbb", *broken.CompareURL)
+	require.Nil(t, broken.FixedAt)
+
+	// A further failure extends the open breakage
+	ingestRun("105", "eee", "main", "flaky_attempt1.xml")
+	open = listBroken("open")
+	require.Len(t, open, 1)
+	require.Equal(t, broken.ID, open[0].ID)
+	require.Equal(t, 4, open[0].FailingRuns)
+
+	ingestRun("106", "ggg", "main", "flaky_attempt2.xml")
+	require.Empty(t, listBroken("open"))
+	fixed := listBroken("fixed")
+	require.Len(t, fixed, 1)
+	require.NotNil(t, fixed[0].FixedAt)
+	require.NotNil(t, fixed[0].FixedSHA)
+	require.Equal(t, "ggg", *fixed[0].FixedSHA)
+
+	env := doJSONExpectSuccess(t, client, http.MethodPut, projectBase+"/broken-detection", csrf, http.StatusOK, map[string]any{"consecutive_runs": 2})
+	var configured struct {
+		BrokenDetection projects.BrokenDetectionConfig `json:"broken_detection"`
+	}
+	require.NoError(t, json.Unmarshal(env.Data, &configured))
+	require.Equal(t, 2, configured.BrokenDetection.ConsecutiveRuns)
+
+	ingestRun("107", "hhh", "main", "flaky_attempt1.xml")
+	ingestRun("108", "iii", "main", "flaky_attempt1.xml")
+	open = listBroken("open")
+	require.Len(t, open, 1, "a later streak opens a new breakage")
+	require.NotEqual(t, broken.ID, open[0].ID)
+	require.Equal(t, "ggg", *open[0].LastGoodSHA)
+	require.Len(t, listBroken("all"), 2)
+}
@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/dashboard"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_OrgDashboardAggregatesProjects(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	ownerClient, ownerCSRF := newCSRFClient(t, srv.URL)
+	outsiderClient, outsiderCSRF := newCSRFClient(t, srv.URL)
+	ownerID := signupAndLogin(t, ownerClient, srv.URL, ownerCSRF, "owner@example.com", "password123")
+	signupAndLogin(t, outsiderClient, srv.URL, outsiderCSRF, "outsider@example.com", "password123")
+
+	orgID := createOrg(t, ownerClient, srv.URL, ownerCSRF, "Acme", "acme")
+	projectService := projects.NewService(pool)
+	flaky, err := projectService.Create(ctx, orgID, "Flaky", "flaky", "main", ownerID)
+	require.NoError(t, err)
+	_, err = projectService.Create(ctx, orgID, "Idle", "idle", "main", ownerID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, flaky.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, ownerID, nil)
+	require.NoError(t, err)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  flaky.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunID:        "700",
+		RunNumber:    "7",
+		RunURL:       "https://github.example/runs/700",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	// One flaky run and one clean run
+	meta.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+	meta.RunAttempt = 2
+	require.Equal(t, 1, ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml").FlakeEventsCreated)
+	meta.RunID, meta.RunNumber, meta.SHA, meta.RunAttempt = "701", "8", "cafebabe", 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+
+	env := doJSONExpectSuccess(t, ownerClient, http.MethodGet, srv.URL+"/api/v1/orgs/"+orgID.String()+"/dashboard?days=7", ownerCSRF, http.StatusOK, nil)
+	var resp struct {
+		Dashboard dashboard.Dashboard `json:"dashboard"`
+	}
+	require.NoError(t, json.Unmarshal(env.Data, &resp))
+	d := resp.Dashboard
+
+	require.Equal(t, 7, d.Days)
+	require.Equal(t, []dashboard.Org{{ID: orgID, Name: "Acme", Slug: "acme"}}, d.Orgs)
+	require.Len(t, d.Trend, 7)
+	require.Equal(t, 2, d.Trend[6].Runs)
+	require.Equal(t, 1, d.Trend[6].FlakyTests)
+
+	require.Len(t, d.Projects, 2)
+	health := make(map[string]dashboard.ProjectHealth)
+	for _, p := range d.Projects {
+		health[p.Slug] = p
+	}
+	require.Equal(t, 2, health["flaky"].CIRuns)
+	require.Equal(t, 1, health["flaky"].FlakyCIRuns)
+	require.NotNil(t, health["flaky"].HealthScore)
+	require.Equal(t, 50, *health["flaky"].HealthScore)
+	require.Equal(t, 1, health["flaky"].FlakyTests)
+	require.InDelta(t, 0.5, health["flaky"].FlakeRate, 1e-9)
+	require.Empty(t, health["flaky"].Trend, "no runs in the period before")
+	require.Nil(t, health["idle"].HealthScore)
+
+	require.Len(t, d.TopFlakes, 1)
+	require.Equal(t, "com.example.FlakyTest#testFlaky", d.TopFlakes[0].TestIdentifier)
+	require.Equal(t, "flaky", d.TopFlakes[0].ProjectSlug)
+	require.Equal(t, "acme", d.TopFlakes[0].OrgSlug)
+
+	require.Len(t, d.Regressions, 1, "a test that never flaked before regressed")
+	require.Equal(t, 1, d.Regressions[0].FlakyRuns)
+	require.Zero(t, d.Regressions[0].BaselineRuns)
+
+	// The dashboard without an org covers every org of the user
+	otherOrgID := createOrg(t, ownerClient, srv.URL, ownerCSRF, "Other", "other")
+	_, err = projectService.Create(ctx, otherOrgID, "Other", "other", "main", ownerID)
+	require.NoError(t, err)
+
+	env = doJSONExpectSuccess(t, ownerClient, http.MethodGet, srv.URL+"/api/v1/dashboard?days=7", ownerCSRF, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &resp))
+	d = resp.Dashboard
+	require.Len(t, d.Orgs, 2)
+	require.Len(t, d.Projects, 3)
+	orgSlugs := make(map[string]string)
+	for _, p := range d.Projects {
+		orgSlugs[p.Slug] = p.OrgSlug
+	}
+	require.Equal(t, map[string]string{"flaky": "acme", "idle": "acme", "other": "other"}, orgSlugs)
+	require.Len(t, d.TopFlakes, 1)
+	require.Equal(t, 2, d.Trend[6].Runs)
+
+	env = doJSONExpectSuccess(t, outsiderClient, http.MethodGet, srv.URL+"/api/v1/dashboard", outsiderCSRF, http.StatusOK, nil)
+	var outsiderResp struct {
+		Dashboard dashboard.Dashboard `json:"dashboard"`
+	}
+	require.NoError(t, json.Unmarshal(env.Data, &outsiderResp))
+	require.Empty(t, outsiderResp.Dashboard.Orgs)
+	require.Empty(t, outsiderResp.Dashboard.Projects)
+
+	// Non-members don't see the org
+	errEnv := doJSONExpectError(t, outsiderClient, http.MethodGet, srv.URL+"/api/v1/orgs/"+orgID.String()+"/dashboard", outsiderCSRF, http.StatusNotFound, nil)
+	require.Equal(t, "not_found", errEnv.Error.Code)
+}
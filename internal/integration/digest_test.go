@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/digest"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_WeeklyDigestIsStoredAndDelivered(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		// The receiver listens on loopback
+		NotifyAllowPrivateURLs: true,
+		SessionDays:            7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrfToken := newCSRFClient(t, srv.URL)
+	signupAndLogin(t, client, srv.URL, csrfToken, "digest@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrfToken, "Acme", "acme")
+
+	projectResp := postJSONExpectStatus(t, client, srv.URL+"/api/v1/orgs/"+orgID.String()+"/projects", csrfToken, http.StatusCreated, map[string]any{
+		"name":           "Project",
+		"slug":           "my-project",
+		"default_branch": "main",
+	})
+	var project struct {
+		Project struct {
+			ID   uuid.UUID `json:"id"`
+			Slug string    `json:"slug"`
+		} `json:"project"`
+	}
+	require.NoError(t, json.Unmarshal(projectResp.Data, &project))
+	projectURL := srv.URL + "/api/v1/projects/" + project.Project.ID.String()
+
+	doJSONExpectError(t, client, http.MethodPut, projectURL+"/digest", csrfToken, http.StatusBadRequest, map[string]any{
+		"frequency": "hourly",
+	})
+	configured := doJSONExpectSuccess(t, client, http.MethodPut, projectURL+"/digest", csrfToken, http.StatusOK, map[string]any{
+		"frequency": "weekly",
+	})
+	require.JSONEq(t, `{"digest":{"frequency":"weekly"}}`, string(configured.Data))
+
+	doJSONExpectSuccess(t, client, http.MethodPost, projectURL+"/notification-channels", csrfToken, http.StatusCreated, map[string]any{
+		"type": "webhook",
+		"name": "digest-bot",
+		"url":  receiver.URL,
+	})
+
+	token := createAPIKey(t, client, srv.URL, csrfToken, project.Project.ID)
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunID:        "700",
+		RunNumber:    "7",
+		RunURL:       "https://github.example/runs/700",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-12 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+	}
+	meta.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+	meta.RunAttempt = 2
+	accepted := ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+	require.Equal(t, 1, accepted.FlakeEventsCreated)
+
+	// Flush the flake notification so only the digest is delivered below
+	startNotificationWorker(t, pool, cfg)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A week from now the digest of the week with the flake is due
+	nextWeek := time.Now().AddDate(0, 0, 7)
+	nextWeek = time.Date(nextWeek.Year(), nextWeek.Month(), nextWeek.Day(), digest.SendHour+1, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	require.NoError(t, digest.RunDigestJob(ctx, pool, cfg.BaseURL, nextWeek))
+	require.NoError(t, digest.RunDigestJob(ctx, pool, cfg.BaseURL, nextWeek), "generated once per period")
+
+	listed := doJSONExpectSuccess(t, client, http.MethodGet, projectURL+"/digests", csrfToken, http.StatusOK, nil)
+	var list struct {
+		Digests []digest.Digest `json:"digests"`
+	}
+	require.NoError(t, json.Unmarshal(listed.Data, &list))
+	require.Len(t, list.Digests, 1)
+	d := list.Digests[0]
+	require.Equal(t, "weekly", d.Frequency)
+	require.Equal(t, 1, d.Report.FlakyTests)
+	require.Len(t, d.Report.NewFlakes, 1)
+	require.Equal(t, "com.example.FlakyTest#testFlaky", d.Report.NewFlakes[0].TestIdentifier)
+	require.InDelta(t, 10, d.Report.RetryMinutesWasted, 0.1, "the failed first attempt ran 10 minutes")
+
+	got := doJSONExpectSuccess(t, client, http.MethodGet, projectURL+"/digests/"+d.ID.String(), csrfToken, http.StatusOK, nil)
+	require.Contains(t, string(got.Data), "com.example.FlakyTest#testFlaky")
+	doJSONExpectError(t, client, http.MethodGet, projectURL+"/digests/"+uuid.New().String(), csrfToken, http.StatusNotFound, nil)
+
+	preview := doJSONExpectSuccess(t, client, http.MethodGet, projectURL+"/digests/preview?frequency=daily", csrfToken, http.StatusOK, nil)
+	require.Contains(t, string(preview.Data), `"frequency":"daily"`)
+	doJSONExpectError(t, client, http.MethodGet, projectURL+"/digests/preview?frequency=hourly", csrfToken, http.StatusBadRequest, nil)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mu.Lock()
+	var payload struct {
+		Event  string         `json:"event"`
+		Digest *notify.Digest `json:"digest"`
+	}
+	require.NoError(t, json.Unmarshal(bodies[1], &payload))
+	mu.Unlock()
+	require.Equal(t, notify.EventDigest, payload.Event)
+	require.NotNil(t, payload.Digest)
+	require.Equal(t, 1, payload.Digest.FlakyTests)
+	require.Equal(t, "http://localhost/orgs/acme/projects/my-project/digests/"+d.ID.String(), payload.Digest.URL)
+}
@@ -43,6 +43,7 @@ func TestE2E_MinimalFlow_SignupToListFlakes(t *testing.T) {
 
 	srv := httptest.NewServer(app.NewRouter(pool, cfg))
 	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
 
 	jar, err := cookiejar.New(nil)
 	require.NoError(t, err)
@@ -246,5 +247,8 @@ func postIngest(t *testing.T, client *http.Client, baseURL, apiKeyToken string,
 	require.NotEmpty(t, env.RequestID)
 	require.NotEmpty(t, env.Data.IngestionID)
 
-	return env.Data
+	var processed ingestAccepted
+	require.NoError(t, json.Unmarshal(waitForIngestion(t, client, baseURL, apiKeyToken, env.Data.IngestionID), &processed))
+
+	return processed
 }
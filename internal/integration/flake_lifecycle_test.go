@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_FlakeLifecycle(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrf := newCSRFClient(t, srv.URL)
+	userID := signupAndLogin(t, client, srv.URL, csrf, "owner@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrf, "Acme", "acme")
+	project, err := projects.NewService(pool).Create(ctx, orgID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	projectBase := srv.URL + "/api/v1/projects/" + project.ID.String()
+	errEnv := doJSONExpectError(t, client, http.MethodPut, projectBase+"/flake-lifecycle", csrf, http.StatusBadRequest, map[string]any{"resolve_after_runs": 0})
+	require.Equal(t, "resolve_after_runs must be between 1 and 500", errEnv.Error.Message)
+
+	env := doJSONExpectSuccess(t, client, http.MethodPut, projectBase+"/flake-lifecycle", csrf, http.StatusOK, map[string]any{"resolve_after_runs": 2})
+	var configured struct {
+		FlakeLifecycle projects.FlakeLifecycleConfig `json:"flake_lifecycle"`
+	}
+	require.NoError(t, json.Unmarshal(env.Data, &configured))
+	require.Equal(t, 2, configured.FlakeLifecycle.ResolveAfterRuns)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunURL:       "https://github.com/acme/repo/actions/runs/1",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+	flakyRun := func(runID string) {
+		meta.RunID, meta.RunNumber, meta.SHA = runID, runID, runID
+		meta.RunAttempt = 1
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+		meta.RunAttempt = 2
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+	}
+	cleanRun := func(runID string) {
+		meta.RunID, meta.RunNumber, meta.SHA, meta.RunAttempt = runID, runID, runID, 1
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+	}
+
+	flakyRun("100")
+	var testCaseID uuid.UUID
+	require.NoError(t, pool.QueryRow(ctx, `SELECT id FROM test_cases WHERE project_id = $1`, project.ID).Scan(&testCaseID))
+	flakeBase := projectBase + "/flakes/" + testCaseID.String()
+
+	getDetail := func() flake.FlakeDetail {
+		var detail struct {
+			Flake flake.FlakeDetail `json:"flake"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodGet, flakeBase, csrf, http.StatusOK, nil)
+		require.NoError(t, json.Unmarshal(env.Data, &detail))
+		return detail.Flake
+	}
+	listFlakes := func(status string) []flake.FlakeListItem {
+		var list struct {
+			Flakes []flake.FlakeListItem `json:"flakes"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodGet, projectBase+"/flakes?status="+status, csrf, http.StatusOK, nil)
+		require.NoError(t, json.Unmarshal(env.Data, &list))
+		return list.Flakes
+	}
+	changeStatus := func(status, note string) flake.FlakeStatusChange {
+		var changed struct {
+			StatusChange flake.FlakeStatusChange `json:"status_change"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodPut, flakeBase+"/status", csrf, http.StatusOK, map[string]any{"status": status, "note": note})
+		require.NoError(t, json.Unmarshal(env.Data, &changed))
+		return changed.StatusChange
+	}
+
+	detail := getDetail()
+	require.Equal(t, flake.FlakeStatusNew, detail.Status)
+	require.Equal(t, 2, detail.ResolveAfterRuns)
+	require.Len(t, detail.StatusHistory, 1)
+	require.Nil(t, detail.StatusHistory[0].FromStatus)
+	require.Equal(t, flake.StatusReasonDetected, detail.StatusHistory[0].Reason)
+	require.Len(t, listFlakes("open"), 1)
+
+	flakyRun("101")
+	require.Equal(t, flake.FlakeStatusActive, getDetail().Status)
+
+	cleanRun("102")
+	detail = getDetail()
+	require.Equal(t, flake.FlakeStatusActive, detail.Status)
+	require.Equal(t, 1, detail.CleanRuns)
+
+	cleanRun("103")
+	detail = getDetail()
+	require.Equal(t, flake.FlakeStatusResolved, detail.Status)
+	require.Equal(t, flake.StatusReasonCleanRuns, detail.StatusHistory[0].Reason)
+	require.Nil(t, detail.StatusHistory[0].ActorUserID)
+	require.Empty(t, listFlakes("open"))
+	require.Len(t, listFlakes("resolved"), 1)
+
+	flakyRun("104")
+	detail = getDetail()
+	require.Equal(t, flake.FlakeStatusRegressed, detail.Status)
+	require.Zero(t, detail.CleanRuns)
+
+	change := changeStatus("acknowledged", "looking into it")
+	require.NotNil(t, change.FromStatus)
+	require.Equal(t, flake.FlakeStatusRegressed, *change.FromStatus)
+	require.Equal(t, flake.FlakeStatusAcknowledged, change.ToStatus)
+	require.Equal(t, flake.StatusReasonManual, change.Reason)
+
+	errEnv = doJSONExpectError(t, client, http.MethodPut, flakeBase+"/status", csrf, http.StatusConflict, map[string]any{"status": "regressed"})
+	require.Equal(t, "conflict", errEnv.Error.Code)
+	doJSONExpectError(t, client, http.MethodPut, flakeBase+"/status", csrf, http.StatusBadRequest, map[string]any{"status": "closed"})
+	doJSONExpectError(t, client, http.MethodPut, projectBase+"/flakes/"+uuid.New().String()+"/status", csrf, http.StatusNotFound, map[string]any{"status": "resolved"})
+
+	// A fix pending verification resolves after clean runs made since the fix
+	changeStatus("fixed_pending", "")
+	cleanRun("105")
+	require.Equal(t, flake.FlakeStatusFixedPending, getDetail().Status)
+	cleanRun("106")
+	detail = getDetail()
+	require.Equal(t, flake.FlakeStatusResolved, detail.Status)
+
+	var manual *flake.FlakeStatusChange
+	for i := range detail.StatusHistory {
+		if detail.StatusHistory[i].ToStatus == flake.FlakeStatusAcknowledged {
+			manual = &detail.StatusHistory[i]
+		}
+	}
+	require.NotNil(t, manual)
+	require.NotNil(t, manual.ActorEmail)
+	require.Equal(t, "owner@example.com", *manual.ActorEmail)
+	require.NotNil(t, manual.Note)
+	require.Equal(t, "looking into it", *manual.Note)
+
+	actions := make(map[string]bool)
+	for _, ev := range listAudit(t, client, srv.URL, orgID, 50) {
+		actions[ev.Action] = true
+	}
+	require.True(t, actions["flake.status_changed"], "missing flake.status_changed audit event")
+	require.True(t, actions["flake_lifecycle.configured"], "missing flake_lifecycle.configured audit event")
+}
@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/github"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_IngestReportsPullRequestFlakesAsComment(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "github-report@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+
+	projectService := projects.NewService(pool)
+	project, err := projectService.Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, err = projectService.ConfigureGitHub(ctx, project.ID, projects.GitHubReportComment)
+	require.NoError(t, err)
+
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	// GitHub API stub: one installation, records PR comments
+	var mu sync.Mutex
+	var comments []string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/repo/installation":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 1})
+		case "POST /app/installations/1/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "ghs_test", "expires_at": time.Now().Add(time.Hour)})
+		case "GET /repos/acme/repo/issues/12/comments":
+			_, _ = w.Write([]byte("[]"))
+		case "POST /repos/acme/repo/issues/12/comments":
+			var body struct {
+				Body string `json:"body"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			comments = append(comments, body.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+	t.Cleanup(stub.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Env:                 "dev",
+		BaseURL:             "http://localhost",
+		JWTSecret:           "test-secret",
+		RateLimitRPM:        120,
+		MaxUploadBytes:      5 * 1024 * 1024,
+		MaxUploadFiles:      20,
+		MaxFileBytes:        1 * 1024 * 1024,
+		SlackTimeoutMS:      2000,
+		SessionDays:         7,
+		GitHubAppID:         1,
+		GitHubAppPrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		GitHubAPIURL:        stub.URL,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	prNumber := int64(12)
+	metaBase := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  ".github/workflows/ci.yml",
+		RunID:        "300",
+		RunNumber:    "9",
+		RunURL:       "https://github.example/runs/300",
+		SHA:          "deadbeef",
+		Branch:       "feature",
+		Event:        "pull_request",
+		PRNumber:     &prNumber,
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	firstAttempt := metaBase
+	firstAttempt.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, firstAttempt, "flaky_attempt1.xml")
+
+	retry := metaBase
+	retry.RunAttempt = 2
+	accepted := ingestJUnit(t, srv.URL, token, retry, "flaky_attempt2.xml")
+	require.Equal(t, 1, accepted.FlakeEventsCreated)
+
+	// Reported after the ingestion succeeded; the clean first attempt made none
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(comments) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, comments[0], github.CommentMarker)
+	require.Contains(t, comments[0], "com.example.FlakyTest")
+	require.Contains(t, comments[0], "No, new in this PR")
+}
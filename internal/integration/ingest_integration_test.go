@@ -65,6 +65,7 @@ func TestIntegration_IngestEndpointWritesRowsAndUpdatesFlakeStats(t *testing.T)
 
 	srv := httptest.NewServer(app.NewRouter(pool, cfg))
 	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
 
 	metaBase := ingest.IngestionMetadata{
 		ProjectSlug:     project.Slug,
@@ -117,6 +118,336 @@ func TestIntegration_IngestEndpointWritesRowsAndUpdatesFlakeStats(t *testing.T)
 	require.Equal(t, 1.0, flakeScore)
 }
 
+func TestIntegration_IngestDetectsFlakeAcrossRunsOfSameSHA(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "cross-run@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+
+	project, err := projects.NewService(pool).Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	metaBase := ingest.IngestionMetadata{
+		ProjectSlug:      project.Slug,
+		RepoFullName:     "acme/repo",
+		WorkflowName:     "CI",
+		WorkflowRef:      "refs/heads/main",
+		GitHubRunAttempt: 1,
+		RunURL:           "https://github.example/runs",
+		SHA:              "deadbeef",
+		Branch:           "main",
+		Event:            "push",
+		JobName:          "unit",
+		StartedAt:        time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:      time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	failedRun := metaBase
+	failedRun.GitHubRunID = 200
+	failedRun.GitHubRunNumber = 1
+	accepted1 := ingestJUnit(t, srv.URL, token, failedRun, "flaky_attempt1.xml")
+	require.Equal(t, 0, accepted1.FlakeEventsCreated)
+
+	// Re-triggered workflow for the same commit: new run id, attempt 1 passes.
+	passedRun := metaBase
+	passedRun.GitHubRunID = 201
+	passedRun.GitHubRunNumber = 2
+	passedRun.Event = "workflow_dispatch"
+	accepted2 := ingestJUnit(t, srv.URL, token, passedRun, "flaky_attempt2.xml")
+	require.Equal(t, 1, accepted2.FlakeEventsCreated)
+
+	accepted2Repeat := ingestJUnit(t, srv.URL, token, passedRun, "flaky_attempt2.xml")
+	require.Equal(t, 0, accepted2Repeat.FlakeEventsCreated)
+
+	// A different commit never correlates with the earlier failure.
+	otherSHA := metaBase
+	otherSHA.GitHubRunID = 202
+	otherSHA.GitHubRunNumber = 3
+	otherSHA.SHA = "cafef00d"
+	accepted3 := ingestJUnit(t, srv.URL, token, otherSHA, "flaky_attempt2.xml")
+	require.Equal(t, 0, accepted3.FlakeEventsCreated)
+
+	assertDBCounts(t, pool, map[string]int{
+		"ci_runs":      3,
+		"flake_events": 1,
+		"flake_stats":  1,
+	})
+
+	var kind, failedRunID, passedRunID string
+	err = pool.QueryRow(ctx, `
+		SELECT fe.kind::text, fr.run_id, pr.run_id
+		FROM flake_events fe
+		JOIN ci_runs fr ON fr.id = fe.ci_run_id
+		JOIN ci_runs pr ON pr.id = fe.passed_ci_run_id
+	`).Scan(&kind, &failedRunID, &passedRunID)
+	require.NoError(t, err)
+	require.Equal(t, "same_sha_rerun", kind)
+	require.Equal(t, "200", failedRunID)
+	require.Equal(t, "201", passedRunID)
+
+	// The deprecated columns are still filled for GitHub runs
+	var githubRunID *int64
+	err = pool.QueryRow(ctx, `SELECT github_run_id FROM ci_runs WHERE run_id = '200'`).Scan(&githubRunID)
+	require.NoError(t, err)
+	require.NotNil(t, githubRunID)
+	require.Equal(t, int64(200), *githubRunID)
+}
+
+func TestIntegration_IngestNumbersProviderAttemptsByAttemptID(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "gitlab@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+
+	project, err := projects.NewService(pool).Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	// A GitLab pipeline whose failed job was retried: the retry has a new job
+	// ID and no attempt counter.
+	metaBase := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "group/repo",
+		WorkflowName: "gitlab-ci",
+		WorkflowRef:  ".gitlab-ci.yml",
+		Provider:     ingest.ProviderGitLab,
+		RunID:        "9001",
+		RunNumber:    "55",
+		RunURL:       "https://gitlab.example/group/repo/-/pipelines/9001",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	firstJob := metaBase
+	firstJob.AttemptID = "1001"
+	accepted1 := ingestJUnit(t, srv.URL, token, firstJob, "flaky_attempt1.xml")
+	require.Equal(t, 0, accepted1.FlakeEventsCreated)
+
+	retriedJob := metaBase
+	retriedJob.AttemptID = "1002"
+	accepted2 := ingestJUnit(t, srv.URL, token, retriedJob, "flaky_attempt2.xml")
+	require.Equal(t, 1, accepted2.FlakeEventsCreated)
+
+	// Re-uploading a job keeps its attempt number
+	accepted2Repeat := ingestJUnit(t, srv.URL, token, retriedJob, "flaky_attempt2.xml")
+	require.Equal(t, 0, accepted2Repeat.Stored.TestResults)
+
+	assertDBCounts(t, pool, map[string]int{
+		"ci_runs":         1,
+		"ci_run_attempts": 2,
+		"flake_events":    1,
+	})
+
+	var provider, kind string
+	var failedAttempt, passedAttempt int
+	err = pool.QueryRow(ctx, `
+		SELECT cr.provider::text, fe.kind::text, fe.failed_attempt_number, fe.passed_attempt_number
+		FROM flake_events fe
+		JOIN ci_runs cr ON cr.id = fe.ci_run_id
+	`).Scan(&provider, &kind, &failedAttempt, &passedAttempt)
+	require.NoError(t, err)
+	require.Equal(t, "gitlab", provider)
+	require.Equal(t, "retry_attempt", kind)
+
+	var githubRunID *int64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT github_run_id FROM ci_runs`).Scan(&githubRunID))
+	require.Nil(t, githubRunID, "only GitHub runs fill the deprecated columns")
+	require.Equal(t, 1, failedAttempt)
+	require.Equal(t, 2, passedAttempt)
+}
+
+func TestIntegration_IngestDetectsInJobRetryOnFirstAttempt(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "in-job-retry@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+
+	project, err := projects.NewService(pool).Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:      project.Slug,
+		RepoFullName:     "acme/repo",
+		WorkflowName:     "CI",
+		WorkflowRef:      "refs/heads/main",
+		GitHubRunID:      300,
+		GitHubRunAttempt: 1,
+		GitHubRunNumber:  1,
+		RunURL:           "https://github.example/runs/300",
+		SHA:              "deadbeef",
+		Branch:           "main",
+		Event:            "push",
+		JobName:          "unit",
+		StartedAt:        time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:      time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	accepted := ingestJUnit(t, srv.URL, token, meta, "surefire_reruns.xml")
+	require.Equal(t, 3, accepted.Stored.TestResults)
+	require.Equal(t, 1, accepted.FlakeEventsCreated)
+
+	accepted = ingestJUnit(t, srv.URL, token, meta, "surefire_reruns.xml")
+	require.Equal(t, 0, accepted.FlakeEventsCreated)
+
+	var kind, systemOut string
+	var failedAttempt, passedAttempt, rerunFailures int
+	err = pool.QueryRow(ctx, `
+		SELECT fe.kind::text, fe.failed_attempt_number, fe.passed_attempt_number, tr.rerun_failures, tr.system_out
+		FROM flake_events fe
+		JOIN test_results tr ON tr.test_case_id = fe.test_case_id
+	`).Scan(&kind, &failedAttempt, &passedAttempt, &rerunFailures, &systemOut)
+	require.NoError(t, err)
+	require.Equal(t, "in_job_retry", kind)
+	require.Equal(t, 1, failedAttempt)
+	require.Equal(t, 1, passedAttempt)
+	require.Equal(t, 1, rerunFailures)
+	require.Equal(t, "attempt 2: connected", systemOut)
+}
+
+func TestIntegration_IngestQueueDeadLettersUnparseableReport(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "dead-letter@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+
+	project, err := projects.NewService(pool).Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+
+	apiKey, _, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:      project.Slug,
+		RepoFullName:     "acme/repo",
+		WorkflowName:     "CI",
+		WorkflowRef:      "refs/heads/main",
+		GitHubRunID:      400,
+		GitHubRunAttempt: 1,
+		GitHubRunNumber:  1,
+		RunURL:           "https://github.example/runs/400",
+		SHA:              "deadbeef",
+		Branch:           "main",
+		Event:            "push",
+		JobName:          "unit",
+		StartedAt:        time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:      time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+	require.NoError(t, meta.Validate())
+
+	queue := ingest.NewQueue(pool)
+	ingestionID, err := queue.Enqueue(ctx, project.ID, apiKey.ID, &meta, []ingest.QueuedFile{
+		{Filename: "broken.xml", Format: ingest.FormatJUnit, Content: bytes.NewReader([]byte(`<testsuites><testsuite name="s">`))},
+	}, nil)
+	require.NoError(t, err)
+
+	status, err := queue.Get(ctx, project.ID, ingestionID)
+	require.NoError(t, err)
+	require.Equal(t, ingest.StatusQueued, status.Status)
+
+	worker := ingest.NewWorker(pool, &config.Config{BaseURL: "http://localhost", SlackTimeoutMS: 2000})
+	processed, err := worker.ProcessNext(ctx)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	processed, err = worker.ProcessNext(ctx)
+	require.NoError(t, err)
+	require.False(t, processed)
+
+	status, err = queue.Get(ctx, project.ID, ingestionID)
+	require.NoError(t, err)
+	require.Equal(t, ingest.StatusFailed, status.Status)
+	require.Equal(t, 1, status.Attempts)
+	require.NotNil(t, status.Error)
+	require.Contains(t, *status.Error, "broken.xml")
+	require.NotNil(t, status.ErrorCode)
+	require.Equal(t, "invalid_junit_xml", *status.ErrorCode)
+
+	_, err = queue.Get(ctx, uuid.New(), ingestionID)
+	require.ErrorIs(t, err, ingest.ErrIngestionNotFound)
+
+	assertDBCounts(t, pool, map[string]int{
+		"ingestion_payloads": 1,
+		"junit_files":        0,
+		"test_results":       0,
+	})
+}
+
 type ingestAcceptedData struct {
 	IngestionID string `json:"ingestion_id"`
 	Stored      struct {
@@ -162,13 +493,73 @@ func ingestJUnit(t *testing.T, baseURL, token string, meta ingest.IngestionMetad
 	require.NoError(t, json.Unmarshal(respBody, &env))
 	require.NotEmpty(t, env.RequestID)
 
+	var queued ingestAcceptedData
+	require.NoError(t, json.Unmarshal(env.Data, &queued))
+	require.NotEmpty(t, queued.IngestionID)
+
 	var data ingestAcceptedData
-	require.NoError(t, json.Unmarshal(env.Data, &data))
-	require.NotEmpty(t, data.IngestionID)
+	require.NoError(t, json.Unmarshal(waitForIngestion(t, http.DefaultClient, baseURL, token, queued.IngestionID), &data))
 
 	return data
 }
 
+// startIngestWorker processes queued ingestions in the background until the test ends
+func startIngestWorker(t *testing.T, pool *pgxpool.Pool, cfg *config.Config) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker := ingest.NewWorker(pool, cfg)
+	worker.PollInterval = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForIngestion polls the ingestion status endpoint until the ingestion has
+// succeeded and returns its data
+func waitForIngestion(t *testing.T, client *http.Client, baseURL, token, ingestionID string) json.RawMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		req, err := http.NewRequest(http.MethodGet, baseURL+"/api/v1/ingest/"+ingestionID, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", string(respBody))
+
+		var env successEnvelope
+		require.NoError(t, json.Unmarshal(respBody, &env))
+
+		var status struct {
+			Status string `json:"status"`
+		}
+		require.NoError(t, json.Unmarshal(env.Data, &status))
+
+		switch status.Status {
+		case ingest.StatusSucceeded:
+			return env.Data
+		case ingest.StatusFailed:
+			t.Fatalf("ingestion %s failed: %s", ingestionID, string(env.Data))
+		}
+		require.True(t, time.Now().Before(deadline), "ingestion %s still %s", ingestionID, status.Status)
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
 func insertUser(t *testing.T, pool *pgxpool.Pool, email string) uuid.UUID {
 	t.Helper()
 
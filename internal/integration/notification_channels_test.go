@@ -0,0 +1,384 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_NotificationChannelsDeliverSignedWebhook(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	// Webhook receiver: records the signed deliveries
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	var mu sync.Mutex
+	var deliveries []delivery
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		deliveries = append(deliveries, delivery{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		// The receiver listens on loopback
+		NotifyAllowPrivateURLs: true,
+		SessionDays:            7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+	startNotificationWorker(t, pool, cfg)
+
+	client, csrfToken := newCSRFClient(t, srv.URL)
+	signupAndLogin(t, client, srv.URL, csrfToken, "channels@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrfToken, "Acme", "acme")
+
+	projectResp := postJSONExpectStatus(t, client, srv.URL+"/api/v1/orgs/"+orgID.String()+"/projects", csrfToken, http.StatusCreated, map[string]any{
+		"name":           "Project",
+		"slug":           "my-project",
+		"default_branch": "main",
+	})
+	var project struct {
+		Project struct {
+			ID   uuid.UUID `json:"id"`
+			Slug string    `json:"slug"`
+		} `json:"project"`
+	}
+	require.NoError(t, json.Unmarshal(projectResp.Data, &project))
+	channelsURL := srv.URL + "/api/v1/projects/" + project.Project.ID.String() + "/notification-channels"
+
+	// Email needs an SMTP relay, which this server lacks
+	errEnv := doJSONExpectError(t, client, http.MethodPost, channelsURL, csrfToken, http.StatusBadRequest, map[string]any{
+		"type":       "email",
+		"name":       "oncall",
+		"recipients": []string{"oncall@example.com"},
+	})
+	require.Contains(t, errEnv.Error.Message, "SMTP")
+
+	// The signing secret is returned once and never listed
+	created := doJSONExpectSuccess(t, client, http.MethodPost, channelsURL, csrfToken, http.StatusCreated, map[string]any{
+		"type": "webhook",
+		"name": "ci-bot",
+		"url":  receiver.URL + "/flakes",
+	})
+	var createdData struct {
+		Channel       notify.ChannelResponse `json:"channel"`
+		SigningSecret string                 `json:"signing_secret"`
+	}
+	require.NoError(t, json.Unmarshal(created.Data, &createdData))
+	require.NotEmpty(t, createdData.SigningSecret)
+	require.True(t, createdData.Channel.Enabled)
+
+	doJSONExpectError(t, client, http.MethodPost, channelsURL, csrfToken, http.StatusConflict, map[string]any{
+		"type": "webhook",
+		"name": "ci-bot",
+		"url":  receiver.URL,
+	})
+
+	listed := doJSONExpectSuccess(t, client, http.MethodGet, channelsURL, csrfToken, http.StatusOK, nil)
+	require.NotContains(t, string(listed.Data), createdData.SigningSecret)
+	require.NotContains(t, string(listed.Data), "/flakes")
+
+	token := createAPIKey(t, client, srv.URL, csrfToken, project.Project.ID)
+
+	metaBase := ingest.IngestionMetadata{
+		ProjectSlug:  project.Project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunID:        "500",
+		RunNumber:    "3",
+		RunURL:       "https://github.example/runs/500",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	firstAttempt := metaBase
+	firstAttempt.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, firstAttempt, "flaky_attempt1.xml")
+
+	retry := metaBase
+	retry.RunAttempt = 2
+	accepted := ingestJUnit(t, srv.URL, token, retry, "flaky_attempt2.xml")
+	require.Equal(t, 1, accepted.FlakeEventsCreated)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mu.Lock()
+	got := deliveries[0]
+	mu.Unlock()
+
+	require.Equal(t, notify.EventFlakeDetected, got.header.Get(notify.HeaderEvent))
+	require.Equal(t,
+		notify.Sign(createdData.SigningSecret, got.header.Get(notify.HeaderTimestamp), got.body),
+		got.header.Get(notify.HeaderSignature))
+	require.Contains(t, string(got.body), "com.example.FlakyTest")
+
+	var payload struct {
+		Run struct {
+			Label        string `json:"label"`
+			URL          string `json:"url"`
+			DashboardURL string `json:"dashboard_url"`
+		} `json:"run"`
+		Flakes []struct {
+			TotalRuns int `json:"total_runs"`
+		} `json:"flakes"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "GitHub run #3", payload.Run.Label)
+	require.Equal(t, metaBase.RunURL, payload.Run.URL)
+	require.Equal(t, "http://localhost/orgs/acme/projects/my-project/flakes", payload.Run.DashboardURL)
+	require.Len(t, payload.Flakes, 1)
+	require.Positive(t, payload.Flakes[0].TotalRuns)
+
+	// The same test flaking again within the cooldown is not notified
+	ingestFlakyRun := func(runID string) {
+		meta := metaBase
+		meta.RunID = runID
+		meta.RunNumber = runID
+		meta.SHA = "cafe" + runID
+		meta.RunAttempt = 1
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+		meta.RunAttempt = 2
+		accepted := ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+		require.Equal(t, 1, accepted.FlakeEventsCreated)
+	}
+	ingestFlakyRun("501")
+	require.Never(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) > 1
+	}, 500*time.Millisecond, 20*time.Millisecond)
+
+	// A cooldown of 0 notifies on every flake event
+	doJSONExpectSuccess(t, client, http.MethodPut, srv.URL+"/api/v1/projects/"+project.Project.ID.String()+"/notifications", csrfToken, http.StatusOK, map[string]any{
+		"cooldown_hours": 0,
+	})
+	doJSONExpectError(t, client, http.MethodPut, srv.URL+"/api/v1/projects/"+project.Project.ID.String()+"/notifications", csrfToken, http.StatusBadRequest, map[string]any{
+		"cooldown_hours": 721,
+	})
+	ingestFlakyRun("502")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Deliveries are logged per project
+	projectURL := srv.URL + "/api/v1/projects/" + project.Project.ID.String()
+	require.Eventually(t, func() bool {
+		deliveries := listDeliveries(t, client, projectURL, csrfToken)
+		return len(deliveries) == 2 && deliveries[0].Status == notify.DeliverySent && deliveries[1].Status == notify.DeliverySent
+	}, 5*time.Second, 20*time.Millisecond)
+	logged := listDeliveries(t, client, projectURL, csrfToken)
+	require.Equal(t, "ci-bot", logged[0].ChannelName)
+	require.Equal(t, notify.EventFlakeDetected, logged[0].Event)
+	require.Equal(t, 1, logged[0].Flakes)
+	require.Equal(t, 1, logged[0].Attempts)
+
+	// A test notification goes through the outbox to every channel
+	doJSONExpectSuccess(t, client, http.MethodPost, projectURL+"/notifications/test", csrfToken, http.StatusAccepted, nil)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) == 3
+	}, 5*time.Second, 20*time.Millisecond)
+	mu.Lock()
+	require.Equal(t, notify.EventTest, deliveries[2].header.Get(notify.HeaderEvent))
+	mu.Unlock()
+
+	// Channels can be disabled and removed
+	doJSONExpectSuccess(t, client, http.MethodPut, channelsURL+"/"+createdData.Channel.ID.String(), csrfToken, http.StatusOK, map[string]any{
+		"enabled": false,
+	})
+	doJSONExpectSuccess(t, client, http.MethodDelete, channelsURL+"/"+createdData.Channel.ID.String(), csrfToken, http.StatusOK, nil)
+	doJSONExpectError(t, client, http.MethodDelete, channelsURL+"/"+createdData.Channel.ID.String(), csrfToken, http.StatusNotFound, nil)
+}
+
+func TestIntegration_NotificationsMergeJobsOfRun(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		// The receiver listens on loopback
+		NotifyAllowPrivateURLs: true,
+		SessionDays:            7,
+		NotifyQuietSeconds:     2,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+	startNotificationWorker(t, pool, cfg)
+
+	client, csrfToken := newCSRFClient(t, srv.URL)
+	signupAndLogin(t, client, srv.URL, csrfToken, "matrix@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrfToken, "Acme", "acme")
+
+	projectResp := postJSONExpectStatus(t, client, srv.URL+"/api/v1/orgs/"+orgID.String()+"/projects", csrfToken, http.StatusCreated, map[string]any{
+		"name":           "Project",
+		"slug":           "my-project",
+		"default_branch": "main",
+	})
+	var project struct {
+		Project struct {
+			ID   uuid.UUID `json:"id"`
+			Slug string    `json:"slug"`
+		} `json:"project"`
+	}
+	require.NoError(t, json.Unmarshal(projectResp.Data, &project))
+	doJSONExpectSuccess(t, client, http.MethodPost, srv.URL+"/api/v1/projects/"+project.Project.ID.String()+"/notification-channels", csrfToken, http.StatusCreated, map[string]any{
+		"type": "webhook",
+		"name": "ci-bot",
+		"url":  receiver.URL,
+	})
+	token := createAPIKey(t, client, srv.URL, csrfToken, project.Project.ID)
+
+	// Two jobs of one run flake in separate ingestions
+	for _, job := range []string{"unit-linux", "unit-macos"} {
+		meta := ingest.IngestionMetadata{
+			ProjectSlug:  project.Project.Slug,
+			RepoFullName: "acme/repo",
+			WorkflowName: "CI",
+			WorkflowRef:  "refs/heads/main",
+			RunID:        "600",
+			RunNumber:    "7",
+			RunURL:       "https://github.example/runs/600",
+			SHA:          "deadbeef",
+			Branch:       "main",
+			Event:        "push",
+			JobName:      job,
+			StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+			CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+		}
+		meta.RunAttempt = 1
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+		meta.RunAttempt = 2
+		accepted := ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+		require.Equal(t, 1, accepted.FlakeEventsCreated)
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 1
+	}, 10*time.Second, 20*time.Millisecond)
+	require.Never(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) > 1
+	}, 500*time.Millisecond, 20*time.Millisecond)
+
+	var payload struct {
+		Flakes []struct {
+			Job string `json:"job"`
+		} `json:"flakes"`
+	}
+	mu.Lock()
+	require.NoError(t, json.Unmarshal(bodies[0], &payload))
+	mu.Unlock()
+	require.Len(t, payload.Flakes, 2)
+	require.Equal(t, "unit-linux", payload.Flakes[0].Job)
+	require.Equal(t, "unit-macos", payload.Flakes[1].Job)
+}
+
+func createAPIKey(t *testing.T, client *http.Client, baseURL, csrfToken string, projectID uuid.UUID) string {
+	t.Helper()
+
+	resp := postJSONExpectStatus(t, client, baseURL+"/api/v1/projects/"+projectID.String()+"/api-keys", csrfToken, http.StatusCreated, map[string]any{
+		"name": "CI",
+	})
+	var parsed struct {
+		APIKey struct {
+			Token string `json:"token"`
+		} `json:"api_key"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &parsed))
+	require.NotEmpty(t, parsed.APIKey.Token)
+	return parsed.APIKey.Token
+}
+
+func startNotificationWorker(t *testing.T, pool *pgxpool.Pool, cfg *config.Config) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker := notify.NewWorker(pool, cfg)
+	worker.PollInterval = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func listDeliveries(t *testing.T, client *http.Client, projectURL, csrfToken string) []notify.DeliveryResponse {
+	t.Helper()
+
+	resp := doJSONExpectSuccess(t, client, http.MethodGet, projectURL+"/notification-deliveries", csrfToken, http.StatusOK, nil)
+	var parsed struct {
+		Deliveries []notify.DeliveryResponse `json:"deliveries"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &parsed))
+	return parsed.Deliveries
+}
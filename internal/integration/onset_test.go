@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_FlakeOnsetFromDefaultBranchHistory(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   600,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrf := newCSRFClient(t, srv.URL)
+	userID := signupAndLogin(t, client, srv.URL, csrf, "owner@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrf, "Acme", "acme")
+	project, err := projects.NewService(pool).Create(ctx, orgID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	// 10 stable runs, then 10 runs that flake on a re-run
+	for i := 0; i < 20; i++ {
+		meta.RunID = fmt.Sprint(200 + i)
+		meta.RunNumber = meta.RunID
+		meta.RunURL = "https://github.com/acme/repo/actions/runs/" + meta.RunID
+		meta.SHA = fmt.Sprintf("%040d", i)
+		meta.RunAttempt = 1
+		if i >= 10 {
+			ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+			meta.RunAttempt = 2
+		}
+		ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+	}
+	// Pull request runs are not part of the default-branch history
+	meta.RunID, meta.Branch, meta.Event, meta.RunAttempt = "300", "feature", "pull_request", 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+	meta.RunAttempt = 2
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+
+	var testCaseID uuid.UUID
+	require.NoError(t, pool.QueryRow(ctx, `SELECT id FROM test_cases WHERE project_id = $1`, project.ID).Scan(&testCaseID))
+
+	var detail flake.FlakeDetail
+	env := doJSONExpectSuccess(t, client, http.MethodGet, srv.URL+"/api/v1/projects/"+project.ID.String()+"/flakes/"+testCaseID.String(), csrf, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &detail))
+
+	onset := detail.Onset
+	require.NotNil(t, onset)
+	require.Equal(t, fmt.Sprintf("%040d", 9), onset.LastStable.SHA)
+	require.Equal(t, "https://github.com/acme/repo/actions/runs/209", onset.LastStable.RunURL)
+	require.Equal(t, fmt.Sprintf("%040d", 10), onset.FirstFlaky.SHA)
+	require.Equal(t, "GitHub run #210", onset.FirstFlaky.RunLabel)
+	require.Equal(t, 10, onset.RunsBefore)
+	require.Zero(t, onset.FlakyRunsBefore)
+	require.Equal(t, 10, onset.RunsAfter)
+	require.Equal(t, 10, onset.FlakyRunsAfter)
+	require.NotNil(t, onset.CompareURL)
+	require.Equal(t, fmt.Sprintf("https://github.com/acme/repo/compare/%040d...%040d", 9, 10), *onset.CompareURL)
+}
@@ -0,0 +1,205 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_TestOwnership(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrf := newCSRFClient(t, srv.URL)
+	userID := signupAndLogin(t, client, srv.URL, csrf, "owner@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrf, "Acme", "acme")
+	project, err := projects.NewService(pool).Create(ctx, orgID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunURL:       "https://github.com/acme/repo/actions/runs/1",
+		RunID:        "100",
+		RunNumber:    "100",
+		SHA:          "100",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+	meta.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+	meta.RunAttempt = 2
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+
+	projectBase := srv.URL + "/api/v1/projects/" + project.ID.String()
+	listFlakes := func(owner string) []flake.FlakeListItem {
+		var list struct {
+			Flakes []flake.FlakeListItem `json:"flakes"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodGet, projectBase+"/flakes?status=all&owner="+url.QueryEscape(owner), csrf, http.StatusOK, nil)
+		require.NoError(t, json.Unmarshal(env.Data, &list))
+		return list.Flakes
+	}
+	listOwners := func() []ownership.OwnerSummary {
+		var list struct {
+			Owners []ownership.OwnerSummary `json:"owners"`
+		}
+		env := doJSONExpectSuccess(t, client, http.MethodGet, projectBase+"/owners", csrf, http.StatusOK, nil)
+		require.NoError(t, json.Unmarshal(env.Data, &list))
+		return list.Owners
+	}
+
+	// Before CODEOWNERS is set up every test is unowned
+	require.Len(t, listFlakes(ownership.OwnerUnowned), 1)
+	owners := listOwners()
+	require.Len(t, owners, 1)
+	require.Equal(t, "", owners[0].Owner)
+	require.Equal(t, 1, owners[0].OpenFlakes)
+
+	errEnv := doJSONExpectError(t, client, http.MethodPut, projectBase+"/codeowners", csrf, http.StatusBadRequest, map[string]any{"content": "* platform"})
+	require.Contains(t, errEnv.Error.Message, "Invalid CODEOWNERS")
+	doJSONExpectError(t, client, http.MethodPut, projectBase+"/codeowners", csrf, http.StatusBadRequest, map[string]any{"path_rules": []string{"com.example"}})
+
+	env := doJSONExpectSuccess(t, client, http.MethodPut, projectBase+"/codeowners", csrf, http.StatusOK, map[string]any{
+		"content":    "* @acme/platform\n/src/test/java/com/example/ @acme/qa qa@example.com\n",
+		"path_rules": []string{"com.example = src/test/java/com/example/{}.java"},
+	})
+	var configured struct {
+		Codeowners      ownership.Config `json:"codeowners"`
+		ReassignedTests int              `json:"reassigned_tests"`
+	}
+	require.NoError(t, json.Unmarshal(env.Data, &configured))
+	require.Equal(t, 1, configured.ReassignedTests)
+	require.Equal(t, []string{"com.example=src/test/java/com/example/{}.java"}, configured.Codeowners.PathRules)
+	require.NotNil(t, configured.Codeowners.Source)
+	require.Equal(t, ownership.SourceSettings, *configured.Codeowners.Source)
+
+	flakes := listFlakes("@acme/qa")
+	require.Len(t, flakes, 1)
+	require.Equal(t, []string{"@acme/qa", "qa@example.com"}, flakes[0].Owners)
+	require.Empty(t, listFlakes("@acme/platform"))
+	require.Empty(t, listFlakes(ownership.OwnerUnowned))
+
+	owners = listOwners()
+	require.Len(t, owners, 2)
+	require.Equal(t, "@acme/qa", owners[0].Owner)
+	require.Equal(t, 1, owners[0].Tests)
+	require.Equal(t, 1, owners[0].FlakyTests)
+	require.Equal(t, 1, owners[0].OpenFlakes)
+	require.Equal(t, "qa@example.com", owners[1].Owner)
+
+	// A CODEOWNERS file sent with an upload replaces the configured one but
+	// keeps the path rules
+	resp := ingestWithCodeowners(t, srv.URL, token, meta, "flaky_attempt2.xml", "/src/test/java/ @acme/platform\n")
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp = ingestWithCodeowners(t, srv.URL, token, meta, "flaky_attempt2.xml", "/src/ platform-team\n")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	require.Len(t, listFlakes("@acme/platform"), 1)
+	require.Empty(t, listFlakes("@acme/qa"))
+
+	env = doJSONExpectSuccess(t, client, http.MethodGet, projectBase+"/codeowners", csrf, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &configured))
+	require.Equal(t, "/src/test/java/ @acme/platform\n", configured.Codeowners.Content)
+	require.Equal(t, ownership.SourceIngest, *configured.Codeowners.Source)
+	require.Len(t, configured.Codeowners.PathRules, 1)
+
+	doJSONExpectError(t, client, http.MethodGet, srv.URL+"/api/v1/projects/"+uuid.New().String()+"/owners", csrf, http.StatusNotFound, nil)
+
+	actions := make(map[string]bool)
+	for _, ev := range listAudit(t, client, srv.URL, orgID, 50) {
+		actions[ev.Action] = true
+	}
+	require.True(t, actions["codeowners.configured"], "missing codeowners.configured audit event")
+}
+
+// ingestWithCodeowners uploads a JUnit fixture with a CODEOWNERS file and
+// waits for accepted uploads to be processed
+func ingestWithCodeowners(t *testing.T, baseURL, token string, meta ingest.IngestionMetadata, fixtureName, codeowners string) *http.Response {
+	t.Helper()
+
+	metaBytes, err := json.Marshal(meta)
+	require.NoError(t, err)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("meta", string(metaBytes)))
+
+	fileBytes, err := os.ReadFile(junitFixturePath(t, fixtureName))
+	require.NoError(t, err)
+	part, err := writer.CreateFormFile("junit", fixtureName)
+	require.NoError(t, err)
+	_, err = part.Write(fileBytes)
+	require.NoError(t, err)
+
+	part, err = writer.CreateFormFile("codeowners", "CODEOWNERS")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(codeowners))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/api/v1/ingest/junit", body)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if resp.StatusCode != http.StatusAccepted {
+		return resp
+	}
+
+	var env successEnvelope
+	require.NoError(t, json.Unmarshal(respBody, &env))
+	var queued ingestAcceptedData
+	require.NoError(t, json.Unmarshal(env.Data, &queued))
+	waitForIngestion(t, http.DefaultClient, baseURL, token, queued.IngestionID)
+	return resp
+}
@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/signatures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_FailureSignaturesClusterTestsAcrossRuns(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	client, csrf := newCSRFClient(t, srv.URL)
+	userID := signupAndLogin(t, client, srv.URL, csrf, "owner@example.com", "password123")
+	orgID := createOrg(t, client, srv.URL, csrf, "Acme", "acme")
+	project, err := projects.NewService(pool).Create(ctx, orgID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunID:        "500",
+		RunNumber:    "5",
+		RunURL:       "https://github.example/runs/500",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "integration",
+		RunAttempt:   1,
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	// Two runs, and a duplicate upload that must not be counted again
+	ingestJUnit(t, srv.URL, token, meta, "shared_failure.xml")
+	ingestJUnit(t, srv.URL, token, meta, "shared_failure.xml")
+	meta.RunID, meta.RunNumber, meta.SHA = "501", "6", "cafebabe"
+	ingestJUnit(t, srv.URL, token, meta, "shared_failure.xml")
+
+	base := srv.URL + "/api/v1/projects/" + project.ID.String() + "/failure-signatures"
+	var list struct {
+		Signatures []signatures.Signature `json:"signatures"`
+	}
+	env := doJSONExpectSuccess(t, client, http.MethodGet, base, csrf, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &list))
+	require.Len(t, list.Signatures, 2)
+
+	shared := list.Signatures[0]
+	require.Equal(t, 2, shared.AffectedTests, "the connection failure of both tests is one signature")
+	require.Equal(t, 4, shared.Occurrences)
+	require.Contains(t, shared.NormalizedMessage, "Connection to postgres:<n> refused after <n> ms (session <uuid>)")
+	require.Equal(t, 1, list.Signatures[1].AffectedTests)
+	require.Equal(t, 2, list.Signatures[1].Occurrences)
+
+	env = doJSONExpectSuccess(t, client, http.MethodGet, base+"?min_tests=2", csrf, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &list))
+	require.Len(t, list.Signatures, 1)
+
+	var detail struct {
+		Signature signatures.SignatureDetail `json:"signature"`
+	}
+	env = doJSONExpectSuccess(t, client, http.MethodGet, base+"/"+shared.ID.String(), csrf, http.StatusOK, nil)
+	require.NoError(t, json.Unmarshal(env.Data, &detail))
+	require.Len(t, detail.Signature.Tests, 2)
+	identifiers := []string{detail.Signature.Tests[0].TestIdentifier, detail.Signature.Tests[1].TestIdentifier}
+	require.ElementsMatch(t, []string{"com.example.OrderRepositoryTest#testSave", "com.example.UserRepositoryTest#testFindByEmail"}, identifiers)
+	require.Equal(t, 2, detail.Signature.Tests[0].Occurrences)
+
+	errEnv := doJSONExpectError(t, client, http.MethodGet, base+"/"+project.ID.String(), csrf, http.StatusNotFound, nil)
+	require.Equal(t, "not_found", errEnv.Error.Code)
+}
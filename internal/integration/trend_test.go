@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikeys"
+	"github.com/aliuyar1234/flakeguard/internal/app"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_DailyTrendIsMaintainedAtIngestAndBackfillable(t *testing.T) {
+	pool, cleanup := newTestDB(t)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+
+	userID := insertUser(t, pool, "trend@example.com")
+	org, err := orgs.NewService(pool).CreateWithOwner(ctx, "Acme", "acme", userID)
+	require.NoError(t, err)
+	project, err := projects.NewService(pool).Create(ctx, org.ID, "Project", "my-project", "main", userID)
+	require.NoError(t, err)
+	_, token, err := apikeys.NewService(pool).Create(ctx, project.ID, "CI", []apikeys.ApiKeyScope{apikeys.ScopeIngestWrite}, userID, nil)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Env:            "dev",
+		BaseURL:        "http://localhost",
+		JWTSecret:      "test-secret",
+		RateLimitRPM:   120,
+		MaxUploadBytes: 5 * 1024 * 1024,
+		MaxUploadFiles: 20,
+		MaxFileBytes:   1 * 1024 * 1024,
+		SlackTimeoutMS: 2000,
+		SessionDays:    7,
+	}
+
+	srv := httptest.NewServer(app.NewRouter(pool, cfg))
+	t.Cleanup(srv.Close)
+	startIngestWorker(t, pool, cfg)
+
+	meta := ingest.IngestionMetadata{
+		ProjectSlug:  project.Slug,
+		RepoFullName: "acme/repo",
+		WorkflowName: "CI",
+		WorkflowRef:  "refs/heads/main",
+		RunID:        "900",
+		RunNumber:    "9",
+		RunURL:       "https://github.example/runs/900",
+		SHA:          "deadbeef",
+		Branch:       "main",
+		Event:        "push",
+		JobName:      "unit",
+		StartedAt:    time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339),
+		CompletedAt:  time.Now().Add(-1 * time.Minute).UTC().Format(time.RFC3339),
+	}
+
+	// A flaky run: failed, then passed on a re-run, uploaded twice
+	meta.RunAttempt = 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt1.xml")
+	meta.RunAttempt = 2
+	require.Equal(t, 1, ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml").FlakeEventsCreated)
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+
+	// A passing run of another commit
+	meta.RunID, meta.RunNumber, meta.SHA, meta.RunAttempt = "901", "10", "cafebabe", 1
+	ingestJUnit(t, srv.URL, token, meta, "flaky_attempt2.xml")
+
+	var testCaseID uuid.UUID
+	require.NoError(t, pool.QueryRow(ctx, `SELECT id FROM test_cases WHERE project_id = $1`, project.ID).Scan(&testCaseID))
+
+	service := flake.NewService(pool)
+	today := time.Now().UTC().Format(time.DateOnly)
+	wantToday := flake.TrendPoint{Date: today, Runs: 2, FailedRuns: 1, FlakyRuns: 1, FlakeRate: 0.5}
+
+	testTrend, err := service.GetTestTrend(ctx, project.ID, testCaseID, 7, time.Now())
+	require.NoError(t, err)
+	require.Len(t, testTrend, 7)
+	require.Equal(t, wantToday, testTrend[6], "each run counts once however many attempts and uploads it had")
+	require.Zero(t, testTrend[0].Runs)
+
+	projectTrend, err := service.GetProjectTrend(ctx, project.ID, flake.TrendRequest{Days: 7}, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, flake.ProjectTrendPoint{TrendPoint: wantToday, FlakyTests: 1}, projectTrend[6])
+
+	otherJob := "integration"
+	filtered, err := service.GetProjectTrend(ctx, project.ID, flake.TrendRequest{Days: 7, JobName: otherJob}, time.Now())
+	require.NoError(t, err)
+	require.Zero(t, filtered[6].Runs)
+
+	_, err = service.GetTestTrend(ctx, uuid.New(), testCaseID, 7, time.Now())
+	require.ErrorIs(t, err, flake.ErrFlakeNotFound)
+
+	// The backfill rebuilds the same rollup from the stored results
+	_, err = pool.Exec(ctx, `DELETE FROM test_daily_stats`)
+	require.NoError(t, err)
+	written, err := flake.BackfillDailyStats(ctx, pool, project.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, written)
+
+	testTrend, err = service.GetTestTrend(ctx, project.ID, testCaseID, 7, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, wantToday, testTrend[6])
+
+	_, err = flake.BackfillDailyStats(ctx, pool, project.ID)
+	require.NoError(t, err)
+	testTrend, err = service.GetTestTrend(ctx, project.ID, testCaseID, 7, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, wantToday, testTrend[6], "backfilling again changes nothing")
+}
@@ -0,0 +1,136 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB runs the queue statements; satisfied by *pgxpool.Pool and pgx.Tx
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Backoff is an exponential retry delay, doubling from Base up to Max
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns the backoff after the given failed attempt
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
+
+// Table is a Postgres job table leased to workers. The table has the columns
+// id, status, attempts, max_attempts, next_attempt_at, locked_at,
+// completed_at and last_error.
+type Table struct {
+	// Name is the table name
+	Name string
+	// Noun names a job in error messages
+	Noun string
+
+	// Statuses of jobs waiting to run, claimed by a worker, and dead-lettered
+	Pending string
+	Running string
+	Failed  string
+
+	// Lease is how long a claimed job may stay running before another
+	// worker assumes its worker died and reclaims it
+	Lease time.Duration
+	// Backoff is the delay before a failed job is retried
+	Backoff Backoff
+
+	// ClaimSet holds extra assignments made when a job is claimed
+	ClaimSet string
+}
+
+// Claim takes the oldest due job, or one whose worker lease expired, marks it
+// running and returns its returning columns. Jobs whose lease expired on
+// their final attempt are dead-lettered first, as they would otherwise be
+// reclaimed forever. The row yields pgx.ErrNoRows when nothing is due.
+func (t *Table) Claim(ctx context.Context, db DB, returning string) (pgx.Row, error) {
+	_, err := db.Exec(ctx, `
+		UPDATE `+t.Name+`
+		SET status = '`+t.Failed+`',
+		    completed_at = NOW(),
+		    locked_at = NULL,
+		    last_error = 'worker lease expired on final attempt'
+		WHERE status = '`+t.Running+`'
+		  AND locked_at < NOW() - $1::float8 * INTERVAL '1 second'
+		  AND attempts >= max_attempts
+	`, t.Lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire stale %s: %w", t.Name, err)
+	}
+
+	set := "locked_at = NOW()"
+	if t.ClaimSet != "" {
+		set += ", " + t.ClaimSet
+	}
+	query := `
+		UPDATE ` + t.Name + `
+		SET status = '` + t.Running + `',
+		    attempts = attempts + 1,
+		    ` + set + `
+		WHERE id = (
+			SELECT id
+			FROM ` + t.Name + `
+			WHERE (status = '` + t.Pending + `' AND next_attempt_at <= NOW())
+			   OR (status = '` + t.Running + `' AND locked_at < NOW() - $1::float8 * INTERVAL '1 second')
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + returning
+
+	return db.QueryRow(ctx, query, t.Lease.Seconds()), nil
+}
+
+// Fail records a failed attempt of a job. The job is retried after its
+// backoff unless the failure is permanent or it was the last attempt, in
+// which case it is dead-lettered. Returns whether the job will be retried.
+func (t *Table) Fail(ctx context.Context, db DB, id uuid.UUID, attempts, maxAttempts int, message string, permanent bool) (bool, error) {
+	if permanent || attempts >= maxAttempts {
+		_, err := db.Exec(ctx, `
+			UPDATE `+t.Name+`
+			SET status = '`+t.Failed+`', completed_at = NOW(), locked_at = NULL, last_error = $2
+			WHERE id = $1
+		`, id, message)
+		if err != nil {
+			return false, fmt.Errorf("failed to mark %s failed: %w", t.Noun, err)
+		}
+		return false, nil
+	}
+
+	_, err := db.Exec(ctx, `
+		UPDATE `+t.Name+`
+		SET status = '`+t.Pending+`', next_attempt_at = NOW() + $2::float8 * INTERVAL '1 second', locked_at = NULL, last_error = $3
+		WHERE id = $1
+	`, id, t.Backoff.Delay(attempts).Seconds(), message)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue %s: %w", t.Noun, err)
+	}
+	return true, nil
+}
+
+// Release puts a running job back in the queue without counting the attempt,
+// for workers that stop mid-job
+func (t *Table) Release(ctx context.Context, db DB, id uuid.UUID) error {
+	_, err := db.Exec(ctx, `
+		UPDATE `+t.Name+`
+		SET status = '`+t.Pending+`', attempts = GREATEST(attempts - 1, 0), locked_at = NULL
+		WHERE id = $1 AND status = '`+t.Running+`'
+	`, id)
+	return err
+}
@@ -0,0 +1,89 @@
+package jobqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDB records the statements run against it
+type recordingDB struct {
+	statements []string
+	args       [][]any
+}
+
+func (db *recordingDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.statements = append(db.statements, sql)
+	db.args = append(db.args, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (db *recordingDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	db.statements = append(db.statements, sql)
+	db.args = append(db.args, args)
+	return nil
+}
+
+var testJobs = &Table{
+	Name:     "jobs",
+	Noun:     "job",
+	Pending:  "queued",
+	Running:  "running",
+	Failed:   "failed",
+	Lease:    time.Minute,
+	Backoff:  Backoff{Base: 10 * time.Second, Max: time.Minute},
+	ClaimSet: "started_at = COALESCE(started_at, NOW())",
+}
+
+func TestBackoff_DoublesUpToMax(t *testing.T) {
+	b := Backoff{Base: 30 * time.Second, Max: 15 * time.Minute}
+	require.Equal(t, 30*time.Second, b.Delay(0))
+	require.Equal(t, 30*time.Second, b.Delay(1))
+	require.Equal(t, time.Minute, b.Delay(2))
+	require.Equal(t, 8*time.Minute, b.Delay(5))
+	require.Equal(t, 15*time.Minute, b.Delay(6))
+	require.Equal(t, 15*time.Minute, b.Delay(100))
+}
+
+func TestTable_ClaimExpiresFinalAttemptsThenLeases(t *testing.T) {
+	db := &recordingDB{}
+	_, err := testJobs.Claim(context.Background(), db, "id, attempts")
+	require.NoError(t, err)
+
+	require.Len(t, db.statements, 2)
+	require.Contains(t, db.statements[0], "SET status = 'failed'")
+	require.Contains(t, db.statements[0], "attempts >= max_attempts")
+	require.Contains(t, db.statements[1], "SET status = 'running'")
+	require.Contains(t, db.statements[1], "locked_at = NOW(), started_at = COALESCE(started_at, NOW())")
+	require.Contains(t, db.statements[1], "status = 'queued' AND next_attempt_at <= NOW()")
+	require.Contains(t, db.statements[1], "RETURNING id, attempts")
+	require.Equal(t, []any{60.0}, db.args[1])
+}
+
+func TestTable_FailRequeuesWithBackoffUntilLastAttempt(t *testing.T) {
+	id := uuid.New()
+
+	db := &recordingDB{}
+	retried, err := testJobs.Fail(context.Background(), db, id, 2, 3, "boom", false)
+	require.NoError(t, err)
+	require.True(t, retried)
+	require.Contains(t, db.statements[0], "SET status = 'queued'")
+	require.Equal(t, []any{id, 20.0, "boom"}, db.args[0])
+
+	db = &recordingDB{}
+	retried, err = testJobs.Fail(context.Background(), db, id, 3, 3, "boom", false)
+	require.NoError(t, err)
+	require.False(t, retried)
+	require.Contains(t, db.statements[0], "SET status = 'failed'")
+
+	db = &recordingDB{}
+	retried, err = testJobs.Fail(context.Background(), db, id, 1, 3, "bad input", true)
+	require.NoError(t, err)
+	require.False(t, retried)
+	require.Contains(t, db.statements[0], "SET status = 'failed'")
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned when a channel would post to an address
+// inside the server's network
+var errPrivateAddress = errors.New("destination is not a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is reachable on the internet, as opposed to
+// loopback, private, link-local (including cloud metadata endpoints such as
+// 169.254.169.254), unspecified or multicast addresses
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip4[0] == 0 || sharedAddressSpace.Contains(ip4) {
+			return false
+		}
+	}
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// isPrivateHost reports whether a URL host names the server's own network:
+// localhost or a non-public IP literal. Other names are checked when dialed.
+func isPrivateHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && !isPublicIP(ip)
+}
+
+// publicOnlyControl refuses connections to non-public addresses. It runs
+// after name resolution, so names that resolve, or redirect, to an internal
+// address are refused too.
+func publicOnlyControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// newHTTPClient creates the client channels post with. Unless allowPrivate
+// is set, it only connects to public addresses and ignores proxy settings,
+// as the proxy would connect on its behalf.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: timeout}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: publicOnlyControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BrokenTest is a test that failed on every attempt of consecutive
+// default-branch runs. It is the content of EventTestBroken messages.
+type BrokenTest struct {
+	Repo           string `json:"repo"`
+	Workflow       string `json:"workflow"`
+	Job            string `json:"job"`
+	JobVariant     string `json:"job_variant"`
+	TestID         string `json:"test_id"`
+	FailingRuns    int    `json:"failing_runs"`
+	LastGoodSHA    string `json:"last_good_sha"` // empty when the test never passed
+	FirstBadSHA    string `json:"first_bad_sha"`
+	CompareURL     string `json:"compare_url,omitempty"` // commits of the suspect range, when the code host is known
+	FailureMessage string `json:"failure_message,omitempty"`
+	DashboardURL   string `json:"dashboard_url"`
+}
+
+// JobLabel names the job of the broken test, including its variant
+func (b *BrokenTest) JobLabel() string {
+	if b.JobVariant == "" {
+		return b.Job
+	}
+	return b.Job + " (" + b.JobVariant + ")"
+}
+
+// Evidence describes how the breakage was observed
+func (b *BrokenTest) Evidence() string {
+	return fmt.Sprintf("Failed on every attempt of %d consecutive default-branch runs", b.FailingRuns)
+}
+
+// SuspectRange names the commits that may have broken the test, e.g.
+// "1a2b3c4..5d6e7f8"
+func (b *BrokenTest) SuspectRange() string {
+	if b.LastGoodSHA == "" {
+		return "up to " + shortSHA(b.FirstBadSHA) + " (no passing run seen)"
+	}
+	return shortSHA(b.LastGoodSHA) + ".." + shortSHA(b.FirstBadSHA)
+}
+
+// FailureSummary is the first line of the failure message, or ""
+func (b *BrokenTest) FailureSummary() string {
+	line, _, _ := strings.Cut(strings.TrimSpace(b.FailureMessage), "\n")
+	return truncate(strings.TrimSpace(line), 300)
+}
+
+// brokenTitle counts the broken tests of a message
+func brokenTitle(count int) string {
+	if count == 1 {
+		return "Broken Test Detected"
+	}
+	return fmt.Sprintf("%d Broken Tests Detected", count)
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func brokenMessage() *Message {
+	return &Message{
+		Event:       EventTestBroken,
+		ProjectName: "api",
+		Run: &Run{
+			Repo:         "acme/api",
+			Workflow:     "CI",
+			Branch:       "main",
+			DashboardURL: "https://fg.example.com/orgs/acme/projects/api/broken-tests",
+		},
+		BrokenTests: []BrokenTest{{
+			Repo:           "acme/api",
+			Workflow:       "CI",
+			Job:            "test",
+			TestID:         "pkg.TestBroken",
+			FailingRuns:    3,
+			LastGoodSHA:    "1111111aaaaaaa",
+			FirstBadSHA:    "2222222bbbbbbb",
+			CompareURL:     "https://github.com/acme/api/compare/1111111aaaaaaa...2222222bbbbbbb",
+			FailureMessage: "expected 1, got 2\nstack trace",
+			DashboardURL:   "https://fg.example.com/orgs/acme/projects/api/broken-tests#1",
+		}},
+	}
+}
+
+func TestBrokenTest_SuspectRange(t *testing.T) {
+	b := brokenMessage().BrokenTests[0]
+	require.Equal(t, "1111111..2222222", b.SuspectRange())
+	require.Equal(t, "expected 1, got 2", b.FailureSummary())
+
+	b.LastGoodSHA = ""
+	require.Equal(t, "up to 2222222 (no passing run seen)", b.SuspectRange())
+}
+
+func TestSlackNotifier_SendsBrokenTests(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{Type: ChannelSlack, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	require.NoError(t, n.Send(context.Background(), brokenMessage()))
+
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "Broken Test Detected in acme/api", payload.Text)
+	require.Equal(t, "🚨 Broken Test Detected", payload.Blocks[0].Text.Text)
+	require.Equal(t, "<https://fg.example.com/orgs/acme/projects/api/broken-tests#1|`pkg.TestBroken`>  _test_\n"+
+		"Failed on every attempt of 3 consecutive default-branch runs\n"+
+		"*Suspect commits:* <https://github.com/acme/api/compare/1111111aaaaaaa...2222222bbbbbbb|`1111111..2222222`>\n"+
+		">expected 1, got 2", payload.Blocks[2].Text.Text)
+	require.Equal(t, "actions", payload.Blocks[len(payload.Blocks)-1].Type)
+}
+
+func TestWebhookNotifier_SendsBrokenTests(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{Type: ChannelWebhook, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	require.NoError(t, n.Send(context.Background(), brokenMessage()))
+	require.Equal(t, EventTestBroken, got.headers.Get(HeaderEvent))
+
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Empty(t, payload.Flakes)
+	require.Len(t, payload.BrokenTests, 1)
+	require.Equal(t, "2222222bbbbbbb", payload.BrokenTests[0].FirstBadSHA)
+	require.Equal(t, 3, payload.BrokenTests[0].FailingRuns)
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Digest summarizes the flakiness of a project over a period. It is the
+// content of EventDigest messages.
+type Digest struct {
+	Frequency          string        `json:"frequency"` // "daily" or "weekly"
+	PeriodStart        time.Time     `json:"period_start"`
+	PeriodEnd          time.Time     `json:"period_end"` // exclusive
+	URL                string        `json:"url"`        // HTML view of the digest
+	FlakyTests         int           `json:"flaky_tests"`
+	FlakeEvents        int           `json:"flake_events"`
+	RetryMinutesWasted float64       `json:"retry_minutes_wasted"`
+	NewFlakes          []DigestEntry `json:"new_flakes"`
+	ScoreIncreases     []DigestEntry `json:"score_increases"`
+	Stabilized         []DigestEntry `json:"stabilized"`
+}
+
+// DigestEntry is one test listed in a digest
+type DigestEntry struct {
+	TestID       string `json:"test_id"`
+	Job          string `json:"job"`
+	Detail       string `json:"detail"` // e.g. "Flake rate 5% → 20%"
+	DashboardURL string `json:"dashboard_url"`
+}
+
+// DigestSection is a titled list of a digest
+type DigestSection struct {
+	Title   string
+	Entries []DigestEntry
+}
+
+// Sections returns the non-empty lists of the digest in display order
+func (d *Digest) Sections() []DigestSection {
+	all := []DigestSection{
+		{Title: "New flaky tests", Entries: d.NewFlakes},
+		{Title: "Biggest flake rate increases", Entries: d.ScoreIncreases},
+		{Title: "Became stable", Entries: d.Stabilized},
+	}
+	var sections []DigestSection
+	for _, s := range all {
+		if len(s.Entries) > 0 {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// Period formats the covered days, e.g. "Oct 5 – Oct 11, 2026"
+func (d *Digest) Period() string {
+	start := d.PeriodStart.UTC()
+	last := d.PeriodEnd.UTC().Add(-time.Nanosecond)
+	if start.Format("2006-01-02") == last.Format("2006-01-02") {
+		return start.Format("Mon, Jan 2, 2006")
+	}
+	return start.Format("Jan 2") + " – " + last.Format("Jan 2, 2006")
+}
+
+// Summary is a one-line overview of the period
+func (d *Digest) Summary() string {
+	if d.FlakyTests == 0 {
+		return "No flaky tests were detected."
+	}
+	tests := "flaky tests"
+	if d.FlakyTests == 1 {
+		tests = "flaky test"
+	}
+	summary := fmt.Sprintf("%d %s, %d new", d.FlakyTests, tests, len(d.NewFlakes))
+	if d.RetryMinutesWasted > 0 {
+		summary += fmt.Sprintf(", %s of CI retries wasted", formatMinutes(d.RetryMinutesWasted))
+	}
+	return summary + "."
+}
+
+// digestText renders the digest as plain text lines, used by channels without
+// rich formatting
+func (d *Digest) digestText() []string {
+	lines := []string{d.Period(), d.Summary()}
+	for _, s := range d.Sections() {
+		lines = append(lines, "", s.Title+":")
+		for _, e := range s.Entries {
+			lines = append(lines, fmt.Sprintf("- %s (%s): %s", e.TestID, e.Job, e.Detail))
+		}
+	}
+	if d.URL != "" {
+		lines = append(lines, "", "Full digest: "+d.URL)
+	}
+	return lines
+}
+
+// formatMinutes renders a duration in minutes, switching to hours above two hours
+func formatMinutes(minutes float64) string {
+	if minutes >= 120 {
+		return fmt.Sprintf("%.1f hours", minutes/60)
+	}
+	if minutes < 1 {
+		return "<1 minute"
+	}
+	return fmt.Sprintf("%.0f minutes", minutes)
+}
+
+// digestTitle names a digest of the given frequency
+func digestTitle(frequency string) string {
+	if frequency == "" {
+		return "Flake Digest"
+	}
+	return strings.ToUpper(frequency[:1]) + frequency[1:] + " Flake Digest"
+}
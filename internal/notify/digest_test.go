@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func digestMessage() *Message {
+	return &Message{
+		Event:       EventDigest,
+		ProjectName: "api",
+		Flakes:      []Flake{},
+		Digest: &Digest{
+			Frequency:          "weekly",
+			PeriodStart:        time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:          time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			URL:                "https://fg.example.com/orgs/acme/projects/api/digests/1",
+			FlakyTests:         4,
+			FlakeEvents:        9,
+			RetryMinutesWasted: 150,
+			NewFlakes: []DigestEntry{
+				{TestID: "pkg.TestNew", Job: "test (linux)", Detail: "Flaked in 2 of 10 runs", DashboardURL: "https://fg.example.com/orgs/acme/projects/api/flakes/1"},
+			},
+			Stabilized: []DigestEntry{
+				{TestID: "pkg.TestFixed<T>", Job: "test", Detail: "No flakes in 12 runs, after 3 flaky runs the period before"},
+			},
+		},
+	}
+}
+
+func TestDigest_Summary(t *testing.T) {
+	d := digestMessage().Digest
+	require.Equal(t, "Weekly Flake Digest", digestMessage().Title())
+	require.Equal(t, "Oct 5 – Oct 11, 2026", d.Period())
+	require.Equal(t, "4 flaky tests, 1 new, 2.5 hours of CI retries wasted.", d.Summary())
+
+	sections := d.Sections()
+	require.Len(t, sections, 2, "empty lists are left out")
+	require.Equal(t, "New flaky tests", sections[0].Title)
+	require.Equal(t, "Became stable", sections[1].Title)
+
+	daily := &Digest{
+		Frequency:   "daily",
+		PeriodStart: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		FlakyTests:  1,
+	}
+	require.Equal(t, "Sun, Oct 11, 2026", daily.Period())
+	require.Equal(t, "1 flaky test, 0 new.", daily.Summary())
+	require.Equal(t, "No flaky tests were detected.", (&Digest{}).Summary())
+}
+
+func TestSlackNotifier_SendsDigest(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{Type: ChannelSlack, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	require.NoError(t, n.Send(context.Background(), digestMessage()))
+
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Elements []map[string]any `json:"elements"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "Weekly Flake Digest for api: 4 flaky tests, 1 new, 2.5 hours of CI retries wasted.", payload.Text)
+
+	var types []string
+	for _, b := range payload.Blocks {
+		types = append(types, b.Type)
+	}
+	require.Equal(t, []string{"header", "context", "section", "divider", "section", "divider", "section", "actions"}, types)
+	require.Equal(t, "📊 Weekly Flake Digest", payload.Blocks[0].Text.Text)
+	require.Equal(t, "*New flaky tests*\n• <https://fg.example.com/orgs/acme/projects/api/flakes/1|`pkg.TestNew`>  _test (linux)_ — Flaked in 2 of 10 runs", payload.Blocks[4].Text.Text)
+	require.Contains(t, payload.Blocks[6].Text.Text, "`pkg.TestFixed&lt;T&gt;`")
+	require.Equal(t, "https://fg.example.com/orgs/acme/projects/api/digests/1", payload.Blocks[7].Elements[0]["url"])
+}
+
+func TestDigestSection_StaysWithinTextLimit(t *testing.T) {
+	s := DigestSection{Title: "New flaky tests"}
+	for i := 0; i < 40; i++ {
+		s.Entries = append(s.Entries, DigestEntry{TestID: strings.Repeat("x", 100), Job: "test", Detail: "Flaked in 1 of 1 runs"})
+	}
+
+	text := digestSection(s)
+	require.LessOrEqual(t, len(text), 3000)
+	require.Regexp(t, `…and \d+ more$`, text)
+}
+
+func TestWebhookNotifier_SendsDigest(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{
+		Type:          ChannelWebhook,
+		URL:           sql.NullString{String: srv.URL, Valid: true},
+		SigningSecret: sql.NullString{String: "s3cret", Valid: true},
+	})
+
+	require.NoError(t, n.Send(context.Background(), digestMessage()))
+	require.Equal(t, EventDigest, got.headers.Get(HeaderEvent))
+
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Empty(t, payload.Flakes)
+	require.NotNil(t, payload.Digest)
+	require.Equal(t, digestMessage().Digest, payload.Digest)
+}
+
+func TestEmailNotifier_SendsDigest(t *testing.T) {
+	body := string(buildEmail("flakeguard@example.com", []string{"a@example.com"}, digestMessage(), time.Now()))
+
+	require.Contains(t, body, "Subject: [FlakeGuard] api: Weekly Flake Digest\r\n")
+	require.Contains(t, body, "Oct 5 – Oct 11, 2026\r\n4 flaky tests, 1 new, 2.5 hours of CI retries wasted.\r\n")
+	require.Contains(t, body, "New flaky tests:\r\n- pkg.TestNew (test (linux)): Flaked in 2 of 10 runs\r\n")
+	require.Contains(t, body, "Full digest: https://fg.example.com/orgs/acme/projects/api/digests/1\r\n")
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxDiscordEmbeds is Discord's limit of embeds per message
+const maxDiscordEmbeds = 10
+
+// DiscordNotifier posts to a Discord channel webhook
+type DiscordNotifier struct {
+	client *http.Client
+	url    string
+}
+
+type discordPayload struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// Embed colors of flake and broken test notifications
+const (
+	discordOrange = 0xF59E0B
+	discordRed    = 0xDC2626
+)
+
+func (n *DiscordNotifier) Type() ChannelType { return ChannelDiscord }
+
+// Send posts msg with one embed per flake
+func (n *DiscordNotifier) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(discordMessage(msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Discord payload: %w", err)
+	}
+	return postJSON(ctx, n.client, n.url, body, nil)
+}
+
+func discordMessage(msg *Message) discordPayload {
+	if msg.Event == EventTest {
+		return discordPayload{Content: fmt.Sprintf("**%s**\n%s", msg.Title(), msg.testText())}
+	}
+
+	if d := msg.Digest; msg.Event == EventDigest && d != nil {
+		return discordDigest(msg.Title(), msg.ProjectName, d)
+	}
+
+	if msg.Event == EventTestBroken {
+		return discordBroken(msg)
+	}
+
+	payload := discordPayload{Content: fmt.Sprintf("**%s**", msg.Title())}
+	for i := range msg.Flakes {
+		if i == maxDiscordEmbeds {
+			payload.Content += fmt.Sprintf(" (showing %d of %d)", maxDiscordEmbeds, len(msg.Flakes))
+			break
+		}
+		f := &msg.Flakes[i]
+		description := f.Evidence()
+		if history := f.History(); history != "" {
+			description += "\n" + history
+		}
+		payload.Embeds = append(payload.Embeds, discordEmbed{
+			Title:       truncate(f.TestID, 256),
+			URL:         f.DashboardURL,
+			Description: description,
+			Color:       discordOrange,
+			Fields: []discordField{
+				{Name: "Repository", Value: f.Repo, Inline: true},
+				{Name: "Workflow", Value: f.Workflow, Inline: true},
+				{Name: "Job", Value: f.JobLabel(), Inline: true},
+			},
+		})
+	}
+	return payload
+}
+
+// discordBroken renders broken tests with one embed per test
+func discordBroken(msg *Message) discordPayload {
+	payload := discordPayload{Content: fmt.Sprintf("**%s**", msg.Title())}
+	for i := range msg.BrokenTests {
+		if i == maxDiscordEmbeds {
+			payload.Content += fmt.Sprintf(" (showing %d of %d)", maxDiscordEmbeds, len(msg.BrokenTests))
+			break
+		}
+		b := &msg.BrokenTests[i]
+		suspects := "`" + b.SuspectRange() + "`"
+		if b.CompareURL != "" {
+			suspects = "[" + suspects + "](" + b.CompareURL + ")"
+		}
+		description := b.Evidence() + "\nSuspect commits: " + suspects
+		if summary := b.FailureSummary(); summary != "" {
+			description += "\n" + summary
+		}
+		payload.Embeds = append(payload.Embeds, discordEmbed{
+			Title:       truncate(b.TestID, 256),
+			URL:         b.DashboardURL,
+			Description: description,
+			Color:       discordRed,
+			Fields: []discordField{
+				{Name: "Repository", Value: b.Repo, Inline: true},
+				{Name: "Workflow", Value: b.Workflow, Inline: true},
+				{Name: "Job", Value: b.JobLabel(), Inline: true},
+			},
+		})
+	}
+	return payload
+}
+
+// discordDigest renders a digest with one embed per section
+func discordDigest(title, projectName string, d *Digest) discordPayload {
+	payload := discordPayload{Content: fmt.Sprintf("**%s** · %s · %s\n%s", title, projectName, d.Period(), d.Summary())}
+	for _, s := range d.Sections() {
+		lines := make([]string, 0, len(s.Entries))
+		for _, e := range s.Entries {
+			name := "`" + truncate(e.TestID, 200) + "`"
+			if e.DashboardURL != "" {
+				name = "[" + name + "](" + e.DashboardURL + ")"
+			}
+			lines = append(lines, fmt.Sprintf("%s (%s): %s", name, e.Job, e.Detail))
+		}
+		payload.Embeds = append(payload.Embeds, discordEmbed{
+			Title:       s.Title,
+			URL:         d.URL,
+			Description: truncate(strings.Join(lines, "\n"), 4096),
+			Color:       discordOrange,
+		})
+	}
+	return payload
+}
+
+func truncate(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes-3]) + "..."
+}
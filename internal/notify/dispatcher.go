@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// legacySlackChannelName names the project Slack webhook when it is delivered
+// to alongside the configured channels
+const legacySlackChannelName = "Project Slack webhook"
+
+// Dispatcher resolves the channels of a project and sends notifications to them
+type Dispatcher struct {
+	pool *pgxpool.Pool
+	opts Options
+}
+
+// NewDispatcher creates a dispatcher using the HTTP timeout and SMTP relay of cfg
+func NewDispatcher(pool *pgxpool.Pool, cfg *config.Config) *Dispatcher {
+	return NewDispatcherWithOptions(pool, OptionsFromConfig(cfg))
+}
+
+// NewDispatcherWithOptions creates a dispatcher with explicit notifier options
+func NewDispatcherWithOptions(pool *pgxpool.Pool, opts Options) *Dispatcher {
+	return &Dispatcher{pool: pool, opts: opts}
+}
+
+// Channels returns the channels a project's notifications are delivered to:
+// its enabled channels plus the project Slack webhook, when configured
+func (d *Dispatcher) Channels(ctx context.Context, project *projects.Project) ([]Channel, error) {
+	channels, err := NewService(d.pool).ListEnabled(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	if project.HasSlackConfigured() {
+		channels = append(channels, legacySlackChannel(project))
+	}
+	return channels, nil
+}
+
+// legacySlackChannel is the project Slack webhook as a channel. It has no ID.
+func legacySlackChannel(project *projects.Project) Channel {
+	return Channel{
+		ProjectID: project.ID,
+		Type:      ChannelSlack,
+		Name:      legacySlackChannelName,
+		Enabled:   true,
+		URL:       sql.NullString{String: project.SlackWebhookURL.String, Valid: true},
+	}
+}
+
+// deliveryChannel loads the current configuration of the channel a delivery
+// is for. Channels that were disabled or removed since are a permanent error.
+func (d *Dispatcher) deliveryChannel(ctx context.Context, delivery *Delivery) (*Channel, error) {
+	if delivery.ChannelID == nil {
+		project, err := projects.NewService(d.pool).GetByID(ctx, delivery.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load project: %w", err)
+		}
+		if !project.HasSlackConfigured() {
+			return nil, errChannelGone
+		}
+		ch := legacySlackChannel(project)
+		return &ch, nil
+	}
+
+	ch, err := NewService(d.pool).GetByID(ctx, *delivery.ChannelID)
+	if errors.Is(err, ErrChannelNotFound) {
+		return nil, errChannelGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ch.Enabled {
+		return nil, errChannelGone
+	}
+	return ch, nil
+}
+
+// errChannelGone fails deliveries whose channel was disabled or removed
+var errChannelGone = errors.New("channel was disabled or removed")
+
+// Send delivers msg to a single channel
+func (d *Dispatcher) Send(ctx context.Context, ch *Channel, msg *Message) error {
+	notifier, err := NewNotifier(ch, d.opts)
+	if err != nil {
+		// The channel cannot be delivered to with this server's configuration
+		err = &permanentError{err: err}
+	} else {
+		err = notifier.Send(ctx, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("%s channel %q: %w", ch.Type, ch.Name, err)
+	}
+
+	log.Info().
+		Str("project_id", ch.ProjectID.String()).
+		Str("channel_type", string(ch.Type)).
+		Str("channel_name", ch.Name).
+		Str("event", msg.Event).
+		Int("flakes", len(msg.Flakes)).
+		Msg("Notification sent")
+	return nil
+}
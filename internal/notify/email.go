@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends plain-text email through the configured SMTP relay
+type EmailNotifier struct {
+	smtp       SMTPConfig
+	recipients []string
+	send       func(ctx context.Context, cfg SMTPConfig, to []string, msg []byte) error
+}
+
+func (n *EmailNotifier) Type() ChannelType { return ChannelEmail }
+
+// Send mails msg to all recipients of the channel
+func (n *EmailNotifier) Send(ctx context.Context, msg *Message) error {
+	return n.send(ctx, n.smtp, n.recipients, buildEmail(n.smtp.From, n.recipients, msg, time.Now()))
+}
+
+// buildEmail renders msg as an RFC 5322 message
+func buildEmail(from string, to []string, msg *Message, date time.Time) []byte {
+	subject := fmt.Sprintf("[FlakeGuard] %s: %s", msg.ProjectName, msg.Title())
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+
+	if msg.Event == EventTest {
+		b.WriteString(msg.testText() + "\r\n")
+		return b.Bytes()
+	}
+	if msg.Event == EventDigest && msg.Digest != nil {
+		for _, line := range msg.Digest.digestText() {
+			b.WriteString(line + "\r\n")
+		}
+		return b.Bytes()
+	}
+
+	if run := msg.Run; run != nil {
+		fmt.Fprintf(&b, "%s on %s", msg.Title(), run.Repo)
+		if run.Branch != "" {
+			fmt.Fprintf(&b, " (%s)", run.Branch)
+		}
+		if run.Label != "" {
+			fmt.Fprintf(&b, ", %s", run.Label)
+		}
+		b.WriteString(".\r\n")
+		if run.URL != "" {
+			fmt.Fprintf(&b, "Run: %s\r\n", run.URL)
+		}
+		if run.DashboardURL != "" && msg.Event == EventTestBroken {
+			fmt.Fprintf(&b, "All broken tests: %s\r\n", run.DashboardURL)
+		} else if run.DashboardURL != "" {
+			fmt.Fprintf(&b, "All flaky tests: %s\r\n", run.DashboardURL)
+		}
+	}
+
+	for i := range msg.BrokenTests {
+		bt := &msg.BrokenTests[i]
+		b.WriteString("\r\n")
+		fmt.Fprintf(&b, "Test:       %s\r\n", bt.TestID)
+		fmt.Fprintf(&b, "Repository: %s\r\n", bt.Repo)
+		fmt.Fprintf(&b, "Workflow:   %s\r\n", bt.Workflow)
+		fmt.Fprintf(&b, "Job:        %s\r\n", bt.JobLabel())
+		fmt.Fprintf(&b, "Evidence:   %s\r\n", bt.Evidence())
+		fmt.Fprintf(&b, "Suspects:   %s\r\n", bt.SuspectRange())
+		if bt.CompareURL != "" {
+			fmt.Fprintf(&b, "Compare:    %s\r\n", bt.CompareURL)
+		}
+		if summary := bt.FailureSummary(); summary != "" {
+			fmt.Fprintf(&b, "Failure:    %s\r\n", summary)
+		}
+		if bt.DashboardURL != "" {
+			fmt.Fprintf(&b, "Details:    %s\r\n", bt.DashboardURL)
+		}
+	}
+
+	for i := range msg.Flakes {
+		f := &msg.Flakes[i]
+		b.WriteString("\r\n")
+		fmt.Fprintf(&b, "Test:       %s\r\n", f.TestID)
+		fmt.Fprintf(&b, "Repository: %s\r\n", f.Repo)
+		fmt.Fprintf(&b, "Workflow:   %s\r\n", f.Workflow)
+		fmt.Fprintf(&b, "Job:        %s\r\n", f.JobLabel())
+		fmt.Fprintf(&b, "Evidence:   %s\r\n", f.Evidence())
+		if history := f.History(); history != "" {
+			fmt.Fprintf(&b, "History:    %s\r\n", history)
+		}
+		if f.DashboardURL != "" {
+			fmt.Fprintf(&b, "Details:    %s\r\n", f.DashboardURL)
+		}
+	}
+	return b.Bytes()
+}
+
+// sendMail is smtp.SendMail bounded by ctx. STARTTLS is used when the relay
+// offers it; credentials are only sent when a username is configured.
+func sendMail(ctx context.Context, cfg SMTPConfig, to []string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP relay: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(cfg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", rcpt, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,575 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/audit"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxNameLength   = 100
+	maxURLLength    = 500
+	maxRecipients   = 20
+	maxSecretLength = 200
+
+	// signingSecretPrefix marks generated webhook signing secrets
+	signingSecretPrefix = "fgwh_"
+)
+
+// CreateRequest represents the request to create a notification channel.
+// url is required for slack, teams, discord and webhook channels; recipients
+// for email. A webhook signing_secret is generated when omitted.
+type CreateRequest struct {
+	Type          ChannelType `json:"type"`
+	Name          string      `json:"name"`
+	URL           string      `json:"url,omitempty"`
+	Recipients    []string    `json:"recipients,omitempty"`
+	SigningSecret string      `json:"signing_secret,omitempty"`
+}
+
+// UpdateRequest represents the request to update a notification channel.
+// Omitted fields are left unchanged.
+type UpdateRequest struct {
+	Name       *string   `json:"name,omitempty"`
+	Enabled    *bool     `json:"enabled,omitempty"`
+	URL        *string   `json:"url,omitempty"`
+	Recipients *[]string `json:"recipients,omitempty"`
+}
+
+// HandleCreate handles POST /api/v1/projects/{project_id}/notification-channels
+func HandleCreate(pool *pgxpool.Pool, cfg *config.Config, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		var req CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if !req.Type.IsValid() {
+			apperrors.WriteBadRequest(w, r, "type must be one of: slack, teams, webhook, email, discord")
+			return
+		}
+		name, err := validateName(req.Name)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
+
+		params := CreateParams{Type: req.Type, Name: name}
+		if req.Type == ChannelEmail {
+			if !cfg.SMTPEnabled() {
+				apperrors.WriteBadRequest(w, r, "Email channels require an SMTP relay; set FG_SMTP_HOST on the server")
+				return
+			}
+			if params.Recipients, err = validateRecipients(req.Recipients); err != nil {
+				apperrors.WriteBadRequest(w, r, err.Error())
+				return
+			}
+		} else if params.URL, err = validateURL(req.Type, req.URL, cfg.NotifyAllowPrivateURLs); err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
+
+		// The signing secret is returned once, like API key tokens
+		var signingSecret string
+		if req.Type == ChannelWebhook {
+			signingSecret = strings.TrimSpace(req.SigningSecret)
+			if signingSecret == "" {
+				if signingSecret, err = generateSigningSecret(); err != nil {
+					log.Error().Err(err).Msg("Failed to generate signing secret")
+					apperrors.WriteInternalError(w, r, "Failed to create notification channel")
+					return
+				}
+			}
+			if len(signingSecret) > maxSecretLength {
+				apperrors.WriteBadRequest(w, r, "signing_secret is too long")
+				return
+			}
+			params.SigningSecret = signingSecret
+		}
+
+		service := NewService(pool)
+		ch, err := service.Create(ctx, project.ID, userID, params)
+		if err != nil {
+			if errors.Is(err, ErrNameConflict) {
+				apperrors.WriteConflict(w, r, "A notification channel with this name already exists")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to create notification channel")
+			apperrors.WriteInternalError(w, r, "Failed to create notification channel")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogChannelCreated(ctx, project.OrgID, project.ID, ch.ID, userID, string(ch.Type), ch.Name); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		data := map[string]any{
+			"channel": ch.ToResponse(),
+		}
+		if signingSecret != "" {
+			data["signing_secret"] = signingSecret
+		}
+		apperrors.WriteSuccess(w, r, http.StatusCreated, data)
+	}
+}
+
+// HandleList handles GET /api/v1/projects/{project_id}/notification-channels
+func HandleList(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		channels, err := service.ListByProject(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list notification channels")
+			apperrors.WriteInternalError(w, r, "Failed to list notification channels")
+			return
+		}
+
+		resp := make([]ChannelResponse, len(channels))
+		for i := range channels {
+			resp[i] = channels[i].ToResponse()
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"channels": resp,
+		})
+	}
+}
+
+// HandleUpdate handles PUT /api/v1/projects/{project_id}/notification-channels/{channel_id}
+func HandleUpdate(pool *pgxpool.Pool, cfg *config.Config, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		ch, ok := requireChannel(w, r, service, project.ID)
+		if !ok {
+			return
+		}
+
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		params := UpdateParams{}
+		changes := map[string]interface{}{}
+
+		if req.Name != nil {
+			name, err := validateName(*req.Name)
+			if err != nil {
+				apperrors.WriteBadRequest(w, r, err.Error())
+				return
+			}
+			params.Name = &name
+			changes["name"] = name
+		}
+		if req.Enabled != nil {
+			params.Enabled = req.Enabled
+			changes["enabled"] = *req.Enabled
+		}
+		if req.URL != nil {
+			if ch.Type == ChannelEmail {
+				apperrors.WriteBadRequest(w, r, "Email channels have recipients, not a url")
+				return
+			}
+			u, err := validateURL(ch.Type, *req.URL, cfg.NotifyAllowPrivateURLs)
+			if err != nil {
+				apperrors.WriteBadRequest(w, r, err.Error())
+				return
+			}
+			params.URL = &u
+			// The URL is a credential; only record that it changed
+			changes["url"] = "<updated>"
+		}
+		if req.Recipients != nil {
+			if ch.Type != ChannelEmail {
+				apperrors.WriteBadRequest(w, r, "Only email channels have recipients")
+				return
+			}
+			recipients, err := validateRecipients(*req.Recipients)
+			if err != nil {
+				apperrors.WriteBadRequest(w, r, err.Error())
+				return
+			}
+			params.Recipients = recipients
+			changes["recipients"] = recipients
+		}
+
+		if len(changes) == 0 {
+			apperrors.WriteBadRequest(w, r, "No changes provided")
+			return
+		}
+
+		updated, err := service.Update(ctx, ch.ID, params)
+		if err != nil {
+			if errors.Is(err, ErrChannelNotFound) {
+				apperrors.WriteNotFound(w, r, "Notification channel not found")
+				return
+			}
+			if errors.Is(err, ErrNameConflict) {
+				apperrors.WriteConflict(w, r, "A notification channel with this name already exists")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to update notification channel")
+			apperrors.WriteInternalError(w, r, "Failed to update notification channel")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogChannelUpdated(ctx, project.OrgID, project.ID, ch.ID, userID, ch.Name, changes); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"channel": updated.ToResponse(),
+		})
+	}
+}
+
+// HandleRemove handles DELETE /api/v1/projects/{project_id}/notification-channels/{channel_id}
+func HandleRemove(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		ch, ok := requireChannel(w, r, service, project.ID)
+		if !ok {
+			return
+		}
+
+		if err := service.Delete(ctx, ch.ID); err != nil {
+			if errors.Is(err, ErrChannelNotFound) {
+				apperrors.WriteNotFound(w, r, "Notification channel already removed or not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to remove notification channel")
+			apperrors.WriteInternalError(w, r, "Failed to remove notification channel")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogChannelRemoved(ctx, project.OrgID, project.ID, ch.ID, userID, string(ch.Type), ch.Name); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"removed": true,
+		})
+	}
+}
+
+// HandleSendTest handles POST /api/v1/projects/{project_id}/notifications/test.
+// It queues a test message for every channel of the project; the outcome shows
+// in the delivery log.
+func HandleSendTest(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		channels, err := NewDispatcherWithOptions(pool, Options{}).Channels(ctx, project)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load notification channels")
+			apperrors.WriteInternalError(w, r, "Failed to send test notification")
+			return
+		}
+		if len(channels) == 0 {
+			apperrors.WriteBadRequest(w, r, "No enabled notification channels")
+			return
+		}
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to begin transaction")
+			apperrors.WriteInternalError(w, r, "Failed to send test notification")
+			return
+		}
+		defer func() {
+			_ = tx.Rollback(ctx)
+		}()
+
+		msg := &Message{Event: EventTest, ProjectName: project.Name}
+		deliveries, err := NewOutbox(pool).Enqueue(ctx, tx, project.ID, nil, channels, msg)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to enqueue test notification")
+			apperrors.WriteInternalError(w, r, "Failed to send test notification")
+			return
+		}
+
+		resp := make([]DeliveryResponse, len(deliveries))
+		for i := range deliveries {
+			resp[i] = deliveries[i].ToResponse()
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusAccepted, map[string]any{
+			"deliveries": resp,
+		})
+	}
+}
+
+// HandleListDeliveries handles GET /api/v1/projects/{project_id}/notification-deliveries
+func HandleListDeliveries(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		limit := 20
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+				limit = min(v, MaxDeliveriesListed)
+			}
+		}
+
+		deliveries, err := NewOutbox(pool).ListByProject(ctx, project.ID, limit)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list notification deliveries")
+			apperrors.WriteInternalError(w, r, "Failed to list notification deliveries")
+			return
+		}
+
+		resp := make([]DeliveryResponse, len(deliveries))
+		for i := range deliveries {
+			resp[i] = deliveries[i].ToResponse()
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"deliveries": resp,
+		})
+	}
+}
+
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len(name) > maxNameLength {
+		return "", errors.New("name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+// validateURL checks the destination URL of a non-email channel. Teams and
+// generic webhooks must use https to a host outside the server's network,
+// unless allowPrivate is set.
+func validateURL(channelType ChannelType, raw string, allowPrivate bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if channelType == ChannelSlack {
+		return raw, validation.ValidateWebhookURL(raw)
+	}
+
+	if raw == "" {
+		return "", errors.New("url is required")
+	}
+	if len(raw) > maxURLLength {
+		return "", errors.New("url must be at most 500 characters")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", errors.New("url must be an absolute http(s) URL")
+	}
+
+	switch channelType {
+	case ChannelTeams:
+		if u.Scheme != "https" {
+			return "", errors.New("Teams webhook URL must use https")
+		}
+		if !allowPrivate && isPrivateHost(u.Hostname()) {
+			return "", errors.New("url must not point to a private or loopback address")
+		}
+	case ChannelDiscord:
+		if u.Scheme != "https" ||
+			(u.Host != "discord.com" && u.Host != "discordapp.com") ||
+			!strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return "", errors.New("Discord webhook URL must start with https://discord.com/api/webhooks/")
+		}
+	case ChannelWebhook:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return "", errors.New("url must be an absolute http(s) URL")
+		}
+		if allowPrivate {
+			break
+		}
+		if u.Scheme != "https" {
+			return "", errors.New("webhook URL must use https")
+		}
+		if isPrivateHost(u.Hostname()) {
+			return "", errors.New("url must not point to a private or loopback address")
+		}
+	}
+	return raw, nil
+}
+
+// validateRecipients normalizes and checks the addresses of an email channel
+func validateRecipients(raw []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var recipients []string
+	for _, r := range raw {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(r)
+		if err != nil || addr.Name != "" {
+			return nil, fmt.Errorf("invalid email address: %s", r)
+		}
+		email := strings.ToLower(addr.Address)
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		recipients = append(recipients, email)
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("recipients is required for email channels")
+	}
+	if len(recipients) > maxRecipients {
+		return nil, fmt.Errorf("at most %d recipients are allowed", maxRecipients)
+	}
+	return recipients, nil
+}
+
+func generateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return signingSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// requireProject loads the {project_id} project and checks org membership
+// (or OWNER/ADMIN when mutate is true). Writes the error response on failure.
+func requireProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool, mutate bool) (*projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid project ID")
+		return nil, false
+	}
+
+	projectService := projects.NewService(pool)
+	project, err := projectService.GetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get project")
+		apperrors.WriteInternalError(w, r, "Failed to get project")
+		return nil, false
+	}
+
+	orgService := orgs.NewService(pool)
+	if mutate {
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+	} else {
+		_, err = orgService.RequireOrgMember(ctx, userID, project.OrgID)
+	}
+	if err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		if errors.Is(err, orgs.ErrInsufficientPermissions) {
+			apperrors.WriteForbidden(w, r, "Insufficient permissions")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org permissions")
+		apperrors.WriteInternalError(w, r, "Failed to check permissions")
+		return nil, false
+	}
+
+	return project, true
+}
+
+// requireChannel loads the {channel_id} channel and verifies it belongs to the project
+func requireChannel(w http.ResponseWriter, r *http.Request, service *Service, projectID uuid.UUID) (*Channel, bool) {
+	channelID, err := uuid.Parse(chi.URLParam(r, "channel_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid channel ID")
+		return nil, false
+	}
+
+	ch, err := service.GetByID(r.Context(), channelID)
+	if err != nil {
+		if errors.Is(err, ErrChannelNotFound) {
+			apperrors.WriteNotFound(w, r, "Notification channel not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get notification channel")
+		apperrors.WriteInternalError(w, r, "Failed to get notification channel")
+		return nil, false
+	}
+
+	if ch.ProjectID != projectID {
+		apperrors.WriteNotFound(w, r, "Notification channel not found")
+		return nil, false
+	}
+
+	return ch, true
+}
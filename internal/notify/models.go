@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChannelType is the kind of destination a channel delivers to
+type ChannelType string
+
+const (
+	ChannelSlack   ChannelType = "slack"
+	ChannelTeams   ChannelType = "teams"
+	ChannelWebhook ChannelType = "webhook"
+	ChannelEmail   ChannelType = "email"
+	ChannelDiscord ChannelType = "discord"
+)
+
+// IsValid returns true if the channel type is known
+func (t ChannelType) IsValid() bool {
+	switch t {
+	case ChannelSlack, ChannelTeams, ChannelWebhook, ChannelEmail, ChannelDiscord:
+		return true
+	}
+	return false
+}
+
+// Channel is a notification destination configured for a project
+type Channel struct {
+	ID              uuid.UUID      `db:"id"`
+	ProjectID       uuid.UUID      `db:"project_id"`
+	Type            ChannelType    `db:"type"`
+	Name            string         `db:"name"`
+	Enabled         bool           `db:"enabled"`
+	URL             sql.NullString `db:"url"`
+	Recipients      []string       `db:"recipients"`
+	SigningSecret   sql.NullString `db:"signing_secret"`
+	CreatedByUserID uuid.UUID      `db:"created_by_user_id"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+}
+
+// ChannelResponse is the API view of a channel. Webhook URLs and signing
+// secrets are credentials and never returned; Target identifies the destination.
+type ChannelResponse struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       ChannelType `json:"type"`
+	Name       string      `json:"name"`
+	Enabled    bool        `json:"enabled"`
+	Target     string      `json:"target"`
+	Recipients []string    `json:"recipients,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// Target returns the host of the channel URL, or the recipients of an email channel
+func (c *Channel) Target() string {
+	if c.Type == ChannelEmail {
+		return strings.Join(c.Recipients, ", ")
+	}
+	u, err := url.Parse(c.URL.String)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (c *Channel) ToResponse() ChannelResponse {
+	resp := ChannelResponse{
+		ID:        c.ID,
+		Type:      c.Type,
+		Name:      c.Name,
+		Enabled:   c.Enabled,
+		Target:    c.Target(),
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.Type == ChannelEmail {
+		resp.Recipients = c.Recipients
+	}
+	return resp
+}
+
+// Delivery statuses
+const (
+	DeliveryPending = "pending"
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+// Delivery is a message queued in the outbox for one channel
+type Delivery struct {
+	ID            uuid.UUID   `db:"id"`
+	ProjectID     uuid.UUID   `db:"project_id"`
+	ChannelID     *uuid.UUID  `db:"channel_id"` // nil for the project Slack webhook
+	ChannelType   ChannelType `db:"channel_type"`
+	ChannelName   string      `db:"channel_name"`
+	Event         string      `db:"event"`
+	CIRunID       *uuid.UUID  `db:"ci_run_id"`
+	Message       Message     `db:"message"`
+	Status        string      `db:"status"`
+	Attempts      int         `db:"attempts"` // including the current one while sending
+	MaxAttempts   int         `db:"max_attempts"`
+	NextAttemptAt time.Time   `db:"next_attempt_at"`
+	LastError     *string     `db:"last_error"`
+	CreatedAt     time.Time   `db:"created_at"`
+	CompletedAt   *time.Time  `db:"completed_at"`
+}
+
+// DeliveryResponse is the API view of a delivery
+type DeliveryResponse struct {
+	ID            uuid.UUID   `json:"id"`
+	ChannelID     *uuid.UUID  `json:"channel_id"`
+	ChannelType   ChannelType `json:"channel_type"`
+	ChannelName   string      `json:"channel_name"`
+	Event         string      `json:"event"`
+	CIRunID       *uuid.UUID  `json:"ci_run_id"`
+	Flakes        int         `json:"flakes"`
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	NextAttemptAt *time.Time  `json:"next_attempt_at,omitempty"`
+	Error         *string     `json:"error,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	CompletedAt   *time.Time  `json:"completed_at"`
+}
+
+func (d *Delivery) ToResponse() DeliveryResponse {
+	resp := DeliveryResponse{
+		ID:          d.ID,
+		ChannelID:   d.ChannelID,
+		ChannelType: d.ChannelType,
+		ChannelName: d.ChannelName,
+		Event:       d.Event,
+		CIRunID:     d.CIRunID,
+		Flakes:      len(d.Message.Flakes),
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		Error:       d.LastError,
+		CreatedAt:   d.CreatedAt,
+		CompletedAt: d.CompletedAt,
+	}
+	// Only a retry has a meaningful next attempt
+	if d.Status == DeliveryPending && d.Attempts > 0 {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
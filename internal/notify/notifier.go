@@ -0,0 +1,297 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/config"
+)
+
+// Notification events
+const (
+	EventFlakeDetected = "flake.detected"
+	EventTestBroken    = "test.broken"
+	EventTest          = "test"
+	EventDigest        = "digest"
+)
+
+// Notifier delivers notifications to one channel
+type Notifier interface {
+	// Type returns the channel type the notifier delivers to
+	Type() ChannelType
+	// Send delivers msg. Errors are returned to the caller, which decides
+	// whether they matter.
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Message is one notification for a project. It is stored as JSON in the
+// delivery outbox until sent.
+type Message struct {
+	Event       string       `json:"event"`
+	ProjectName string       `json:"project_name"`
+	Run         *Run         `json:"run,omitempty"` // CI run the flakes were detected on; nil for test notifications
+	Flakes      []Flake      `json:"flakes"`        // empty for test notifications, digests and broken tests
+	Digest      *Digest      `json:"digest,omitempty"`
+	BrokenTests []BrokenTest `json:"broken_tests,omitempty"`
+}
+
+// Run identifies the CI run a batch of flakes was detected on
+type Run struct {
+	Repo         string `json:"repo"`
+	Workflow     string `json:"workflow"`
+	Branch       string `json:"branch"`
+	SHA          string `json:"sha"`
+	Label        string `json:"label"`         // e.g. "GitHub run #42"
+	URL          string `json:"url"`           // may be empty
+	DashboardURL string `json:"dashboard_url"` // flaky tests of the project
+}
+
+// Flake contains the details of one flake event
+type Flake struct {
+	Repo          string `json:"repo"`
+	Workflow      string `json:"workflow"`
+	Job           string `json:"job"`
+	JobVariant    string `json:"job_variant"`
+	TestID        string `json:"test_id"`
+	CrossRun      bool   `json:"cross_run"`    // failed and passed attempts belong to separate runs of the same commit
+	InJobRetry    bool   `json:"in_job_retry"` // failed and passed within one attempt via test framework reruns
+	FailedAttempt int    `json:"failed_attempt"`
+	PassedAttempt int    `json:"passed_attempt"`
+	DashboardURL  string `json:"dashboard_url"`
+
+	// History of the test across all runs, including this one
+	FlakeScore  float64   `json:"flake_score"`
+	FlakyRuns   int       `json:"flaky_runs"`
+	TotalRuns   int       `json:"total_runs"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+}
+
+// SortFlakes orders flakes by job, then by descending flake score so the
+// worst offenders of each job come first
+func SortFlakes(flakes []Flake) {
+	sort.SliceStable(flakes, func(i, j int) bool {
+		a, b := &flakes[i], &flakes[j]
+		if a.Job != b.Job {
+			return a.Job < b.Job
+		}
+		if a.JobVariant != b.JobVariant {
+			return a.JobVariant < b.JobVariant
+		}
+		if a.FlakeScore != b.FlakeScore {
+			return a.FlakeScore > b.FlakeScore
+		}
+		return a.TestID < b.TestID
+	})
+}
+
+// MergeFlakes adds the flakes of a later ingestion of a run to those already
+// queued. A test listed in both keeps its latest entry.
+func MergeFlakes(queued, added []Flake) []Flake {
+	type key struct{ job, variant, test string }
+	index := make(map[key]int, len(queued)+len(added))
+	merged := make([]Flake, 0, len(queued)+len(added))
+	for _, flakes := range [][]Flake{queued, added} {
+		for _, f := range flakes {
+			k := key{f.Job, f.JobVariant, f.TestID}
+			if i, ok := index[k]; ok {
+				merged[i] = f
+				continue
+			}
+			index[k] = len(merged)
+			merged = append(merged, f)
+		}
+	}
+	SortFlakes(merged)
+	return merged
+}
+
+// JobLabel names the job of the flake, including its variant
+func (f *Flake) JobLabel() string {
+	if f.JobVariant == "" {
+		return f.Job
+	}
+	return f.Job + " (" + f.JobVariant + ")"
+}
+
+// History summarizes how often the test has flaked, or "" when unknown
+func (f *Flake) History() string {
+	if f.TotalRuns == 0 {
+		return ""
+	}
+	history := fmt.Sprintf("Flake score %.0f%%, flaked in %d of %d runs", f.FlakeScore*100, f.FlakyRuns, f.TotalRuns)
+	if !f.FirstSeenAt.IsZero() {
+		history += " since " + f.FirstSeenAt.UTC().Format("Jan 2, 2006")
+	}
+	return history
+}
+
+// JobGroup is the flakes of one job within a message
+type JobGroup struct {
+	Job    string
+	Flakes []Flake
+}
+
+// ByJob groups the flakes by job label, keeping the order of first appearance
+func (m *Message) ByJob() []JobGroup {
+	var groups []JobGroup
+	index := make(map[string]int)
+	for _, f := range m.Flakes {
+		job := f.JobLabel()
+		i, ok := index[job]
+		if !ok {
+			i = len(groups)
+			index[job] = i
+			groups = append(groups, JobGroup{Job: job})
+		}
+		groups[i].Flakes = append(groups[i].Flakes, f)
+	}
+	return groups
+}
+
+// Evidence describes how the flake was observed
+func (f *Flake) Evidence() string {
+	if f.InJobRetry {
+		return fmt.Sprintf("Failed and passed on a test framework rerun within attempt %d", f.PassedAttempt)
+	}
+	if f.CrossRun {
+		return "Failed on one run, passed on a separate run of the same commit"
+	}
+	return fmt.Sprintf("Failed on attempt %d, passed on attempt %d", f.FailedAttempt, f.PassedAttempt)
+}
+
+// Title is a one-line summary of the message
+func (m *Message) Title() string {
+	if m.Event == EventTest {
+		return "FlakeGuard test notification"
+	}
+	if m.Event == EventDigest && m.Digest != nil {
+		return digestTitle(m.Digest.Frequency)
+	}
+	if m.Event == EventTestBroken {
+		return brokenTitle(len(m.BrokenTests))
+	}
+	if len(m.Flakes) == 1 {
+		return "Flaky Test Detected"
+	}
+	return fmt.Sprintf("%d Flaky Tests Detected", len(m.Flakes))
+}
+
+// testText is the body of a test notification
+func (m *Message) testText() string {
+	return fmt.Sprintf("This channel will receive flaky test notifications for project %s.", m.ProjectName)
+}
+
+// Options configures the notifiers created by NewNotifier
+type Options struct {
+	HTTPClient *http.Client
+	SMTP       SMTPConfig
+}
+
+// SMTPConfig is the SMTP relay used by email channels
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// OptionsFromConfig builds notifier options from the server config
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		HTTPClient: newHTTPClient(time.Duration(cfg.SlackTimeoutMS)*time.Millisecond, cfg.NotifyAllowPrivateURLs),
+		SMTP: SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
+	}
+}
+
+// NewNotifier creates the notifier for a channel
+func NewNotifier(ch *Channel, opts Options) (Notifier, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	switch ch.Type {
+	case ChannelSlack:
+		return &SlackNotifier{client: client, url: ch.URL.String}, nil
+	case ChannelTeams:
+		return &TeamsNotifier{client: client, url: ch.URL.String}, nil
+	case ChannelDiscord:
+		return &DiscordNotifier{client: client, url: ch.URL.String}, nil
+	case ChannelWebhook:
+		return &WebhookNotifier{client: client, url: ch.URL.String, secret: ch.SigningSecret.String}, nil
+	case ChannelEmail:
+		if opts.SMTP.Host == "" {
+			return nil, fmt.Errorf("email channel %q requires an SMTP relay (FG_SMTP_HOST)", ch.Name)
+		}
+		return &EmailNotifier{smtp: opts.SMTP, recipients: ch.Recipients, send: sendMail}, nil
+	}
+	return nil, fmt.Errorf("unknown channel type %q", ch.Type)
+}
+
+// postJSON posts a JSON body and fails on non-2xx responses. The URL is left
+// out of errors since webhook URLs are credentials.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FlakeGuard")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("request failed: %w", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// StatusError is a non-2xx response of a channel endpoint
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("endpoint returned status %d", e.StatusCode)
+}
+
+// Permanent reports whether retrying the request cannot succeed: the endpoint
+// rejected it with a client error other than a timeout or rate limit
+func (e *StatusError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+// unwrapURLError strips the URL from net/http client errors
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
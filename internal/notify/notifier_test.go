@@ -0,0 +1,379 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// capture is an endpoint recording the requests it receives
+type capture struct {
+	status  int
+	headers http.Header
+	body    []byte
+}
+
+func newCapture(t *testing.T, status int) (*capture, *httptest.Server) {
+	c := &capture{status: status}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.headers = r.Header.Clone()
+		c.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func flakeMessage() *Message {
+	return &Message{
+		Event:       EventFlakeDetected,
+		ProjectName: "api",
+		Flakes: []Flake{{
+			Repo:          "acme/api",
+			Workflow:      "CI",
+			Job:           "test",
+			TestID:        "pkg.TestFlaky",
+			FailedAttempt: 1,
+			PassedAttempt: 2,
+			DashboardURL:  "https://fg.example.com/orgs/acme/projects/api/flakes/1",
+		}},
+	}
+}
+
+func notifierFor(t *testing.T, ch *Channel) Notifier {
+	n, err := NewNotifier(ch, Options{HTTPClient: &http.Client{Timeout: 2 * time.Second}})
+	require.NoError(t, err)
+	require.Equal(t, ch.Type, n.Type())
+	return n
+}
+
+func TestSlackNotifier_SendsOneBlockKitMessagePerRun(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{Type: ChannelSlack, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	msg := flakeMessage()
+	msg.Run = &Run{
+		Repo:         "acme/api",
+		Workflow:     "CI",
+		Branch:       "main",
+		Label:        "GitHub run #42",
+		URL:          "https://github.com/acme/api/actions/runs/1",
+		DashboardURL: "https://fg.example.com/orgs/acme/projects/api/flakes",
+	}
+	msg.Flakes[0].FlakeScore = 0.125
+	msg.Flakes[0].FlakyRuns = 3
+	msg.Flakes[0].TotalRuns = 40
+	msg.Flakes[0].FirstSeenAt = time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	msg.Flakes = append(msg.Flakes,
+		Flake{Repo: "acme/api", Workflow: "CI", Job: "lint", TestID: "pkg.TestLint<T>", FailedAttempt: 1, PassedAttempt: 2},
+		Flake{Repo: "acme/api", Workflow: "CI", Job: "test", TestID: "pkg.TestOther", FailedAttempt: 1, PassedAttempt: 2},
+	)
+
+	require.NoError(t, n.Send(context.Background(), msg))
+
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Elements []map[string]any `json:"elements"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "3 Flaky Tests Detected in acme/api", payload.Text)
+
+	var types, texts []string
+	for _, b := range payload.Blocks {
+		types = append(types, b.Type)
+		texts = append(texts, b.Text.Text)
+	}
+	// Grouped by job in order of first appearance: test (2 flakes), lint (1)
+	require.Equal(t, []string{"header", "context", "divider", "section", "section", "section", "divider", "section", "section", "actions"}, types)
+	require.Equal(t, "🔄 3 Flaky Tests Detected", texts[0])
+	require.Equal(t, "*Job:* test", texts[3])
+	require.Equal(t, "<https://fg.example.com/orgs/acme/projects/api/flakes/1|`pkg.TestFlaky`>\n"+
+		"Failed on attempt 1, passed on attempt 2\n"+
+		"_Flake score 12%, flaked in 3 of 40 runs since Jan 2, 2024_", texts[4])
+	require.Contains(t, texts[5], "pkg.TestOther")
+	require.Equal(t, "*Job:* lint", texts[7])
+	require.Contains(t, texts[8], "`pkg.TestLint&lt;T&gt;`")
+
+	require.Contains(t, payload.Blocks[1].Elements[0]["text"], "<https://github.com/acme/api/actions/runs/1|GitHub run #42>")
+	require.Equal(t, msg.Run.DashboardURL, payload.Blocks[9].Elements[0]["url"])
+}
+
+func TestSlackMessage_StaysWithinBlockLimit(t *testing.T) {
+	msg := flakeMessage()
+	msg.Run = &Run{Repo: "acme/api", DashboardURL: "https://fg.example.com/flakes"}
+	for i := 0; i < 60; i++ {
+		msg.Flakes = append(msg.Flakes, Flake{Job: fmt.Sprintf("job-%d", i%20), TestID: fmt.Sprintf("pkg.Test%d", i)})
+	}
+
+	payload := slackMessage(msg)
+	require.LessOrEqual(t, len(payload.Blocks), maxSlackBlocks)
+	require.Equal(t, "actions", payload.Blocks[len(payload.Blocks)-1].Type)
+	footer := payload.Blocks[len(payload.Blocks)-2]
+	require.Equal(t, "context", footer.Type)
+	require.Regexp(t, `^…and \d+ more flaky tests$`, footer.Elements[0].(slackText).Text)
+}
+
+func TestNotifier_ReportsHTTPFailureWithoutURL(t *testing.T) {
+	_, srv := newCapture(t, http.StatusNotFound)
+	n := notifierFor(t, &Channel{Type: ChannelSlack, URL: sql.NullString{String: srv.URL + "/secret-path", Valid: true}})
+
+	err := n.Send(context.Background(), flakeMessage())
+	require.EqualError(t, err, "endpoint returned status 404")
+
+	srv.Close()
+	err = n.Send(context.Background(), flakeMessage())
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret-path")
+}
+
+func TestTeamsNotifier_SendsAdaptiveCard(t *testing.T) {
+	got, srv := newCapture(t, http.StatusAccepted)
+	n := notifierFor(t, &Channel{Type: ChannelTeams, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	require.NoError(t, n.Send(context.Background(), flakeMessage()))
+
+	var payload struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string           `json:"type"`
+				Body []map[string]any `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "message", payload.Type)
+	require.Len(t, payload.Attachments, 1)
+	require.Equal(t, "application/vnd.microsoft.card.adaptive", payload.Attachments[0].ContentType)
+	require.Equal(t, "AdaptiveCard", payload.Attachments[0].Content.Type)
+	require.Equal(t, "Flaky Test Detected", payload.Attachments[0].Content.Body[0]["text"])
+	require.Contains(t, string(got.body), "pkg.TestFlaky")
+}
+
+func TestDiscordNotifier_LimitsEmbeds(t *testing.T) {
+	got, srv := newCapture(t, http.StatusNoContent)
+	n := notifierFor(t, &Channel{Type: ChannelDiscord, URL: sql.NullString{String: srv.URL, Valid: true}})
+
+	msg := flakeMessage()
+	for len(msg.Flakes) < 12 {
+		msg.Flakes = append(msg.Flakes, msg.Flakes[0])
+	}
+	require.NoError(t, n.Send(context.Background(), msg))
+
+	var payload discordPayload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "**12 Flaky Tests Detected** (showing 10 of 12)", payload.Content)
+	require.Len(t, payload.Embeds, maxDiscordEmbeds)
+	require.Equal(t, "pkg.TestFlaky", payload.Embeds[0].Title)
+	require.Equal(t, msg.Flakes[0].DashboardURL, payload.Embeds[0].URL)
+}
+
+func TestWebhookNotifier_SignsPayload(t *testing.T) {
+	got, srv := newCapture(t, http.StatusOK)
+	n := notifierFor(t, &Channel{
+		Type:          ChannelWebhook,
+		URL:           sql.NullString{String: srv.URL, Valid: true},
+		SigningSecret: sql.NullString{String: "s3cret", Valid: true},
+	}).(*WebhookNotifier)
+	n.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	msg := flakeMessage()
+	msg.Flakes[0].InJobRetry = true
+	require.NoError(t, n.Send(context.Background(), msg))
+
+	require.Equal(t, EventFlakeDetected, got.headers.Get(HeaderEvent))
+	require.Equal(t, "1700000000", got.headers.Get(HeaderTimestamp))
+	require.Equal(t, Sign("s3cret", "1700000000", got.body), got.headers.Get(HeaderSignature))
+	require.NotEqual(t, Sign("other", "1700000000", got.body), got.headers.Get(HeaderSignature))
+
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	require.Equal(t, "api", payload.Project)
+	require.Len(t, payload.Flakes, 1)
+	require.True(t, payload.Flakes[0].InJobRetry)
+	require.Equal(t, "Failed and passed on a test framework rerun within attempt 2", payload.Flakes[0].Evidence)
+}
+
+func TestEmailNotifier_Send(t *testing.T) {
+	_, err := NewNotifier(&Channel{Type: ChannelEmail, Name: "oncall", Recipients: []string{"a@example.com"}}, Options{})
+	require.Error(t, err, "email channels need an SMTP relay")
+
+	n, err := NewNotifier(&Channel{Type: ChannelEmail, Recipients: []string{"a@example.com", "b@example.com"}}, Options{
+		SMTP: SMTPConfig{Host: "smtp.example.com", Port: 587, From: "flakeguard@example.com"},
+	})
+	require.NoError(t, err)
+
+	var sentTo []string
+	var sent string
+	n.(*EmailNotifier).send = func(_ context.Context, cfg SMTPConfig, to []string, msg []byte) error {
+		require.Equal(t, "smtp.example.com", cfg.Host)
+		sentTo, sent = to, string(msg)
+		return nil
+	}
+
+	require.NoError(t, n.Send(context.Background(), flakeMessage()))
+	require.Equal(t, []string{"a@example.com", "b@example.com"}, sentTo)
+	require.Contains(t, sent, "From: flakeguard@example.com\r\n")
+	require.Contains(t, sent, "To: a@example.com, b@example.com\r\n")
+	require.Contains(t, sent, "Subject: [FlakeGuard] api: Flaky Test Detected\r\n")
+	require.Contains(t, sent, "Test:       pkg.TestFlaky\r\n")
+	require.True(t, strings.HasSuffix(sent, "Details:    https://fg.example.com/orgs/acme/projects/api/flakes/1\r\n"))
+}
+
+func TestValidateURL(t *testing.T) {
+	_, err := validateURL(ChannelSlack, "https://example.com/hook", false)
+	require.Error(t, err)
+	_, err = validateURL(ChannelSlack, "https://hooks.slack.com/services/T/B/X", false)
+	require.NoError(t, err)
+
+	_, err = validateURL(ChannelTeams, "http://example.webhook.office.com/x", false)
+	require.Error(t, err)
+	_, err = validateURL(ChannelTeams, "https://example.webhook.office.com/x", false)
+	require.NoError(t, err)
+
+	_, err = validateURL(ChannelDiscord, "https://example.com/api/webhooks/1/abc", false)
+	require.Error(t, err)
+	_, err = validateURL(ChannelDiscord, "https://discord.com/api/webhooks/1/abc", false)
+	require.NoError(t, err)
+
+	_, err = validateURL(ChannelWebhook, "ftp://example.com/x", false)
+	require.Error(t, err)
+	u, err := validateURL(ChannelWebhook, " https://ci.example.com:8443/flakes ", false)
+	require.NoError(t, err)
+	require.Equal(t, "https://ci.example.com:8443/flakes", u)
+}
+
+func TestValidateURL_RejectsPrivateWebhooks(t *testing.T) {
+	for _, raw := range []string{
+		"http://ci.example.com/flakes",
+		"https://localhost/flakes",
+		"https://127.0.0.1/flakes",
+		"https://10.0.0.5/flakes",
+		"https://192.168.1.10:8443/flakes",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/flakes",
+		"https://[fd00:ec2::254]/flakes",
+		"https://0.0.0.0/flakes",
+	} {
+		_, err := validateURL(ChannelWebhook, raw, false)
+		require.Error(t, err, raw)
+	}
+	_, err := validateURL(ChannelTeams, "https://10.0.0.5/x", false)
+	require.Error(t, err)
+
+	// Allowed when the server is configured for internal receivers
+	u, err := validateURL(ChannelWebhook, "http://ci.internal:8080/flakes", true)
+	require.NoError(t, err)
+	require.Equal(t, "http://ci.internal:8080/flakes", u)
+	_, err = validateURL(ChannelWebhook, "https://127.0.0.1/flakes", true)
+	require.NoError(t, err)
+}
+
+func TestHTTPClient_RefusesPrivateAddressesAtDialTime(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	// httptest listens on loopback, standing in for an internal service or a
+	// public name that resolves to one
+	err := postJSON(context.Background(), newHTTPClient(2*time.Second, false), server.URL, []byte(`{}`), nil)
+	require.Error(t, err)
+	require.Zero(t, hits)
+
+	err = postJSON(context.Background(), newHTTPClient(2*time.Second, true), server.URL, []byte(`{}`), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, hits)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, raw := range []string{"8.8.8.8", "140.82.112.3", "2606:4700::1111"} {
+		require.True(t, isPublicIP(net.ParseIP(raw)), raw)
+	}
+	for _, raw := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.0.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"} {
+		require.False(t, isPublicIP(net.ParseIP(raw)), raw)
+	}
+}
+
+func TestValidateRecipients(t *testing.T) {
+	got, err := validateRecipients([]string{" A@Example.com", "", "b@example.com", "a@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a@example.com", "b@example.com"}, got)
+
+	_, err = validateRecipients([]string{"not-an-address"})
+	require.Error(t, err)
+	_, err = validateRecipients([]string{" "})
+	require.Error(t, err)
+}
+
+func TestChannel_ResponseHidesCredentials(t *testing.T) {
+	ch := &Channel{
+		Type:          ChannelWebhook,
+		Name:          "ci",
+		URL:           sql.NullString{String: "https://ci.example.com/hooks/flakes?token=abc", Valid: true},
+		SigningSecret: sql.NullString{String: "s3cret", Valid: true},
+	}
+	resp := ch.ToResponse()
+	require.Equal(t, "ci.example.com", resp.Target)
+
+	body, err := json.Marshal(resp)
+	require.NoError(t, err)
+	require.NotContains(t, string(body), "token=abc")
+	require.NotContains(t, string(body), "s3cret")
+}
+
+func TestSortFlakes_ByJobThenScore(t *testing.T) {
+	flakes := []Flake{
+		{Job: "test", TestID: "pkg.TestLow", FlakeScore: 0.1},
+		{Job: "lint", TestID: "pkg.TestLint", FlakeScore: 0.05},
+		{Job: "test", TestID: "pkg.TestHigh", FlakeScore: 0.6},
+		{Job: "test", JobVariant: "go1.22", TestID: "pkg.TestVariant", FlakeScore: 0.9},
+	}
+
+	SortFlakes(flakes)
+
+	var got []string
+	for _, f := range flakes {
+		got = append(got, f.TestID)
+	}
+	require.Equal(t, []string{"pkg.TestLint", "pkg.TestHigh", "pkg.TestLow", "pkg.TestVariant"}, got)
+}
+
+func TestMergeFlakes_KeepsLatestEntryPerTest(t *testing.T) {
+	queued := []Flake{
+		{Job: "test", TestID: "pkg.TestA", FlakeScore: 0.2},
+		{Job: "test", TestID: "pkg.TestB", FlakeScore: 0.3},
+	}
+	added := []Flake{
+		{Job: "lint", TestID: "pkg.TestLint", FlakeScore: 0.1},
+		{Job: "test", TestID: "pkg.TestA", FlakeScore: 0.5},
+	}
+
+	merged := MergeFlakes(queued, added)
+
+	var got []string
+	for _, f := range merged {
+		got = append(got, f.TestID)
+	}
+	require.Equal(t, []string{"pkg.TestLint", "pkg.TestA", "pkg.TestB"}, got)
+	require.Equal(t, 0.5, merged[1].FlakeScore)
+	require.Len(t, queued, 2)
+}
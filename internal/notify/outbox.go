@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/jobqueue"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxDeliveryErrorRunes = 1024
+
+	// MaxDeliveriesListed bounds the delivery log returned by the API
+	MaxDeliveriesListed = 100
+)
+
+// deliveryJobs leases deliveries to notification workers. A claimed delivery
+// may stay sending for 5 minutes before another worker assumes its worker died.
+var deliveryJobs = &jobqueue.Table{
+	Name:    "notification_deliveries",
+	Noun:    "delivery",
+	Pending: DeliveryPending,
+	Running: DeliverySending,
+	Failed:  DeliveryFailed,
+	Lease:   5 * time.Minute,
+	Backoff: jobqueue.Backoff{Base: 30 * time.Second, Max: time.Hour},
+}
+
+// Outbox is the Postgres-backed queue of notification deliveries. Messages are
+// enqueued in the transaction that produced them, so a notification is sent
+// exactly when its flake events are committed, and survives restarts.
+type Outbox struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutbox(pool *pgxpool.Pool) *Outbox {
+	return &Outbox{pool: pool}
+}
+
+const deliveryColumns = `
+	id, project_id, channel_id, channel_type::text, channel_name, event, ci_run_id, message,
+	status::text, attempts, max_attempts, next_attempt_at, last_error, created_at, completed_at
+`
+
+func scanDelivery(row pgx.Row) (*Delivery, error) {
+	var d Delivery
+	var channelType string
+	var message []byte
+	err := row.Scan(
+		&d.ID,
+		&d.ProjectID,
+		&d.ChannelID,
+		&channelType,
+		&d.ChannelName,
+		&d.Event,
+		&d.CIRunID,
+		&message,
+		&d.Status,
+		&d.Attempts,
+		&d.MaxAttempts,
+		&d.NextAttemptAt,
+		&d.LastError,
+		&d.CreatedAt,
+		&d.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.ChannelType = ChannelType(channelType)
+	if err := json.Unmarshal(message, &d.Message); err != nil {
+		return &d, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return &d, nil
+}
+
+// Enqueue queues msg for each channel within tx. Nothing is sent before tx
+// commits. ciRunID is nil for messages not about a CI run.
+func (o *Outbox) Enqueue(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, ciRunID *uuid.UUID, channels []Channel, msg *Message) ([]Delivery, error) {
+	message, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_deliveries (project_id, channel_id, channel_type, channel_name, event, ci_run_id, message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb)
+		RETURNING ` + deliveryColumns
+
+	deliveries := make([]Delivery, 0, len(channels))
+	for i := range channels {
+		ch := &channels[i]
+		var channelID *uuid.UUID
+		if ch.ID != uuid.Nil {
+			channelID = &ch.ID
+		}
+
+		d, err := scanDelivery(tx.QueryRow(ctx, query,
+			projectID, channelID, string(ch.Type), ch.Name, msg.Event, ciRunID, string(message)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to enqueue delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// EnqueueRunFlakes queues the flakes of a CI run for each channel within tx,
+// delivered once no further flakes of the run arrived for quietPeriod. Flakes
+// found by later ingestions of the same run, such as the other jobs of a
+// matrix, are merged into the pending delivery in place, so each channel gets
+// one message per run.
+func (o *Outbox) EnqueueRunFlakes(ctx context.Context, tx pgx.Tx, projectID, ciRunID uuid.UUID, channels []Channel, msg *Message, quietPeriod time.Duration) error {
+	// Serializes the ingestions of a run until tx ends, so they merge into
+	// one pending delivery per channel
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM ci_runs WHERE id = $1 FOR NO KEY UPDATE`, ciRunID); err != nil {
+		return fmt.Errorf("failed to lock ci run: %w", err)
+	}
+
+	pendingQuery := `
+		SELECT ` + deliveryColumns + `
+		FROM notification_deliveries
+		WHERE ci_run_id = $1
+		  AND event = $2
+		  AND channel_type = $3
+		  AND channel_id IS NOT DISTINCT FROM $4
+		  AND status = 'pending'
+		  AND attempts = 0
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`
+	insertQuery := `
+		INSERT INTO notification_deliveries (project_id, channel_id, channel_type, channel_name, event, ci_run_id, message, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, NOW() + $8::float8 * INTERVAL '1 second')
+	`
+	updateQuery := `
+		UPDATE notification_deliveries
+		SET message = $2::jsonb, next_attempt_at = NOW() + $3::float8 * INTERVAL '1 second'
+		WHERE id = $1
+	`
+
+	for i := range channels {
+		ch := &channels[i]
+		var channelID *uuid.UUID
+		if ch.ID != uuid.Nil {
+			channelID = &ch.ID
+		}
+
+		merged := *msg
+		pending, err := scanDelivery(tx.QueryRow(ctx, pendingQuery, ciRunID, msg.Event, string(ch.Type), channelID))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to load pending delivery: %w", err)
+		}
+		if pending != nil {
+			merged.Flakes = MergeFlakes(pending.Message.Flakes, msg.Flakes)
+		}
+
+		message, err := json.Marshal(&merged)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+
+		if pending != nil {
+			_, err = tx.Exec(ctx, updateQuery, pending.ID, string(message), quietPeriod.Seconds())
+		} else {
+			_, err = tx.Exec(ctx, insertQuery,
+				projectID, channelID, string(ch.Type), ch.Name, msg.Event, ciRunID, string(message), quietPeriod.Seconds())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to enqueue delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// Claim takes the oldest due delivery, or one whose worker lease expired, and
+// marks it sending. Returns nil when nothing is due.
+func (o *Outbox) Claim(ctx context.Context) (*Delivery, error) {
+	row, err := deliveryJobs.Claim(ctx, o.pool, deliveryColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDelivery(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil && d == nil {
+		return nil, fmt.Errorf("failed to claim delivery: %w", err)
+	}
+	return d, err
+}
+
+// Succeed marks a delivery sent
+func (o *Outbox) Succeed(ctx context.Context, deliveryID uuid.UUID) error {
+	_, err := o.pool.Exec(ctx, `
+		UPDATE notification_deliveries
+		SET status = 'sent', completed_at = NOW(), locked_at = NULL, last_error = NULL
+		WHERE id = $1
+	`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to mark delivery sent: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The delivery is retried with exponential
+// backoff unless the failure is permanent or it was the last attempt, in which
+// case it is dead-lettered as failed. Returns whether it will be retried.
+func (o *Outbox) Fail(ctx context.Context, d *Delivery, cause error, permanent bool) (bool, error) {
+	message := truncate(cause.Error(), maxDeliveryErrorRunes)
+	return deliveryJobs.Fail(ctx, o.pool, d.ID, d.Attempts, d.MaxAttempts, message, permanent)
+}
+
+// Release puts a delivery back in the queue without counting the attempt, for
+// workers that stop mid-send
+func (o *Outbox) Release(ctx context.Context, deliveryID uuid.UUID) error {
+	return deliveryJobs.Release(ctx, o.pool, deliveryID)
+}
+
+// ListByProject returns the most recent deliveries of a project, newest first
+func (o *Outbox) ListByProject(ctx context.Context, projectID uuid.UUID, limit int) ([]Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM notification_deliveries
+		WHERE project_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2
+	`
+
+	rows, err := o.pool.Query(ctx, query, projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
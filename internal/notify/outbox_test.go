@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRetryDelay(t *testing.T) {
+	require.Equal(t, 30*time.Second, deliveryJobs.Backoff.Delay(1))
+	require.Equal(t, time.Minute, deliveryJobs.Backoff.Delay(2))
+	require.Equal(t, 32*time.Minute, deliveryJobs.Backoff.Delay(7))
+	require.Equal(t, time.Hour, deliveryJobs.Backoff.Delay(8))
+	require.Equal(t, time.Hour, deliveryJobs.Backoff.Delay(100))
+}
+
+func TestIsPermanent(t *testing.T) {
+	wrap := func(err error) error { return fmt.Errorf("slack channel %q: %w", "alerts", err) }
+
+	require.True(t, isPermanent(wrap(&StatusError{StatusCode: 404})))
+	require.True(t, isPermanent(wrap(&StatusError{StatusCode: 400})))
+	require.False(t, isPermanent(wrap(&StatusError{StatusCode: 429})))
+	require.False(t, isPermanent(wrap(&StatusError{StatusCode: 408})))
+	require.False(t, isPermanent(wrap(&StatusError{StatusCode: 503})))
+	require.False(t, isPermanent(wrap(errors.New("request failed: connection refused"))))
+
+	require.True(t, isPermanent(errChannelGone))
+	require.True(t, isPermanent(wrap(&permanentError{err: errors.New("requires an SMTP relay")})))
+}
+
+func TestMessage_RoundTripsThroughOutboxJSON(t *testing.T) {
+	msg := flakeMessage()
+	msg.Run = &Run{Repo: "acme/api", Label: "GitHub run #42"}
+	msg.Flakes[0].JobVariant = "linux"
+	msg.Flakes[0].FirstSeenAt = time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	stored, err := json.Marshal(msg)
+	require.NoError(t, err)
+
+	var decoded Message
+	require.NoError(t, json.Unmarshal(stored, &decoded))
+	require.Equal(t, *msg, decoded)
+}
+
+func TestDelivery_ToResponseShowsNextAttemptOnlyForRetries(t *testing.T) {
+	d := &Delivery{Status: DeliveryPending, NextAttemptAt: time.Now(), Message: *flakeMessage()}
+	require.Nil(t, d.ToResponse().NextAttemptAt)
+	require.Equal(t, 1, d.ToResponse().Flakes)
+
+	d.Attempts = 1
+	require.NotNil(t, d.ToResponse().NextAttemptAt)
+
+	d.Status = DeliveryFailed
+	require.Nil(t, d.ToResponse().NextAttemptAt)
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrChannelNotFound is returned when a notification channel is not found
+	ErrChannelNotFound = errors.New("notification channel not found")
+
+	// ErrNameConflict is returned when the project already has a channel with the name
+	ErrNameConflict = errors.New("notification channel name already exists")
+)
+
+// Service provides notification channel operations
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new notification channel service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+const channelColumns = `
+	id, project_id, type::text, name, enabled, url, recipients, signing_secret,
+	created_by_user_id, created_at, updated_at
+`
+
+func scanChannel(row pgx.Row) (*Channel, error) {
+	var ch Channel
+	err := row.Scan(
+		&ch.ID,
+		&ch.ProjectID,
+		&ch.Type,
+		&ch.Name,
+		&ch.Enabled,
+		&ch.URL,
+		&ch.Recipients,
+		&ch.SigningSecret,
+		&ch.CreatedByUserID,
+		&ch.CreatedAt,
+		&ch.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// GetByID retrieves a notification channel by ID
+func (s *Service) GetByID(ctx context.Context, channelID uuid.UUID) (*Channel, error) {
+	query := `SELECT ` + channelColumns + ` FROM notification_channels WHERE id = $1`
+
+	ch, err := scanChannel(s.pool.QueryRow(ctx, query, channelID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification channel: %w", err)
+	}
+
+	return ch, nil
+}
+
+// ListByProject retrieves all channels of a project, oldest first
+func (s *Service) ListByProject(ctx context.Context, projectID uuid.UUID) ([]Channel, error) {
+	query := `
+		SELECT ` + channelColumns + `
+		FROM notification_channels
+		WHERE project_id = $1
+		ORDER BY created_at ASC
+	`
+
+	return s.queryChannels(ctx, query, projectID)
+}
+
+// ListEnabled retrieves the enabled channels of a project
+func (s *Service) ListEnabled(ctx context.Context, projectID uuid.UUID) ([]Channel, error) {
+	query := `
+		SELECT ` + channelColumns + `
+		FROM notification_channels
+		WHERE project_id = $1 AND enabled
+		ORDER BY created_at ASC
+	`
+
+	return s.queryChannels(ctx, query, projectID)
+}
+
+func (s *Service) queryChannels(ctx context.Context, query string, args ...any) ([]Channel, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []Channel
+	for rows.Next() {
+		ch, err := scanChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		channels = append(channels, *ch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification channel rows: %w", err)
+	}
+
+	return channels, nil
+}
+
+// CreateParams contains the fields for a new notification channel
+type CreateParams struct {
+	Type          ChannelType
+	Name          string
+	URL           string
+	Recipients    []string
+	SigningSecret string
+}
+
+// Create creates a new, enabled notification channel
+func (s *Service) Create(ctx context.Context, projectID, userID uuid.UUID, params CreateParams) (*Channel, error) {
+	query := `
+		INSERT INTO notification_channels (project_id, type, name, url, recipients, signing_secret, created_by_user_id)
+		VALUES ($1, $2::notification_channel_type, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7)
+		RETURNING ` + channelColumns
+
+	recipients := params.Recipients
+	if recipients == nil {
+		recipients = []string{}
+	}
+
+	ch, err := scanChannel(s.pool.QueryRow(ctx, query,
+		projectID,
+		string(params.Type),
+		params.Name,
+		params.URL,
+		recipients,
+		params.SigningSecret,
+		userID,
+	))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrNameConflict
+		}
+		return nil, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+
+	return ch, nil
+}
+
+// UpdateParams contains the mutable fields of a notification channel.
+// Nil fields are left unchanged.
+type UpdateParams struct {
+	Name       *string
+	Enabled    *bool
+	URL        *string
+	Recipients []string
+}
+
+// Update changes the name, enabled flag or target of a channel
+func (s *Service) Update(ctx context.Context, channelID uuid.UUID, params UpdateParams) (*Channel, error) {
+	query := `
+		UPDATE notification_channels
+		SET
+			name = COALESCE($2, name),
+			enabled = COALESCE($3, enabled),
+			url = COALESCE($4, url),
+			recipients = COALESCE($5, recipients)
+		WHERE id = $1
+		RETURNING ` + channelColumns
+
+	ch, err := scanChannel(s.pool.QueryRow(ctx, query, channelID, params.Name, params.Enabled, params.URL, params.Recipients))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrNameConflict
+		}
+		return nil, fmt.Errorf("failed to update notification channel: %w", err)
+	}
+
+	return ch, nil
+}
+
+// Delete removes a notification channel
+func (s *Service) Delete(ctx context.Context, channelID uuid.UUID) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM notification_channels WHERE id = $1`, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
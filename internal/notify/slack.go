@@ -0,0 +1,292 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxSlackBlocks is Slack's limit of blocks per message. Flakes that do not
+// fit are counted in a footer and reachable through the dashboard link.
+const maxSlackBlocks = 50
+
+// SlackNotifier posts to a Slack incoming webhook
+type SlackNotifier struct {
+	client *http.Client
+	url    string
+}
+
+// slackPayload represents the JSON payload sent to Slack. Text is the
+// notification fallback when Blocks are present.
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+// slackBlock is a Block Kit block
+type slackBlock struct {
+	Type     string     `json:"type"`
+	Text     *slackText `json:"text,omitempty"`
+	Elements []any      `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+type slackButton struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+	URL  string    `json:"url"`
+}
+
+func (n *SlackNotifier) Type() ChannelType { return ChannelSlack }
+
+// Send posts msg as one Block Kit message
+func (n *SlackNotifier) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(slackMessage(msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack payload: %w", err)
+	}
+	return postJSON(ctx, n.client, n.url, body, nil)
+}
+
+// slackMessage renders msg as a header, the run it was detected on, and the
+// flakes grouped by job with their history and dashboard links
+func slackMessage(msg *Message) slackPayload {
+	if msg.Event == EventTest {
+		return slackPayload{
+			Text: msg.Title(),
+			Blocks: []slackBlock{
+				header("✅ " + msg.Title()),
+				section(escapeSlack(msg.testText())),
+			},
+		}
+	}
+
+	if msg.Event == EventDigest && msg.Digest != nil {
+		return slackDigest(msg)
+	}
+
+	if msg.Event == EventTestBroken {
+		return slackBroken(msg)
+	}
+
+	payload := slackPayload{Text: msg.Title()}
+	if msg.Run != nil {
+		payload.Text = fmt.Sprintf("%s in %s", msg.Title(), msg.Run.Repo)
+	}
+	payload.Blocks = append(payload.Blocks, header("🔄 "+msg.Title()))
+	if msg.Run != nil {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []any{slackText{Type: "mrkdwn", Text: runContext(msg.Run)}},
+		})
+	}
+
+	// Keep two blocks for the footer and the dashboard button
+	room := func(blocks int) bool { return len(payload.Blocks)+blocks <= maxSlackBlocks-2 }
+
+	shown := 0
+	for _, group := range msg.ByJob() {
+		if !room(3) {
+			break
+		}
+		payload.Blocks = append(payload.Blocks,
+			slackBlock{Type: "divider"},
+			section("*Job:* "+escapeSlack(group.Job)),
+		)
+		for i := range group.Flakes {
+			if !room(1) {
+				break
+			}
+			payload.Blocks = append(payload.Blocks, section(flakeSection(&group.Flakes[i])))
+			shown++
+		}
+	}
+
+	if shown < len(msg.Flakes) {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []any{slackText{Type: "mrkdwn", Text: fmt.Sprintf("…and %d more flaky tests", len(msg.Flakes)-shown)}},
+		})
+	}
+	if msg.Run != nil && msg.Run.DashboardURL != "" {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type: "actions",
+			Elements: []any{slackButton{
+				Type: "button",
+				Text: slackText{Type: "plain_text", Text: "View flaky tests"},
+				URL:  msg.Run.DashboardURL,
+			}},
+		})
+	}
+	return payload
+}
+
+// slackDigest renders a digest as a header, its summary, one section per list
+// and a link to the full digest
+func slackDigest(msg *Message) slackPayload {
+	d := msg.Digest
+	payload := slackPayload{
+		Text: fmt.Sprintf("%s for %s: %s", msg.Title(), msg.ProjectName, d.Summary()),
+		Blocks: []slackBlock{
+			header("📊 " + msg.Title()),
+			{
+				Type:     "context",
+				Elements: []any{slackText{Type: "mrkdwn", Text: "*Project:* " + escapeSlack(msg.ProjectName) + "  ·  " + d.Period()}},
+			},
+			section(escapeSlack(d.Summary())),
+		},
+	}
+	for _, s := range d.Sections() {
+		payload.Blocks = append(payload.Blocks, slackBlock{Type: "divider"}, section(digestSection(s)))
+	}
+	if d.URL != "" {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type: "actions",
+			Elements: []any{slackButton{
+				Type: "button",
+				Text: slackText{Type: "plain_text", Text: "View digest"},
+				URL:  d.URL,
+			}},
+		})
+	}
+	return payload
+}
+
+// slackBroken renders broken tests as a header, the run they were detected
+// on, and one section per test with its suspect commit range
+func slackBroken(msg *Message) slackPayload {
+	payload := slackPayload{Text: msg.Title()}
+	if msg.Run != nil {
+		payload.Text = fmt.Sprintf("%s in %s", msg.Title(), msg.Run.Repo)
+	}
+	payload.Blocks = append(payload.Blocks, header("🚨 "+msg.Title()))
+	if msg.Run != nil {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []any{slackText{Type: "mrkdwn", Text: runContext(msg.Run)}},
+		})
+	}
+
+	// Keep two blocks for the footer and the dashboard button
+	shown := 0
+	for i := range msg.BrokenTests {
+		if len(payload.Blocks)+1 > maxSlackBlocks-2 {
+			break
+		}
+		payload.Blocks = append(payload.Blocks, section(brokenSection(&msg.BrokenTests[i])))
+		shown++
+	}
+
+	if shown < len(msg.BrokenTests) {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type:     "context",
+			Elements: []any{slackText{Type: "mrkdwn", Text: fmt.Sprintf("…and %d more broken tests", len(msg.BrokenTests)-shown)}},
+		})
+	}
+	if msg.Run != nil && msg.Run.DashboardURL != "" {
+		payload.Blocks = append(payload.Blocks, slackBlock{
+			Type: "actions",
+			Elements: []any{slackButton{
+				Type: "button",
+				Text: slackText{Type: "plain_text", Text: "View broken tests"},
+				URL:  msg.Run.DashboardURL,
+			}},
+		})
+	}
+	return payload
+}
+
+func brokenSection(b *BrokenTest) string {
+	var s strings.Builder
+	name := "`" + escapeSlack(truncate(b.TestID, 500)) + "`"
+	if b.DashboardURL != "" {
+		name = fmt.Sprintf("<%s|%s>", b.DashboardURL, name)
+	}
+	s.WriteString(name + "  _" + escapeSlack(b.JobLabel()) + "_")
+	s.WriteString("\n" + escapeSlack(b.Evidence()))
+	suspects := "`" + escapeSlack(b.SuspectRange()) + "`"
+	if b.CompareURL != "" {
+		suspects = fmt.Sprintf("<%s|%s>", b.CompareURL, suspects)
+	}
+	s.WriteString("\n*Suspect commits:* " + suspects)
+	if summary := b.FailureSummary(); summary != "" {
+		s.WriteString("\n>" + escapeSlack(summary))
+	}
+	return s.String()
+}
+
+// digestSection lists the entries of a digest section, leaving out entries
+// that would exceed the section text limit
+func digestSection(s DigestSection) string {
+	const maxLen = 2900
+
+	var b strings.Builder
+	b.WriteString("*" + s.Title + "*")
+	for i, e := range s.Entries {
+		name := "`" + escapeSlack(truncate(e.TestID, 200)) + "`"
+		if e.DashboardURL != "" {
+			name = fmt.Sprintf("<%s|%s>", e.DashboardURL, name)
+		}
+		line := fmt.Sprintf("\n• %s  _%s_ — %s", name, escapeSlack(e.Job), escapeSlack(e.Detail))
+		if b.Len()+len(line) > maxLen {
+			fmt.Fprintf(&b, "\n…and %d more", len(s.Entries)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func runContext(run *Run) string {
+	parts := []string{"*Repository:* " + escapeSlack(run.Repo)}
+	if run.Workflow != "" {
+		parts = append(parts, "*Workflow:* "+escapeSlack(run.Workflow))
+	}
+	if run.Branch != "" {
+		parts = append(parts, "*Branch:* "+escapeSlack(run.Branch))
+	}
+	if run.Label != "" {
+		label := escapeSlack(run.Label)
+		if run.URL != "" {
+			label = fmt.Sprintf("<%s|%s>", run.URL, label)
+		}
+		parts = append(parts, label)
+	}
+	return strings.Join(parts, "  ·  ")
+}
+
+func flakeSection(f *Flake) string {
+	var b strings.Builder
+	name := "`" + escapeSlack(truncate(f.TestID, 500)) + "`"
+	if f.DashboardURL != "" {
+		name = fmt.Sprintf("<%s|%s>", f.DashboardURL, name)
+	}
+	b.WriteString(name)
+	b.WriteString("\n" + escapeSlack(f.Evidence()))
+	if history := f.History(); history != "" {
+		b.WriteString("\n_" + history + "_")
+	}
+	return b.String()
+}
+
+func header(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(text, 150), Emoji: true}}
+}
+
+func section(mrkdwn string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(mrkdwn, 3000)}}
+}
+
+// escapeSlack escapes the control characters of Slack mrkdwn
+func escapeSlack(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.ReplaceAll(s, ">", "&gt;")
+}
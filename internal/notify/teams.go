@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TeamsNotifier posts an Adaptive Card to a Microsoft Teams incoming webhook
+// (Workflows or legacy connector)
+type TeamsNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func (n *TeamsNotifier) Type() ChannelType { return ChannelTeams }
+
+// Send posts msg as one Adaptive Card
+func (n *TeamsNotifier) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(teamsPayload(msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Teams payload: %w", err)
+	}
+	return postJSON(ctx, n.client, n.url, body, nil)
+}
+
+func teamsPayload(msg *Message) map[string]any {
+	body := []map[string]any{
+		{"type": "TextBlock", "text": msg.Title(), "weight": "Bolder", "size": "Medium", "wrap": true},
+	}
+
+	if msg.Event == EventTest {
+		body = append(body, map[string]any{"type": "TextBlock", "text": msg.testText(), "wrap": true})
+	}
+	if d := msg.Digest; msg.Event == EventDigest && d != nil {
+		body = append(body, teamsDigest(msg.ProjectName, d)...)
+	}
+	for i := range msg.BrokenTests {
+		b := &msg.BrokenTests[i]
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": b.TestID, "fontType": "Monospace", "wrap": true, "separator": i > 0},
+			map[string]any{"type": "FactSet", "facts": teamsBrokenFacts(b)},
+		)
+		if b.DashboardURL != "" {
+			body = append(body, map[string]any{"type": "TextBlock", "text": "[View Details](" + b.DashboardURL + ")", "wrap": true})
+		}
+	}
+	for i := range msg.Flakes {
+		f := &msg.Flakes[i]
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": f.TestID, "fontType": "Monospace", "wrap": true, "separator": i > 0},
+			map[string]any{"type": "FactSet", "facts": teamsFacts(f)},
+		)
+		if f.DashboardURL != "" {
+			body = append(body, map[string]any{"type": "TextBlock", "text": "[View Details](" + f.DashboardURL + ")", "wrap": true})
+		}
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+func teamsDigest(projectName string, d *Digest) []map[string]any {
+	body := []map[string]any{
+		{"type": "TextBlock", "text": projectName + " · " + d.Period(), "isSubtle": true, "wrap": true},
+		{"type": "TextBlock", "text": d.Summary(), "wrap": true},
+	}
+	for _, s := range d.Sections() {
+		lines := make([]string, 0, len(s.Entries))
+		for _, e := range s.Entries {
+			name := e.TestID
+			if e.DashboardURL != "" {
+				name = "[" + e.TestID + "](" + e.DashboardURL + ")"
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s): %s", name, e.Job, e.Detail))
+		}
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": s.Title, "weight": "Bolder", "separator": true, "wrap": true},
+			map[string]any{"type": "TextBlock", "text": strings.Join(lines, "\n"), "wrap": true},
+		)
+	}
+	if d.URL != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": "[View digest](" + d.URL + ")", "wrap": true})
+	}
+	return body
+}
+
+func teamsBrokenFacts(b *BrokenTest) []map[string]string {
+	suspects := b.SuspectRange()
+	if b.CompareURL != "" {
+		suspects = "[" + suspects + "](" + b.CompareURL + ")"
+	}
+	facts := []map[string]string{
+		{"title": "Repository", "value": b.Repo},
+		{"title": "Workflow", "value": b.Workflow},
+		{"title": "Job", "value": b.JobLabel()},
+		{"title": "Evidence", "value": b.Evidence()},
+		{"title": "Suspect commits", "value": suspects},
+	}
+	if summary := b.FailureSummary(); summary != "" {
+		facts = append(facts, map[string]string{"title": "Failure", "value": summary})
+	}
+	return facts
+}
+
+func teamsFacts(f *Flake) []map[string]string {
+	facts := []map[string]string{
+		{"title": "Repository", "value": f.Repo},
+		{"title": "Workflow", "value": f.Workflow},
+		{"title": "Job", "value": f.JobLabel()},
+		{"title": "Evidence", "value": f.Evidence()},
+	}
+	if history := f.History(); history != "" {
+		facts = append(facts, map[string]string{"title": "History", "value": history})
+	}
+	return facts
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of generic webhook deliveries
+const (
+	HeaderEvent     = "X-FlakeGuard-Event"
+	HeaderTimestamp = "X-FlakeGuard-Timestamp"
+	HeaderSignature = "X-FlakeGuard-Signature"
+)
+
+// WebhookNotifier posts a signed JSON payload to an arbitrary endpoint
+type WebhookNotifier struct {
+	client *http.Client
+	url    string
+	secret string
+	now    func() time.Time
+}
+
+// webhookPayload is the documented JSON body of generic webhook deliveries
+type webhookPayload struct {
+	Event       string         `json:"event"`
+	Project     string         `json:"project"`
+	SentAt      time.Time      `json:"sent_at"`
+	Run         *webhookRun    `json:"run,omitempty"`
+	Flakes      []webhookFlake `json:"flakes"`
+	Digest      *Digest        `json:"digest,omitempty"`
+	BrokenTests []BrokenTest   `json:"broken_tests,omitempty"`
+}
+
+type webhookRun struct {
+	Repo         string `json:"repo"`
+	Workflow     string `json:"workflow"`
+	Branch       string `json:"branch"`
+	SHA          string `json:"sha"`
+	Label        string `json:"label"`
+	URL          string `json:"url,omitempty"`
+	DashboardURL string `json:"dashboard_url,omitempty"`
+}
+
+type webhookFlake struct {
+	Repo          string `json:"repo"`
+	Workflow      string `json:"workflow"`
+	Job           string `json:"job"`
+	JobVariant    string `json:"job_variant"`
+	TestID        string `json:"test_id"`
+	Evidence      string `json:"evidence"`
+	FailedAttempt int    `json:"failed_attempt"`
+	PassedAttempt int    `json:"passed_attempt"`
+	CrossRun      bool   `json:"cross_run"`
+	InJobRetry    bool   `json:"in_job_retry"`
+	DashboardURL  string `json:"dashboard_url"`
+
+	FlakeScore float64 `json:"flake_score"`
+	FlakyRuns  int     `json:"flaky_runs"`
+	TotalRuns  int     `json:"total_runs"`
+}
+
+func (n *WebhookNotifier) Type() ChannelType { return ChannelWebhook }
+
+// Send posts msg as JSON, signed with the channel's secret
+func (n *WebhookNotifier) Send(ctx context.Context, msg *Message) error {
+	now := time.Now
+	if n.now != nil {
+		now = n.now
+	}
+	sentAt := now().UTC()
+
+	payload := webhookPayload{
+		Event:       msg.Event,
+		Project:     msg.ProjectName,
+		SentAt:      sentAt,
+		Flakes:      make([]webhookFlake, 0, len(msg.Flakes)),
+		Digest:      msg.Digest,
+		BrokenTests: msg.BrokenTests,
+	}
+	if run := msg.Run; run != nil {
+		payload.Run = &webhookRun{
+			Repo:         run.Repo,
+			Workflow:     run.Workflow,
+			Branch:       run.Branch,
+			SHA:          run.SHA,
+			Label:        run.Label,
+			URL:          run.URL,
+			DashboardURL: run.DashboardURL,
+		}
+	}
+	for i := range msg.Flakes {
+		f := &msg.Flakes[i]
+		payload.Flakes = append(payload.Flakes, webhookFlake{
+			Repo:          f.Repo,
+			Workflow:      f.Workflow,
+			Job:           f.Job,
+			JobVariant:    f.JobVariant,
+			TestID:        f.TestID,
+			Evidence:      f.Evidence(),
+			FailedAttempt: f.FailedAttempt,
+			PassedAttempt: f.PassedAttempt,
+			CrossRun:      f.CrossRun,
+			InJobRetry:    f.InJobRetry,
+			DashboardURL:  f.DashboardURL,
+			FlakeScore:    f.FlakeScore,
+			FlakyRuns:     f.FlakyRuns,
+			TotalRuns:     f.TotalRuns,
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+	return postJSON(ctx, n.client, n.url, body, map[string]string{
+		HeaderEvent:     msg.Event,
+		HeaderTimestamp: timestamp,
+		HeaderSignature: Sign(n.secret, timestamp, body),
+	})
+}
+
+// Sign computes the X-FlakeGuard-Signature of a delivery: "sha256=" followed
+// by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the signing secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
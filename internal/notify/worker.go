@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultPollInterval is how often an idle worker checks the outbox
+	DefaultPollInterval = 2 * time.Second
+
+	// deliveryTimeout bounds a single delivery attempt
+	deliveryTimeout = time.Minute
+)
+
+// permanentError marks a delivery failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent reports whether a failed delivery should be dead-lettered
+// instead of retried
+func isPermanent(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) || errors.Is(err, errChannelGone) {
+		return true
+	}
+	var status *StatusError
+	return errors.As(err, &status) && status.Permanent()
+}
+
+// Worker sends the deliveries queued in the outbox, retrying failed ones with
+// exponential backoff
+type Worker struct {
+	outbox       *Outbox
+	dispatcher   *Dispatcher
+	PollInterval time.Duration
+}
+
+func NewWorker(pool *pgxpool.Pool, cfg *config.Config) *Worker {
+	return &Worker{
+		outbox:       NewOutbox(pool),
+		dispatcher:   NewDispatcher(pool, cfg),
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Run sends deliveries until ctx is cancelled, sleeping PollInterval whenever
+// the outbox is empty. A delivery interrupted by cancellation is put back.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		processed, err := w.processNextRecovered(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Notification worker error")
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// processNextRecovered keeps a panicking delivery from killing the worker. The
+// delivery stays sending and is reclaimed when its lease expires.
+func (w *Worker) processNextRecovered(ctx context.Context) (processed bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			processed, err = true, fmt.Errorf("notification worker panicked: %v", r)
+		}
+	}()
+	return w.ProcessNext(ctx)
+}
+
+// ProcessNext claims and sends one delivery. Returns false when none was due.
+// Delivery failures are recorded on the delivery; the returned error only
+// reports failures to talk to the outbox.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	delivery, err := w.outbox.Claim(ctx)
+	if delivery == nil {
+		return false, err
+	}
+	if err == nil {
+		err = w.send(ctx, delivery)
+	} else {
+		// The stored message itself is unusable
+		err = &permanentError{err: err}
+	}
+
+	if err == nil {
+		return true, w.outbox.Succeed(ctx, delivery.ID)
+	}
+
+	if ctx.Err() != nil {
+		if releaseErr := w.outbox.Release(context.WithoutCancel(ctx), delivery.ID); releaseErr != nil {
+			return true, fmt.Errorf("failed to release delivery %s: %w", delivery.ID, releaseErr)
+		}
+		return true, nil
+	}
+
+	retrying, failErr := w.outbox.Fail(ctx, delivery, err, isPermanent(err))
+	if failErr != nil {
+		return true, failErr
+	}
+
+	log.Warn().
+		Err(err).
+		Str("delivery_id", delivery.ID.String()).
+		Str("project_id", delivery.ProjectID.String()).
+		Str("channel_type", string(delivery.ChannelType)).
+		Str("channel_name", delivery.ChannelName).
+		Int("attempt", delivery.Attempts).
+		Bool("retrying", retrying).
+		Msg("Notification delivery failed")
+	return true, nil
+}
+
+func (w *Worker) send(ctx context.Context, delivery *Delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	ch, err := w.dispatcher.deliveryChannel(ctx, delivery)
+	if err != nil {
+		return err
+	}
+	return w.dispatcher.Send(ctx, ch, &delivery.Message)
+}
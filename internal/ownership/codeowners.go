@@ -0,0 +1,136 @@
+package ownership
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxCodeownersBytes bounds the size of a CODEOWNERS file
+const MaxCodeownersBytes = 1024 * 1024
+
+// Codeowners is a parsed CODEOWNERS file. As on GitHub and GitLab, the last
+// rule matching a path decides its owners.
+type Codeowners struct {
+	rules []codeownersRule
+}
+
+type codeownersRule struct {
+	pattern string
+	re      *regexp.Regexp
+	owners  []string
+}
+
+// ParseCodeowners parses a CODEOWNERS file. Each line is a gitignore-style
+// path pattern followed by owners (@user, @org/team or an email address);
+// a pattern without owners leaves its paths unowned. Comments, blank lines
+// and GitLab section headers are skipped.
+func ParseCodeowners(content string) (*Codeowners, error) {
+	if len(content) > MaxCodeownersBytes {
+		return nil, fmt.Errorf("CODEOWNERS must be at most %d bytes", MaxCodeownersBytes)
+	}
+
+	c := &Codeowners{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		pattern := strings.ReplaceAll(fields[0], `\#`, "#")
+		var owners []string
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			if !validOwner(field) {
+				return nil, fmt.Errorf("line %d: invalid owner %q", i+1, field)
+			}
+			owners = append(owners, field)
+		}
+
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		c.rules = append(c.rules, codeownersRule{pattern: pattern, re: re, owners: owners})
+	}
+	return c, nil
+}
+
+// Owners returns the owners of a repository path, or nil when it is unowned
+func (c *Codeowners) Owners(path string) []string {
+	if c == nil || path == "" {
+		return nil
+	}
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].re.MatchString(path) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// Rules is the number of rules in the file
+func (c *Codeowners) Rules() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.rules)
+}
+
+// validOwner accepts @user, @org/team and email addresses
+func validOwner(owner string) bool {
+	if name, ok := strings.CutPrefix(owner, "@"); ok {
+		return name != "" && !strings.Contains(name, "@")
+	}
+	local, domain, ok := strings.Cut(owner, "@")
+	return ok && local != "" && strings.Contains(domain, ".")
+}
+
+// compilePattern turns a CODEOWNERS pattern into a regexp over repository
+// paths. A pattern with a leading or inner slash is relative to the
+// repository root, others match at any depth; a match of a directory covers
+// everything below it. * and ? do not cross a slash, ** does.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, "[]") {
+		return nil, fmt.Errorf("unsupported pattern %q", pattern)
+	}
+
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.Trim(pattern, "/")
+	if p == "" {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
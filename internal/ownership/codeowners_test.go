@@ -0,0 +1,73 @@
+package ownership
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeowners_LastMatchWins(t *testing.T) {
+	c, err := ParseCodeowners(`# Default owners
+*                       @acme/platform
+
+[Payments]
+/services/payments/     @acme/payments pay@acme.io
+*.spec.ts               @acme/web # frontend specs
+docs/**/*.md            @acme/docs
+/services/payments/legacy/
+`)
+	require.NoError(t, err)
+	require.Equal(t, 5, c.Rules())
+
+	require.Equal(t, []string{"@acme/platform"}, c.Owners("cmd/main_test.go"))
+	require.Equal(t, []string{"@acme/payments", "pay@acme.io"}, c.Owners("services/payments/refund_test.go"))
+	require.Equal(t, []string{"@acme/web"}, c.Owners("services/payments/ui/refund.spec.ts"))
+	require.Equal(t, []string{"@acme/docs"}, c.Owners("docs/api/v1/index.md"))
+	require.Equal(t, []string{"@acme/docs"}, c.Owners("docs/index.md"))
+	require.Nil(t, c.Owners("services/payments/legacy/old_test.go"))
+	require.Nil(t, c.Owners(""))
+}
+
+func TestCodeowners_Patterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"apps/", "apps/web/a_test.go", true},
+		{"apps/", "src/apps/web/a_test.go", true},
+		{"apps/", "apps", false},
+		{"/apps", "apps/web/a_test.go", true},
+		{"/apps", "src/apps/a_test.go", false},
+		{"apps/*.go", "apps/a_test.go", true},
+		{"apps/*.go", "apps/web/a_test.go", false},
+		{"apps/**/*.go", "apps/web/deep/a_test.go", true},
+		{"**/testdata", "a/b/testdata/x.json", true},
+		{"Test?.java", "src/TestA.java", true},
+		{"Test?.java", "src/TestAB.java", false},
+		{"a.b", "axb", false},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCodeowners(tt.pattern + " @owner")
+		require.NoError(t, err, tt.pattern)
+		require.Equal(t, tt.want, c.Owners(tt.path) != nil, "%s vs %s", tt.pattern, tt.path)
+	}
+}
+
+func TestParseCodeowners_Invalid(t *testing.T) {
+	_, err := ParseCodeowners("* @acme/platform\n/src owner-without-at\n")
+	require.EqualError(t, err, `line 2: invalid owner "owner-without-at"`)
+
+	_, err = ParseCodeowners("!vendor/ @acme/platform")
+	require.Error(t, err)
+
+	_, err = ParseCodeowners("src/[ab]/ @acme/platform")
+	require.Error(t, err)
+}
+
+func TestCodeowners_Nil(t *testing.T) {
+	var c *Codeowners
+	require.Nil(t, c.Owners("src/a_test.go"))
+	require.Equal(t, 0, c.Rules())
+}
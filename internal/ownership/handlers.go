@@ -0,0 +1,175 @@
+package ownership
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/audit"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// ConfigureRequest represents the request to set a project's CODEOWNERS.
+// An empty content removes all owners.
+type ConfigureRequest struct {
+	Content   string   `json:"content"`
+	PathRules []string `json:"path_rules"`
+}
+
+// HandleGetCodeowners handles GET /api/v1/projects/{project_id}/codeowners
+func HandleGetCodeowners(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		config, err := NewService(pool).GetConfig(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to get CODEOWNERS")
+			apperrors.WriteInternalError(w, r, "Failed to get CODEOWNERS")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"codeowners": config,
+		})
+	}
+}
+
+// HandleConfigureCodeowners handles PUT /api/v1/projects/{project_id}/codeowners
+func HandleConfigureCodeowners(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		var req ConfigureRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		codeowners, err := ParseCodeowners(req.Content)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid CODEOWNERS: "+err.Error())
+			return
+		}
+		rules, err := ParsePathRules(req.PathRules)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
+		pathRules := make([]string, len(rules))
+		for i, rule := range rules {
+			pathRules[i] = rule.String()
+		}
+
+		service := NewService(pool)
+		reassigned, err := service.Configure(ctx, project.ID, req.Content, pathRules, userID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to configure CODEOWNERS")
+			apperrors.WriteInternalError(w, r, "Failed to configure CODEOWNERS")
+			return
+		}
+
+		if err := auditor.LogCodeownersConfigured(ctx, project.OrgID, project.ID, userID, codeowners.Rules(), len(pathRules), reassigned); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		config, err := service.GetConfig(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to get CODEOWNERS")
+			apperrors.WriteInternalError(w, r, "Failed to get CODEOWNERS")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"codeowners":       config,
+			"reassigned_tests": reassigned,
+		})
+	}
+}
+
+// HandleListOwners handles GET /api/v1/projects/{project_id}/owners
+func HandleListOwners(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		owners, err := NewService(pool).ListOwners(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list owners")
+			apperrors.WriteInternalError(w, r, "Failed to list owners")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"owners": owners,
+		})
+	}
+}
+
+// requireProject loads the {project_id} project and checks the user's access
+// to it; mutate requires OWNER or ADMIN
+func requireProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool, mutate bool) (*projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid project ID")
+		return nil, false
+	}
+
+	project, err := projects.NewService(pool).GetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get project")
+		apperrors.WriteInternalError(w, r, "Failed to get project")
+		return nil, false
+	}
+
+	orgService := orgs.NewService(pool)
+	if mutate {
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+	} else {
+		_, err = orgService.RequireOrgMember(ctx, userID, project.OrgID)
+	}
+	if err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		if errors.Is(err, orgs.ErrInsufficientPermissions) {
+			apperrors.WriteForbidden(w, r, "Insufficient permissions")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org permissions")
+		apperrors.WriteInternalError(w, r, "Failed to check permissions")
+		return nil, false
+	}
+
+	return project, true
+}
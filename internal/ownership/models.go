@@ -0,0 +1,43 @@
+package ownership
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources of a project's CODEOWNERS
+const (
+	SourceSettings = "settings"
+	SourceIngest   = "ingest"
+)
+
+// OwnerUnowned filters flake lists to tests without owners
+const OwnerUnowned = "unowned"
+
+// Config is the CODEOWNERS setup of a project
+type Config struct {
+	Content         string     `json:"content"`
+	PathRules       []string   `json:"path_rules"`
+	Source          *string    `json:"source"`
+	UpdatedByUserID *uuid.UUID `json:"updated_by_user_id,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+}
+
+// OwnerSummary is the flakiness of the tests of one owner. Owner is empty
+// for the tests without owners.
+type OwnerSummary struct {
+	Owner            string `json:"owner"`
+	Tests            int    `json:"tests"`
+	FlakyTests       int    `json:"flaky_tests"`
+	OpenFlakes       int    `json:"open_flakes"`
+	MixedOutcomeRuns int    `json:"mixed_outcome_runs"`
+}
+
+// FilterValue is the owner filter of the flake list for the summary
+func (s OwnerSummary) FilterValue() string {
+	if s.Owner == "" {
+		return OwnerUnowned
+	}
+	return s.Owner
+}
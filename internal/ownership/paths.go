@@ -0,0 +1,120 @@
+package ownership
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxPathRules bounds the classname-to-path rules of a project
+	MaxPathRules = 100
+	// maxPathRuleLength bounds a single rule
+	maxPathRuleLength = 1024
+	// classPlaceholder marks where a rule's template takes the classname
+	classPlaceholder = "{}"
+)
+
+// PathRule maps test classnames starting with Prefix to a repository path.
+// The rest of the classname, with dots turned into slashes unless it
+// already has slashes, replaces {} in Template, or is appended to it as a
+// directory when Template has no {}.
+type PathRule struct {
+	Prefix   string
+	Template string
+}
+
+// ParsePathRule parses a "<classname prefix>=<path template>" rule, e.g.
+// "com.acme.payments=services/payments/src/test/java/com/acme/payments/{}.java"
+func ParsePathRule(s string) (PathRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > maxPathRuleLength {
+		return PathRule{}, fmt.Errorf("path rule is too long")
+	}
+	prefix, template, ok := strings.Cut(s, "=")
+	if !ok {
+		return PathRule{}, fmt.Errorf("path rule %q must have the form <classname prefix>=<path template>", s)
+	}
+	return PathRule{Prefix: strings.TrimSpace(prefix), Template: strings.TrimSpace(template)}, nil
+}
+
+// ParsePathRules parses a list of path rules
+func ParsePathRules(values []string) ([]PathRule, error) {
+	if len(values) > MaxPathRules {
+		return nil, fmt.Errorf("at most %d path rules are allowed", MaxPathRules)
+	}
+	rules := make([]PathRule, 0, len(values))
+	for _, v := range values {
+		rule, err := ParsePathRule(v)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// String formats the rule as it is parsed
+func (r PathRule) String() string {
+	return r.Prefix + "=" + r.Template
+}
+
+// apply returns the path of a classname the rule matches
+func (r PathRule) apply(classname string) string {
+	rest := strings.TrimLeft(strings.TrimPrefix(classname, r.Prefix), "./")
+	rest = classnamePath(rest)
+	if strings.Contains(r.Template, classPlaceholder) {
+		return strings.ReplaceAll(r.Template, classPlaceholder, rest)
+	}
+	if r.Template == "" {
+		return rest
+	}
+	return strings.TrimSuffix(r.Template, "/") + "/" + rest
+}
+
+// TestPath is the repository path owners are looked up for: the source file
+// the report gave for the test, otherwise its classname mapped by the rule
+// with the longest matching prefix, otherwise its classname as a path
+func TestPath(filePath, classname string, rules []PathRule) string {
+	if filePath != "" {
+		return NormalizePath(filePath)
+	}
+	if classname == "" {
+		return ""
+	}
+
+	best := -1
+	for i, rule := range rules {
+		if strings.HasPrefix(classname, rule.Prefix) && (best < 0 || len(rule.Prefix) > len(rules[best].Prefix)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return NormalizePath(rules[best].apply(classname))
+	}
+	return NormalizePath(classnamePath(classname))
+}
+
+// classnamePath turns a dotted classname into a path; classnames that are
+// already paths (Go packages, JS files) are kept
+func classnamePath(classname string) string {
+	if strings.Contains(classname, "/") {
+		return classname
+	}
+	return strings.ReplaceAll(classname, ".", "/")
+}
+
+// NormalizePath makes a reported file path relative to the repository root
+// with forward slashes
+func NormalizePath(path string) string {
+	path = strings.ReplaceAll(strings.TrimSpace(path), `\`, "/")
+	for strings.HasPrefix(path, "./") {
+		path = path[2:]
+	}
+	return strings.TrimLeft(path, "/")
+}
+
+// ClassnameOf returns the classname part of a "classname#name" test identifier
+func ClassnameOf(testIdentifier string) string {
+	classname, _, _ := strings.Cut(testIdentifier, "#")
+	return classname
+}
@@ -0,0 +1,59 @@
+package ownership
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestPath(t *testing.T) {
+	rules, err := ParsePathRules([]string{
+		"com.acme=src/test/java/com/acme",
+		"com.acme.payments = services/payments/src/test/java/com/acme/payments/{}.java",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		filePath  string
+		classname string
+		want      string
+	}{
+		{"file path wins", `.\services\api\login_test.py`, "com.acme.payments.RefundTest", "services/api/login_test.py"},
+		{"longest prefix", "", "com.acme.payments.RefundTest", "services/payments/src/test/java/com/acme/payments/RefundTest.java"},
+		{"directory template", "", "com.acme.web.LoginTest", "src/test/java/com/acme/web/LoginTest"},
+		{"no rule", "", "tests.api.test_users", "tests/api/test_users"},
+		{"path classname", "", "github.com/acme/api", "github.com/acme/api"},
+		{"nothing", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, TestPath(tt.filePath, tt.classname, rules))
+		})
+	}
+}
+
+func TestParsePathRule(t *testing.T) {
+	rule, err := ParsePathRule(" com.acme = src/{}.java ")
+	require.NoError(t, err)
+	require.Equal(t, "com.acme=src/{}.java", rule.String())
+
+	_, err = ParsePathRule("com.acme")
+	require.Error(t, err)
+
+	_, err = ParsePathRules(make([]string, MaxPathRules+1))
+	require.Error(t, err)
+}
+
+func TestMatcher_Owners(t *testing.T) {
+	codeowners, err := ParseCodeowners("/services/payments/ @acme/payments")
+	require.NoError(t, err)
+	rules, err := ParsePathRules([]string{"com.acme.payments=services/payments/src/test/java/com/acme/payments/{}.java"})
+	require.NoError(t, err)
+	m := NewMatcher(codeowners, rules)
+
+	require.Equal(t, []string{"@acme/payments"}, m.Owners("", ClassnameOf("com.acme.payments.RefundTest#testRefund")))
+	require.Equal(t, []string{}, m.Owners("web/login.spec.ts", "com.acme.payments.RefundTest"))
+	require.Equal(t, []string{}, (&Matcher{}).Owners("services/payments/a_test.go", ""))
+}
@@ -0,0 +1,267 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Service provides CODEOWNERS and test ownership operations
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new ownership service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+// Matcher assigns owners to the tests of a project
+type Matcher struct {
+	codeowners *Codeowners
+	rules      []PathRule
+}
+
+// NewMatcher creates a matcher from a CODEOWNERS file and path rules
+func NewMatcher(codeowners *Codeowners, rules []PathRule) *Matcher {
+	return &Matcher{codeowners: codeowners, rules: rules}
+}
+
+// Owners returns the owners of a test from its reported file path or
+// classname. The result is never nil, so it can be stored as is.
+func (m *Matcher) Owners(filePath, classname string) []string {
+	owners := m.codeowners.Owners(TestPath(filePath, classname, m.rules))
+	if owners == nil {
+		return []string{}
+	}
+	return owners
+}
+
+// LoadMatcher reads the CODEOWNERS setup of a project in the caller's
+// transaction. Projects without CODEOWNERS get a matcher that owns nothing.
+// The setup is share-locked, so it cannot change while the caller assigns
+// owners with it.
+func LoadMatcher(ctx context.Context, tx pgx.Tx, projectID uuid.UUID) (*Matcher, error) {
+	var content string
+	var pathRules []string
+	err := tx.QueryRow(ctx, `SELECT content, path_rules FROM project_codeowners WHERE project_id = $1 FOR SHARE`, projectID).Scan(&content, &pathRules)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CODEOWNERS: %w", err)
+	}
+	return newMatcher(content, pathRules)
+}
+
+func newMatcher(content string, pathRules []string) (*Matcher, error) {
+	codeowners, err := ParseCodeowners(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CODEOWNERS: %w", err)
+	}
+	rules, err := ParsePathRules(pathRules)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatcher(codeowners, rules), nil
+}
+
+// GetConfig returns the CODEOWNERS setup of a project, empty when none was set
+func (s *Service) GetConfig(ctx context.Context, projectID uuid.UUID) (*Config, error) {
+	config := Config{PathRules: []string{}}
+	query := `
+		SELECT content, path_rules, source, updated_by_user_id, updated_at
+		FROM project_codeowners
+		WHERE project_id = $1
+	`
+	err := s.pool.QueryRow(ctx, query, projectID).Scan(
+		&config.Content,
+		&config.PathRules,
+		&config.Source,
+		&config.UpdatedByUserID,
+		&config.UpdatedAt,
+	)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get CODEOWNERS: %w", err)
+	}
+	return &config, nil
+}
+
+// Configure sets the CODEOWNERS file and path rules of a project from its
+// settings and reassigns the owners of its tests. Returns how many tests
+// changed owners.
+func (s *Service) Configure(ctx context.Context, projectID uuid.UUID, content string, pathRules []string, userID uuid.UUID) (int, error) {
+	matcher, err := newMatcher(content, pathRules)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		INSERT INTO project_codeowners (project_id, content, path_rules, source, updated_by_user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id)
+		DO UPDATE SET
+			content = EXCLUDED.content,
+			path_rules = EXCLUDED.path_rules,
+			source = EXCLUDED.source,
+			updated_by_user_id = EXCLUDED.updated_by_user_id,
+			updated_at = NOW()
+	`
+	if _, err := tx.Exec(ctx, query, projectID, content, pathRules, SourceSettings, userID); err != nil {
+		return 0, fmt.Errorf("failed to save CODEOWNERS: %w", err)
+	}
+
+	changed, err := reassign(ctx, tx, projectID, matcher)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// UpdateFromUpload replaces the CODEOWNERS file of a project with one sent
+// with an upload received at receivedAt, in the caller's transaction, keeping
+// its path rules. Files older than the project's current CODEOWNERS are
+// ignored, and tests are only reassigned when the file changed. Returns how
+// many tests changed owners.
+func UpdateFromUpload(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, content string, receivedAt time.Time) (int, error) {
+	var current string
+	var updatedAt time.Time
+	pathRules := []string{}
+	err := tx.QueryRow(ctx, `SELECT content, path_rules, updated_at FROM project_codeowners WHERE project_id = $1 FOR UPDATE`, projectID).Scan(&current, &pathRules, &updatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get CODEOWNERS: %w", err)
+	}
+	if err == nil && (current == content || !updatedAt.Before(receivedAt)) {
+		return 0, nil
+	}
+
+	matcher, err := newMatcher(content, pathRules)
+	if err != nil {
+		return 0, err
+	}
+
+	// updated_at is the upload's, so a retried older upload cannot replace
+	// the file of a newer one
+	query := `
+		INSERT INTO project_codeowners (project_id, content, source, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id)
+		DO UPDATE SET
+			content = EXCLUDED.content,
+			source = EXCLUDED.source,
+			updated_by_user_id = NULL,
+			updated_at = EXCLUDED.updated_at
+	`
+	if _, err := tx.Exec(ctx, query, projectID, content, SourceIngest, receivedAt); err != nil {
+		return 0, fmt.Errorf("failed to save CODEOWNERS: %w", err)
+	}
+
+	return reassign(ctx, tx, projectID, matcher)
+}
+
+// reassign recomputes the owners of every test of a project and stores
+// those that changed
+func reassign(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, matcher *Matcher) (int, error) {
+	rows, err := tx.Query(ctx, `SELECT id, test_identifier, file_path, owners FROM test_cases WHERE project_id = $1`, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list test cases: %w", err)
+	}
+
+	type assignment struct {
+		testCaseID uuid.UUID
+		owners     []string
+	}
+	var changed []assignment
+	for rows.Next() {
+		var (
+			testCaseID     uuid.UUID
+			testIdentifier string
+			filePath       *string
+			current        []string
+		)
+		if err := rows.Scan(&testCaseID, &testIdentifier, &filePath, &current); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan test case: %w", err)
+		}
+		path := ""
+		if filePath != nil {
+			path = *filePath
+		}
+		owners := matcher.Owners(path, ClassnameOf(testIdentifier))
+		if !slices.Equal(owners, current) {
+			changed = append(changed, assignment{testCaseID: testCaseID, owners: owners})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, a := range changed {
+		batch.Queue(`UPDATE test_cases SET owners = $2 WHERE id = $1`, a.testCaseID, a.owners)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, fmt.Errorf("failed to update test owners: %w", err)
+	}
+	return len(changed), nil
+}
+
+// ListOwners breaks the tests of a project down by owner, the owners with
+// the most open flakes first. A test with several owners counts for each;
+// tests without owners are summed up under an empty owner, listed last.
+func (s *Service) ListOwners(ctx context.Context, projectID uuid.UUID) ([]OwnerSummary, error) {
+	query := `
+		SELECT
+			COALESCE(o.owner, ''),
+			COUNT(*),
+			COUNT(fs.test_case_id),
+			COUNT(*) FILTER (WHERE fs.status <> 'resolved'),
+			COALESCE(SUM(fs.mixed_outcome_runs), 0)
+		FROM test_cases tc
+		CROSS JOIN LATERAL unnest(
+			CASE WHEN cardinality(tc.owners) = 0 THEN ARRAY[NULL::text] ELSE tc.owners END
+		) AS o(owner)
+		LEFT JOIN flake_stats fs ON fs.test_case_id = tc.id
+		WHERE tc.project_id = $1
+		GROUP BY o.owner
+		ORDER BY o.owner IS NULL, 4 DESC, 3 DESC, 1
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owners: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []OwnerSummary{}
+	for rows.Next() {
+		var o OwnerSummary
+		if err := rows.Scan(&o.Owner, &o.Tests, &o.FlakyTests, &o.OpenFlakes, &o.MixedOutcomeRuns); err != nil {
+			return nil, fmt.Errorf("failed to scan owner: %w", err)
+		}
+		summaries = append(summaries, o)
+	}
+	return summaries, rows.Err()
+}
@@ -346,3 +346,437 @@ func HandleRemoveSlack(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFu
 		})
 	}
 }
+
+// GitHubConfigRequest represents the request to configure GitHub reporting
+type GitHubConfigRequest struct {
+	ReportMode string `json:"report_mode"`
+}
+
+// HandleConfigureGitHub handles PUT /api/v1/projects/{project_id}/github
+func HandleConfigureGitHub(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		// Get project ID from path
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project ID")
+			return
+		}
+
+		// Get project to check org membership
+		service := NewService(pool)
+		project, err := service.GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		// Check if user can mutate org resources (OWNER or ADMIN)
+		orgService := orgs.NewService(pool)
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		// Parse request
+		var req GitHubConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if !ValidGitHubReportMode(req.ReportMode) {
+			apperrors.WriteBadRequest(w, r, "report_mode must be one of: off, check_run, comment")
+			return
+		}
+
+		config, err := service.ConfigureGitHub(ctx, projectID, req.ReportMode)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure GitHub reporting")
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			apperrors.WriteInternalError(w, r, "Failed to configure GitHub reporting")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogGitHubConfigured(ctx, project.OrgID, projectID, userID, config.ReportMode); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"github": config,
+		})
+	}
+}
+
+// NotificationConfigRequest represents the request to configure notifications
+type NotificationConfigRequest struct {
+	CooldownHours *int `json:"cooldown_hours"`
+}
+
+// HandleConfigureNotifications handles PUT /api/v1/projects/{project_id}/notifications
+func HandleConfigureNotifications(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		// Get project ID from path
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project ID")
+			return
+		}
+
+		// Get project to check org membership
+		service := NewService(pool)
+		project, err := service.GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		// Check if user can mutate org resources (OWNER or ADMIN)
+		orgService := orgs.NewService(pool)
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		// Parse request
+		var req NotificationConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if req.CooldownHours == nil {
+			apperrors.WriteBadRequest(w, r, "cooldown_hours is required")
+			return
+		}
+		if *req.CooldownHours < 0 || *req.CooldownHours > MaxNotificationCooldownHours {
+			apperrors.WriteBadRequest(w, r, "cooldown_hours must be between 0 and 720")
+			return
+		}
+
+		config, err := service.ConfigureNotifications(ctx, projectID, *req.CooldownHours)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure notifications")
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			apperrors.WriteInternalError(w, r, "Failed to configure notifications")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogNotificationsConfigured(ctx, project.OrgID, projectID, userID, config.CooldownHours); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"notifications": config,
+		})
+	}
+}
+
+// DigestConfigRequest represents the request to configure flake digests
+type DigestConfigRequest struct {
+	Frequency string `json:"frequency"`
+}
+
+// HandleConfigureDigest handles PUT /api/v1/projects/{project_id}/digest
+func HandleConfigureDigest(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		// Get project ID from path
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project ID")
+			return
+		}
+
+		// Get project to check org membership
+		service := NewService(pool)
+		project, err := service.GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		// Check if user can mutate org resources (OWNER or ADMIN)
+		orgService := orgs.NewService(pool)
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		// Parse request
+		var req DigestConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if !ValidDigestFrequency(req.Frequency) {
+			apperrors.WriteBadRequest(w, r, "frequency must be one of: off, daily, weekly")
+			return
+		}
+
+		config, err := service.ConfigureDigest(ctx, projectID, req.Frequency)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure digest")
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			apperrors.WriteInternalError(w, r, "Failed to configure digest")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogDigestConfigured(ctx, project.OrgID, projectID, userID, config.Frequency); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"digest": config,
+		})
+	}
+}
+
+// BrokenDetectionConfigRequest represents the request to configure broken
+// test detection
+type BrokenDetectionConfigRequest struct {
+	ConsecutiveRuns *int `json:"consecutive_runs"`
+}
+
+// HandleConfigureBrokenDetection handles PUT /api/v1/projects/{project_id}/broken-detection
+func HandleConfigureBrokenDetection(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		// Get project ID from path
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project ID")
+			return
+		}
+
+		// Get project to check org membership
+		service := NewService(pool)
+		project, err := service.GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		// Check if user can mutate org resources (OWNER or ADMIN)
+		orgService := orgs.NewService(pool)
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		// Parse request
+		var req BrokenDetectionConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if req.ConsecutiveRuns == nil {
+			apperrors.WriteBadRequest(w, r, "consecutive_runs is required")
+			return
+		}
+		if *req.ConsecutiveRuns < MinBrokenTestRuns || *req.ConsecutiveRuns > MaxBrokenTestRuns {
+			apperrors.WriteBadRequest(w, r, "consecutive_runs must be between 2 and 50")
+			return
+		}
+
+		config, err := service.ConfigureBrokenDetection(ctx, projectID, *req.ConsecutiveRuns)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure broken test detection")
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			apperrors.WriteInternalError(w, r, "Failed to configure broken test detection")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogBrokenDetectionConfigured(ctx, project.OrgID, projectID, userID, config.ConsecutiveRuns); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"broken_detection": config,
+		})
+	}
+}
+
+// FlakeLifecycleConfigRequest represents the request to configure the flake
+// lifecycle
+type FlakeLifecycleConfigRequest struct {
+	ResolveAfterRuns *int `json:"resolve_after_runs"`
+}
+
+// HandleConfigureFlakeLifecycle handles PUT /api/v1/projects/{project_id}/flake-lifecycle
+func HandleConfigureFlakeLifecycle(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		// Get project ID from path
+		projectIDStr := chi.URLParam(r, "project_id")
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid project ID")
+			return
+		}
+
+		// Get project to check org membership
+		service := NewService(pool)
+		project, err := service.GetByID(ctx, projectID)
+		if err != nil {
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get project")
+			apperrors.WriteInternalError(w, r, "Failed to get project")
+			return
+		}
+
+		// Check if user can mutate org resources (OWNER or ADMIN)
+		orgService := orgs.NewService(pool)
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			if errors.Is(err, orgs.ErrInsufficientPermissions) {
+				apperrors.WriteForbidden(w, r, "Insufficient permissions")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to check org permissions")
+			apperrors.WriteInternalError(w, r, "Failed to check permissions")
+			return
+		}
+
+		// Parse request
+		var req FlakeLifecycleConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		if req.ResolveAfterRuns == nil {
+			apperrors.WriteBadRequest(w, r, "resolve_after_runs is required")
+			return
+		}
+		if *req.ResolveAfterRuns < MinFlakeResolveRuns || *req.ResolveAfterRuns > MaxFlakeResolveRuns {
+			apperrors.WriteBadRequest(w, r, "resolve_after_runs must be between 1 and 500")
+			return
+		}
+
+		config, err := service.ConfigureFlakeLifecycle(ctx, projectID, *req.ResolveAfterRuns)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure flake lifecycle")
+			if errors.Is(err, ErrProjectNotFound) {
+				apperrors.WriteNotFound(w, r, "Project not found")
+				return
+			}
+			apperrors.WriteInternalError(w, r, "Failed to configure flake lifecycle")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogFlakeLifecycleConfigured(ctx, project.OrgID, projectID, userID, config.ResolveAfterRuns); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"flake_lifecycle": config,
+		})
+	}
+}
@@ -16,9 +16,99 @@ type Project struct {
 	DefaultBranch   string         `db:"default_branch"`
 	SlackEnabled    bool           `db:"slack_enabled"`
 	SlackWebhookURL sql.NullString `db:"slack_webhook_url"`
-	CreatedByUserID uuid.UUID      `db:"created_by_user_id"`
-	CreatedAt       time.Time      `db:"created_at"`
-	UpdatedAt       time.Time      `db:"updated_at"`
+	// GitHubReportMode is one of the GitHubReport* constants
+	GitHubReportMode string `db:"github_report_mode"`
+	// NotificationCooldownHours suppresses repeat notifications for a test;
+	// 0 notifies on every flake event
+	NotificationCooldownHours int `db:"notification_cooldown_hours"`
+	// DigestFrequency is one of the Digest* constants
+	DigestFrequency string `db:"digest_frequency"`
+	// BrokenTestRuns is the number of consecutive default-branch runs a test
+	// must fail on every attempt to be reported as broken
+	BrokenTestRuns int `db:"broken_test_runs"`
+	// FlakeResolveRuns is the number of clean runs after which a flaky test
+	// is resolved
+	FlakeResolveRuns int       `db:"flake_resolve_runs"`
+	CreatedByUserID  uuid.UUID `db:"created_by_user_id"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+// How flakes of pull request runs are reported back to GitHub
+const (
+	GitHubReportOff      = "off"
+	GitHubReportCheckRun = "check_run"
+	GitHubReportComment  = "comment"
+)
+
+// ValidGitHubReportMode reports whether mode is a known GitHub report mode
+func ValidGitHubReportMode(mode string) bool {
+	switch mode {
+	case GitHubReportOff, GitHubReportCheckRun, GitHubReportComment:
+		return true
+	}
+	return false
+}
+
+// GitHubConfig represents the GitHub pull request reporting settings of a
+// project. This is used for API requests/responses.
+type GitHubConfig struct {
+	ReportMode string `json:"report_mode"`
+}
+
+// MaxNotificationCooldownHours bounds the notification cooldown (30 days)
+const MaxNotificationCooldownHours = 720
+
+// NotificationConfig represents the notification settings of a project.
+// This is used for API requests/responses.
+type NotificationConfig struct {
+	CooldownHours int `json:"cooldown_hours"`
+}
+
+// How often a flake digest is sent to the notification channels of a project
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// ValidDigestFrequency reports whether frequency is a known digest frequency
+func ValidDigestFrequency(frequency string) bool {
+	switch frequency {
+	case DigestOff, DigestDaily, DigestWeekly:
+		return true
+	}
+	return false
+}
+
+// DigestConfig represents the flake digest settings of a project.
+// This is used for API requests/responses.
+type DigestConfig struct {
+	Frequency string `json:"frequency"`
+}
+
+// Bounds of the broken test detection streak
+const (
+	MinBrokenTestRuns = 2
+	MaxBrokenTestRuns = 50
+)
+
+// BrokenDetectionConfig represents the broken test detection settings of a
+// project. This is used for API requests/responses.
+type BrokenDetectionConfig struct {
+	ConsecutiveRuns int `json:"consecutive_runs"`
+}
+
+// Bounds of the clean runs that resolve a flaky test
+const (
+	MinFlakeResolveRuns = 1
+	MaxFlakeResolveRuns = 500
+)
+
+// FlakeLifecycleConfig represents the flake lifecycle settings of a project.
+// This is used for API requests/responses.
+type FlakeLifecycleConfig struct {
+	ResolveAfterRuns int `json:"resolve_after_runs"`
 }
 
 // SlackConfig represents the Slack configuration for a project
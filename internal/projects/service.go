@@ -35,7 +35,7 @@ func (s *Service) GetByID(ctx context.Context, projectID uuid.UUID) (*Project, e
 	var project Project
 
 	query := `
-		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url,
+		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url, github_report_mode::text, notification_cooldown_hours, digest_frequency::text, broken_test_runs, flake_resolve_runs,
 		       created_by_user_id, created_at, updated_at
 		FROM projects
 		WHERE id = $1
@@ -49,6 +49,11 @@ func (s *Service) GetByID(ctx context.Context, projectID uuid.UUID) (*Project, e
 		&project.DefaultBranch,
 		&project.SlackEnabled,
 		&project.SlackWebhookURL,
+		&project.GitHubReportMode,
+		&project.NotificationCooldownHours,
+		&project.DigestFrequency,
+		&project.BrokenTestRuns,
+		&project.FlakeResolveRuns,
 		&project.CreatedByUserID,
 		&project.CreatedAt,
 		&project.UpdatedAt,
@@ -69,7 +74,7 @@ func (s *Service) GetBySlug(ctx context.Context, slug string) (*Project, error)
 	var project Project
 
 	query := `
-		SELECT p.id, p.org_id, p.name, p.slug, p.default_branch, p.slack_enabled, p.slack_webhook_url,
+		SELECT p.id, p.org_id, p.name, p.slug, p.default_branch, p.slack_enabled, p.slack_webhook_url, p.github_report_mode::text, p.notification_cooldown_hours, p.digest_frequency::text, p.broken_test_runs, p.flake_resolve_runs,
 		       p.created_by_user_id, p.created_at, p.updated_at
 		FROM projects p
 		JOIN orgs o ON p.org_id = o.id
@@ -84,6 +89,11 @@ func (s *Service) GetBySlug(ctx context.Context, slug string) (*Project, error)
 		&project.DefaultBranch,
 		&project.SlackEnabled,
 		&project.SlackWebhookURL,
+		&project.GitHubReportMode,
+		&project.NotificationCooldownHours,
+		&project.DigestFrequency,
+		&project.BrokenTestRuns,
+		&project.FlakeResolveRuns,
 		&project.CreatedByUserID,
 		&project.CreatedAt,
 		&project.UpdatedAt,
@@ -104,7 +114,7 @@ func (s *Service) GetByOrgAndSlug(ctx context.Context, orgID uuid.UUID, slug str
 	var project Project
 
 	query := `
-		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url,
+		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url, github_report_mode::text, notification_cooldown_hours, digest_frequency::text, broken_test_runs, flake_resolve_runs,
 		       created_by_user_id, created_at, updated_at
 		FROM projects
 		WHERE org_id = $1 AND slug = $2
@@ -118,6 +128,11 @@ func (s *Service) GetByOrgAndSlug(ctx context.Context, orgID uuid.UUID, slug str
 		&project.DefaultBranch,
 		&project.SlackEnabled,
 		&project.SlackWebhookURL,
+		&project.GitHubReportMode,
+		&project.NotificationCooldownHours,
+		&project.DigestFrequency,
+		&project.BrokenTestRuns,
+		&project.FlakeResolveRuns,
 		&project.CreatedByUserID,
 		&project.CreatedAt,
 		&project.UpdatedAt,
@@ -136,7 +151,7 @@ func (s *Service) GetByOrgAndSlug(ctx context.Context, orgID uuid.UUID, slug str
 // ListByOrg retrieves all projects for an organization
 func (s *Service) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]Project, error) {
 	query := `
-		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url,
+		SELECT id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url, github_report_mode::text, notification_cooldown_hours, digest_frequency::text, broken_test_runs, flake_resolve_runs,
 		       created_by_user_id, created_at, updated_at
 		FROM projects
 		WHERE org_id = $1
@@ -160,6 +175,11 @@ func (s *Service) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]Project, er
 			&project.DefaultBranch,
 			&project.SlackEnabled,
 			&project.SlackWebhookURL,
+			&project.GitHubReportMode,
+			&project.NotificationCooldownHours,
+			&project.DigestFrequency,
+			&project.BrokenTestRuns,
+			&project.FlakeResolveRuns,
 			&project.CreatedByUserID,
 			&project.CreatedAt,
 			&project.UpdatedAt,
@@ -184,7 +204,7 @@ func (s *Service) Create(ctx context.Context, orgID uuid.UUID, name, slug, defau
 	query := `
 		INSERT INTO projects (org_id, name, slug, default_branch, created_by_user_id)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url,
+		RETURNING id, org_id, name, slug, default_branch, slack_enabled, slack_webhook_url, github_report_mode::text, notification_cooldown_hours, digest_frequency::text, broken_test_runs, flake_resolve_runs,
 		          created_by_user_id, created_at, updated_at
 	`
 
@@ -196,6 +216,11 @@ func (s *Service) Create(ctx context.Context, orgID uuid.UUID, name, slug, defau
 		&project.DefaultBranch,
 		&project.SlackEnabled,
 		&project.SlackWebhookURL,
+		&project.GitHubReportMode,
+		&project.NotificationCooldownHours,
+		&project.DigestFrequency,
+		&project.BrokenTestRuns,
+		&project.FlakeResolveRuns,
 		&project.CreatedByUserID,
 		&project.CreatedAt,
 		&project.UpdatedAt,
@@ -263,6 +288,123 @@ func (s *Service) RemoveSlack(ctx context.Context, projectID uuid.UUID) error {
 	return nil
 }
 
+// ConfigureGitHub sets how flakes of pull request runs are reported to GitHub
+func (s *Service) ConfigureGitHub(ctx context.Context, projectID uuid.UUID, reportMode string) (*GitHubConfig, error) {
+	var storedMode string
+
+	query := `
+		UPDATE projects
+		SET github_report_mode = $2::github_report_mode,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING github_report_mode::text
+	`
+
+	err := s.pool.QueryRow(ctx, query, projectID, reportMode).Scan(&storedMode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to configure GitHub reporting: %w", err)
+	}
+
+	return &GitHubConfig{ReportMode: storedMode}, nil
+}
+
+// ConfigureNotifications sets the notification cooldown of a project
+func (s *Service) ConfigureNotifications(ctx context.Context, projectID uuid.UUID, cooldownHours int) (*NotificationConfig, error) {
+	var config NotificationConfig
+
+	query := `
+		UPDATE projects
+		SET notification_cooldown_hours = $2,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING notification_cooldown_hours
+	`
+
+	err := s.pool.QueryRow(ctx, query, projectID, cooldownHours).Scan(&config.CooldownHours)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to configure notifications: %w", err)
+	}
+
+	return &config, nil
+}
+
+// ConfigureDigest sets how often a flake digest is sent for a project
+func (s *Service) ConfigureDigest(ctx context.Context, projectID uuid.UUID, frequency string) (*DigestConfig, error) {
+	var config DigestConfig
+
+	query := `
+		UPDATE projects
+		SET digest_frequency = $2::digest_frequency,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING digest_frequency::text
+	`
+
+	err := s.pool.QueryRow(ctx, query, projectID, frequency).Scan(&config.Frequency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to configure digest: %w", err)
+	}
+
+	return &config, nil
+}
+
+// ConfigureBrokenDetection sets after how many consecutive failing
+// default-branch runs a test is reported as broken
+func (s *Service) ConfigureBrokenDetection(ctx context.Context, projectID uuid.UUID, consecutiveRuns int) (*BrokenDetectionConfig, error) {
+	var config BrokenDetectionConfig
+
+	query := `
+		UPDATE projects
+		SET broken_test_runs = $2,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING broken_test_runs
+	`
+
+	err := s.pool.QueryRow(ctx, query, projectID, consecutiveRuns).Scan(&config.ConsecutiveRuns)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to configure broken test detection: %w", err)
+	}
+
+	return &config, nil
+}
+
+// ConfigureFlakeLifecycle sets after how many clean runs a flaky test is
+// resolved
+func (s *Service) ConfigureFlakeLifecycle(ctx context.Context, projectID uuid.UUID, resolveAfterRuns int) (*FlakeLifecycleConfig, error) {
+	var config FlakeLifecycleConfig
+
+	query := `
+		UPDATE projects
+		SET flake_resolve_runs = $2,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING flake_resolve_runs
+	`
+
+	err := s.pool.QueryRow(ctx, query, projectID, resolveAfterRuns).Scan(&config.ResolveAfterRuns)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to configure flake lifecycle: %w", err)
+	}
+
+	return &config, nil
+}
+
 // GetSlackWebhookURL retrieves the Slack webhook URL for a project
 // This should only be used internally for sending notifications
 func (s *Service) GetSlackWebhookURL(ctx context.Context, projectID uuid.UUID) (string, error) {
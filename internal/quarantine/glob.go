@@ -0,0 +1,47 @@
+package quarantine
+
+// GlobMatch reports whether s matches pattern, where * matches any run of
+// characters (including '/', '.' and '#') and ? matches a single character.
+// Unlike path.Match, separators are not special: test identifiers such as
+// "com.example.PaymentTest#test*" or "TestFoo/*" match across them.
+func GlobMatch(pattern, s string) bool {
+	p := []rune(pattern)
+	str := []rune(s)
+
+	pi, si := 0, 0
+	starP, starS := -1, 0
+
+	for si < len(str) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == str[si]):
+			pi++
+			si++
+		case pi < len(p) && p[pi] == '*':
+			starP = pi
+			starS = si
+			pi++
+		case starP >= 0:
+			// Backtrack: let the last * absorb one more character
+			pi = starP + 1
+			starS++
+			si = starS
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// IsGlobPattern returns true if the pattern contains glob metacharacters
+func IsGlobPattern(pattern string) bool {
+	for _, c := range pattern {
+		if c == '*' || c == '?' {
+			return true
+		}
+	}
+	return false
+}
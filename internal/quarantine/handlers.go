@@ -0,0 +1,445 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apikey"
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/audit"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxPatternLength = 1024
+	maxOwnerLength   = 200
+	maxReasonLength  = 2000
+)
+
+// CreateRequest represents the request to create a quarantine rule
+type CreateRequest struct {
+	MatchType     MatchType `json:"match_type,omitempty"`
+	Pattern       string    `json:"pattern"`
+	Owner         string    `json:"owner"`
+	Reason        string    `json:"reason"`
+	ExpiresInDays int       `json:"expires_in_days,omitempty"`
+}
+
+// UpdateRequest represents the request to update a quarantine rule.
+// expires_in_days = 0 clears the expiry; omit it to leave the expiry unchanged.
+type UpdateRequest struct {
+	Owner         *string `json:"owner,omitempty"`
+	Reason        *string `json:"reason,omitempty"`
+	ExpiresInDays *int    `json:"expires_in_days,omitempty"`
+}
+
+// HandleCreate handles POST /api/v1/projects/{project_id}/quarantine
+func HandleCreate(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		var req CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		req.Pattern = strings.TrimSpace(req.Pattern)
+		req.Owner = strings.TrimSpace(req.Owner)
+		req.Reason = strings.TrimSpace(req.Reason)
+
+		if req.Pattern == "" {
+			apperrors.WriteBadRequest(w, r, "pattern is required")
+			return
+		}
+		if len(req.Pattern) > maxPatternLength {
+			apperrors.WriteBadRequest(w, r, "pattern is too long")
+			return
+		}
+		if req.Owner == "" {
+			apperrors.WriteBadRequest(w, r, "owner is required")
+			return
+		}
+		if len(req.Owner) > maxOwnerLength {
+			apperrors.WriteBadRequest(w, r, "owner is too long")
+			return
+		}
+		if req.Reason == "" {
+			apperrors.WriteBadRequest(w, r, "reason is required")
+			return
+		}
+		if len(req.Reason) > maxReasonLength {
+			apperrors.WriteBadRequest(w, r, "reason is too long")
+			return
+		}
+
+		// Infer match type from the pattern if not provided
+		if req.MatchType == "" {
+			req.MatchType = MatchExact
+			if IsGlobPattern(req.Pattern) {
+				req.MatchType = MatchGlob
+			}
+		}
+		if !req.MatchType.IsValid() {
+			apperrors.WriteBadRequest(w, r, "match_type must be one of: exact, glob")
+			return
+		}
+
+		if req.ExpiresInDays < 0 {
+			apperrors.WriteBadRequest(w, r, "expires_in_days must be >= 0")
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresInDays > 0 {
+			t := time.Now().AddDate(0, 0, req.ExpiresInDays).UTC()
+			expiresAt = &t
+		}
+
+		service := NewService(pool)
+		rule, err := service.Create(ctx, project.ID, userID, CreateParams{
+			MatchType: req.MatchType,
+			Pattern:   req.Pattern,
+			Owner:     req.Owner,
+			Reason:    req.Reason,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			if errors.Is(err, ErrPatternConflict) {
+				apperrors.WriteConflict(w, r, "An active quarantine rule already exists for this pattern")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to create quarantine rule")
+			apperrors.WriteInternalError(w, r, "Failed to create quarantine rule")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogQuarantineCreated(ctx, project.OrgID, project.ID, rule.ID, userID, string(rule.MatchType), rule.Pattern, rule.Owner, rule.Reason, expiresAt); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusCreated, map[string]any{
+			"rule": rule.ToResponse(),
+		})
+	}
+}
+
+// HandleList handles GET /api/v1/projects/{project_id}/quarantine
+func HandleList(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool, false)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		rules, err := service.ListByProject(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list quarantine rules")
+			apperrors.WriteInternalError(w, r, "Failed to list quarantine rules")
+			return
+		}
+
+		resp := make([]RuleResponse, len(rules))
+		for i := range rules {
+			resp[i] = rules[i].ToResponse()
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"rules": resp,
+		})
+	}
+}
+
+// HandleUpdate handles PUT /api/v1/projects/{project_id}/quarantine/{rule_id}
+func HandleUpdate(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		rule, ok := requireRule(w, r, service, project.ID)
+		if !ok {
+			return
+		}
+
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid request body")
+			return
+		}
+
+		params := UpdateParams{}
+		changes := map[string]interface{}{}
+
+		if req.Owner != nil {
+			owner := strings.TrimSpace(*req.Owner)
+			if owner == "" || len(owner) > maxOwnerLength {
+				apperrors.WriteBadRequest(w, r, "owner must be non-empty and at most 200 characters")
+				return
+			}
+			params.Owner = &owner
+			changes["owner"] = owner
+		}
+		if req.Reason != nil {
+			reason := strings.TrimSpace(*req.Reason)
+			if reason == "" || len(reason) > maxReasonLength {
+				apperrors.WriteBadRequest(w, r, "reason must be non-empty and at most 2000 characters")
+				return
+			}
+			params.Reason = &reason
+			changes["reason"] = reason
+		}
+		if req.ExpiresInDays != nil {
+			switch {
+			case *req.ExpiresInDays < 0:
+				apperrors.WriteBadRequest(w, r, "expires_in_days must be >= 0")
+				return
+			case *req.ExpiresInDays == 0:
+				params.ClearExpiry = true
+				changes["expires_at"] = nil
+			default:
+				t := time.Now().AddDate(0, 0, *req.ExpiresInDays).UTC()
+				params.ExpiresAt = &t
+				changes["expires_at"] = t
+			}
+		}
+
+		if len(changes) == 0 {
+			apperrors.WriteBadRequest(w, r, "No changes provided")
+			return
+		}
+
+		updated, err := service.Update(ctx, rule.ID, params)
+		if err != nil {
+			if errors.Is(err, ErrRuleNotFound) {
+				apperrors.WriteNotFound(w, r, "Quarantine rule not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to update quarantine rule")
+			apperrors.WriteInternalError(w, r, "Failed to update quarantine rule")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogQuarantineUpdated(ctx, project.OrgID, project.ID, rule.ID, userID, rule.Pattern, changes); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"rule": updated.ToResponse(),
+		})
+	}
+}
+
+// HandleRemove handles DELETE /api/v1/projects/{project_id}/quarantine/{rule_id}
+func HandleRemove(pool *pgxpool.Pool, auditor *audit.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		project, ok := requireProject(w, r, pool, true)
+		if !ok {
+			return
+		}
+
+		service := NewService(pool)
+		rule, ok := requireRule(w, r, service, project.ID)
+		if !ok {
+			return
+		}
+
+		if err := service.Remove(ctx, rule.ID, userID); err != nil {
+			if errors.Is(err, ErrRuleNotFound) {
+				apperrors.WriteNotFound(w, r, "Quarantine rule already removed or not found")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to remove quarantine rule")
+			apperrors.WriteInternalError(w, r, "Failed to remove quarantine rule")
+			return
+		}
+
+		// Log audit event
+		if err := auditor.LogQuarantineRemoved(ctx, project.OrgID, project.ID, rule.ID, userID, rule.Pattern); err != nil {
+			log.Error().Err(err).Msg("Failed to log audit event")
+			// Continue - don't fail the request
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"removed": true,
+		})
+	}
+}
+
+// requireProject loads the {project_id} project and checks org membership
+// (or OWNER/ADMIN when mutate is true). Writes the error response on failure.
+func requireProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool, mutate bool) (*projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid project ID")
+		return nil, false
+	}
+
+	projectService := projects.NewService(pool)
+	project, err := projectService.GetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get project")
+		apperrors.WriteInternalError(w, r, "Failed to get project")
+		return nil, false
+	}
+
+	orgService := orgs.NewService(pool)
+	if mutate {
+		_, err = orgService.RequireOrgMutatePermission(ctx, userID, project.OrgID)
+	} else {
+		_, err = orgService.RequireOrgMember(ctx, userID, project.OrgID)
+	}
+	if err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		if errors.Is(err, orgs.ErrInsufficientPermissions) {
+			apperrors.WriteForbidden(w, r, "Insufficient permissions")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org permissions")
+		apperrors.WriteInternalError(w, r, "Failed to check permissions")
+		return nil, false
+	}
+
+	return project, true
+}
+
+// requireRule loads the {rule_id} rule and verifies it belongs to the project
+func requireRule(w http.ResponseWriter, r *http.Request, service *Service, projectID uuid.UUID) (*Rule, bool) {
+	ruleID, err := uuid.Parse(chi.URLParam(r, "rule_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid rule ID")
+		return nil, false
+	}
+
+	rule, err := service.GetByID(r.Context(), ruleID)
+	if err != nil {
+		if errors.Is(err, ErrRuleNotFound) {
+			apperrors.WriteNotFound(w, r, "Quarantine rule not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get quarantine rule")
+		apperrors.WriteInternalError(w, r, "Failed to get quarantine rule")
+		return nil, false
+	}
+
+	if rule.ProjectID != projectID || rule.IsRemoved() {
+		apperrors.WriteNotFound(w, r, "Quarantine rule not found")
+		return nil, false
+	}
+
+	return rule, true
+}
+
+// HandleManifest handles GET /api/v1/quarantine/manifest (API key auth, read:project scope).
+// ?format=json (default) returns the active rules plus all skip lists; go, pytest and
+// junit5 return a plain-text skip file for that runner.
+func HandleManifest(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		key := apikey.GetAPIKey(ctx)
+		if key == nil {
+			apperrors.WriteUnauthorized(w, r, "API key required")
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = FormatJSON
+		}
+		if !IsValidFormat(format) {
+			apperrors.WriteBadRequest(w, r, "format must be one of: json, go, pytest, junit5")
+			return
+		}
+
+		service := NewService(pool)
+		ruleSet, err := service.ActiveRuleSet(ctx, key.ProjectID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", key.ProjectID.String()).Msg("Failed to load quarantine manifest")
+			apperrors.WriteInternalError(w, r, "Failed to load quarantine rules")
+			return
+		}
+		rules := ruleSet.Rules()
+
+		var lines []string
+		switch format {
+		case FormatJSON:
+			resp := make([]RuleResponse, len(rules))
+			for i := range rules {
+				resp[i] = rules[i].ToResponse()
+			}
+
+			pytest := PytestDeselect(rules)
+			if pytest == nil {
+				pytest = []string{}
+			}
+			junit5 := JUnit5Exclude(rules)
+			if junit5 == nil {
+				junit5 = []string{}
+			}
+
+			apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+				"project_id":      key.ProjectID,
+				"generated_at":    time.Now().UTC(),
+				"rules":           resp,
+				"go_skip_regex":   GoSkipRegex(rules),
+				"pytest_deselect": pytest,
+				"junit5_exclude":  junit5,
+			})
+			return
+		case FormatGo:
+			if re := GoSkipRegex(rules); re != "" {
+				lines = []string{re}
+			}
+		case FormatPytest:
+			lines = PytestDeselect(rules)
+		case FormatJUnit5:
+			lines = JUnit5Exclude(rules)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		for _, line := range lines {
+			_, _ = w.Write([]byte(line + "\n"))
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package quarantine
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Manifest formats accepted by the manifest endpoint
+const (
+	FormatJSON   = "json"
+	FormatGo     = "go"
+	FormatPytest = "pytest"
+	FormatJUnit5 = "junit5"
+)
+
+// IsValidFormat returns true if the manifest format is known
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatGo, FormatPytest, FormatJUnit5:
+		return true
+	}
+	return false
+}
+
+// splitIdentifier splits a "classname#name" test identifier. Identifiers
+// without a '#' are treated as a bare test name.
+func splitIdentifier(identifier string) (classname, name string) {
+	if i := strings.LastIndex(identifier, "#"); i >= 0 {
+		return identifier[:i], identifier[i+1:]
+	}
+	return "", identifier
+}
+
+// globToRegexp converts a glob to an unanchored regexp fragment
+func globToRegexp(glob string) string {
+	var b, literal strings.Builder
+	flush := func() {
+		b.WriteString(regexp.QuoteMeta(literal.String()))
+		literal.Reset()
+	}
+	for _, c := range glob {
+		switch c {
+		case '*':
+			flush()
+			b.WriteString(".*")
+		case '?':
+			flush()
+			b.WriteString(".")
+		default:
+			literal.WriteRune(c)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// GoSkipRegex builds a regexp for `go test -skip`. go test matches -skip
+// against test names across all packages, so only the name part of each rule
+// is used. go test splits the pattern on unbracketed '|' into alternatives and
+// each alternative on '/' into one pattern per subtest level, so top-level
+// tests share one anchored alternative and each subtest rule gets its own,
+// anchored per level ("TestA/case_1" becomes "^TestA$/^case_1$"): a subtest
+// rule skips only that subtest. Returns "" if no rule applies.
+func GoSkipRegex(rules []Rule) string {
+	seen := make(map[string]bool)
+	var tops, subtests []string
+	for _, rule := range rules {
+		_, name := splitIdentifier(rule.Pattern)
+		if name == "" {
+			continue
+		}
+
+		levels := strings.Split(name, "/")
+		for i, level := range levels {
+			if rule.MatchType == MatchGlob {
+				levels[i] = globToRegexp(level)
+			} else {
+				levels[i] = regexp.QuoteMeta(level)
+			}
+		}
+
+		if len(levels) == 1 {
+			if !seen[levels[0]] {
+				seen[levels[0]] = true
+				tops = append(tops, levels[0])
+			}
+			continue
+		}
+		alt := "^" + strings.Join(levels, "$/^") + "$"
+		if !seen[alt] {
+			seen[alt] = true
+			subtests = append(subtests, alt)
+		}
+	}
+
+	var alts []string
+	if len(tops) > 0 {
+		alts = append(alts, "^("+strings.Join(tops, "|")+")$")
+	}
+	alts = append(alts, subtests...)
+	return strings.Join(alts, "|")
+}
+
+// PytestDeselect builds pytest node IDs for `--deselect`. The node ID is
+// derived from the JUnit classname: leading lowercase segments form the
+// module path and segments from the first Test* class onward form the class
+// path, e.g. "tests.api.test_users.TestLogin#test_ok" becomes
+// "tests/api/test_users.py::TestLogin::test_ok". pytest cannot deselect by
+// pattern, so glob rules are left out.
+func PytestDeselect(rules []Rule) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.MatchType != MatchExact {
+			continue
+		}
+		classname, name := splitIdentifier(rule.Pattern)
+		if classname == "" || name == "" {
+			continue
+		}
+
+		segments := strings.Split(classname, ".")
+		split := len(segments)
+		for i := 1; i < len(segments); i++ {
+			if strings.HasPrefix(segments[i], "Test") {
+				split = i
+				break
+			}
+		}
+
+		parts := []string{strings.Join(segments[:split], "/") + ".py"}
+		parts = append(parts, segments[split:]...)
+		parts = append(parts, name)
+
+		id := strings.Join(parts, "::")
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// JUnit5Exclude builds "fully.qualified.Class#method" exclusion patterns in
+// the format of Maven Surefire's excludesFile. This is not tag exclusion:
+// JUnit 5 tags are compiled into the test classes, so a tag expression
+// cannot select tests named at run time (see docs/api.md). Glob wildcards are
+// passed through; display suffixes such as "()" or "(String)[1]" are stripped
+// from the method name.
+func JUnit5Exclude(rules []Rule) []string {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		classname, name := splitIdentifier(rule.Pattern)
+		if classname == "" || name == "" {
+			continue
+		}
+		if i := strings.Index(name, "("); i > 0 {
+			name = name[:i]
+		}
+
+		p := classname + "#" + name
+		if !seen[p] {
+			seen[p] = true
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
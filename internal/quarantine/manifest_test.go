@@ -0,0 +1,128 @@
+package quarantine
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoSkipRegex(t *testing.T) {
+	rules := []Rule{
+		{MatchType: MatchExact, Pattern: "github.com/acme/api#TestLogin"},
+		{MatchType: MatchGlob, Pattern: "github.com/acme/db#TestSlow*"},
+		{MatchType: MatchExact, Pattern: "github.com/acme/api#TestLogin/with_mfa"},
+		{MatchType: MatchExact, Pattern: "github.com/acme/web#TestLogin"},
+		{MatchType: MatchExact, Pattern: "pkg#Test.Dots"},
+	}
+
+	got := GoSkipRegex(rules)
+	require.Equal(t, `^(TestLogin|TestSlow.*|Test\.Dots)$|^TestLogin$/^with_mfa$`, got)
+
+	re := regexp.MustCompile(`^(TestLogin|TestSlow.*|Test\.Dots)$`)
+	require.True(t, re.MatchString("TestSlowQuery"))
+	require.True(t, re.MatchString("Test.Dots"))
+	require.False(t, re.MatchString("TestXDots"))
+	require.False(t, re.MatchString("TestLoginFlow"))
+}
+
+func TestGoSkipRegex_Empty(t *testing.T) {
+	require.Equal(t, "", GoSkipRegex(nil))
+	require.Equal(t, "", GoSkipRegex([]Rule{{MatchType: MatchExact, Pattern: "pkg#"}}))
+}
+
+func TestGoSkipRegex_Subtests(t *testing.T) {
+	rules := []Rule{
+		{MatchType: MatchExact, Pattern: "pkg#TestA/case_1"},
+		{MatchType: MatchGlob, Pattern: "pkg#TestB/*/slow_*"},
+		{MatchType: MatchExact, Pattern: "other#TestA/case_1"},
+		{MatchType: MatchExact, Pattern: "pkg#TestC/a|b"},
+	}
+
+	got := GoSkipRegex(rules)
+	require.Equal(t, `^TestA$/^case_1$|^TestB$/^.*$/^slow_.*$|^TestC$/^a\|b$`, got)
+
+	require.True(t, goSkips(got, "TestA/case_1"))
+	require.True(t, goSkips(got, "TestA/case_1/nested"))
+	require.True(t, goSkips(got, "TestB/go1.22/slow_query"))
+	require.True(t, goSkips(got, "TestC/a|b"))
+	require.False(t, goSkips(got, "TestA"))
+	require.False(t, goSkips(got, "TestA/case_10"))
+	require.False(t, goSkips(got, "TestAB/case_1"))
+	require.False(t, goSkips(got, "TestB/go1.22/fast_query"))
+}
+
+// goSkips reports whether `go test -skip pattern` skips the named test: the
+// pattern is split on unbracketed '|' and '/', and an alternative skips a
+// test when each of its levels matches the test's name at that level
+func goSkips(pattern, name string) bool {
+	levels := strings.Split(name, "/")
+	for _, alt := range splitUnescaped(pattern, '|') {
+		parts := splitUnescaped(alt, '/')
+		if len(parts) > len(levels) {
+			continue
+		}
+		matched := true
+		for i, part := range parts {
+			if !regexp.MustCompile(part).MatchString(levels[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func TestPytestDeselect(t *testing.T) {
+	rules := []Rule{
+		{MatchType: MatchExact, Pattern: "tests.api.test_users.TestLogin#test_ok"},
+		{MatchType: MatchExact, Pattern: "tests.test_math#test_add[1-2]"},
+		{MatchType: MatchGlob, Pattern: "tests.*#test_*"},
+		{MatchType: MatchExact, Pattern: "no_classname"},
+	}
+
+	require.Equal(t, []string{
+		"tests/api/test_users.py::TestLogin::test_ok",
+		"tests/test_math.py::test_add[1-2]",
+	}, PytestDeselect(rules))
+}
+
+func TestJUnit5Exclude(t *testing.T) {
+	rules := []Rule{
+		{MatchType: MatchExact, Pattern: "com.acme.PaymentTest#refund()"},
+		{MatchType: MatchExact, Pattern: "com.acme.PaymentTest#charge(String)[1]"},
+		{MatchType: MatchExact, Pattern: "com.acme.PaymentTest#charge(String)[2]"},
+		{MatchType: MatchGlob, Pattern: "com.acme.*IT#*"},
+	}
+
+	require.Equal(t, []string{
+		"com.acme.PaymentTest#refund",
+		"com.acme.PaymentTest#charge",
+		"com.acme.*IT#*",
+	}, JUnit5Exclude(rules))
+}
@@ -0,0 +1,129 @@
+package quarantine
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MatchType determines how a rule's pattern is matched against test identifiers
+type MatchType string
+
+const (
+	MatchExact MatchType = "exact"
+	MatchGlob  MatchType = "glob"
+)
+
+// IsValid returns true if the match type is known
+func (m MatchType) IsValid() bool {
+	return m == MatchExact || m == MatchGlob
+}
+
+// Rule represents a quarantine rule for a project
+type Rule struct {
+	ID              uuid.UUID     `db:"id"`
+	ProjectID       uuid.UUID     `db:"project_id"`
+	MatchType       MatchType     `db:"match_type"`
+	Pattern         string        `db:"pattern"`
+	Owner           string        `db:"owner"`
+	Reason          string        `db:"reason"`
+	ExpiresAt       sql.NullTime  `db:"expires_at"`
+	CreatedByUserID uuid.UUID     `db:"created_by_user_id"`
+	CreatedAt       time.Time     `db:"created_at"`
+	UpdatedAt       time.Time     `db:"updated_at"`
+	RemovedAt       sql.NullTime  `db:"removed_at"`
+	RemovedByUserID uuid.NullUUID `db:"removed_by_user_id"`
+}
+
+// IsRemoved returns true if the rule has been removed
+func (r *Rule) IsRemoved() bool {
+	return r.RemovedAt.Valid
+}
+
+// IsExpired returns true if the rule has passed its expiry
+func (r *Rule) IsExpired() bool {
+	return r.ExpiresAt.Valid && !r.ExpiresAt.Time.After(time.Now())
+}
+
+// IsActive returns true if the rule is neither removed nor expired
+func (r *Rule) IsActive() bool {
+	return !r.IsRemoved() && !r.IsExpired()
+}
+
+// Matches returns true if the rule's pattern matches the test identifier
+func (r *Rule) Matches(testIdentifier string) bool {
+	if r.MatchType == MatchGlob {
+		return GlobMatch(r.Pattern, testIdentifier)
+	}
+	return r.Pattern == testIdentifier
+}
+
+type RuleResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	MatchType MatchType  `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	Owner     string     `json:"owner"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Expired   bool       `json:"expired"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (r *Rule) ToResponse() RuleResponse {
+	resp := RuleResponse{
+		ID:        r.ID,
+		MatchType: r.MatchType,
+		Pattern:   r.Pattern,
+		Owner:     r.Owner,
+		Reason:    r.Reason,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+	if r.ExpiresAt.Valid {
+		resp.ExpiresAt = &r.ExpiresAt.Time
+		resp.Expired = r.IsExpired()
+	}
+	return resp
+}
+
+// RuleSet is the set of active rules for a project, used to tag and mute tests
+type RuleSet struct {
+	rules []Rule
+}
+
+// NewRuleSet creates a rule set from active rules
+func NewRuleSet(rules []Rule) *RuleSet {
+	return &RuleSet{rules: rules}
+}
+
+// Match returns the first rule matching the test identifier, or nil.
+// Exact rules take precedence over glob rules.
+func (s *RuleSet) Match(testIdentifier string) *Rule {
+	if s == nil {
+		return nil
+	}
+	var globMatch *Rule
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if !rule.IsActive() || !rule.Matches(testIdentifier) {
+			continue
+		}
+		if rule.MatchType == MatchExact {
+			return rule
+		}
+		if globMatch == nil {
+			globMatch = rule
+		}
+	}
+	return globMatch
+}
+
+// Rules returns the active rules in the set
+func (s *RuleSet) Rules() []Rule {
+	if s == nil {
+		return nil
+	}
+	return s.rules
+}
@@ -0,0 +1,77 @@
+package quarantine
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"TestFoo", "TestFoo", true},
+		{"TestFoo", "TestFooBar", false},
+		{"TestFoo*", "TestFooBar", true},
+		{"TestFoo/*", "TestFoo/subtest_a", true},
+		{"*Integration*", "pkg.TestIntegrationSlow", true},
+		{"com.example.*Test#test*", "com.example.PaymentTest#testRefund", true},
+		{"com.example.*Test#test*", "com.example.PaymentSpec#testRefund", false},
+		{"Test?oo", "TestFoo", true},
+		{"Test?oo", "TestFFoo", false},
+		{"*", "", true},
+		{"", "", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, GlobMatch(tc.pattern, tc.s), "pattern=%q s=%q", tc.pattern, tc.s)
+	}
+}
+
+func TestIsGlobPattern(t *testing.T) {
+	require.True(t, IsGlobPattern("TestFoo*"))
+	require.True(t, IsGlobPattern("Test?oo"))
+	require.False(t, IsGlobPattern("pkg.TestFoo"))
+}
+
+func TestRuleSet_MatchPrefersExact(t *testing.T) {
+	glob := Rule{ID: uuid.New(), MatchType: MatchGlob, Pattern: "TestFoo*"}
+	exact := Rule{ID: uuid.New(), MatchType: MatchExact, Pattern: "TestFooBar"}
+	rules := NewRuleSet([]Rule{glob, exact})
+
+	got := rules.Match("TestFooBar")
+	require.NotNil(t, got)
+	require.Equal(t, exact.ID, got.ID)
+
+	got = rules.Match("TestFooBaz")
+	require.NotNil(t, got)
+	require.Equal(t, glob.ID, got.ID)
+
+	require.Nil(t, rules.Match("TestOther"))
+}
+
+func TestRuleSet_MatchSkipsExpiredAndRemoved(t *testing.T) {
+	expired := Rule{
+		ID:        uuid.New(),
+		MatchType: MatchExact,
+		Pattern:   "TestFoo",
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true},
+	}
+	removed := Rule{
+		ID:        uuid.New(),
+		MatchType: MatchExact,
+		Pattern:   "TestFoo",
+		RemovedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	require.Nil(t, NewRuleSet([]Rule{expired, removed}).Match("TestFoo"))
+
+	var nilSet *RuleSet
+	require.Nil(t, nilSet.Match("TestFoo"))
+}
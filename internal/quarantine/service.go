@@ -0,0 +1,239 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrRuleNotFound is returned when a quarantine rule is not found
+	ErrRuleNotFound = errors.New("quarantine rule not found")
+
+	// ErrPatternConflict is returned when an active rule already exists for the pattern
+	ErrPatternConflict = errors.New("active quarantine rule already exists for pattern")
+)
+
+// Service provides quarantine rule operations
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new quarantine service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+const ruleColumns = `
+	id, project_id, match_type::text, pattern, owner, reason, expires_at,
+	created_by_user_id, created_at, updated_at, removed_at, removed_by_user_id
+`
+
+func scanRule(row pgx.Row) (*Rule, error) {
+	var rule Rule
+	err := row.Scan(
+		&rule.ID,
+		&rule.ProjectID,
+		&rule.MatchType,
+		&rule.Pattern,
+		&rule.Owner,
+		&rule.Reason,
+		&rule.ExpiresAt,
+		&rule.CreatedByUserID,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+		&rule.RemovedAt,
+		&rule.RemovedByUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetByID retrieves a quarantine rule by ID
+func (s *Service) GetByID(ctx context.Context, ruleID uuid.UUID) (*Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM quarantine_rules WHERE id = $1`
+
+	rule, err := scanRule(s.pool.QueryRow(ctx, query, ruleID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get quarantine rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// ListByProject retrieves all non-removed rules for a project, including expired ones
+func (s *Service) ListByProject(ctx context.Context, projectID uuid.UUID) ([]Rule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM quarantine_rules
+		WHERE project_id = $1 AND removed_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	return s.queryRules(ctx, query, projectID)
+}
+
+// ActiveRuleSet loads the active (non-removed, unexpired) rules for a project
+func (s *Service) ActiveRuleSet(ctx context.Context, projectID uuid.UUID) (*RuleSet, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM quarantine_rules
+		WHERE project_id = $1
+		  AND removed_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at ASC
+	`
+
+	rules, err := s.queryRules(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return NewRuleSet(rules), nil
+}
+
+func (s *Service) queryRules(ctx context.Context, query string, args ...any) ([]Rule, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantine rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quarantine rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quarantine rule rows: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CreateParams contains the fields for a new quarantine rule
+type CreateParams struct {
+	MatchType MatchType
+	Pattern   string
+	Owner     string
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+// Create creates a new quarantine rule. An expired rule for the same pattern
+// is marked removed as of its expiry so the pattern can be quarantined again.
+func (s *Service) Create(ctx context.Context, projectID, userID uuid.UUID, params CreateParams) (*Rule, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	expire := `
+		UPDATE quarantine_rules
+		SET removed_at = expires_at
+		WHERE project_id = $1
+		  AND match_type = $2::quarantine_match
+		  AND pattern = $3
+		  AND removed_at IS NULL
+		  AND expires_at <= NOW()
+	`
+	if _, err := tx.Exec(ctx, expire, projectID, string(params.MatchType), params.Pattern); err != nil {
+		return nil, fmt.Errorf("failed to retire expired quarantine rule: %w", err)
+	}
+
+	query := `
+		INSERT INTO quarantine_rules (project_id, match_type, pattern, owner, reason, expires_at, created_by_user_id)
+		VALUES ($1, $2::quarantine_match, $3, $4, $5, $6, $7)
+		RETURNING ` + ruleColumns
+
+	rule, err := scanRule(tx.QueryRow(ctx, query,
+		projectID,
+		string(params.MatchType),
+		params.Pattern,
+		params.Owner,
+		params.Reason,
+		params.ExpiresAt,
+		userID,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrPatternConflict
+		}
+		return nil, fmt.Errorf("failed to create quarantine rule: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rule, nil
+}
+
+// UpdateParams contains the mutable fields of a quarantine rule.
+// Nil fields are left unchanged; ClearExpiry removes the expiry.
+type UpdateParams struct {
+	Owner       *string
+	Reason      *string
+	ExpiresAt   *time.Time
+	ClearExpiry bool
+}
+
+// Update changes owner, reason or expiry of an active rule
+func (s *Service) Update(ctx context.Context, ruleID uuid.UUID, params UpdateParams) (*Rule, error) {
+	query := `
+		UPDATE quarantine_rules
+		SET
+			owner = COALESCE($2, owner),
+			reason = COALESCE($3, reason),
+			expires_at = CASE WHEN $5 THEN NULL ELSE COALESCE($4, expires_at) END
+		WHERE id = $1 AND removed_at IS NULL
+		RETURNING ` + ruleColumns
+
+	rule, err := scanRule(s.pool.QueryRow(ctx, query, ruleID, params.Owner, params.Reason, params.ExpiresAt, params.ClearExpiry))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to update quarantine rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// Remove marks a quarantine rule as removed
+func (s *Service) Remove(ctx context.Context, ruleID, userID uuid.UUID) error {
+	query := `
+		UPDATE quarantine_rules
+		SET removed_at = NOW(), removed_by_user_id = $2
+		WHERE id = $1 AND removed_at IS NULL
+	`
+
+	result, err := s.pool.Exec(ctx, query, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove quarantine rule: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRuleNotFound
+	}
+
+	return nil
+}
@@ -42,7 +42,25 @@ func DeleteOldFlakeEvents(ctx context.Context, pool *pgxpool.Pool, retentionDays
 	return tag.RowsAffected(), nil
 }
 
-// RunRetentionJob executes both retention operations and logs the results.
+// DeleteOldNotificationDeliveries deletes sent and dead-lettered
+// notification_deliveries rows older than the specified days. Pending
+// deliveries are kept until they complete. The function is idempotent.
+func DeleteOldNotificationDeliveries(ctx context.Context, pool *pgxpool.Pool, retentionDays int) (int64, error) {
+	query := `
+		DELETE FROM notification_deliveries
+		WHERE status IN ('sent', 'failed')
+		  AND created_at < NOW() - INTERVAL '1 day' * $1
+	`
+
+	tag, err := pool.Exec(ctx, query, retentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notification deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RunRetentionJob executes the retention operations and logs the results.
+// Notification deliveries are kept as long as flake events.
 func RunRetentionJob(ctx context.Context, pool *pgxpool.Pool, junitDays, eventsDays int) error {
 	log.Info().
 		Int("junit_retention_days", junitDays).
@@ -63,9 +81,16 @@ func RunRetentionJob(ctx context.Context, pool *pgxpool.Pool, junitDays, eventsD
 		return fmt.Errorf("flake events cleanup failed: %w", err)
 	}
 
+	deliveriesDeleted, err := DeleteOldNotificationDeliveries(ctx, pool, eventsDays)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete old notification deliveries")
+		return fmt.Errorf("notification deliveries cleanup failed: %w", err)
+	}
+
 	log.Info().
 		Int64("junit_content_cleared", junitCleared).
 		Int64("flake_events_deleted", eventsDeleted).
+		Int64("notification_deliveries_deleted", deliveriesDeleted).
 		Dur("duration", time.Since(startTime)).
 		Msg("Retention job completed")
 
@@ -0,0 +1,157 @@
+package signatures
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleList handles GET /api/v1/projects/{project_id}/failure-signatures?days=30&min_tests=1&limit=50&offset=0
+func HandleList(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		req, err := ParseListRequest(r)
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, err.Error())
+			return
+		}
+
+		signatures, err := NewService(pool).List(ctx, project.ID, req, time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list failure signatures")
+			apperrors.WriteInternalError(w, r, "Failed to list failure signatures")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"signatures": signatures,
+		})
+	}
+}
+
+// HandleGet handles GET /api/v1/projects/{project_id}/failure-signatures/{signature_id}
+func HandleGet(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		project, ok := requireProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		signatureID, err := uuid.Parse(chi.URLParam(r, "signature_id"))
+		if err != nil {
+			apperrors.WriteBadRequest(w, r, "Invalid signature ID")
+			return
+		}
+
+		signature, err := NewService(pool).Get(ctx, project.ID, signatureID)
+		if err != nil {
+			if errors.Is(err, ErrSignatureNotFound) {
+				apperrors.WriteNotFound(w, r, "Failure signature not found")
+				return
+			}
+			log.Error().Err(err).Str("signature_id", signatureID.String()).Msg("Failed to get failure signature")
+			apperrors.WriteInternalError(w, r, "Failed to get failure signature")
+			return
+		}
+
+		apperrors.WriteSuccess(w, r, http.StatusOK, map[string]any{
+			"signature": signature,
+		})
+	}
+}
+
+// ParseListRequest reads the list query parameters: days (default 30, max
+// 365), min_tests (default 1), limit (default 50, max MaxSignaturesListed)
+// and offset
+func ParseListRequest(r *http.Request) (ListRequest, error) {
+	req := ListRequest{Days: 30, MinTests: 1, Limit: 50}
+	q := r.URL.Query()
+
+	if raw := q.Get("days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days <= 0 {
+			return req, errors.New("days must be a positive integer")
+		}
+		req.Days = min(days, 365)
+	}
+
+	if raw := q.Get("min_tests"); raw != "" {
+		minTests, err := strconv.Atoi(raw)
+		if err != nil || minTests <= 0 {
+			return req, errors.New("min_tests must be a positive integer")
+		}
+		req.MinTests = minTests
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return req, errors.New("limit must be a positive integer")
+		}
+		req.Limit = min(limit, MaxSignaturesListed)
+	}
+
+	if raw := q.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return req, errors.New("offset must be a non-negative integer")
+		}
+		req.Offset = offset
+	}
+
+	return req, nil
+}
+
+// requireProject loads the {project_id} project and checks org membership.
+// Writes the error response on failure.
+func requireProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) (*projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "project_id"))
+	if err != nil {
+		apperrors.WriteBadRequest(w, r, "Invalid project ID")
+		return nil, false
+	}
+
+	project, err := projects.NewService(pool).GetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to get project")
+		apperrors.WriteInternalError(w, r, "Failed to get project")
+		return nil, false
+	}
+
+	if _, err := orgs.NewService(pool).RequireOrgMember(ctx, userID, project.OrgID); err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			apperrors.WriteNotFound(w, r, "Project not found")
+			return nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org membership")
+		apperrors.WriteInternalError(w, r, "Failed to check permissions")
+		return nil, false
+	}
+
+	return project, true
+}
@@ -0,0 +1,64 @@
+package signatures
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signature is a cluster of a project's failures with the same fingerprint.
+// Occurrences counts failed results and failed in-job reruns.
+type Signature struct {
+	ID                uuid.UUID `json:"id"`
+	ProjectID         uuid.UUID `json:"project_id"`
+	Fingerprint       string    `json:"fingerprint"`
+	NormalizedMessage string    `json:"normalized_message"`
+	ExampleMessage    string    `json:"example_message"`
+	Occurrences       int       `json:"occurrences"`
+	AffectedTests     int       `json:"affected_tests"`
+	FirstSeenAt       time.Time `json:"first_seen_at"`
+	LastSeenAt        time.Time `json:"last_seen_at"`
+}
+
+// Title is the first line of the normalized message
+func (s *Signature) Title() string {
+	title, _, _ := strings.Cut(s.NormalizedMessage, "\n")
+	return title
+}
+
+// AffectedTest is a test a signature was seen in. Flaky tells whether the
+// test has flake stats, and so a flake detail page.
+type AffectedTest struct {
+	TestCaseID     uuid.UUID `json:"test_case_id"`
+	RepoFullName   string    `json:"repo_full_name"`
+	JobName        string    `json:"job_name"`
+	JobVariant     string    `json:"job_variant"`
+	TestIdentifier string    `json:"test_identifier"`
+	Flaky          bool      `json:"flaky"`
+	Occurrences    int       `json:"occurrences"`
+	FirstSeenAt    time.Time `json:"first_seen_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+}
+
+// SignatureDetail is a signature with the tests it was seen in
+type SignatureDetail struct {
+	Signature
+	Tests []AffectedTest `json:"tests"`
+}
+
+// Failure is a failed test result or rerun to record
+type Failure struct {
+	TestCaseID uuid.UUID
+	Message    string
+	Output     string
+}
+
+// ListRequest selects the signatures of a project seen in the last Days.
+// MinTests > 1 keeps the signatures shared by several tests.
+type ListRequest struct {
+	Days     int
+	MinTests int
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,131 @@
+package signatures
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxMessageLines is how many lines of a failure message are part of its
+	// signature
+	maxMessageLines = 5
+	// maxFrames is how many of the innermost stack frames of a failure output
+	// are part of its signature. Outer frames are mostly the calling test, so
+	// keeping them would split a shared root cause into one signature per test.
+	maxFrames = 3
+)
+
+var (
+	uuidRe      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	timestampRe = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2})?(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)?\b|\b\d{1,2}:\d{2}:\d{2}(?:[.,]\d+)?\b`)
+	tempPathRe  = regexp.MustCompile(`(?:/private)?/(?:tmp|var/tmp|var/folders)/[^\s"'):,]*|(?i)[a-z]:\\(?:[^\s\\"']+\\)*?te?mp\\[^\s"'):,]*`)
+	addressRe   = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	ipRe        = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+	hexRe       = regexp.MustCompile(`\b[0-9a-fA-F]{8,}\b`)
+	numberRe    = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	spaceRe     = regexp.MustCompile(`\s+`)
+
+	// Stack frames: Java/C#/JavaScript "at ...", Python "File ..., line N,
+	// in f" and Go "pkg.(*T).F(args)"; Go's file lines below a function are
+	// not needed to tell frames apart
+	atFrameRe     = regexp.MustCompile(`^at\s+(.*)$`)
+	locationRe    = regexp.MustCompile(`(?:[^\s()]*[/\\])?([^/\\\s():]+):(?:line\s+)?\d+(?::\d+)?`)
+	pythonFrameRe = regexp.MustCompile(`^File\s+"(?:[^"]*[/\\])?([^"/\\]+)",\s+line\s+\d+,\s+in\s+(\S+)`)
+	goFuncRe      = regexp.MustCompile(`^((?:[\w.-]+/)*[\w.-]+\.(?:\(\*?\w+\)\.)?[\w.]+)\([^()]*\)$`)
+)
+
+// Fingerprint returns the signature of a failure and its normalized text, or
+// empty strings when the failure has no text to cluster on.
+func Fingerprint(message, output string) (string, string) {
+	normalized := Normalize(message, output)
+	if normalized == "" {
+		return "", ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:16]), normalized
+}
+
+// Normalize reduces a failure to the lines that identify its cause: the
+// first lines of the message (or of the output, without a message) and the
+// top stack frames of the output, with volatile values replaced by
+// placeholders. Other output lines, such as assertion diffs, are dropped.
+func Normalize(message, output string) string {
+	message = strings.TrimSpace(message)
+	outputLines := strings.Split(output, "\n")
+	if message == "" {
+		for i, line := range outputLines {
+			if strings.TrimSpace(line) != "" {
+				message = line
+				outputLines = outputLines[i+1:]
+				break
+			}
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if len(lines) == maxMessageLines {
+			break
+		}
+		if line = normalizeText(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var frames []string
+	innermostLast := false
+	for _, line := range outputLines {
+		frame, ok := canonicalFrame(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		frames = append(frames, normalizeText(frame))
+		// Python prints the innermost frame last
+		innermostLast = innermostLast || strings.HasPrefix(frame, "File ")
+	}
+	if len(frames) > maxFrames {
+		if innermostLast {
+			frames = frames[len(frames)-maxFrames:]
+		} else {
+			frames = frames[:maxFrames]
+		}
+	}
+
+	return strings.Join(append(lines, frames...), "\n")
+}
+
+// canonicalFrame reduces a stack frame to its function and file name,
+// without arguments, line numbers and directories. ok is false for lines that are not
+// frames.
+func canonicalFrame(line string) (string, bool) {
+	if m := atFrameRe.FindStringSubmatch(line); m != nil {
+		return "at " + locationRe.ReplaceAllString(m[1], "$1"), true
+	}
+	if m := pythonFrameRe.FindStringSubmatch(line); m != nil {
+		return "File " + m[1] + " in " + m[2], true
+	}
+	if m := goFuncRe.FindStringSubmatch(line); m != nil {
+		return m[1], true
+	}
+	return "", false
+}
+
+// normalizeText replaces the volatile values of a line with placeholders
+func normalizeText(s string) string {
+	s = uuidRe.ReplaceAllString(s, "<uuid>")
+	s = timestampRe.ReplaceAllString(s, "<time>")
+	s = tempPathRe.ReplaceAllString(s, "<tmp>")
+	s = addressRe.ReplaceAllString(s, "<addr>")
+	s = ipRe.ReplaceAllString(s, "<ip>")
+	s = hexRe.ReplaceAllStringFunc(s, func(h string) string {
+		// Only strings with a digit, so plain words stay
+		if strings.ContainsAny(h, "0123456789") {
+			return "<hex>"
+		}
+		return h
+	})
+	s = numberRe.ReplaceAllString(s, "<n>")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,121 @@
+package signatures
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize_StripsVolatileValues(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"numbers and ports", "connection refused to postgres:5432 after 3 attempts", "connection refused to postgres:<n> after <n> attempts"},
+		{"uuid", "order 3f2b8c1e-9a4d-4e6f-8b2a-1c3d5e7f9a0b not found", "order <uuid> not found"},
+		{"timestamp", "deadline 2026-10-14T09:30:12.123Z exceeded at 09:30:15", "deadline <time> exceeded at <time>"},
+		{"address", "nil pointer dereference at 0xc000123abc", "nil pointer dereference at <addr>"},
+		{"ip", "dial tcp 10.0.3.17:443: i/o timeout", "dial tcp <ip>:<n>: i/o timeout"},
+		{"temp path", "cannot open /tmp/pytest-of-ci/pytest-12/test_upload0/data.csv: EOF", "cannot open <tmp>: EOF"},
+		{"macos temp path", "lock held on /var/folders/x1/abc123/T/build.lock", "lock held on <tmp>"},
+		{"windows temp path", `cannot delete C:\Users\ci\AppData\Local\Temp\foo123\out.txt`, "cannot delete <tmp>"},
+		{"hex", "commit 9fe9b3e2a1 is not an ancestor", "commit <hex> is not an ancestor"},
+		{"words stay", "expected deadbeefcafe to be cafebabefeed", "expected deadbeefcafe to be cafebabefeed"},
+		{"whitespace", "  too   many\tspaces ", "too many spaces"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, Normalize(tc.in, ""))
+		})
+	}
+}
+
+func TestNormalize_CanonicalizesStackFrames(t *testing.T) {
+	java := `java.net.ConnectException: Connection refused
+	at java.base/sun.nio.ch.Net.connect0(Native Method)
+	at org.postgresql.core.PGStream.<init>(PGStream.java:95)
+	at com.example.db.PoolTest.lambda$setUp$0(PoolTest.java:42)
+	... 23 more`
+	require.Equal(t, `connection refused
+at java.base/sun.nio.ch.Net.connect<n>(Native Method)
+at org.postgresql.core.PGStream.<init>(PGStream.java)
+at com.example.db.PoolTest.lambda$setUp$<n>(PoolTest.java)`, Normalize("connection refused", java))
+
+	python := `Traceback (most recent call last):
+  File "/home/ci/.venv/lib/python3.12/site-packages/_pytest/python.py", line 159, in pytest_pyfunc_call
+  File "/home/ci/work/app/tests/test_db.py", line 12, in test_connect
+    conn = connect()
+  File "/home/ci/work/app/app/db.py", line 5, in connect
+  File "/usr/lib/python3.12/socket.py", line 836, in create_connection
+ConnectionRefusedError: [Errno 111] Connection refused`
+	require.Equal(t, `ConnectionRefusedError: [Errno <n>] Connection refused
+File test_db.py in test_connect
+File db.py in connect
+File socket.py in create_connection`, Normalize("ConnectionRefusedError: [Errno 111] Connection refused", python), "the innermost frames are last")
+
+	goPanic := `panic: runtime error: invalid memory address or nil pointer dereference
+goroutine 7 [running]:
+github.com/acme/app/db.(*Pool).Get(0x0, {0xc0000a2000, 0x3})
+	/home/runner/work/app/db/pool.go:88 +0x1d
+testing.tRunner(0xc000102340, 0x1234)
+	/usr/local/go/src/testing/testing.go:1689 +0xfb`
+	require.Equal(t, `panic: runtime error: invalid memory address or nil pointer dereference
+github.com/acme/app/db.(*Pool).Get
+testing.tRunner`, Normalize("", goPanic), "without a message the first output line is used")
+
+	js := `Error: connect ECONNREFUSED 127.0.0.1:6379
+    at TCPConnectWrap.afterConnect [as oncomplete] (node:net:1555:16)
+    at Object.<anonymous> (/home/ci/app/test/cache.test.js:10:5)
+    at /home/ci/app/node_modules/jest-circus/build/utils.js:298:28`
+	require.Equal(t, `Error: connect ECONNREFUSED <ip>:<n>
+at TCPConnectWrap.afterConnect [as oncomplete] (node:net)
+at Object.<anonymous> (cache.test.js)
+at utils.js`, Normalize("", js))
+
+	csharp := `   at Acme.Db.Pool.Open() in /src/Acme/Db/Pool.cs:line 42`
+	require.Equal(t, "boom\nat Acme.Db.Pool.Open() in Pool.cs", Normalize("boom", csharp))
+}
+
+func TestNormalize_DropsNonFrameOutputAndDeepFrames(t *testing.T) {
+	output := "expected: 1\nactual: 2\n"
+	for i := 0; i < maxFrames+5; i++ {
+		output += "\tat com.example.Deep.call(Deep.java:1)\n"
+	}
+	lines := Normalize("assertion failed", output)
+	require.Len(t, strings.Split(lines, "\n"), 1+maxFrames)
+	require.Equal(t, "", Normalize(" ", "\n\n"))
+}
+
+func TestFingerprint_ClustersSameRootCause(t *testing.T) {
+	a, normalized := Fingerprint("connection refused to postgres:5432 (attempt 1 at 2026-10-14T09:30:12Z)", "\tat com.example.Db.connect(Db.java:10)")
+	b, _ := Fingerprint("connection refused to postgres:5433 (attempt 3 at 2026-10-15T11:02:59Z)", "\tat com.example.Db.connect(Db.java:12)")
+	c, _ := Fingerprint("timeout waiting for redis", "")
+
+	require.Len(t, a, 32)
+	require.Equal(t, a, b)
+	require.NotEqual(t, a, c)
+	require.Equal(t, "connection refused to postgres:<n> (attempt <n> at <time>)\nat com.example.Db.connect(Db.java)", normalized)
+
+	empty, normalized := Fingerprint("", "")
+	require.Empty(t, empty)
+	require.Empty(t, normalized)
+}
+
+func TestCounts_AccumulatesAcrossBatches(t *testing.T) {
+	testA, testB := uuid.New(), uuid.New()
+	counts := NewCounts()
+
+	counts.Add(Failure{TestCaseID: testA, Message: "connection refused to postgres:5432"})
+	counts.Add(Failure{TestCaseID: testB, Message: "connection refused to postgres:6543"})
+	counts.Add(Failure{TestCaseID: testA, Message: "connection refused to postgres:5432"})
+	counts.Add(Failure{TestCaseID: testB})
+
+	require.Len(t, counts.byFingerprint, 1)
+	for _, c := range counts.byFingerprint {
+		require.Equal(t, 3, c.occurrences)
+		require.Equal(t, map[uuid.UUID]int{testA: 2, testB: 1}, c.tests)
+	}
+}
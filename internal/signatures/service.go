@@ -0,0 +1,322 @@
+package signatures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSignatureNotFound = errors.New("failure signature not found")
+)
+
+const (
+	// MaxSignaturesListed bounds the signatures returned by a list
+	MaxSignaturesListed = 200
+	// MaxAffectedTests bounds the tests returned with a signature
+	MaxAffectedTests = 500
+
+	// maxExampleLength bounds the stored example message, like
+	// flake.MaxFailureMessageLength
+	maxExampleLength = 1024
+)
+
+// Service handles failure signature operations
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+// NewService creates a new failure signature service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+// signatureCount is the failures of one fingerprint in a batch
+type signatureCount struct {
+	normalized  string
+	example     string
+	occurrences int
+	tests       map[uuid.UUID]int
+}
+
+// Counts accumulates the failures of an ingestion by signature, so they are
+// written by a single Record call however many batches the results came in
+type Counts struct {
+	byFingerprint map[string]*signatureCount
+}
+
+// NewCounts creates an empty set of signature counts
+func NewCounts() *Counts {
+	return &Counts{byFingerprint: make(map[string]*signatureCount)}
+}
+
+// Add counts a failure towards its signature. Failures without text to
+// cluster on are skipped.
+func (c *Counts) Add(f Failure) {
+	fingerprint, normalized := Fingerprint(f.Message, f.Output)
+	if fingerprint == "" {
+		return
+	}
+	sc := c.byFingerprint[fingerprint]
+	if sc == nil {
+		sc = &signatureCount{normalized: normalized, example: exampleMessage(f), tests: make(map[uuid.UUID]int)}
+		c.byFingerprint[fingerprint] = sc
+	}
+	sc.occurrences++
+	sc.tests[f.TestCaseID]++
+}
+
+// Record adds counted failures of a project to their signatures in the
+// caller's transaction. Rows are locked in fingerprint and test order, so
+// concurrent ingestions of the project cannot deadlock as long as each
+// transaction calls Record at most once; callers collect their failures in
+// one Counts and record them just before committing.
+func Record(ctx context.Context, tx pgx.Tx, projectID uuid.UUID, c *Counts) error {
+	counts := c.byFingerprint
+	if len(counts) == 0 {
+		return nil
+	}
+
+	fingerprints := make([]string, 0, len(counts))
+	for fingerprint := range counts {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Strings(fingerprints)
+
+	signatureQuery := `
+		INSERT INTO failure_signatures (project_id, fingerprint, normalized_message, example_message, occurrences)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id, fingerprint)
+		DO UPDATE SET
+			occurrences = failure_signatures.occurrences + EXCLUDED.occurrences,
+			example_message = EXCLUDED.example_message,
+			last_seen_at = NOW()
+		RETURNING id
+	`
+
+	batch := &pgx.Batch{}
+	for _, fingerprint := range fingerprints {
+		c := counts[fingerprint]
+		batch.Queue(signatureQuery, projectID, fingerprint, c.normalized, c.example, c.occurrences)
+	}
+
+	signatureIDs := make([]uuid.UUID, len(fingerprints))
+	br := tx.SendBatch(ctx, batch)
+	for i := range fingerprints {
+		if err := br.QueryRow().Scan(&signatureIDs[i]); err != nil {
+			br.Close()
+			return fmt.Errorf("failed to upsert failure signature: %w", err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("failed to upsert failure signatures: %w", err)
+	}
+
+	testQuery := `
+		INSERT INTO failure_signature_tests (signature_id, test_case_id, occurrences)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (signature_id, test_case_id)
+		DO UPDATE SET
+			occurrences = failure_signature_tests.occurrences + EXCLUDED.occurrences,
+			last_seen_at = NOW()
+	`
+
+	batch = &pgx.Batch{}
+	for i, fingerprint := range fingerprints {
+		tests := counts[fingerprint].tests
+		testCaseIDs := make([]uuid.UUID, 0, len(tests))
+		for testCaseID := range tests {
+			testCaseIDs = append(testCaseIDs, testCaseID)
+		}
+		sort.Slice(testCaseIDs, func(a, b int) bool {
+			return testCaseIDs[a].String() < testCaseIDs[b].String()
+		})
+		for _, testCaseID := range testCaseIDs {
+			batch.Queue(testQuery, signatureIDs[i], testCaseID, tests[testCaseID])
+		}
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to upsert failure signature tests: %w", err)
+	}
+	return nil
+}
+
+// exampleMessage is the raw text shown for a signature: the failure message,
+// or the first line of its output without one
+func exampleMessage(f Failure) string {
+	example := strings.TrimSpace(f.Message)
+	if example == "" {
+		for _, line := range strings.Split(f.Output, "\n") {
+			if example = strings.TrimSpace(line); example != "" {
+				break
+			}
+		}
+	}
+	if len(example) > maxExampleLength {
+		example = strings.ToValidUTF8(example[:maxExampleLength], "")
+	}
+	return example
+}
+
+const signatureColumns = `
+	s.id, s.project_id, s.fingerprint, s.normalized_message, s.example_message,
+	s.occurrences, COUNT(t.test_case_id), s.first_seen_at, s.last_seen_at
+`
+
+func scanSignature(row pgx.Row) (*Signature, error) {
+	var s Signature
+	err := row.Scan(
+		&s.ID,
+		&s.ProjectID,
+		&s.Fingerprint,
+		&s.NormalizedMessage,
+		&s.ExampleMessage,
+		&s.Occurrences,
+		&s.AffectedTests,
+		&s.FirstSeenAt,
+		&s.LastSeenAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List returns the signatures of a project seen in the last req.Days, those
+// affecting the most tests first
+func (s *Service) List(ctx context.Context, projectID uuid.UUID, req ListRequest, now time.Time) ([]Signature, error) {
+	query := `
+		SELECT ` + signatureColumns + `
+		FROM failure_signatures s
+		JOIN failure_signature_tests t ON t.signature_id = s.id
+		WHERE s.project_id = $1
+		  AND s.last_seen_at >= $2
+		GROUP BY s.id
+		HAVING COUNT(t.test_case_id) >= $3
+		ORDER BY COUNT(t.test_case_id) DESC, s.occurrences DESC, s.last_seen_at DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	cutoff := now.AddDate(0, 0, -req.Days)
+	rows, err := s.pool.Query(ctx, query, projectID, cutoff, max(req.MinTests, 1), req.Limit, req.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failure signatures: %w", err)
+	}
+	defer rows.Close()
+
+	signatures := []Signature{}
+	for rows.Next() {
+		sig, err := scanSignature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan failure signature: %w", err)
+		}
+		signatures = append(signatures, *sig)
+	}
+	return signatures, rows.Err()
+}
+
+// ListByTest returns the signatures a test was seen with, most recent first
+func (s *Service) ListByTest(ctx context.Context, projectID, testCaseID uuid.UUID) ([]Signature, error) {
+	query := `
+		SELECT ` + signatureColumns + `
+		FROM failure_signatures s
+		JOIN failure_signature_tests t ON t.signature_id = s.id
+		WHERE s.project_id = $1
+		  AND s.id IN (SELECT signature_id FROM failure_signature_tests WHERE test_case_id = $2)
+		GROUP BY s.id
+		ORDER BY s.last_seen_at DESC
+		LIMIT $3
+	`
+
+	rows, err := s.pool.Query(ctx, query, projectID, testCaseID, MaxSignaturesListed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failure signatures of test: %w", err)
+	}
+	defer rows.Close()
+
+	signatures := []Signature{}
+	for rows.Next() {
+		sig, err := scanSignature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan failure signature: %w", err)
+		}
+		signatures = append(signatures, *sig)
+	}
+	return signatures, rows.Err()
+}
+
+// Get returns a signature of a project with the tests it was seen in, most
+// recent first
+func (s *Service) Get(ctx context.Context, projectID, signatureID uuid.UUID) (*SignatureDetail, error) {
+	query := `
+		SELECT ` + signatureColumns + `
+		FROM failure_signatures s
+		JOIN failure_signature_tests t ON t.signature_id = s.id
+		WHERE s.id = $1 AND s.project_id = $2
+		GROUP BY s.id
+	`
+
+	sig, err := scanSignature(s.pool.QueryRow(ctx, query, signatureID, projectID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSignatureNotFound
+		}
+		return nil, fmt.Errorf("failed to get failure signature: %w", err)
+	}
+
+	testsQuery := `
+		SELECT
+			tc.id,
+			tc.repo_full_name,
+			tc.job_name,
+			tc.job_variant,
+			tc.test_identifier,
+			EXISTS (SELECT 1 FROM flake_stats fs WHERE fs.test_case_id = tc.id),
+			t.occurrences,
+			t.first_seen_at,
+			t.last_seen_at
+		FROM failure_signature_tests t
+		JOIN test_cases tc ON tc.id = t.test_case_id
+		WHERE t.signature_id = $1
+		ORDER BY t.last_seen_at DESC, tc.test_identifier
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, testsQuery, signatureID, MaxAffectedTests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list affected tests: %w", err)
+	}
+	defer rows.Close()
+
+	detail := &SignatureDetail{Signature: *sig, Tests: []AffectedTest{}}
+	for rows.Next() {
+		var t AffectedTest
+		if err := rows.Scan(
+			&t.TestCaseID,
+			&t.RepoFullName,
+			&t.JobName,
+			&t.JobVariant,
+			&t.TestIdentifier,
+			&t.Flaky,
+			&t.Occurrences,
+			&t.FirstSeenAt,
+			&t.LastSeenAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan affected test: %w", err)
+		}
+		detail.Tests = append(detail.Tests, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list affected tests: %w", err)
+	}
+
+	return detail, nil
+}
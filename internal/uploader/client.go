@@ -0,0 +1,249 @@
+package uploader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxRetries = 4
+	DefaultRetryDelay = time.Second
+
+	maxRetryDelay = 30 * time.Second
+)
+
+// Client uploads reports to the FlakeGuard ingestion API
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	MaxRetries int           // retries after the first attempt
+	RetryDelay time.Duration // initial backoff, doubled per retry
+	Codeowners string        // CODEOWNERS file sent with each upload, if set
+}
+
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 2 * time.Minute},
+		MaxRetries: DefaultMaxRetries,
+		RetryDelay: DefaultRetryDelay,
+	}
+}
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// retryable reports whether a request failing with this status may succeed
+// when repeated
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// Upload gzips the report files, and the CODEOWNERS file if set, into one
+// multipart request and returns the queued ingestion
+func (c *Client) Upload(ctx context.Context, meta *ingest.IngestionMetadata, paths []string) (*ingest.IngestionStatus, error) {
+	body, contentType, err := buildUploadBody(meta, paths, c.Codeowners)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ingest.IngestionStatus
+	err = c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/v1/ingest/junit", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return req, nil
+	}, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetIngestion returns the processing status of an ingestion
+func (c *Client) GetIngestion(ctx context.Context, ingestionID uuid.UUID) (*ingest.IngestionStatus, error) {
+	var status ingest.IngestionStatus
+	err := c.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/v1/ingest/"+ingestionID.String(), nil)
+	}, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// WaitForIngestion polls an ingestion until it succeeded or failed
+func (c *Client) WaitForIngestion(ctx context.Context, ingestionID uuid.UUID, interval time.Duration) (*ingest.IngestionStatus, error) {
+	for {
+		status, err := c.GetIngestion(ctx, ingestionID)
+		if err != nil {
+			return nil, err
+		}
+		if status.Status == ingest.StatusSucceeded || status.Status == ingest.StatusFailed {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// do sends a request, retrying network errors, 429 and 5xx responses with
+// exponential backoff or the server's Retry-After, and decodes the envelope
+// data into out
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error), out any) error {
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return fmt.Errorf("failed to build request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+		wait := delay
+		resp, err := c.HTTPClient.Do(req)
+		if err == nil {
+			err = decodeResponse(resp, out)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+		}
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !retryable(apiErr.StatusCode) {
+			return err
+		}
+		if ctx.Err() != nil || attempt >= c.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(wait, maxRetryDelay)):
+		}
+		delay *= 2
+	}
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var envelope apperrors.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err == nil {
+			apiErr.Code = envelope.Error.Code
+			apiErr.Message = envelope.Error.Message
+		}
+		return apiErr
+	}
+
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// buildUploadBody encodes meta, the gzip-compressed report files and the
+// optional CODEOWNERS file as multipart form data
+func buildUploadBody(meta *ingest.IngestionMetadata, paths []string, codeowners string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal meta: %w", err)
+	}
+	if err := mw.WriteField("meta", string(metaJSON)); err != nil {
+		return nil, "", err
+	}
+
+	for _, path := range paths {
+		if err := writeGzipPart(mw, "junit", path); err != nil {
+			return nil, "", err
+		}
+	}
+	if codeowners != "" {
+		if err := writeGzipPart(mw, "codeowners", codeowners); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+func writeGzipPart(mw *multipart.Writer, field, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, filepath.Base(path)))
+	header.Set("Content-Type", "application/gzip")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(part)
+	if _, err := io.Copy(gz, f); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return gz.Close()
+}
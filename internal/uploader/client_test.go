@@ -0,0 +1,143 @@
+package uploader
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/apperrors"
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientUpload_GzipsReportsAndRetries(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "TEST-a.xml")
+	require.NoError(t, os.WriteFile(report, []byte(`<testsuite name="a"/>`), 0o644))
+
+	ingestionID := uuid.New()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			apperrors.WriteError(w, r, http.StatusServiceUnavailable, "service_unavailable", "try later")
+			return
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			apperrors.WriteError(w, r, http.StatusTooManyRequests, "rate_limited", "slow down")
+			return
+		}
+
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+
+		var meta ingest.IngestionMetadata
+		require.NoError(t, json.Unmarshal([]byte(r.FormValue("meta")), &meta))
+		require.Equal(t, "proj", meta.ProjectSlug)
+
+		files := r.MultipartForm.File["junit"]
+		require.Len(t, files, 1)
+		require.Equal(t, "TEST-a.xml", files[0].Filename)
+		f, err := files[0].Open()
+		require.NoError(t, err)
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		content, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, `<testsuite name="a"/>`, string(content))
+
+		apperrors.WriteSuccess(w, r, http.StatusAccepted, ingest.IngestionStatus{IngestionID: ingestionID, Status: ingest.StatusQueued})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "secret")
+	client.RetryDelay = time.Millisecond
+
+	status, err := client.Upload(context.Background(), &ingest.IngestionMetadata{ProjectSlug: "proj"}, []string{report})
+	require.NoError(t, err)
+	require.Equal(t, ingestionID, status.IngestionID)
+	require.Equal(t, ingest.StatusQueued, status.Status)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestClientUpload_DoesNotRetryClientErrors(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "TEST-a.xml")
+	require.NoError(t, os.WriteFile(report, []byte(`<testsuite/>`), 0o644))
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		apperrors.WriteError(w, r, http.StatusBadRequest, "invalid_meta", "meta.sha is required")
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	client.RetryDelay = time.Millisecond
+
+	_, err := client.Upload(context.Background(), &ingest.IngestionMetadata{}, []string{report})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "invalid_meta", apiErr.Code)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestClientUpload_GivesUpAfterMaxRetries(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "TEST-a.xml")
+	require.NoError(t, os.WriteFile(report, []byte(`<testsuite/>`), 0o644))
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		apperrors.WriteInternalError(w, r, "boom")
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	client.RetryDelay = time.Millisecond
+	client.MaxRetries = 2
+
+	_, err := client.Upload(context.Background(), &ingest.IngestionMetadata{}, []string{report})
+	require.Error(t, err)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestClientUpload_SendsCodeowners(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "TEST-a.xml")
+	require.NoError(t, os.WriteFile(report, []byte(`<testsuite/>`), 0o644))
+	codeowners := filepath.Join(dir, "CODEOWNERS")
+	require.NoError(t, os.WriteFile(codeowners, []byte("* @acme/platform\n"), 0o644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+
+		files := r.MultipartForm.File["codeowners"]
+		require.Len(t, files, 1)
+		f, err := files[0].Open()
+		require.NoError(t, err)
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		content, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, "* @acme/platform\n", string(content))
+		require.Len(t, r.MultipartForm.File["junit"], 1)
+
+		apperrors.WriteSuccess(w, r, http.StatusAccepted, ingest.IngestionStatus{IngestionID: uuid.New(), Status: ingest.StatusQueued})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	client.Codeowners = codeowners
+
+	status, err := client.Upload(context.Background(), &ingest.IngestionMetadata{}, []string{report})
+	require.NoError(t, err)
+	require.Equal(t, ingest.StatusQueued, status.Status)
+}
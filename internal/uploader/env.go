@@ -0,0 +1,378 @@
+package uploader
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/ingest"
+)
+
+// Provider identifies the CI system an upload runs on
+type Provider string
+
+const (
+	ProviderGitHub    Provider = ingest.ProviderGitHub
+	ProviderGitLab    Provider = ingest.ProviderGitLab
+	ProviderJenkins   Provider = ingest.ProviderJenkins
+	ProviderCircleCI  Provider = ingest.ProviderCircleCI
+	ProviderBuildkite Provider = ingest.ProviderBuildkite
+	ProviderAzure     Provider = ingest.ProviderAzure
+	ProviderLocal     Provider = ingest.ProviderLocal
+)
+
+// Providers lists the supported providers in detection order. Local is the
+// fallback when no CI environment is recognized.
+var Providers = []Provider{
+	ProviderGitHub,
+	ProviderGitLab,
+	ProviderJenkins,
+	ProviderCircleCI,
+	ProviderBuildkite,
+	ProviderAzure,
+	ProviderLocal,
+}
+
+// Getenv looks up an environment variable (os.Getenv in production)
+type Getenv func(key string) string
+
+// DetectProvider returns the CI provider of the current environment
+func DetectProvider(getenv Getenv) Provider {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return ProviderGitHub
+	case getenv("GITLAB_CI") == "true":
+		return ProviderGitLab
+	case getenv("JENKINS_URL") != "":
+		return ProviderJenkins
+	case getenv("CIRCLECI") == "true":
+		return ProviderCircleCI
+	case getenv("BUILDKITE") == "true":
+		return ProviderBuildkite
+	case strings.EqualFold(getenv("TF_BUILD"), "true"):
+		return ProviderAzure
+	default:
+		return ProviderLocal
+	}
+}
+
+// MetadataFromEnv fills the run metadata a provider exposes through its
+// environment. Project slug and timestamps are left to the caller, and fields
+// a provider does not expose are left empty for flags to supply.
+//
+// GitLab has no attempt counter and identifies attempts by job ID, which the
+// server numbers in order of arrival. Jenkins and CircleCI reruns are new
+// builds or workflows and are matched as separate runs of the same commit.
+func MetadataFromEnv(provider Provider, getenv Getenv) (ingest.IngestionMetadata, error) {
+	m, err := providerMetadata(provider, getenv)
+	m.Provider = string(provider)
+	return m, err
+}
+
+func providerMetadata(provider Provider, getenv Getenv) (ingest.IngestionMetadata, error) {
+	switch provider {
+	case ProviderGitHub:
+		return githubMetadata(getenv), nil
+	case ProviderGitLab:
+		return gitlabMetadata(getenv), nil
+	case ProviderJenkins:
+		return jenkinsMetadata(getenv), nil
+	case ProviderCircleCI:
+		return circleCIMetadata(getenv), nil
+	case ProviderBuildkite:
+		return buildkiteMetadata(getenv), nil
+	case ProviderAzure:
+		return azureMetadata(getenv), nil
+	case ProviderLocal:
+		return localMetadata(), nil
+	default:
+		return ingest.IngestionMetadata{}, fmt.Errorf("unknown provider %q", provider)
+	}
+}
+
+func githubMetadata(getenv Getenv) ingest.IngestionMetadata {
+	repo := getenv("GITHUB_REPOSITORY")
+	m := ingest.IngestionMetadata{
+		RepoFullName: repo,
+		WorkflowName: getenv("GITHUB_WORKFLOW"),
+		RunID:        getenv("GITHUB_RUN_ID"),
+		RunAttempt:   int(parseInt(getenv("GITHUB_RUN_ATTEMPT"))),
+		RunNumber:    getenv("GITHUB_RUN_NUMBER"),
+		SHA:          getenv("GITHUB_SHA"),
+		Event:        getenv("GITHUB_EVENT_NAME"),
+		JobName:      getenv("GITHUB_JOB"),
+	}
+
+	// owner/repo/.github/workflows/ci.yml@refs/heads/main -> .github/workflows/ci.yml
+	workflowRef, _, _ := strings.Cut(getenv("GITHUB_WORKFLOW_REF"), "@")
+	m.WorkflowRef = firstNonEmpty(strings.TrimPrefix(workflowRef, repo+"/"), m.WorkflowName)
+
+	ref := getenv("GITHUB_REF")
+	m.Branch = firstNonEmpty(getenv("GITHUB_HEAD_REF"), getenv("GITHUB_REF_NAME"), trimRef(ref))
+	if strings.HasPrefix(m.Event, "pull_request") {
+		if match := githubPullRefRe.FindStringSubmatch(ref); match != nil {
+			m.PRNumber = int64Ptr(parseInt(match[1]))
+		}
+	}
+
+	if m.RunID != "" && repo != "" {
+		server := firstNonEmpty(getenv("GITHUB_SERVER_URL"), "https://github.com")
+		m.RunURL = fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, m.RunID)
+	}
+	return m
+}
+
+var githubPullRefRe = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+func gitlabMetadata(getenv Getenv) ingest.IngestionMetadata {
+	m := ingest.IngestionMetadata{
+		RepoFullName: getenv("CI_PROJECT_PATH"),
+		WorkflowName: firstNonEmpty(getenv("CI_PIPELINE_NAME"), "gitlab-ci"),
+		WorkflowRef:  firstNonEmpty(getenv("CI_CONFIG_PATH"), ".gitlab-ci.yml"),
+		RunID:        getenv("CI_PIPELINE_ID"),
+		AttemptID:    getenv("CI_JOB_ID"),
+		RunNumber:    getenv("CI_PIPELINE_IID"),
+		RunURL:       getenv("CI_PIPELINE_URL"),
+		SHA:          getenv("CI_COMMIT_SHA"),
+		Branch:       firstNonEmpty(getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), getenv("CI_COMMIT_BRANCH"), getenv("CI_COMMIT_REF_NAME")),
+		JobName:      getenv("CI_JOB_NAME"),
+	}
+
+	switch getenv("CI_PIPELINE_SOURCE") {
+	case "push":
+		m.Event = "push"
+	case "merge_request_event", "external_pull_request_event":
+		m.Event = "pull_request"
+	case "schedule":
+		m.Event = "schedule"
+	case "web", "api", "trigger", "pipeline":
+		m.Event = "workflow_dispatch"
+	default:
+		m.Event = "other"
+	}
+
+	if iid := parseInt(getenv("CI_MERGE_REQUEST_IID")); iid > 0 {
+		m.PRNumber = int64Ptr(iid)
+	}
+	return m
+}
+
+func jenkinsMetadata(getenv Getenv) ingest.IngestionMetadata {
+	m := ingest.IngestionMetadata{
+		RepoFullName: firstNonEmpty(repoFromURL(getenv("GIT_URL")), getenv("JOB_NAME")),
+		WorkflowName: getenv("JOB_NAME"),
+		WorkflowRef:  getenv("JOB_NAME"),
+		RunID:        firstNonEmpty(getenv("BUILD_TAG"), joinNonEmpty("#", getenv("JOB_NAME"), getenv("BUILD_NUMBER"))),
+		RunAttempt:   1,
+		RunNumber:    getenv("BUILD_NUMBER"),
+		RunURL:       getenv("BUILD_URL"),
+		SHA:          getenv("GIT_COMMIT"),
+		Branch:       firstNonEmpty(getenv("CHANGE_BRANCH"), getenv("BRANCH_NAME"), strings.TrimPrefix(getenv("GIT_BRANCH"), "origin/")),
+		Event:        "push",
+		JobName:      firstNonEmpty(getenv("STAGE_NAME"), getenv("JOB_BASE_NAME"), getenv("JOB_NAME")),
+	}
+
+	if change := parseInt(getenv("CHANGE_ID")); change > 0 {
+		m.Event = "pull_request"
+		m.PRNumber = int64Ptr(change)
+	}
+	return m
+}
+
+func circleCIMetadata(getenv Getenv) ingest.IngestionMetadata {
+	m := ingest.IngestionMetadata{
+		RepoFullName: joinNonEmpty("/", getenv("CIRCLE_PROJECT_USERNAME"), getenv("CIRCLE_PROJECT_REPONAME")),
+		WorkflowName: "circleci",
+		WorkflowRef:  ".circleci/config.yml",
+		RunID:        firstNonEmpty(getenv("CIRCLE_WORKFLOW_ID"), getenv("CIRCLE_BUILD_NUM")),
+		RunAttempt:   1,
+		RunNumber:    getenv("CIRCLE_BUILD_NUM"),
+		RunURL:       getenv("CIRCLE_BUILD_URL"),
+		SHA:          getenv("CIRCLE_SHA1"),
+		Branch:       firstNonEmpty(getenv("CIRCLE_BRANCH"), getenv("CIRCLE_TAG")),
+		Event:        "push",
+		JobName:      getenv("CIRCLE_JOB"),
+	}
+
+	pr := parseInt(getenv("CIRCLE_PR_NUMBER"))
+	if pr == 0 {
+		pr = parseInt(lastPathSegment(getenv("CIRCLE_PULL_REQUEST")))
+	}
+	if pr > 0 {
+		m.Event = "pull_request"
+		m.PRNumber = int64Ptr(pr)
+	}
+	return m
+}
+
+func buildkiteMetadata(getenv Getenv) ingest.IngestionMetadata {
+	m := ingest.IngestionMetadata{
+		RepoFullName: firstNonEmpty(repoFromURL(getenv("BUILDKITE_REPO")), joinNonEmpty("/", getenv("BUILDKITE_ORGANIZATION_SLUG"), getenv("BUILDKITE_PIPELINE_SLUG"))),
+		WorkflowName: firstNonEmpty(getenv("BUILDKITE_PIPELINE_NAME"), getenv("BUILDKITE_PIPELINE_SLUG")),
+		WorkflowRef:  getenv("BUILDKITE_PIPELINE_SLUG"),
+		RunID:        firstNonEmpty(getenv("BUILDKITE_BUILD_ID"), getenv("BUILDKITE_BUILD_NUMBER")),
+		RunAttempt:   int(parseInt(getenv("BUILDKITE_RETRY_COUNT"))) + 1,
+		RunNumber:    getenv("BUILDKITE_BUILD_NUMBER"),
+		RunURL:       getenv("BUILDKITE_BUILD_URL"),
+		SHA:          getenv("BUILDKITE_COMMIT"),
+		Branch:       getenv("BUILDKITE_BRANCH"),
+		JobName:      firstNonEmpty(getenv("BUILDKITE_STEP_KEY"), getenv("BUILDKITE_LABEL")),
+	}
+
+	switch getenv("BUILDKITE_SOURCE") {
+	case "webhook":
+		m.Event = "push"
+	case "schedule":
+		m.Event = "schedule"
+	case "ui", "api", "trigger_job":
+		m.Event = "workflow_dispatch"
+	default:
+		m.Event = "other"
+	}
+
+	// BUILDKITE_PULL_REQUEST is "false" outside pull request builds
+	if pr := parseInt(getenv("BUILDKITE_PULL_REQUEST")); pr > 0 {
+		m.Event = "pull_request"
+		m.PRNumber = int64Ptr(pr)
+	}
+	return m
+}
+
+func azureMetadata(getenv Getenv) ingest.IngestionMetadata {
+	buildID := getenv("BUILD_BUILDID")
+	m := ingest.IngestionMetadata{
+		RepoFullName: getenv("BUILD_REPOSITORY_NAME"),
+		WorkflowName: getenv("BUILD_DEFINITIONNAME"),
+		WorkflowRef:  getenv("BUILD_DEFINITIONNAME"),
+		RunID:        buildID,
+		RunAttempt:   int(parseInt(firstNonEmpty(getenv("SYSTEM_JOBATTEMPT"), "1"))),
+		RunNumber:    getenv("BUILD_BUILDNUMBER"),
+		SHA:          getenv("BUILD_SOURCEVERSION"),
+		Branch:       firstNonEmpty(trimRef(getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH")), trimRef(getenv("BUILD_SOURCEBRANCH"))),
+		JobName:      firstNonEmpty(getenv("SYSTEM_JOBDISPLAYNAME"), getenv("AGENT_JOBNAME")),
+	}
+
+	if collection := getenv("SYSTEM_COLLECTIONURI"); collection != "" && buildID != "" {
+		m.RunURL = fmt.Sprintf("%s%s/_build/results?buildId=%s", collection, url.PathEscape(getenv("SYSTEM_TEAMPROJECT")), url.QueryEscape(buildID))
+	}
+
+	switch getenv("BUILD_REASON") {
+	case "IndividualCI", "BatchedCI":
+		m.Event = "push"
+	case "PullRequest":
+		m.Event = "pull_request"
+	case "Schedule":
+		m.Event = "schedule"
+	case "Manual":
+		m.Event = "workflow_dispatch"
+	default:
+		m.Event = "other"
+	}
+
+	pr := parseInt(getenv("SYSTEM_PULLREQUEST_PULLREQUESTNUMBER"))
+	if pr == 0 {
+		pr = parseInt(getenv("SYSTEM_PULLREQUEST_PULLREQUESTID"))
+	}
+	if pr > 0 {
+		m.PRNumber = int64Ptr(pr)
+	}
+	return m
+}
+
+// localMetadata describes a run on a developer machine from the git checkout
+// in the working directory. Each invocation is its own run.
+func localMetadata() ingest.IngestionMetadata {
+	runID := fmt.Sprintf("%s-%d", firstNonEmpty(hostname(), "local"), time.Now().UnixNano())
+	return ingest.IngestionMetadata{
+		RepoFullName: firstNonEmpty(repoFromURL(git("config", "--get", "remote.origin.url")), "local"),
+		WorkflowName: "local",
+		WorkflowRef:  "local",
+		RunID:        runID,
+		RunAttempt:   1,
+		RunURL:       "local://run/" + runID,
+		SHA:          git("rev-parse", "HEAD"),
+		Branch:       git("rev-parse", "--abbrev-ref", "HEAD"),
+		Event:        "other",
+		JobName:      "local",
+	}
+}
+
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// git runs a git command and returns its trimmed output, or "" on failure
+func git(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// repoFromURL extracts owner/repo from a git remote URL
+// (https://host/owner/repo.git, git@host:owner/repo.git)
+func repoFromURL(raw string) string {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), ".git")
+	if raw == "" {
+		return ""
+	}
+
+	path := raw
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, rest, ok := strings.Cut(raw, ":"); ok {
+		path = rest
+	}
+	return strings.Trim(path, "/")
+}
+
+func trimRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return ref
+}
+
+func lastPathSegment(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
+
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// joinNonEmpty joins parts with sep, or returns "" if any part is empty
+func joinNonEmpty(sep string, parts ...string) string {
+	for _, p := range parts {
+		if p == "" {
+			return ""
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,173 @@
+package uploader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(vars map[string]string) Getenv {
+	return func(key string) string { return vars[key] }
+}
+
+func TestDetectProvider(t *testing.T) {
+	require.Equal(t, ProviderGitHub, DetectProvider(envMap(map[string]string{"GITHUB_ACTIONS": "true"})))
+	require.Equal(t, ProviderGitLab, DetectProvider(envMap(map[string]string{"GITLAB_CI": "true"})))
+	require.Equal(t, ProviderJenkins, DetectProvider(envMap(map[string]string{"JENKINS_URL": "https://ci.example.com/"})))
+	require.Equal(t, ProviderCircleCI, DetectProvider(envMap(map[string]string{"CIRCLECI": "true"})))
+	require.Equal(t, ProviderBuildkite, DetectProvider(envMap(map[string]string{"BUILDKITE": "true"})))
+	require.Equal(t, ProviderAzure, DetectProvider(envMap(map[string]string{"TF_BUILD": "True"})))
+	require.Equal(t, ProviderLocal, DetectProvider(envMap(nil)))
+}
+
+func TestMetadataFromEnv_GitHub(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderGitHub, envMap(map[string]string{
+		"GITHUB_REPOSITORY":   "acme/api",
+		"GITHUB_WORKFLOW":     "CI",
+		"GITHUB_WORKFLOW_REF": "acme/api/.github/workflows/ci.yml@refs/pull/42/merge",
+		"GITHUB_RUN_ID":       "123",
+		"GITHUB_RUN_ATTEMPT":  "2",
+		"GITHUB_RUN_NUMBER":   "7",
+		"GITHUB_SHA":          "abc",
+		"GITHUB_EVENT_NAME":   "pull_request",
+		"GITHUB_REF":          "refs/pull/42/merge",
+		"GITHUB_HEAD_REF":     "feature",
+		"GITHUB_JOB":          "test",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, ".github/workflows/ci.yml", m.WorkflowRef)
+	require.Equal(t, "github", m.Provider)
+	require.Equal(t, "123", m.RunID)
+	require.Equal(t, 2, m.RunAttempt)
+	require.Equal(t, "7", m.RunNumber)
+	require.Equal(t, "feature", m.Branch)
+	require.Equal(t, "https://github.com/acme/api/actions/runs/123", m.RunURL)
+	require.NotNil(t, m.PRNumber)
+	require.Equal(t, int64(42), *m.PRNumber)
+}
+
+func TestMetadataFromEnv_GitLabMergeRequest(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderGitLab, envMap(map[string]string{
+		"CI_PROJECT_PATH":                     "group/sub/api",
+		"CI_PIPELINE_ID":                      "9001",
+		"CI_PIPELINE_IID":                     "55",
+		"CI_PIPELINE_URL":                     "https://gitlab.example.com/group/sub/api/-/pipelines/9001",
+		"CI_PIPELINE_SOURCE":                  "merge_request_event",
+		"CI_COMMIT_SHA":                       "def",
+		"CI_COMMIT_REF_NAME":                  "refs/merge-requests/3/head",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "fix-login",
+		"CI_MERGE_REQUEST_IID":                "3",
+		"CI_JOB_NAME":                         "rspec 1/4",
+		"CI_JOB_ID":                           "777",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "group/sub/api", m.RepoFullName)
+	require.Equal(t, ".gitlab-ci.yml", m.WorkflowRef)
+	require.Equal(t, "gitlab", m.Provider)
+	require.Equal(t, "9001", m.RunID)
+	require.Equal(t, 0, m.RunAttempt)
+	require.Equal(t, "777", m.AttemptID)
+	require.Equal(t, "55", m.RunNumber)
+	require.Equal(t, "fix-login", m.Branch)
+	require.Equal(t, "pull_request", m.Event)
+	require.Equal(t, int64(3), *m.PRNumber)
+	require.Equal(t, "rspec 1/4", m.JobName)
+}
+
+func TestMetadataFromEnv_Jenkins(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderJenkins, envMap(map[string]string{
+		"JENKINS_URL":   "https://jenkins.example.com/",
+		"JOB_NAME":      "api/main",
+		"JOB_BASE_NAME": "main",
+		"BUILD_NUMBER":  "314",
+		"BUILD_TAG":     "jenkins-api-main-314",
+		"BUILD_URL":     "https://jenkins.example.com/job/api/job/main/314/",
+		"GIT_URL":       "git@github.com:acme/api.git",
+		"GIT_COMMIT":    "0123",
+		"GIT_BRANCH":    "origin/main",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "acme/api", m.RepoFullName)
+	require.Equal(t, "jenkins-api-main-314", m.RunID)
+	require.Equal(t, "314", m.RunNumber)
+	require.Equal(t, "main", m.Branch)
+	require.Equal(t, "push", m.Event)
+	require.Equal(t, "main", m.JobName)
+	require.Nil(t, m.PRNumber)
+}
+
+func TestMetadataFromEnv_CircleCIPullRequest(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderCircleCI, envMap(map[string]string{
+		"CIRCLE_PROJECT_USERNAME": "acme",
+		"CIRCLE_PROJECT_REPONAME": "api",
+		"CIRCLE_BUILD_NUM":        "88",
+		"CIRCLE_WORKFLOW_ID":      "6f1e-workflow",
+		"CIRCLE_BUILD_URL":        "https://circleci.com/gh/acme/api/88",
+		"CIRCLE_SHA1":             "beef",
+		"CIRCLE_BRANCH":           "feature",
+		"CIRCLE_JOB":              "test",
+		"CIRCLE_PULL_REQUEST":     "https://github.com/acme/api/pull/17",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "acme/api", m.RepoFullName)
+	require.Equal(t, "6f1e-workflow", m.RunID)
+	require.Equal(t, "88", m.RunNumber)
+	require.Equal(t, "pull_request", m.Event)
+	require.Equal(t, int64(17), *m.PRNumber)
+}
+
+func TestMetadataFromEnv_BuildkiteRetry(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderBuildkite, envMap(map[string]string{
+		"BUILDKITE_REPO":          "https://github.com/acme/api.git",
+		"BUILDKITE_PIPELINE_SLUG": "api",
+		"BUILDKITE_PIPELINE_NAME": "API",
+		"BUILDKITE_BUILD_NUMBER":  "1200",
+		"BUILDKITE_BUILD_ID":      "0190a3f2-build",
+		"BUILDKITE_RETRY_COUNT":   "1",
+		"BUILDKITE_BUILD_URL":     "https://buildkite.com/acme/api/builds/1200",
+		"BUILDKITE_COMMIT":        "cafe",
+		"BUILDKITE_BRANCH":        "main",
+		"BUILDKITE_SOURCE":        "webhook",
+		"BUILDKITE_PULL_REQUEST":  "false",
+		"BUILDKITE_LABEL":         ":go: test",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "acme/api", m.RepoFullName)
+	require.Equal(t, "API", m.WorkflowName)
+	require.Equal(t, "0190a3f2-build", m.RunID)
+	require.Equal(t, 2, m.RunAttempt)
+	require.Equal(t, "1200", m.RunNumber)
+	require.Equal(t, "push", m.Event)
+	require.Nil(t, m.PRNumber)
+	require.Equal(t, ":go: test", m.JobName)
+}
+
+func TestMetadataFromEnv_Azure(t *testing.T) {
+	m, err := MetadataFromEnv(ProviderAzure, envMap(map[string]string{
+		"BUILD_REPOSITORY_NAME": "acme/api",
+		"BUILD_DEFINITIONNAME":  "api-ci",
+		"BUILD_BUILDID":         "4567",
+		"BUILD_BUILDNUMBER":     "20261016.3",
+		"SYSTEM_JOBATTEMPT":     "3",
+		"SYSTEM_COLLECTIONURI":  "https://dev.azure.com/acme/",
+		"SYSTEM_TEAMPROJECT":    "Platform Team",
+		"BUILD_SOURCEVERSION":   "f00d",
+		"BUILD_SOURCEBRANCH":    "refs/heads/main",
+		"BUILD_REASON":          "IndividualCI",
+		"SYSTEM_JOBDISPLAYNAME": "Unit tests",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "4567", m.RunID)
+	require.Equal(t, 3, m.RunAttempt)
+	require.Equal(t, "20261016.3", m.RunNumber)
+	require.Equal(t, "https://dev.azure.com/acme/Platform%20Team/_build/results?buildId=4567", m.RunURL)
+	require.Equal(t, "main", m.Branch)
+	require.Equal(t, "push", m.Event)
+}
+
+func TestRepoFromURL(t *testing.T) {
+	require.Equal(t, "acme/api", repoFromURL("https://github.com/acme/api.git"))
+	require.Equal(t, "acme/api", repoFromURL("git@github.com:acme/api.git"))
+	require.Equal(t, "group/sub/api", repoFromURL("ssh://git@gitlab.example.com/group/sub/api"))
+	require.Equal(t, "", repoFromURL(""))
+}
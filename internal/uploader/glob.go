@@ -0,0 +1,130 @@
+package uploader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExpandGlobs resolves report path patterns to a sorted, de-duplicated list of
+// regular files. Patterns use filepath.Match syntax plus ** for any number of
+// directories (e.g. build/**/TEST-*.xml). A pattern without wildcards names a
+// file directly.
+func ExpandGlobs(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+
+		if !strings.Contains(pattern, "**") {
+			matches, err := filepath.Glob(filepath.FromSlash(pattern))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			for _, m := range matches {
+				if isRegularFile(m) {
+					add(m)
+				}
+			}
+			continue
+		}
+
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		root := globRoot(pattern)
+		err = filepath.WalkDir(filepath.FromSlash(root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == filepath.FromSlash(root) && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.Type().IsRegular() && re.MatchString(filepath.ToSlash(path)) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %q: %w", pattern, err)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// globRoot returns the directory part of a pattern before its first wildcard
+func globRoot(pattern string) string {
+	idx := strings.IndexAny(pattern, "*?[")
+	dir := pattern[:idx]
+	if slash := strings.LastIndex(dir, "/"); slash >= 0 {
+		if slash == 0 {
+			return "/"
+		}
+		return dir[:slash]
+	}
+	return "."
+}
+
+// globRegexp translates a slash-separated pattern with ** into a regexp
+// matching whole cleaned paths
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,38 @@
+package uploader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"TEST-root.xml",
+		"a/TEST-one.xml",
+		"a/b/TEST-two.xml",
+		"a/b/notes.txt",
+		"c/report.json",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+	}
+
+	files, err := ExpandGlobs([]string{
+		filepath.ToSlash(dir) + "/**/TEST-*.xml",
+		filepath.Join(dir, "c", "*.json"),
+		filepath.Join(dir, "a", "TEST-one.xml"), // duplicate
+		filepath.ToSlash(dir) + "/missing/**/*.xml",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "TEST-root.xml"),
+		filepath.Join(dir, "a", "TEST-one.xml"),
+		filepath.Join(dir, "a", "b", "TEST-two.xml"),
+		filepath.Join(dir, "c", "report.json"),
+	}, files)
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleBrokenTestsPage renders the broken tests of a project.
+func HandleBrokenTestsPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		status, err := flake.ParseBrokenStatusQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		broken, err := flake.NewService(pool).ListBrokenTests(ctx, project.ID, status, flake.MaxBrokenTestsListed)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list broken tests")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		csrfToken, err := auth.GenerateCSRFToken()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+		data := &TemplateData{
+			Title:           "Broken Tests - " + project.Name,
+			UserID:          userID,
+			IsAuthenticated: true,
+			CSRFToken:       csrfToken,
+			Data: map[string]interface{}{
+				"OrgID":           org.ID,
+				"ProjectID":       project.ID,
+				"OrgSlug":         org.Slug,
+				"ProjectSlug":     project.Slug,
+				"ProjectName":     project.Name,
+				"DefaultBranch":   project.DefaultBranch,
+				"ConsecutiveRuns": project.BrokenTestRuns,
+				"Status":          string(status),
+				"BrokenTests":     broken,
+			},
+		}
+		RenderTemplate(w, r, "broken_tests.html", data)
+	}
+}
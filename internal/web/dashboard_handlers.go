@@ -0,0 +1,133 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/dashboard"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// dashboardView formats an org dashboard for its template
+type dashboardView struct {
+	*dashboard.Dashboard
+}
+
+// Percent formats a rate between 0 and 1 as a percentage
+func (v dashboardView) Percent(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// HealthClass is the flake score style of a health score: a project whose
+// runs rarely flake scores low
+func (v dashboardView) HealthClass(score int) string {
+	switch {
+	case score >= 95:
+		return "low"
+	case score >= 80:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// HandleDashboardPage renders the flake dashboard across every organization
+// of the user.
+func HandleDashboardPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		service := dashboard.NewService(pool)
+		orgList, err := service.UserOrgs(ctx, userID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list user orgs")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		d, err := service.Get(ctx, orgList, dashboard.ParseDays(r), time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to build dashboard")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderDashboard(w, r, isProduction, userID, "Dashboard", map[string]interface{}{
+			"Path":      "/dashboard",
+			"Days":      d.Days,
+			"Dashboard": dashboardView{d},
+			"Trend":     projectTrendChart(d.Trend),
+		})
+	}
+}
+
+// HandleOrgDashboardPage renders the flake dashboard of an organization
+// across all of its projects.
+func HandleOrgDashboardPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		orgIDStr := chi.URLParam(r, "org_id")
+		orgID, err := uuid.Parse(orgIDStr)
+		if err != nil {
+			http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+
+		service := dashboard.NewService(pool)
+		org, err := service.MemberOrg(ctx, userID, orgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrNotMember) {
+				http.Error(w, "Organization not found", http.StatusNotFound)
+				return
+			}
+			log.Error().Err(err).Msg("Failed to get organization")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		d, err := service.Get(ctx, []dashboard.Org{org}, dashboard.ParseDays(r), time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("org_id", orgID.String()).Msg("Failed to build org dashboard")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderDashboard(w, r, isProduction, userID, org.Name+" Dashboard", map[string]interface{}{
+			"Path":      "/orgs/" + orgID.String() + "/dashboard",
+			"OrgID":     orgID,
+			"OrgName":   org.Name,
+			"Days":      d.Days,
+			"Dashboard": dashboardView{d},
+			"Trend":     projectTrendChart(d.Trend),
+		})
+	}
+}
+
+// renderDashboard renders org_dashboard.html with a fresh CSRF token
+func renderDashboard(w http.ResponseWriter, r *http.Request, isProduction bool, userID uuid.UUID, title string, pageData map[string]interface{}) {
+	csrfToken, err := auth.GenerateCSRFToken()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+	data := &TemplateData{
+		Title:           title,
+		UserID:          userID,
+		IsAuthenticated: true,
+		CSRFToken:       csrfToken,
+		Data:            pageData,
+	}
+	RenderTemplate(w, r, "org_dashboard.html", data)
+}
@@ -0,0 +1,209 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/digest"
+	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// digestsShown is how many past digests the digests page lists
+const digestsShown = 52
+
+// HandleDigestsPage renders the past digests of a project.
+func HandleDigestsPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		digests, err := digest.NewService(pool).ListByProject(ctx, project.ID, digestsShown)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list digests")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		csrfToken, err := auth.GenerateCSRFToken()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+		data := &TemplateData{
+			Title:           "Flake Digests - " + project.Name,
+			UserID:          userID,
+			IsAuthenticated: true,
+			CSRFToken:       csrfToken,
+			Data: map[string]interface{}{
+				"OrgID":           org.ID,
+				"ProjectID":       project.ID,
+				"OrgSlug":         org.Slug,
+				"ProjectSlug":     project.Slug,
+				"ProjectName":     project.Name,
+				"DigestFrequency": project.DigestFrequency,
+				"Digests":         digests,
+			},
+		}
+		RenderTemplate(w, r, "digests.html", data)
+	}
+}
+
+// HandleDigestPage renders a generated digest.
+func HandleDigestPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		digestID, err := uuid.Parse(chi.URLParam(r, "digest_id"))
+		if err != nil {
+			http.Error(w, "Invalid digest ID", http.StatusBadRequest)
+			return
+		}
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		d, err := digest.NewService(pool).GetByID(ctx, project.ID, digestID)
+		if err != nil {
+			if errors.Is(err, digest.ErrDigestNotFound) {
+				http.Error(w, "Digest not found", http.StatusNotFound)
+				return
+			}
+			log.Error().Err(err).Str("digest_id", digestID.String()).Msg("Failed to get digest")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderDigest(w, r, isProduction, userID, org, project, d.Frequency, d.PeriodStart, d.PeriodEnd, &d.Report, false)
+	}
+}
+
+// HandleDigestPreviewPage renders the digest of the last complete period
+// without storing or sending it.
+func HandleDigestPreviewPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		frequency := r.URL.Query().Get("frequency")
+		if frequency != projects.DigestDaily && frequency != projects.DigestWeekly {
+			frequency = projects.DigestWeekly
+			if project.DigestFrequency == projects.DigestDaily {
+				frequency = projects.DigestDaily
+			}
+		}
+
+		preview, err := digest.NewService(pool).Preview(ctx, project.ID, frequency, time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to compute digest preview")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderDigest(w, r, isProduction, userID, org, project, preview.Frequency, preview.PeriodStart, preview.PeriodEnd, &preview.Report, true)
+	}
+}
+
+// renderDigest renders a stored or previewed digest report
+func renderDigest(w http.ResponseWriter, r *http.Request, isProduction bool, userID uuid.UUID, org *orgs.Org, project *projects.Project, frequency string, start, end time.Time, report *digest.Report, isPreview bool) {
+	title := "Weekly Flake Digest"
+	if frequency == projects.DigestDaily {
+		title = "Daily Flake Digest"
+	}
+
+	csrfToken, err := auth.GenerateCSRFToken()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+	data := &TemplateData{
+		Title:           title + " - " + project.Name,
+		UserID:          userID,
+		IsAuthenticated: true,
+		CSRFToken:       csrfToken,
+		Data: map[string]interface{}{
+			"OrgID":       org.ID,
+			"ProjectID":   project.ID,
+			"OrgSlug":     org.Slug,
+			"ProjectSlug": project.Slug,
+			"ProjectName": project.Name,
+			"Heading":     title,
+			"Frequency":   frequency,
+			"PeriodStart": start,
+			// The period end is exclusive; show its last day
+			"PeriodLast": end.AddDate(0, 0, -1),
+			"Report":     report,
+			"IsPreview":  isPreview,
+		},
+	}
+	RenderTemplate(w, r, "digest.html", data)
+}
+
+// loadSlugProject loads the {org_slug} org and its {project_slug} project and
+// checks org membership. Writes the error response on failure.
+func loadSlugProject(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) (*orgs.Org, *projects.Project, bool) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	orgSlug := chi.URLParam(r, "org_slug")
+	projectSlug := chi.URLParam(r, "project_slug")
+
+	orgService := orgs.NewService(pool)
+	org, err := orgService.GetBySlug(ctx, orgSlug)
+	if err != nil {
+		if errors.Is(err, orgs.ErrOrgNotFound) {
+			http.Error(w, "Organization not found", http.StatusNotFound)
+			return nil, nil, false
+		}
+		log.Error().Err(err).Str("org_slug", orgSlug).Msg("Failed to get organization")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	_, err = orgService.RequireOrgMember(ctx, userID, org.ID)
+	if err != nil {
+		if errors.Is(err, orgs.ErrNotMember) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return nil, nil, false
+		}
+		log.Error().Err(err).Msg("Failed to check org membership")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	project, err := projects.NewService(pool).GetByOrgAndSlug(ctx, org.ID, projectSlug)
+	if err != nil {
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return nil, nil, false
+		}
+		log.Error().Err(err).Str("project_slug", projectSlug).Msg("Failed to get project")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return org, project, true
+}
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aliuyar1234/flakeguard/internal/auth"
 	"github.com/aliuyar1234/flakeguard/internal/flake"
 	"github.com/aliuyar1234/flakeguard/internal/orgs"
 	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/signatures"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -77,13 +79,31 @@ func HandleFlakesListPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFun
 
 		repo := r.URL.Query().Get("repo")
 		jobName := r.URL.Query().Get("job_name")
+		jobVariant := r.URL.Query().Get("job_variant")
+		branchClass, _ := flake.ParseBranchClass(r.URL.Query().Get("branch_class"))
+		owner := strings.TrimSpace(r.URL.Query().Get("owner"))
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = flake.FlakeStatusOpen
+		}
+		statuses, err := flake.ParseFlakeStatusFilter(status)
+		if err != nil {
+			status = flake.FlakeStatusOpen
+			statuses, _ = flake.ParseFlakeStatusFilter(status)
+		}
 
 		req := flake.ListFlakesRequest{
-			Days:    days,
-			Repo:    repo,
-			JobName: jobName,
-			Limit:   100,
-			Offset:  0,
+			Days:        days,
+			Repo:        repo,
+			JobName:     jobName,
+			BranchClass: branchClass,
+			Statuses:    statuses,
+			Owner:       owner,
+			Limit:       100,
+			Offset:      0,
+		}
+		if jobVariant != "" {
+			req.JobVariant = &jobVariant
 		}
 
 		flakeService := flake.NewService(pool)
@@ -94,6 +114,18 @@ func HandleFlakesListPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFun
 			return
 		}
 
+		trend, err := flakeService.GetProjectTrend(ctx, project.ID, flake.TrendRequest{
+			Days:       days,
+			Repo:       repo,
+			JobName:    jobName,
+			JobVariant: req.JobVariant,
+		}, time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to get project trend")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
 		csrfToken, err := auth.GenerateCSRFToken()
 		if err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -114,9 +146,16 @@ func HandleFlakesListPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFun
 				"ProjectName": project.Name,
 				"Flakes":      flakes,
 				"Total":       total,
+				"Trend":       projectTrendChart(trend),
 				"Days":        days,
 				"Repo":        repo,
 				"JobName":     jobName,
+				"JobVariant":  jobVariant,
+				"BranchClass": string(branchClass),
+				"Owner":       owner,
+				"Status":      status,
+				"Statuses":    flake.FlakeStatuses,
+				"Filtered":    repo != "" || jobName != "" || jobVariant != "" || branchClass != "" || owner != "" || status != flake.FlakeStatusOpen,
 			},
 		}
 		RenderTemplate(w, r, "flakes_list.html", data)
@@ -151,7 +190,7 @@ func HandleFlakeDetailPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFu
 			return
 		}
 
-		_, err = orgService.RequireOrgMember(ctx, userID, org.ID)
+		role, err := orgService.RequireOrgMember(ctx, userID, org.ID)
 		if err != nil {
 			if errors.Is(err, orgs.ErrNotMember) {
 				http.Error(w, "Forbidden", http.StatusForbidden)
@@ -196,6 +235,26 @@ func HandleFlakeDetailPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFu
 			return
 		}
 
+		trend, err := flakeService.GetTestTrend(ctx, project.ID, testCaseID, days, time.Now())
+		if err != nil {
+			log.Error().Err(err).
+				Str("project_id", project.ID.String()).
+				Str("test_case_id", testCaseID.String()).
+				Msg("Failed to get flake trend")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		failureSignatures, err := signatures.NewService(pool).ListByTest(ctx, project.ID, testCaseID)
+		if err != nil {
+			log.Error().Err(err).
+				Str("project_id", project.ID.String()).
+				Str("test_case_id", testCaseID.String()).
+				Msg("Failed to list failure signatures")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
 		lastFailureDisplay := ""
 		lastFailureTruncated := false
 		lastFailureIngestionTruncated := false
@@ -226,6 +285,9 @@ func HandleFlakeDetailPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFu
 				"Days":                                 days,
 				"Detail":                               detail,
 				"EvidenceTotal":                        evidenceTotal,
+				"Trend":                                newTrendChart(trend),
+				"Signatures":                           failureSignatures,
+				"CanChangeStatus":                      role != orgs.RoleViewer,
 				"LastFailureMessageDisplay":            lastFailureDisplay,
 				"LastFailureMessageTruncated":          lastFailureTruncated,
 				"LastFailureMessageIngestionTruncated": lastFailureIngestionTruncated,
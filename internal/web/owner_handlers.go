@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleOwnersPage renders the per-owner breakdown of a project's tests.
+func HandleOwnersPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		service := ownership.NewService(pool)
+		config, err := service.GetConfig(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to get CODEOWNERS")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		owners, err := service.ListOwners(ctx, project.ID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list owners")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		csrfToken, err := auth.GenerateCSRFToken()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+		data := &TemplateData{
+			Title:           "Owners - " + project.Name,
+			UserID:          userID,
+			IsAuthenticated: true,
+			CSRFToken:       csrfToken,
+			Data: map[string]interface{}{
+				"OrgID":       org.ID,
+				"ProjectID":   project.ID,
+				"OrgSlug":     org.Slug,
+				"ProjectSlug": project.Slug,
+				"ProjectName": project.Name,
+				"Configured":  config.Source != nil,
+				"Owners":      owners,
+			},
+		}
+		RenderTemplate(w, r, "owners.html", data)
+	}
+}
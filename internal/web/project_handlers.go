@@ -6,14 +6,20 @@ import (
 
 	"github.com/aliuyar1234/flakeguard/internal/apikeys"
 	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/notify"
 	"github.com/aliuyar1234/flakeguard/internal/orgs"
+	"github.com/aliuyar1234/flakeguard/internal/ownership"
 	"github.com/aliuyar1234/flakeguard/internal/projects"
+	"github.com/aliuyar1234/flakeguard/internal/quarantine"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
+// settingsDeliveriesShown is the length of the delivery log on the project settings page
+const settingsDeliveriesShown = 20
+
 // HandleProjectsPage renders the projects list page for an organization.
 func HandleProjectsPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -211,6 +217,47 @@ func HandleProjectSettingsPage(pool *pgxpool.Pool, isProduction bool) http.Handl
 			apiKeyItems = append(apiKeyItems, key.ToListItemResponse())
 		}
 
+		quarantineService := quarantine.NewService(pool)
+		rules, err := quarantineService.ListByProject(ctx, projectID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to list quarantine rules for project settings page")
+			pageError = "Failed to load quarantine rules"
+		}
+
+		quarantineItems := make([]quarantine.RuleResponse, 0, len(rules))
+		for i := range rules {
+			quarantineItems = append(quarantineItems, rules[i].ToResponse())
+		}
+
+		channels, err := notify.NewService(pool).ListByProject(ctx, projectID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to list notification channels for project settings page")
+			pageError = "Failed to load notification channels"
+		}
+
+		channelItems := make([]notify.ChannelResponse, 0, len(channels))
+		for i := range channels {
+			channelItems = append(channelItems, channels[i].ToResponse())
+		}
+
+		deliveries, err := notify.NewOutbox(pool).ListByProject(ctx, projectID, settingsDeliveriesShown)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to list notification deliveries for project settings page")
+			pageError = "Failed to load notification deliveries"
+		}
+
+		deliveryItems := make([]notify.DeliveryResponse, 0, len(deliveries))
+		for i := range deliveries {
+			deliveryItems = append(deliveryItems, deliveries[i].ToResponse())
+		}
+
+		codeowners, err := ownership.NewService(pool).GetConfig(ctx, projectID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to get CODEOWNERS for project settings page")
+			pageError = "Failed to load CODEOWNERS"
+			codeowners = &ownership.Config{}
+		}
+
 		slackWebhookURLSet := project.SlackWebhookURL.Valid && project.SlackWebhookURL.String != ""
 
 		data := &TemplateData{
@@ -220,16 +267,25 @@ func HandleProjectSettingsPage(pool *pgxpool.Pool, isProduction bool) http.Handl
 			CSRFToken:       csrfToken,
 			Error:           pageError,
 			Data: map[string]interface{}{
-				"OrgID":              orgID,
-				"OrgSlug":            org.Slug,
-				"ProjectID":          projectID,
-				"ProjectName":        project.Name,
-				"ProjectSlug":        project.Slug,
-				"DefaultBranch":      project.DefaultBranch,
-				"SlackEnabled":       project.SlackEnabled,
-				"SlackWebhookURLSet": slackWebhookURLSet,
-				"APIKeys":            apiKeyItems,
-				"CanMutate":          role.CanMutate(),
+				"OrgID":                     orgID,
+				"OrgSlug":                   org.Slug,
+				"ProjectID":                 projectID,
+				"ProjectName":               project.Name,
+				"ProjectSlug":               project.Slug,
+				"DefaultBranch":             project.DefaultBranch,
+				"SlackEnabled":              project.SlackEnabled,
+				"SlackWebhookURLSet":        slackWebhookURLSet,
+				"GitHubReportMode":          project.GitHubReportMode,
+				"APIKeys":                   apiKeyItems,
+				"QuarantineRules":           quarantineItems,
+				"Channels":                  channelItems,
+				"Deliveries":                deliveryItems,
+				"NotificationCooldownHours": project.NotificationCooldownHours,
+				"DigestFrequency":           project.DigestFrequency,
+				"BrokenTestRuns":            project.BrokenTestRuns,
+				"FlakeResolveRuns":          project.FlakeResolveRuns,
+				"Codeowners":                codeowners,
+				"CanMutate":                 role.CanMutate(),
 			},
 		}
 		RenderTemplate(w, r, "project_settings.html", data)
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aliuyar1234/flakeguard/internal/auth"
+	"github.com/aliuyar1234/flakeguard/internal/signatures"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleSignaturesPage renders the failure signatures of a project.
+func HandleSignaturesPage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		req, err := signatures.ParseListRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Limit = signatures.MaxSignaturesListed
+
+		list, err := signatures.NewService(pool).List(ctx, project.ID, req, time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list failure signatures")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		csrfToken, err := auth.GenerateCSRFToken()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+		data := &TemplateData{
+			Title:           "Failure Signatures - " + project.Name,
+			UserID:          userID,
+			IsAuthenticated: true,
+			CSRFToken:       csrfToken,
+			Data: map[string]interface{}{
+				"OrgSlug":     org.Slug,
+				"ProjectSlug": project.Slug,
+				"ProjectName": project.Name,
+				"Days":        req.Days,
+				"MinTests":    req.MinTests,
+				"Signatures":  list,
+			},
+		}
+		RenderTemplate(w, r, "signatures.html", data)
+	}
+}
+
+// HandleSignaturePage renders a failure signature and the tests it affects.
+func HandleSignaturePage(pool *pgxpool.Pool, isProduction bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.GetUserID(ctx)
+
+		signatureID, err := uuid.Parse(chi.URLParam(r, "signature_id"))
+		if err != nil {
+			http.Error(w, "Invalid signature ID", http.StatusBadRequest)
+			return
+		}
+
+		org, project, ok := loadSlugProject(w, r, pool)
+		if !ok {
+			return
+		}
+
+		signature, err := signatures.NewService(pool).Get(ctx, project.ID, signatureID)
+		if err != nil {
+			if errors.Is(err, signatures.ErrSignatureNotFound) {
+				http.Error(w, "Failure signature not found", http.StatusNotFound)
+				return
+			}
+			log.Error().Err(err).Str("signature_id", signatureID.String()).Msg("Failed to get failure signature")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		csrfToken, err := auth.GenerateCSRFToken()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		auth.SetCSRFCookie(w, csrfToken, isProduction)
+
+		data := &TemplateData{
+			Title:           "Failure Signature - " + project.Name,
+			UserID:          userID,
+			IsAuthenticated: true,
+			CSRFToken:       csrfToken,
+			Data: map[string]interface{}{
+				"OrgSlug":     org.Slug,
+				"ProjectSlug": project.Slug,
+				"ProjectName": project.Name,
+				"Signature":   signature,
+			},
+		}
+		RenderTemplate(w, r, "signature.html", data)
+	}
+}
@@ -39,17 +39,29 @@ func InitTemplates(templatesDir string) error {
 		"org_list.html",
 		"org_create.html",
 		"org_settings.html",
+		"org_dashboard.html",
 		"project_list.html",
 		"project_create.html",
 		"project_settings.html",
 		"invite_accept.html",
 		"flakes_list.html",
 		"flake_detail.html",
+		"digests.html",
+		"digest.html",
+		"signatures.html",
+		"signature.html",
+		"broken_tests.html",
+		"owners.html",
+	}
+
+	// Partials are parsed with every page
+	partials := []string{
+		filepath.Join(templatesDir, "trend_chart.html"),
 	}
 
 	for _, page := range pages {
 		pagePath := filepath.Join(templatesDir, page)
-		tmpl, err := template.ParseFiles(layoutPath, pagePath)
+		tmpl, err := template.ParseFiles(append([]string{layoutPath, pagePath}, partials...)...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aliuyar1234/flakeguard/internal/flake"
+)
+
+// Trend chart dimensions in SVG user units; the chart scales to its container
+const (
+	trendChartWidth  = 720.0
+	trendChartHeight = 180.0
+	trendPlotTop     = 10.0
+	trendPlotBottom  = 160.0
+	trendPlotLeft    = 40.0
+	trendPlotRight   = 710.0
+)
+
+// trendChart is the SVG geometry of a daily trend: a bar of runs per day
+// overlaid with its failed and flaky runs, and the flake rate as a line.
+// It is computed here so the template only places elements.
+type trendChart struct {
+	Width      float64
+	Height     float64
+	PlotLeft   float64
+	PlotRight  float64
+	PlotTop    float64
+	PlotBottom float64
+	Bars       []trendBar
+	RateLine   string
+	MaxRuns    int
+	MaxRate    float64
+	FirstDate  string
+	LastDate   string
+	HasRuns    bool
+
+	// Flake rates of the first and second half of the period, to tell at a
+	// glance whether a fix reduced flakiness
+	FirstHalfRate  float64
+	SecondHalfRate float64
+}
+
+// trendBar is one day of a trend chart
+type trendBar struct {
+	X      float64
+	Width  float64
+	Runs   trendSegment
+	Failed trendSegment
+	Flaky  trendSegment
+	Title  string
+}
+
+// trendSegment is the vertical extent of a bar
+type trendSegment struct {
+	Y      float64
+	Height float64
+}
+
+// newTrendChart lays out a chart of points, oldest first
+func newTrendChart(points []flake.TrendPoint) *trendChart {
+	c := &trendChart{
+		Width:      trendChartWidth,
+		Height:     trendChartHeight,
+		PlotLeft:   trendPlotLeft,
+		PlotRight:  trendPlotRight,
+		PlotTop:    trendPlotTop,
+		PlotBottom: trendPlotBottom,
+	}
+	if len(points) == 0 {
+		return c
+	}
+	c.FirstDate = points[0].Date
+	c.LastDate = points[len(points)-1].Date
+
+	for _, p := range points {
+		c.MaxRuns = max(c.MaxRuns, p.Runs)
+		c.MaxRate = max(c.MaxRate, p.FlakeRate)
+	}
+	c.HasRuns = c.MaxRuns > 0
+	if !c.HasRuns {
+		return c
+	}
+
+	plotHeight := trendPlotBottom - trendPlotTop
+	slot := (trendPlotRight - trendPlotLeft) / float64(len(points))
+	segment := func(count int) trendSegment {
+		h := plotHeight * float64(count) / float64(c.MaxRuns)
+		return trendSegment{Y: trendPlotBottom - h, Height: h}
+	}
+
+	var line []string
+	half := len(points) / 2
+	var firstRuns, firstFlaky, secondRuns, secondFlaky int
+	for i, p := range points {
+		x := trendPlotLeft + slot*float64(i)
+		c.Bars = append(c.Bars, trendBar{
+			X:      x + slot*0.1,
+			Width:  slot * 0.8,
+			Runs:   segment(p.Runs),
+			Failed: segment(min(p.FailedRuns, p.Runs)),
+			Flaky:  segment(min(p.FlakyRuns, p.Runs)),
+			Title:  fmt.Sprintf("%s: %d runs, %d failed, %d flaky (%.1f%%)", p.Date, p.Runs, p.FailedRuns, p.FlakyRuns, p.FlakeRate*100),
+		})
+
+		if p.Runs > 0 && c.MaxRate > 0 {
+			y := trendPlotBottom - plotHeight*p.FlakeRate/c.MaxRate
+			line = append(line, fmt.Sprintf("%.1f,%.1f", x+slot/2, y))
+		}
+
+		if i < half {
+			firstRuns += p.Runs
+			firstFlaky += p.FlakyRuns
+		} else {
+			secondRuns += p.Runs
+			secondFlaky += p.FlakyRuns
+		}
+	}
+	c.RateLine = strings.Join(line, " ")
+
+	if firstRuns > 0 {
+		c.FirstHalfRate = float64(firstFlaky) / float64(firstRuns)
+	}
+	if secondRuns > 0 {
+		c.SecondHalfRate = float64(secondFlaky) / float64(secondRuns)
+	}
+	return c
+}
+
+// projectTrendChart lays out a chart of a project's series
+func projectTrendChart(points []flake.ProjectTrendPoint) *trendChart {
+	series := make([]flake.TrendPoint, len(points))
+	for i, p := range points {
+		series[i] = p.TrendPoint
+	}
+	return newTrendChart(series)
+}
+
+// Percent formats a rate between 0 and 1 as a percentage
+func (c *trendChart) Percent(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// AxisLabelX is where the run count labels end, left of the plot
+func (c *trendChart) AxisLabelX() float64 {
+	return c.PlotLeft - 6
+}
+
+// DateLabelY is the baseline of the date labels below the plot
+func (c *trendChart) DateLabelY() float64 {
+	return c.Height - 4
+}
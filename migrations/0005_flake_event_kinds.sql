@@ -0,0 +1,46 @@
+BEGIN;
+
+-- FLAKE EVENT KINDS
+-- retry_attempt:  fail->pass across attempts of the same ci_runs row (original detection mode)
+-- same_sha_rerun: fail on one ci_runs row, pass on a different ci_runs row for the same
+--                 (project, repo, sha, job_name, job_variant)
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'flake_event_kind') THEN
+    CREATE TYPE flake_event_kind AS ENUM ('retry_attempt','same_sha_rerun');
+  END IF;
+END $$;
+
+ALTER TABLE flake_events
+  ADD COLUMN IF NOT EXISTS kind flake_event_kind NOT NULL DEFAULT 'retry_attempt';
+
+-- For same_sha_rerun events, ci_run_id is the failing run and passed_ci_run_id the passing one.
+ALTER TABLE flake_events
+  ADD COLUMN IF NOT EXISTS passed_ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE CASCADE;
+
+-- Attempt ordering only applies within a single run.
+ALTER TABLE flake_events
+  DROP CONSTRAINT IF EXISTS flake_attempt_order;
+
+ALTER TABLE flake_events
+  ADD CONSTRAINT flake_attempt_order CHECK (
+    kind <> 'retry_attempt' OR passed_attempt_number > failed_attempt_number
+  );
+
+-- Only same_sha_rerun events reference a second run.
+ALTER TABLE flake_events
+  DROP CONSTRAINT IF EXISTS flake_events_passed_run_consistency;
+
+ALTER TABLE flake_events
+  ADD CONSTRAINT flake_events_passed_run_consistency CHECK (
+    (kind = 'retry_attempt' AND passed_ci_run_id IS NULL) OR
+    (kind = 'same_sha_rerun' AND passed_ci_run_id IS NOT NULL AND passed_ci_run_id <> ci_run_id)
+  );
+
+CREATE INDEX IF NOT EXISTS idx_flake_events_passed_run ON flake_events(passed_ci_run_id)
+  WHERE passed_ci_run_id IS NOT NULL;
+
+-- Lookup of sibling runs for the same commit.
+CREATE INDEX IF NOT EXISTS idx_ci_runs_project_repo_sha ON ci_runs(project_id, repo_full_name, sha);
+
+COMMIT;
@@ -0,0 +1,32 @@
+BEGIN;
+
+-- FLAKE STATS: 95% Wilson score interval around the time-decayed flake_score.
+-- flake_score_lower is the ranking key; it keeps tiny samples (1 flake in 2 runs)
+-- below well-evidenced flakes (40 in 200).
+ALTER TABLE flake_stats
+  ADD COLUMN IF NOT EXISTS flake_score_lower DOUBLE PRECISION NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS flake_score_upper DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE flake_stats
+  ADD CONSTRAINT flake_score_interval_range CHECK (
+    flake_score_lower >= 0 AND flake_score_upper <= 1 AND flake_score_lower <= flake_score_upper
+  );
+
+-- Backfill existing rows with the undecayed interval; rows are rescored with decay
+-- the next time a flake is detected for the test.
+UPDATE flake_stats
+SET
+  flake_score_lower = GREATEST(0, (
+    flake_score + 1.96 * 1.96 / (2 * total_runs_seen)
+    - 1.96 * SQRT(flake_score * (1 - flake_score) / total_runs_seen + 1.96 * 1.96 / (4 * total_runs_seen * total_runs_seen))
+  ) / (1 + 1.96 * 1.96 / total_runs_seen)),
+  flake_score_upper = LEAST(1, (
+    flake_score + 1.96 * 1.96 / (2 * total_runs_seen)
+    + 1.96 * SQRT(flake_score * (1 - flake_score) / total_runs_seen + 1.96 * 1.96 / (4 * total_runs_seen * total_runs_seen))
+  ) / (1 + 1.96 * 1.96 / total_runs_seen))
+WHERE total_runs_seen > 0;
+
+CREATE INDEX IF NOT EXISTS idx_flake_stats_score_lower_last_seen
+  ON flake_stats (flake_score_lower DESC, last_seen_at DESC);
+
+COMMIT;
@@ -0,0 +1,42 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'branch_class') THEN
+    CREATE TYPE branch_class AS ENUM ('default','pr','other');
+  END IF;
+END $$;
+
+-- FLAKE BRANCH STATS (flake_stats broken down by branch class)
+-- default: runs on projects.default_branch
+-- pr:      pull_request runs (or any run with a pr_number)
+-- other:   everything else (feature branches, tags, ...)
+-- Variants need no breakdown table: job_variant is part of the test case identity.
+-- Rows are refreshed whenever a test is rescored; 0023 backfills existing flakes.
+CREATE TABLE IF NOT EXISTS flake_branch_stats (
+  test_case_id UUID NOT NULL REFERENCES test_cases(id) ON DELETE CASCADE,
+  branch_class branch_class NOT NULL,
+  mixed_outcome_runs INT NOT NULL DEFAULT 0,
+  total_runs_seen INT NOT NULL DEFAULT 0,
+  flake_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  flake_score_lower DOUBLE PRECISION NOT NULL DEFAULT 0,
+  flake_score_upper DOUBLE PRECISION NOT NULL DEFAULT 0,
+  last_flake_at TIMESTAMPTZ NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (test_case_id, branch_class),
+  CONSTRAINT flake_branch_counts_nonnegative CHECK (mixed_outcome_runs >= 0 AND total_runs_seen >= 0),
+  CONSTRAINT flake_branch_score_range CHECK (
+    flake_score >= 0 AND flake_score <= 1 AND
+    flake_score_lower >= 0 AND flake_score_upper <= 1 AND flake_score_lower <= flake_score_upper
+  )
+);
+
+DROP TRIGGER IF EXISTS trg_flake_branch_stats_updated_at ON flake_branch_stats;
+CREATE TRIGGER trg_flake_branch_stats_updated_at
+BEFORE UPDATE ON flake_branch_stats
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_flake_branch_stats_class_score
+  ON flake_branch_stats (branch_class, flake_score_lower DESC, last_flake_at DESC);
+
+COMMIT;
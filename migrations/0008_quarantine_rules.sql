@@ -0,0 +1,48 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'quarantine_match') THEN
+    CREATE TYPE quarantine_match AS ENUM ('exact','glob');
+  END IF;
+END $$;
+
+-- QUARANTINE RULES (mute known flaky tests by test_identifier)
+-- exact: pattern must equal test_identifier
+-- glob:  pattern may use * (any run of characters) and ? (single character)
+-- Removed rules are kept (removed_at) for the audit trail.
+CREATE TABLE IF NOT EXISTS quarantine_rules (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  match_type quarantine_match NOT NULL,
+  pattern TEXT NOT NULL,
+  owner TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NULL,
+  created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  removed_at TIMESTAMPTZ NULL,
+  removed_by_user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  CONSTRAINT quarantine_rules_pattern_nonempty CHECK (length(pattern) > 0),
+  CONSTRAINT quarantine_rules_expires_after_created CHECK (expires_at IS NULL OR expires_at > created_at),
+  CONSTRAINT quarantine_rules_removed_consistency CHECK (
+    (removed_at IS NULL AND removed_by_user_id IS NULL) OR removed_at IS NOT NULL
+  )
+);
+
+DROP TRIGGER IF EXISTS trg_quarantine_rules_updated_at ON quarantine_rules;
+CREATE TRIGGER trg_quarantine_rules_updated_at
+BEFORE UPDATE ON quarantine_rules
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_quarantine_rules_project_active
+  ON quarantine_rules(project_id, created_at DESC)
+  WHERE removed_at IS NULL;
+
+-- At most one active rule per pattern within a project.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quarantine_rules_project_pattern_active
+  ON quarantine_rules(project_id, match_type, pattern)
+  WHERE removed_at IS NULL;
+
+COMMIT;
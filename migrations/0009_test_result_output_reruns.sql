@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Captured output and framework-level reruns (Surefire / pytest-rerunfailures
+-- <flakyFailure>, <flakyError>, <rerunFailure>, <rerunError>) per test result.
+-- rerun_failures counts failed in-job executions before the final outcome; a passed
+-- result with rerun_failures > 0 is a flake within a single attempt.
+ALTER TABLE test_results
+  ADD COLUMN IF NOT EXISTS system_out TEXT NULL,
+  ADD COLUMN IF NOT EXISTS system_err TEXT NULL,
+  ADD COLUMN IF NOT EXISTS properties JSONB NULL,
+  ADD COLUMN IF NOT EXISTS rerun_failures INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS rerun_failure_message TEXT NULL;
+
+ALTER TABLE test_results
+  ADD CONSTRAINT test_results_rerun_failures_nonnegative CHECK (rerun_failures >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_test_results_flaky_reruns ON test_results(ci_job_id)
+  WHERE status = 'passed' AND rerun_failures > 0;
+
+-- in_job_retry: failed and passed within one attempt via the test framework's own retries.
+-- The new enum value cannot be referenced until this transaction commits, so the
+-- constraint compares kind as text.
+ALTER TYPE flake_event_kind ADD VALUE IF NOT EXISTS 'in_job_retry';
+
+ALTER TABLE flake_events
+  DROP CONSTRAINT IF EXISTS flake_events_passed_run_consistency;
+
+ALTER TABLE flake_events
+  ADD CONSTRAINT flake_events_passed_run_consistency CHECK (
+    (kind::text IN ('retry_attempt', 'in_job_retry') AND passed_ci_run_id IS NULL) OR
+    (kind::text = 'same_sha_rerun' AND passed_ci_run_id IS NOT NULL AND passed_ci_run_id <> ci_run_id)
+  );
+
+COMMIT;
@@ -0,0 +1,47 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ingestion_status') THEN
+    CREATE TYPE ingestion_status AS ENUM ('queued','processing','succeeded','failed');
+  END IF;
+END $$;
+
+-- INGESTION QUEUE
+-- An upload is stored as a queued ingestion with its raw report files and
+-- processed by background workers. failed is the dead letter state: the report
+-- could not be parsed or max_attempts was reached. Ingestions from before the
+-- queue were processed synchronously and are marked succeeded.
+ALTER TABLE ingestions
+  ADD COLUMN IF NOT EXISTS status ingestion_status NOT NULL DEFAULT 'succeeded',
+  ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS max_attempts INT NOT NULL DEFAULT 5,
+  ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS locked_at TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS last_error TEXT NULL,
+  -- Set once results are committed; a retry then only repeats flake detection
+  ADD COLUMN IF NOT EXISTS ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS flake_events_count INT NOT NULL DEFAULT 0;
+
+ALTER TABLE ingestions ALTER COLUMN status SET DEFAULT 'queued';
+
+CREATE INDEX IF NOT EXISTS idx_ingestions_queue
+  ON ingestions(next_attempt_at)
+  WHERE status IN ('queued','processing');
+
+-- Raw report files of an ingestion, deleted once it has succeeded.
+-- format is empty when the report format is auto-detected.
+CREATE TABLE IF NOT EXISTS ingestion_payloads (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  ingestion_id UUID NOT NULL REFERENCES ingestions(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  filename TEXT NOT NULL,
+  format TEXT NOT NULL DEFAULT '',
+  content BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (ingestion_id, position)
+);
+
+COMMIT;
@@ -0,0 +1,54 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ci_provider') THEN
+    CREATE TYPE ci_provider AS ENUM ('github','gitlab','jenkins','circleci','buildkite','azure','local');
+  END IF;
+END $$;
+
+-- PROVIDER-NEUTRAL RUN IDENTITY
+-- A run is identified by its provider and an opaque provider run ID (GitHub run
+-- ID, GitLab pipeline ID, Buildkite build UUID, ...). run_number is the
+-- provider's display number and may be empty. Existing rows are GitHub runs
+-- and keep their run ID and number as text.
+ALTER TABLE ci_runs
+  ADD COLUMN IF NOT EXISTS provider ci_provider NOT NULL DEFAULT 'github',
+  ADD COLUMN IF NOT EXISTS run_id TEXT NULL,
+  ADD COLUMN IF NOT EXISTS run_number TEXT NOT NULL DEFAULT '';
+
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'ci_runs' AND column_name = 'github_run_id'
+  ) THEN
+    UPDATE ci_runs
+    SET run_id = github_run_id::text,
+        run_number = github_run_number::text
+    WHERE run_id IS NULL;
+
+    -- Also drops UNIQUE (project_id, repo_full_name, github_run_id)
+    ALTER TABLE ci_runs
+      DROP COLUMN github_run_id,
+      DROP COLUMN github_run_number;
+  END IF;
+END $$;
+
+ALTER TABLE ci_runs ALTER COLUMN run_id SET NOT NULL;
+ALTER TABLE ci_runs ALTER COLUMN provider DROP DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_runs_provider_run
+  ON ci_runs(project_id, provider, repo_full_name, run_id);
+
+-- Opaque attempt identity for providers without an attempt counter (e.g. the
+-- GitLab job ID of a retried job). Such attempts are numbered in order of
+-- arrival within their run.
+ALTER TABLE ci_run_attempts
+  ADD COLUMN IF NOT EXISTS attempt_id TEXT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_run_attempts_attempt_id
+  ON ci_run_attempts(ci_run_id, attempt_id)
+  WHERE attempt_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'github_report_mode') THEN
+    CREATE TYPE github_report_mode AS ENUM ('off','check_run','comment');
+  END IF;
+END $$;
+
+-- GITHUB PULL REQUEST REPORTING
+-- Flakes found on pull_request runs are reported back to GitHub through the
+-- configured GitHub App, either as a check run with annotations or as one
+-- upserted PR comment. Off by default.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS github_report_mode github_report_mode NOT NULL DEFAULT 'off';
+
+-- PR lookups for the report span all runs of a pull request
+CREATE INDEX IF NOT EXISTS idx_ci_runs_pr
+  ON ci_runs(project_id, repo_full_name, pr_number)
+  WHERE pr_number IS NOT NULL;
+
+COMMIT;
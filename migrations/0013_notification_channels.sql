@@ -0,0 +1,44 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'notification_channel_type') THEN
+    CREATE TYPE notification_channel_type AS ENUM ('slack','teams','webhook','email','discord');
+  END IF;
+END $$;
+
+-- NOTIFICATION CHANNELS (per-project delivery targets for flake notifications)
+-- slack, teams, discord: incoming webhook URL
+-- webhook:               any HTTP(S) endpoint; payloads are HMAC-SHA256 signed with signing_secret
+-- email:                 recipients, sent through the server's SMTP relay
+-- URLs and secrets are never returned by the API. The project Slack webhook
+-- (projects.slack_webhook_url) keeps working alongside these channels.
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  type notification_channel_type NOT NULL,
+  name TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  url TEXT NULL,
+  recipients TEXT[] NOT NULL DEFAULT '{}',
+  signing_secret TEXT NULL,
+  created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (project_id, name),
+  CONSTRAINT notification_channels_name_nonempty CHECK (length(name) > 0),
+  CONSTRAINT notification_channels_target CHECK (
+    (type = 'email' AND cardinality(recipients) > 0) OR
+    (type <> 'email' AND url IS NOT NULL)
+  ),
+  CONSTRAINT notification_channels_webhook_secret CHECK (type <> 'webhook' OR signing_secret IS NOT NULL)
+);
+
+DROP TRIGGER IF EXISTS trg_notification_channels_updated_at ON notification_channels;
+CREATE TRIGGER trg_notification_channels_updated_at
+BEFORE UPDATE ON notification_channels
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_project ON notification_channels(project_id, created_at);
+
+COMMIT;
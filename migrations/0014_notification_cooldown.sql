@@ -0,0 +1,23 @@
+BEGIN;
+
+-- NOTIFICATION COOLDOWN
+-- A test that was notified about is not notified again for
+-- notification_cooldown_hours (0 = notify on every flake event). The flakes
+-- of one CI run are sent as a single batched message.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS notification_cooldown_hours INT NOT NULL DEFAULT 24;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'projects_notification_cooldown_range') THEN
+    ALTER TABLE projects
+      ADD CONSTRAINT projects_notification_cooldown_range
+      CHECK (notification_cooldown_hours >= 0 AND notification_cooldown_hours <= 720);
+  END IF;
+END $$;
+
+-- Claimed before sending, so concurrent workers notify a test at most once
+ALTER TABLE flake_stats
+  ADD COLUMN IF NOT EXISTS last_notified_at TIMESTAMPTZ NULL;
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'notification_delivery_status') THEN
+    CREATE TYPE notification_delivery_status AS ENUM ('pending','sending','sent','failed');
+  END IF;
+END $$;
+
+-- NOTIFICATION DELIVERIES (transactional outbox)
+-- One row per message and channel, written in the same transaction as the
+-- flake events it reports and delivered by background workers with
+-- exponential backoff. failed is the dead letter state: the channel rejected
+-- the message permanently or max_attempts was reached. channel_id is NULL for
+-- the project Slack webhook (projects.slack_webhook_url). The rows double as
+-- the project's delivery log.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  channel_id UUID NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+  channel_type notification_channel_type NOT NULL,
+  channel_name TEXT NOT NULL,
+  event TEXT NOT NULL,
+  ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  message JSONB NOT NULL,
+  status notification_delivery_status NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 10,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  locked_at TIMESTAMPTZ NULL,
+  last_error TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_queue
+  ON notification_deliveries(next_attempt_at)
+  WHERE status IN ('pending','sending');
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_project_created
+  ON notification_deliveries(project_id, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'digest_frequency') THEN
+    CREATE TYPE digest_frequency AS ENUM ('off','daily','weekly');
+  END IF;
+END $$;
+
+-- FLAKE DIGESTS
+-- Periodic summary of a project's flakiness, delivered to its notification
+-- channels. Daily digests cover the previous UTC day, weekly digests the
+-- previous Monday-to-Monday week. Off by default.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS digest_frequency digest_frequency NOT NULL DEFAULT 'off';
+
+-- One row per generated digest; the unique period makes generation idempotent
+-- across instances. report holds the computed lists (see internal/digest).
+CREATE TABLE IF NOT EXISTS flake_digests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  frequency digest_frequency NOT NULL,
+  period_start TIMESTAMPTZ NOT NULL,
+  period_end TIMESTAMPTZ NOT NULL,
+  report JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (project_id, frequency, period_start),
+  CONSTRAINT flake_digests_frequency CHECK (frequency <> 'off'),
+  CONSTRAINT flake_digests_period CHECK (period_end > period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flake_digests_project_period
+  ON flake_digests(project_id, period_end DESC);
+
+-- Period queries over flake events of a project
+CREATE INDEX IF NOT EXISTS idx_flake_events_created ON flake_events(created_at);
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- TEST DAILY STATS (trend rollup)
+-- One row per test and UTC day, the day of the CI run's first ingestion.
+-- runs counts the CI runs the test ran in, failed_runs those where it failed
+-- on any attempt and flaky_runs those with a flake event. Maintained
+-- incrementally by ingestion and flake detection, and rebuilt from the raw
+-- results with `flakeguard admin backfill-trends`. Like flake_stats, rows are
+-- not removed by retention.
+CREATE TABLE IF NOT EXISTS test_daily_stats (
+  test_case_id UUID NOT NULL REFERENCES test_cases(id) ON DELETE CASCADE,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  runs INT NOT NULL DEFAULT 0,
+  failed_runs INT NOT NULL DEFAULT 0,
+  flaky_runs INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (test_case_id, day),
+  CONSTRAINT test_daily_stats_counts_nonnegative CHECK (runs >= 0 AND failed_runs >= 0 AND flaky_runs >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_daily_stats_project_day ON test_daily_stats(project_id, day);
+
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+-- FAILURE SIGNATURES
+-- A signature clusters the failures of a project whose normalized message and
+-- top stack frames are identical: numbers, UUIDs, timestamps, addresses and
+-- temp paths are stripped so the same root cause yields one fingerprint
+-- across tests and runs. Recorded at ingestion for every failed or errored
+-- result and every failed in-job rerun. Like flake_stats, rows are not
+-- removed by retention.
+CREATE TABLE IF NOT EXISTS failure_signatures (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  fingerprint TEXT NOT NULL,
+  normalized_message TEXT NOT NULL,
+  example_message TEXT NOT NULL,
+  occurrences INT NOT NULL DEFAULT 0,
+  first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (project_id, fingerprint),
+  CONSTRAINT failure_signatures_occurrences_nonnegative CHECK (occurrences >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_failure_signatures_project_last_seen ON failure_signatures(project_id, last_seen_at DESC);
+
+-- The tests a signature was seen in
+CREATE TABLE IF NOT EXISTS failure_signature_tests (
+  signature_id UUID NOT NULL REFERENCES failure_signatures(id) ON DELETE CASCADE,
+  test_case_id UUID NOT NULL REFERENCES test_cases(id) ON DELETE CASCADE,
+  occurrences INT NOT NULL DEFAULT 0,
+  first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (signature_id, test_case_id),
+  CONSTRAINT failure_signature_tests_occurrences_nonnegative CHECK (occurrences >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_failure_signature_tests_test_case ON failure_signature_tests(test_case_id);
+
+COMMIT;
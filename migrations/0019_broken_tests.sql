@@ -0,0 +1,48 @@
+BEGIN;
+
+-- BROKEN TEST DETECTION
+-- A test is broken when it failed on every attempt of broken_test_runs
+-- consecutive default-branch runs. Runs where the test was skipped do not
+-- break the streak; a run where it passed on any attempt does.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS broken_test_runs INT NOT NULL DEFAULT 3;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'projects_broken_test_runs_range') THEN
+    ALTER TABLE projects
+      ADD CONSTRAINT projects_broken_test_runs_range
+      CHECK (broken_test_runs >= 2 AND broken_test_runs <= 50);
+  END IF;
+END $$;
+
+-- One row per breakage. The suspect range is the last default-branch run
+-- where the test passed and the first of the failing streak; SHAs are kept
+-- when retention removes the runs. A breakage is fixed by the next
+-- default-branch run where the test passes, and a later streak opens a new row.
+CREATE TABLE IF NOT EXISTS broken_tests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  test_case_id UUID NOT NULL REFERENCES test_cases(id) ON DELETE CASCADE,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  failing_runs INT NOT NULL,
+  first_bad_ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  first_bad_sha TEXT NOT NULL,
+  last_good_ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  last_good_sha TEXT NULL,
+  last_failure_message TEXT NULL,
+  detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  fixed_ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  fixed_sha TEXT NULL,
+  fixed_at TIMESTAMPTZ NULL,
+  CONSTRAINT broken_tests_failing_runs_positive CHECK (failing_runs >= 1)
+);
+
+-- At most one open breakage per test
+CREATE UNIQUE INDEX IF NOT EXISTS idx_broken_tests_open
+  ON broken_tests(test_case_id) WHERE fixed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_broken_tests_project_detected
+  ON broken_tests(project_id, detected_at DESC);
+
+COMMIT;
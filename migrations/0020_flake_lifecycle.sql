@@ -0,0 +1,59 @@
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'flake_status') THEN
+    CREATE TYPE flake_status AS ENUM ('new','active','acknowledged','fixed_pending','resolved','regressed');
+  END IF;
+END $$;
+
+-- FLAKE LIFECYCLE
+-- new:           first flake of the test, not yet triaged
+-- active:        flaked again, or reopened
+-- acknowledged:  someone is looking into it
+-- fixed_pending: a fix was made and is being verified
+-- resolved:      flake_resolve_runs clean runs since the last flake (or the
+--                fix), or closed by hand
+-- regressed:     flaked again after it was resolved
+-- clean_runs counts the runs the test passed on every attempt of since its
+-- last flake, or since it was marked fixed_pending.
+ALTER TABLE flake_stats
+  ADD COLUMN IF NOT EXISTS status flake_status NOT NULL DEFAULT 'new',
+  ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS clean_runs INT NOT NULL DEFAULT 0;
+
+-- Existing flakes that flaked more than once are already active
+UPDATE flake_stats SET status = 'active' WHERE status = 'new' AND mixed_outcome_runs > 1;
+
+CREATE INDEX IF NOT EXISTS idx_flake_stats_status ON flake_stats(status);
+
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS flake_resolve_runs INT NOT NULL DEFAULT 20;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'projects_flake_resolve_runs_range') THEN
+    ALTER TABLE projects
+      ADD CONSTRAINT projects_flake_resolve_runs_range
+      CHECK (flake_resolve_runs >= 1 AND flake_resolve_runs <= 500);
+  END IF;
+END $$;
+
+-- Every status change; automatic changes have no actor
+CREATE TABLE IF NOT EXISTS flake_status_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  test_case_id UUID NOT NULL REFERENCES test_cases(id) ON DELETE CASCADE,
+  from_status flake_status NULL,
+  to_status flake_status NOT NULL,
+  reason TEXT NOT NULL,
+  actor_user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  ci_run_id UUID NULL REFERENCES ci_runs(id) ON DELETE SET NULL,
+  note TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT flake_status_history_reason CHECK (reason IN ('detected','flaked','clean_runs','manual'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_flake_status_history_test_created
+  ON flake_status_history(test_case_id, created_at DESC);
+
+COMMIT;
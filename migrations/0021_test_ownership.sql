@@ -0,0 +1,24 @@
+BEGIN;
+
+-- CODEOWNERS of a project, set in the settings or sent with an upload.
+-- path_rules map test classnames to repository paths, one
+-- "<classname prefix>=<path template>" per entry.
+CREATE TABLE IF NOT EXISTS project_codeowners (
+  project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+  content TEXT NOT NULL DEFAULT '',
+  path_rules TEXT[] NOT NULL DEFAULT '{}',
+  source TEXT NOT NULL DEFAULT 'settings',
+  updated_by_user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT project_codeowners_source CHECK (source IN ('settings','ingest'))
+);
+
+-- file_path is the source file last reported for the test, if any; owners
+-- are the CODEOWNERS owners of the test's path, empty when unowned
+ALTER TABLE test_cases
+  ADD COLUMN IF NOT EXISTS file_path TEXT NULL,
+  ADD COLUMN IF NOT EXISTS owners TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_test_cases_owners ON test_cases USING GIN (owners);
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- FLAKE SCORE DECAY
+-- Scores decay as runs age, so they are recomputed whenever a flaky test runs
+-- and by an hourly job for the rest. scored_at is when a score was last
+-- computed; existing rows start stale and are rescored by the next job run.
+ALTER TABLE flake_stats
+  ADD COLUMN IF NOT EXISTS scored_at TIMESTAMPTZ NOT NULL DEFAULT '-infinity';
+
+CREATE INDEX IF NOT EXISTS idx_flake_stats_scored_at
+  ON flake_stats (scored_at);
+
+COMMIT;
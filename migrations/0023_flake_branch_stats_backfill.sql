@@ -0,0 +1,78 @@
+BEGIN;
+
+-- FLAKE BRANCH STATS BACKFILL
+-- Breaks down every existing flake_stats row by branch class, using the same
+-- decayed weights and Wilson interval as flake.StatsService.updateBranchStats.
+WITH branch_runs AS (
+  SELECT
+    fs.test_case_id,
+    (CASE
+      WHEN cr.event = 'pull_request' OR cr.pr_number IS NOT NULL THEN 'pr'
+      WHEN cr.branch = p.default_branch THEN 'default'
+      ELSE 'other'
+    END)::branch_class AS branch_class,
+    cr.first_seen_at,
+    POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (NOW() - cr.first_seen_at)), 0) / 86400.0 / 30) AS weight,
+    EXISTS (
+      SELECT 1 FROM flake_events fe
+      WHERE fe.test_case_id = fs.test_case_id AND fe.ci_run_id = cr.id
+    ) AS mixed
+  FROM flake_stats fs
+  JOIN ci_runs cr ON cr.id IN (
+    SELECT cra.ci_run_id
+    FROM test_results tr
+    JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+    JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+    WHERE tr.test_case_id = fs.test_case_id
+  )
+  JOIN projects p ON p.id = cr.project_id
+),
+totals AS (
+  SELECT
+    test_case_id,
+    branch_class,
+    COUNT(*) AS total_runs,
+    SUM(weight) AS total_weight,
+    COUNT(*) FILTER (WHERE mixed) AS mixed_runs,
+    COALESCE(SUM(weight) FILTER (WHERE mixed), 0) AS mixed_weight,
+    MAX(first_seen_at) FILTER (WHERE mixed) AS last_flake_at
+  FROM branch_runs
+  GROUP BY test_case_id, branch_class
+),
+rates AS (
+  SELECT
+    t.*,
+    CASE
+      WHEN t.total_weight > 0 AND t.mixed_weight > 0 THEN LEAST(t.mixed_weight, t.total_weight) / t.total_weight
+    END AS p
+  FROM totals t
+)
+INSERT INTO flake_branch_stats (
+  test_case_id,
+  branch_class,
+  mixed_outcome_runs,
+  total_runs_seen,
+  flake_score,
+  flake_score_lower,
+  flake_score_upper,
+  last_flake_at
+)
+SELECT
+  r.test_case_id,
+  r.branch_class,
+  r.mixed_runs,
+  r.total_runs,
+  COALESCE(r.p, 0),
+  COALESCE(GREATEST(0, (
+    r.p + 1.96 * 1.96 / (2 * r.total_weight)
+    - 1.96 * SQRT(r.p * (1 - r.p) / r.total_weight + 1.96 * 1.96 / (4 * r.total_weight * r.total_weight))
+  ) / (1 + 1.96 * 1.96 / r.total_weight)), 0),
+  COALESCE(LEAST(1, (
+    r.p + 1.96 * 1.96 / (2 * r.total_weight)
+    + 1.96 * SQRT(r.p * (1 - r.p) / r.total_weight + 1.96 * 1.96 / (4 * r.total_weight * r.total_weight))
+  ) / (1 + 1.96 * 1.96 / r.total_weight)), 0),
+  r.last_flake_at
+FROM rates r
+ON CONFLICT (test_case_id, branch_class) DO NOTHING;
+
+COMMIT;
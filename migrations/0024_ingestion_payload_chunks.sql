@@ -0,0 +1,22 @@
+BEGIN;
+
+-- INGESTION PAYLOAD CHUNKS
+-- Raw report files are stored as ordered chunks so uploads are streamed into
+-- the queue and back out to the worker without holding a whole file in memory.
+CREATE TABLE IF NOT EXISTS ingestion_payload_chunks (
+  payload_id UUID NOT NULL REFERENCES ingestion_payloads(id) ON DELETE CASCADE,
+  seq INT NOT NULL,
+  data BYTEA NOT NULL,
+  PRIMARY KEY (payload_id, seq)
+);
+
+-- Payloads queued before this migration become a single chunk
+INSERT INTO ingestion_payload_chunks (payload_id, seq, data)
+SELECT id, 0, content
+FROM ingestion_payloads
+WHERE length(content) > 0
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE ingestion_payloads DROP COLUMN IF EXISTS content;
+
+COMMIT;
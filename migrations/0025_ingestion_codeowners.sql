@@ -0,0 +1,8 @@
+BEGIN;
+
+-- CODEOWNERS file sent with a queued upload. The worker applies it in the
+-- ingestion's transaction, so it only takes effect with the upload's results.
+ALTER TABLE ingestions
+  ADD COLUMN IF NOT EXISTS codeowners TEXT NULL;
+
+COMMIT;
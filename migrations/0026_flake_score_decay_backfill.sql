@@ -0,0 +1,49 @@
+BEGIN;
+
+-- FLAKE SCORE DECAY BACKFILL
+-- 0006 backfilled intervals from the undecayed score. Recompute existing rows
+-- with the time-decayed score and interval: each run weighs
+-- 0.5^(age_days / 30), as in flake.ComputeFlakeScore. Rows without weighted
+-- runs stay stale and are rescored by the hourly job.
+WITH weights AS (
+  SELECT
+    fs.test_case_id,
+    (
+      SELECT COALESCE(SUM(POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (NOW() - cr.first_seen_at)), 0) / 86400.0 / 30)), 0)
+      FROM ci_runs cr
+      WHERE cr.id IN (
+        SELECT cra.ci_run_id
+        FROM test_results tr
+        JOIN ci_jobs cj ON tr.ci_job_id = cj.id
+        JOIN ci_run_attempts cra ON cj.ci_run_attempt_id = cra.id
+        WHERE tr.test_case_id = fs.test_case_id
+      )
+    ) AS total_weight,
+    (
+      SELECT COALESCE(SUM(POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (NOW() - cr.first_seen_at)), 0) / 86400.0 / 30)), 0)
+      FROM ci_runs cr
+      WHERE cr.id IN (SELECT fe.ci_run_id FROM flake_events fe WHERE fe.test_case_id = fs.test_case_id)
+    ) AS mixed_weight
+  FROM flake_stats fs
+),
+rates AS (
+  SELECT test_case_id, total_weight AS n, LEAST(mixed_weight, total_weight) / total_weight AS p
+  FROM weights
+  WHERE total_weight > 0 AND mixed_weight > 0
+)
+UPDATE flake_stats fs
+SET
+  flake_score = r.p,
+  flake_score_lower = GREATEST(0, (
+    r.p + 1.96 * 1.96 / (2 * r.n)
+    - 1.96 * SQRT(r.p * (1 - r.p) / r.n + 1.96 * 1.96 / (4 * r.n * r.n))
+  ) / (1 + 1.96 * 1.96 / r.n)),
+  flake_score_upper = LEAST(1, (
+    r.p + 1.96 * 1.96 / (2 * r.n)
+    + 1.96 * SQRT(r.p * (1 - r.p) / r.n + 1.96 * 1.96 / (4 * r.n * r.n))
+  ) / (1 + 1.96 * 1.96 / r.n)),
+  scored_at = NOW()
+FROM rates r
+WHERE fs.test_case_id = r.test_case_id;
+
+COMMIT;
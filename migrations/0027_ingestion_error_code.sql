@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Machine-readable code of an ingestion's last failure, e.g. invalid_trx when
+-- a TRX report fails to parse. NULL for failures without a code.
+ALTER TABLE ingestions
+  ADD COLUMN IF NOT EXISTS error_code TEXT NULL;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- DEPRECATED GITHUB RUN COLUMNS
+-- 0011 replaced github_run_id and github_run_number with run_id and
+-- run_number. They are kept, filled for GitHub runs with numeric IDs, until
+-- the deprecated github_run_* API fields are removed, so readers of the old
+-- columns keep working through the deprecation window.
+ALTER TABLE ci_runs
+  ADD COLUMN IF NOT EXISTS github_run_id BIGINT NULL,
+  ADD COLUMN IF NOT EXISTS github_run_number BIGINT NULL;
+
+UPDATE ci_runs
+SET github_run_id = run_id::bigint,
+    github_run_number = CASE WHEN run_number ~ '^[0-9]{1,18}$' THEN run_number::bigint END
+WHERE provider = 'github'
+  AND github_run_id IS NULL
+  AND run_id ~ '^[0-9]{1,18}$';
+
+COMMIT;
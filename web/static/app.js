@@ -80,6 +80,22 @@ function serializeFormToJson(form) {
     if (!Number.isNaN(num)) payload[name] = num;
   });
 
+  // Fields marked data-list hold comma or newline separated values
+  form.querySelectorAll('[data-list][name]').forEach((el) => {
+    if (el.disabled) return;
+    const name = el.getAttribute('name');
+    const v = payload[name];
+    if (typeof v !== 'string') return;
+    payload[name] = v.split(/[,\n]/).map((item) => item.trim()).filter((item) => item !== '');
+  });
+
+  // Hidden fields marked data-boolean carry "true" or "false"
+  form.querySelectorAll('input[data-boolean][name]').forEach((el) => {
+    if (el.disabled) return;
+    const name = el.getAttribute('name');
+    if (payload[name] === 'true' || payload[name] === 'false') payload[name] = payload[name] === 'true';
+  });
+
   return payload;
 }
 
@@ -118,14 +134,17 @@ async function submitJsonForm(form) {
       return;
     }
 
+    let revealed = false;
     const tokenTarget = form.dataset.tokenTarget;
     if (tokenTarget) {
       const token =
         body?.data?.api_key?.token ??
         body?.data?.invite?.accept_url ??
         body?.data?.invite?.token ??
+        body?.data?.signing_secret ??
         body?.data?.token;
       if (token) {
+        revealed = true;
         const el = document.querySelector(tokenTarget);
         if (el) el.textContent = token;
 
@@ -143,7 +162,8 @@ async function submitJsonForm(form) {
       return;
     }
 
-    if (form.dataset.reload === 'true') {
+    // A revealed token is only shown once, so keep it on screen
+    if (form.dataset.reload === 'true' && !revealed) {
       window.location.reload();
       return;
     }